@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"compiler/colors"
+	"compiler/ctx"
+	"compiler/internal/backend/bytecode"
+	"compiler/internal/backend/cgen"
+)
+
+const buildUsage = "usage: ferret build <file> --emit=bytecode|--target=c [--out=<path>] [--cc] [--locked]"
+
+// runBuild handles 'ferret build <file> --emit=bytecode|--target=c [--out=<path>] [--cc] [--locked]':
+// the program is compiled to bytecode and written to <file>.ferc, or translated to C and written to
+// <file>.c, or to the path given with --out. With --cc the C file, which must end in .c, is compiled
+// to an executable named after it by the C compiler in $CC, cc by default.
+func runBuild(args []string) error {
+	file, emit, target, out, compile, locked := "", "", "", "", false, false
+	for _, arg := range args {
+		switch {
+		case arg == "--locked":
+			locked = true
+		case arg == "--cc":
+			compile = true
+		case strings.HasPrefix(arg, "--emit="):
+			emit = strings.TrimPrefix(arg, "--emit=")
+			if emit != "bytecode" {
+				return fmt.Errorf("unknown --emit kind '%s' for ferret build, expected bytecode", emit)
+			}
+		case strings.HasPrefix(arg, "--target="):
+			target = strings.TrimPrefix(arg, "--target=")
+			if target != "c" {
+				return fmt.Errorf("unknown --target '%s' for ferret build, expected c", target)
+			}
+		case strings.HasPrefix(arg, "--out="):
+			out = strings.TrimPrefix(arg, "--out=")
+		case !strings.HasPrefix(arg, "-") && file == "":
+			file = arg
+		default:
+			return fmt.Errorf(buildUsage)
+		}
+	}
+	if file == "" || (emit == "") == (target == "") || (compile && target == "") {
+		return fmt.Errorf(buildUsage)
+	}
+	if out == "" {
+		extension := bytecode.EXTENSION
+		if target == "c" {
+			extension = ".c"
+		}
+		out = strings.TrimSuffix(file, filepath.Ext(file)) + extension
+	}
+	if compile && filepath.Ext(out) != ".c" {
+		// The executable is named after the C file without its extension, which must not be the C file
+		return fmt.Errorf("--out must name a .c file with --cc, got '%s'", out)
+	}
+
+	fullPath, err := filepath.Abs(file)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	fullPath = filepath.ToSlash(fullPath)
+
+	context, err := ctx.NewCompilerContext(fullPath)
+	if err != nil {
+		return err
+	}
+	defer context.Destroy()
+	if !compileFiles(context, []string{fullPath}, locked) {
+		return fmt.Errorf("%s has errors, it was not built", file)
+	}
+	if target == "c" {
+		return buildC(context, out, compile)
+	}
+	program, err := bytecode.Compile(context)
+	if err != nil {
+		return err
+	}
+	if err := bytecode.Save(program, out); err != nil {
+		return err
+	}
+	colors.GREEN.Printf("Wrote %s\n", out)
+	return nil
+}
+
+// buildC writes the C translation of a program to out, and compiles it to an executable next to it
+// named out without its .c extension when compile is set
+func buildC(context *ctx.CompilerContext, out string, compile bool) error {
+	code, err := cgen.Generate(context)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(out, code, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", out, err)
+	}
+	colors.GREEN.Printf("Wrote %s\n", out)
+	if !compile {
+		return nil
+	}
+
+	cc := os.Getenv("CC")
+	if cc == "" {
+		cc = "cc"
+	}
+	executable := strings.TrimSuffix(out, filepath.Ext(out))
+	if runtime.GOOS == "windows" {
+		executable += ".exe"
+	}
+	cmd := exec.Command(cc, "-std=c99", "-O2", "-o", executable, out, "-lm")
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed to compile %s: %w", cc, out, err)
+	}
+	colors.GREEN.Printf("Wrote %s\n", executable)
+	return nil
+}
+
+// runDisasm handles 'ferret disasm <file.ferc>', which prints the instructions of a bytecode file
+func runDisasm(args []string) error {
+	if len(args) != 1 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("usage: ferret disasm <file%s>", bytecode.EXTENSION)
+	}
+	program, err := bytecode.Load(args[0])
+	if err != nil {
+		return err
+	}
+	return bytecode.Disassemble(os.Stdout, program)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunBuild(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".ferret.json": `{"name": "app"}`,
+		"main.fer": `import "app/math";
+let x = math::square(7);`,
+		"math.fer":   `fn square(n: i32) -> i32 { return n * n; }`,
+		"broken.fer": `let x: i32 = "six";`,
+	})
+	t.Chdir(root)
+
+	if err := runBuild([]string{"main.fer", "--emit=bytecode"}); err != nil {
+		t.Fatalf("runBuild() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "main.ferc")); err != nil {
+		t.Fatalf("main.ferc was not written: %v", err)
+	}
+	if err := runBuild([]string{"main.fer", "--emit=bytecode", "--out=out/app.ferc"}); err == nil {
+		t.Fatalf("runBuild() wrote into a missing directory")
+	}
+	if err := os.Mkdir(filepath.Join(root, "out"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := runBuild([]string{"main.fer", "--emit=bytecode", "--out=out/app.ferc"}); err != nil {
+		t.Fatalf("runBuild() error = %v", err)
+	}
+
+	// The C target writes main.c, and --cc compiles it to an executable
+	if err := runBuild([]string{"main.fer", "--target=c"}); err != nil {
+		t.Fatalf("runBuild() error = %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "main.c")); err != nil || !strings.Contains(string(data), "#line") {
+		t.Fatalf("main.c was not written with #line directives: %v", err)
+	}
+	if _, err := exec.LookPath("cc"); err == nil {
+		if err := runBuild([]string{"main.fer", "--target=c", "--out=out/app.c", "--cc"}); err != nil {
+			t.Fatalf("runBuild() error = %v", err)
+		}
+		if err := exec.Command(filepath.Join(root, "out", "app")).Run(); err != nil {
+			t.Fatalf("the executable failed: %v", err)
+		}
+	}
+	t.Setenv("CC", filepath.Join(root, "missing-cc"))
+	if err := runBuild([]string{"main.fer", "--target=c", "--cc"}); err == nil || !strings.Contains(err.Error(), "failed to compile main.c") {
+		t.Fatalf("runBuild() error = %v, want the C compiler to fail", err)
+	}
+
+	// The built file runs and disassembles without the sources
+	if err := os.Remove(filepath.Join(root, "math.fer")); err != nil {
+		t.Fatal(err)
+	}
+	if err := runRun([]string{"out/app.ferc"}); err != nil {
+		t.Fatalf("runRun() error = %v", err)
+	}
+	if err := runDisasm([]string{"main.ferc"}); err != nil {
+		t.Fatalf("runDisasm() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "main.ferc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, root, map[string]string{"truncated.ferc": string(data[:len(data)/2])})
+
+	tests := []struct {
+		name    string
+		run     func(args []string) error
+		args    []string
+		wantErr string
+	}{
+		{"compile error", runBuild, []string{"broken.fer", "--emit=bytecode"}, "broken.fer has errors, it was not built"},
+		{"no emit", runBuild, []string{"main.fer"}, "usage: ferret build"},
+		{"unknown emit", runBuild, []string{"main.fer", "--emit=cfg"}, "unknown --emit kind 'cfg' for ferret build"},
+		{"unknown target", runBuild, []string{"main.fer", "--target=wasm"}, "unknown --target 'wasm' for ferret build, expected c"},
+		{"emit and target", runBuild, []string{"main.fer", "--emit=bytecode", "--target=c"}, "usage: ferret build"},
+		{"cc without target", runBuild, []string{"main.fer", "--emit=bytecode", "--cc"}, "usage: ferret build"},
+		{"cc without a .c file", runBuild, []string{"main.fer", "--target=c", "--out=prog", "--cc"}, "--out must name a .c file with --cc, got 'prog'"},
+		{"unknown flag", runBuild, []string{"main.fer", "--emit=bytecode", "--fast"}, "usage: ferret build"},
+		{"disasm truncated", runDisasm, []string{"truncated.ferc"}, "truncated.ferc: invalid bytecode file: the checksum does not match"},
+		{"disasm missing", runDisasm, []string{"missing.ferc"}, "missing.ferc"},
+		{"disasm no file", runDisasm, nil, "usage: ferret disasm <file.ferc>"},
+		{"run truncated", runRun, []string{"truncated.ferc"}, "invalid bytecode file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run(tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"compiler/colors"
+	"compiler/ctx"
+	"compiler/internal/config"
+	"compiler/internal/deps"
+	"compiler/internal/frontend/lexer"
+	"compiler/internal/frontend/parser"
+	"compiler/internal/remote"
+	"compiler/internal/semantic/resolver"
+	"compiler/internal/semantic/typecheck"
+	"compiler/internal/utils/fs"
+)
+
+// runCheck handles 'ferret check [--workspace] [--locked]': every .fer file of the current project is
+// checked, or of every member of its workspace, each project after the members it imports
+func runCheck(args []string) error {
+	workspace, locked := false, false
+	for _, arg := range args {
+		switch arg {
+		case "--workspace":
+			workspace = true
+		case "--locked":
+			locked = true
+		default:
+			return fmt.Errorf("usage: ferret check [--workspace] [--locked]")
+		}
+	}
+
+	var projects []*config.ProjectConfig
+	if workspace {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current working directory: %w", err)
+		}
+		root, err := config.FindWorkspaceRoot(cwd)
+		if err != nil {
+			return err
+		}
+		ws, err := config.LoadWorkspace(root)
+		if err != nil {
+			return err
+		}
+		if projects, err = ws.LoadMembers(); err != nil {
+			return err
+		}
+	} else {
+		project, err := loadProject()
+		if err != nil {
+			return err
+		}
+		projects = []*config.ProjectConfig{project}
+	}
+
+	roots := make([]string, len(projects))
+	for i, project := range projects {
+		roots[i] = project.ProjectRoot
+	}
+	files := make(map[*config.ProjectConfig][]string, len(projects))
+	for _, project := range projects {
+		list, err := projectFiles(project.ProjectRoot, roots)
+		if err != nil {
+			return err
+		}
+		files[project] = list
+	}
+	order, imports, err := memberOrder(projects, files)
+	if err != nil {
+		return err
+	}
+
+	failed := make(map[*config.ProjectConfig]bool)
+	for _, project := range order {
+		name := project.ImportRoot()
+		if i := slices.IndexFunc(imports[project], func(dep *config.ProjectConfig) bool { return failed[dep] }); i >= 0 {
+			colors.YELLOW.Printf("Skipped %s: it imports %s, which has errors\n", name, imports[project][i].ImportRoot())
+			failed[project] = true
+			continue
+		}
+		if len(files[project]) == 0 {
+			fmt.Printf("Skipped %s: no %s files\n", name, fs.EXT)
+			continue
+		}
+		colors.BLUE.Printf("Checking %s (%s)\n", name, project.ProjectRoot)
+		if !checkFiles(files[project], locked) {
+			failed[project] = true
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d project(s) have errors", len(failed), len(order))
+	}
+	colors.GREEN.Printf("%d project(s) checked\n", len(order))
+	return nil
+}
+
+// checkFiles parses, resolves and type checks the files of one project, and reports whether they are free of errors
+func checkFiles(files []string, locked bool) bool {
+	context, err := ctx.NewCompilerContext(files[0])
+	if err != nil {
+		colors.RED.Println(err)
+		return false
+	}
+	defer context.Destroy()
+	return compileFiles(context, files, locked)
+}
+
+// compileFiles runs the analysis of checkFiles in a context created for the first file
+func compileFiles(context *ctx.CompilerContext, files []string, locked bool) (ok bool) {
+	prepareContext(context, locked)
+
+	defer func() {
+		// Syntax and critical errors stop the check with a panic after they are reported
+		r := recover()
+		if r != nil {
+			ok = false
+		}
+		if r != nil && !context.Reports.HasErrors() {
+			// No report explains the panic, e.g. a lexer error: show it instead of a passing status
+			colors.RED.Println(r)
+			return
+		}
+		context.Reports.DisplayAll()
+	}()
+
+	for _, file := range files {
+		if !isParsed(context, file) {
+			parser.NewParser(file, context, false).Parse()
+		}
+	}
+	resolver.ResolveModules(context, false)
+	if !context.Reports.HasErrors() {
+		typecheck.CheckModules(context, false)
+	}
+	if context.Reports.HasErrors() {
+		return false
+	}
+	if err := context.SaveLockFile(); err != nil {
+		colors.RED.Printf("Failed to write the lockfile: %v\n", err)
+	}
+	return true
+}
+
+// isParsed reports whether a file was parsed already, as a module an earlier file imports
+func isParsed(context *ctx.CompilerContext, file string) bool {
+	for _, module := range context.Modules {
+		for _, program := range module.Files {
+			if program.FullPath == file {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// projectFiles lists the .fer files of a project, leaving out hidden directories like the module cache,
+// vendor/ and the projects nested in it
+func projectFiles(root string, projectRoots []string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(filepath.FromSlash(root), func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		slashPath := filepath.ToSlash(path)
+		if d.IsDir() {
+			nested := slashPath != root && (slices.Contains(projectRoots, slashPath) || config.IsProjectRoot(slashPath))
+			if slashPath != root && (strings.HasPrefix(d.Name(), ".") || d.Name() == deps.VENDOR_DIR || nested) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) == fs.EXT {
+			files = append(files, slashPath)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// memberOrder sorts projects so every one comes after the others it imports, found from the import
+// statements of their files. It also returns the projects each one imports.
+func memberOrder(projects []*config.ProjectConfig, files map[*config.ProjectConfig][]string) ([]*config.ProjectConfig, map[*config.ProjectConfig][]*config.ProjectConfig, error) {
+	byName := make(map[string]*config.ProjectConfig, len(projects))
+	for _, project := range projects {
+		byName[project.ImportRoot()] = project
+	}
+	imports := make(map[*config.ProjectConfig][]*config.ProjectConfig, len(projects))
+	for _, project := range projects {
+		for _, file := range files[project] {
+			for _, importPath := range importPaths(file) {
+				if target, ok := project.ImportAlias(importPath); ok {
+					importPath = target
+				}
+				root, _, _ := strings.Cut(importPath, "/")
+				if dep, ok := byName[root]; ok && dep != project && !slices.Contains(imports[project], dep) && !remote.IsRemote(importPath) {
+					imports[project] = append(imports[project], dep)
+				}
+			}
+		}
+	}
+
+	var order []*config.ProjectConfig
+	state := make(map[*config.ProjectConfig]int) // 1 while its imports are visited, 2 once ordered
+	var stack []string
+	var visit func(project *config.ProjectConfig) error
+	visit = func(project *config.ProjectConfig) error {
+		switch state[project] {
+		case 1:
+			cycle := append(stack[slices.Index(stack, project.ImportRoot()):], project.ImportRoot())
+			return fmt.Errorf("workspace members import each other: %s", strings.Join(cycle, " -> "))
+		case 2:
+			return nil
+		}
+		state[project] = 1
+		stack = append(stack, project.ImportRoot())
+		for _, dep := range imports[project] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[project] = 2
+		order = append(order, project)
+		return nil
+	}
+	for _, project := range projects {
+		if err := visit(project); err != nil {
+			return nil, nil, err
+		}
+	}
+	return order, imports, nil
+}
+
+// importPaths returns the import paths a file names, as written
+func importPaths(file string) []string {
+	tokens := lexer.Tokenize(file, false)
+	var paths []string
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i].Kind == lexer.IMPORT_TOKEN && tokens[i+1].Kind == lexer.STRING_TOKEN {
+			paths = append(paths, tokens[i+1].Value)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"compiler/ctx"
+	"compiler/internal/config"
+)
+
+// writeFiles writes files below root, keyed by their slash separated relative paths
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCheckWorkspace(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{"members in dependency order", map[string]string{
+			"app/main.fer":             `import "geo/shapes"; let a: f64 = shapes::area(2.0);`,
+			"libs/geometry/shapes.fer": `fn area(r: f64) -> f64 { return r * r; }`,
+		}, ""},
+		{"error in a dependency", map[string]string{
+			"app/main.fer":             `import "geo/shapes"; let a: f64 = shapes::area(2.0);`,
+			"libs/geometry/shapes.fer": `fn area(r: f64) -> f64 { let w: i32 = "wide"; return r * r; }`,
+		}, "2 of 2 project(s) have errors"},
+		{"error in a dependent", map[string]string{
+			"app/main.fer":             `import "geo/shapes"; let a: i32 = shapes::area(2.0);`,
+			"libs/geometry/shapes.fer": `fn area(r: f64) -> f64 { return r * r; }`,
+		}, "1 of 2 project(s) have errors"},
+		{"import cycle", map[string]string{
+			"app/main.fer":             `import "geo/shapes";`,
+			"libs/geometry/shapes.fer": `import "app/main";`,
+		}, "workspace members import each other: app -> geo -> app"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, map[string]string{
+				config.WORKSPACE_FILE:        `{"members": ["app", "libs/geometry"]}`,
+				"app/.ferret.json":           `{}`,
+				"libs/geometry/.ferret.json": `{"name": "geo"}`,
+			})
+			writeFiles(t, root, tt.files)
+			t.Chdir(filepath.Join(root, "app"))
+
+			err := runCheck([]string{"--workspace"})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("runCheck() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("runCheck() error = %v, want %q", err, tt.wantErr)
+			}
+			if _, err := os.Stat(filepath.Join(root, ".ferret", "modules")); tt.wantErr == "" && err != nil {
+				t.Errorf("expected the members to share the workspace cache: %v", err)
+			}
+		})
+	}
+
+	t.Run("not in a workspace", func(t *testing.T) {
+		t.Chdir(t.TempDir())
+		if err := runCheck([]string{"--workspace"}); err == nil || !strings.Contains(err.Error(), config.WORKSPACE_FILE) {
+			t.Errorf("expected a missing workspace file to fail, got %v", err)
+		}
+	})
+}
+
+// captureStdout returns what f prints to the standard output
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+	defer func() { os.Stdout = stdout }()
+	f()
+	w.Close()
+	return <-done
+}
+
+func TestCompileFilesLexerError(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{".ferret.json": `{"name": "app"}`, "main.fer": "let x = 1 $ 2;"})
+	file := filepath.ToSlash(filepath.Join(root, "main.fer"))
+
+	ok := true
+	out := captureStdout(t, func() {
+		context, err := ctx.NewCompilerContext(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer context.Destroy()
+		ok = compileFiles(context, []string{file}, false)
+	})
+	if ok {
+		t.Fatal("compileFiles() = true for a file the lexer rejects")
+	}
+	if !strings.Contains(out, "Unrecognized token at "+file+":1:11") || strings.Contains(out, "Passed") {
+		t.Errorf("compileFiles() printed %q, want the lexer error", out)
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"compiler/colors"
+	"compiler/internal/cache"
+	"compiler/internal/config"
+	"compiler/internal/deps"
+	"compiler/internal/lockfile"
+	"compiler/internal/remote"
+)
+
+// subcommands run instead of compiling a file, keyed by the first argument
+var subcommands = map[string]func(args []string) error{
+	"cache":  runCache,
+	"add":    runAdd,
+	"remove": runRemove,
+	"update": runUpdate,
+	"deps":   runDeps,
+	"vendor": runVendor,
+	"check":  runCheck,
+	"config": runConfig,
+	"run":    runRun,
+	"build":  runBuild,
+	"disasm": runDisasm,
+}
+
+// newFetcher creates the fetcher dependency commands download with, tests replace it
+var newFetcher = func() remote.Fetcher { return remote.NewHTTPFetcher() }
+
+// loadProject loads the config of the project the working directory is in
+func loadProject() (*config.ProjectConfig, error) {
+	root, err := findProject()
+	if err != nil {
+		return nil, err
+	}
+	return config.LoadProjectConfig(root)
+}
+
+// findProject returns the root of the project the working directory is in
+func findProject() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current working directory: %w", err)
+	}
+	// FindProjectRoot starts from the directory of a file, any name inside the working directory does
+	return config.FindProjectRoot(filepath.Join(cwd, config.CONFIG_FILE))
+}
+
+// runCache handles 'ferret cache clean|list|verify' on the module cache of the current project
+func runCache(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: ferret cache clean|list|verify")
+	}
+	project, err := loadProject()
+	if err != nil {
+		return err
+	}
+	moduleCache, err := cache.Open(project.CachePath())
+	if err != nil && args[0] != "clean" {
+		return err
+	}
+	if moduleCache == nil {
+		// A corrupted index can still be cleaned
+		moduleCache = &cache.Cache{Dir: project.CachePath()}
+	}
+
+	switch args[0] {
+	case "clean":
+		if err := moduleCache.Clean(); err != nil {
+			return fmt.Errorf("failed to clean the cache: %w", err)
+		}
+		colors.GREEN.Printf("Removed the module cache at %s\n", moduleCache.Dir)
+	case "list":
+		entries := moduleCache.Entries()
+		if len(entries) == 0 {
+			fmt.Println("The module cache is empty")
+		}
+		for _, entry := range entries {
+			fmt.Printf("%s  %8d  %s\n", entry.Hash[:12], entry.Size, entry.Key)
+		}
+	case "verify":
+		problems := moduleCache.Verify()
+		for _, problem := range problems {
+			colors.RED.Println(problem)
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("%d problem(s) in the module cache, run 'ferret cache clean' to start over", len(problems))
+		}
+		colors.GREEN.Printf("%d cached file(s) verified\n", len(moduleCache.Entries()))
+	default:
+		return fmt.Errorf("unknown cache command '%s', expected clean, list or verify", args[0])
+	}
+	return nil
+}
+
+// openDependencies opens the current project for the dependency commands
+func openDependencies() (*deps.Project, error) {
+	project, err := loadProject()
+	if err != nil {
+		return nil, err
+	}
+	if !project.Remote.Enabled {
+		return nil, fmt.Errorf("remote dependencies are disabled by remote.enabled in %s", config.CONFIG_FILE)
+	}
+	return deps.OpenProject(project, newFetcher())
+}
+
+// runAdd handles 'ferret add <module>[@version]...'
+func runAdd(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: ferret add <module>[@version]...")
+	}
+	project, err := openDependencies()
+	if err != nil {
+		return err
+	}
+	for _, spec := range args {
+		dep, err := project.Add(spec)
+		if err != nil {
+			return err
+		}
+		colors.GREEN.Printf("Added %s\n", dep)
+	}
+	return nil
+}
+
+// runRemove handles 'ferret remove <module>...'
+func runRemove(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: ferret remove <module>...")
+	}
+	project, err := openDependencies()
+	if err != nil {
+		return err
+	}
+	for _, path := range args {
+		if err := project.Remove(path); err != nil {
+			return err
+		}
+		colors.GREEN.Printf("Removed %s\n", path)
+	}
+	return nil
+}
+
+// runUpdate handles 'ferret update [module...]', without modules every dependency is updated
+func runUpdate(args []string) error {
+	project, err := openDependencies()
+	if err != nil {
+		return err
+	}
+	updates, err := project.Update(args...)
+	if err != nil {
+		return err
+	}
+	if len(updates) == 0 {
+		fmt.Println("No dependencies to update")
+	}
+	for _, update := range updates {
+		colors.GREEN.Printf("Updated %s to %s: %d file(s), %d changed\n", update.Dependency, update.Version, update.Files, update.Changed)
+	}
+	return nil
+}
+
+// runDeps handles 'ferret deps [--tree]'
+func runDeps(args []string) error {
+	if len(args) > 1 || (len(args) == 1 && args[0] != "--tree") {
+		return fmt.Errorf("usage: ferret deps [--tree]")
+	}
+	project, err := openDependencies()
+	if err != nil {
+		return err
+	}
+	if len(args) == 1 {
+		return project.Tree(os.Stdout)
+	}
+	for _, dep := range project.Config.Dependencies.List() {
+		fmt.Println(dep)
+	}
+	return nil
+}
+
+// runVendor handles 'ferret vendor [--check]', with --check vendor/ is compared with ferret.lock instead
+func runVendor(args []string) error {
+	if len(args) > 1 || (len(args) == 1 && args[0] != "--check") {
+		return fmt.Errorf("usage: ferret vendor [--check]")
+	}
+	project, err := openDependencies()
+	if err != nil {
+		return err
+	}
+	if len(args) == 1 {
+		problems := deps.VerifyVendor(project.Config.ProjectRoot, project.Store.Lock)
+		for _, problem := range problems {
+			colors.RED.Println(problem)
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("%d problem(s) in %s, run 'ferret vendor' to vendor the files %s pins", len(problems), deps.VENDOR_DIR, lockfile.FILE)
+		}
+		colors.GREEN.Printf("%s matches %s\n", deps.VENDOR_DIR, lockfile.FILE)
+		return nil
+	}
+
+	count, err := project.Vendor()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		fmt.Printf("%s pins no remote files, compile the project first\n", lockfile.FILE)
+		return nil
+	}
+	colors.GREEN.Printf("Vendored %d file(s) into %s\n", count, deps.VENDOR_DIR)
+	return nil
+}
+
+// runConfig handles 'ferret config migrate', which upgrades the .ferret.json of the current project
+// to the schema of this compiler. It does not load the config, which may not be valid before.
+func runConfig(args []string) error {
+	if len(args) != 1 || args[0] != "migrate" {
+		return fmt.Errorf("usage: ferret config migrate")
+	}
+	root, err := findProject()
+	if err != nil {
+		return err
+	}
+	changes, err := config.MigrateProjectConfig(root)
+	for _, change := range changes {
+		colors.GREEN.Printf("Migrated %s: %s\n", config.CONFIG_FILE, change)
+	}
+	if err != nil {
+		return fmt.Errorf("%s needs to be fixed by hand:\n%w", config.CONFIG_FILE, err)
+	}
+	if len(changes) == 0 {
+		fmt.Printf("%s is up to date\n", config.CONFIG_FILE)
+	}
+	return nil
+}
@@ -5,30 +5,44 @@ import (
 	"os"
 	"path/filepath"
 	"runtime/debug"
+	"slices"
+	"strings"
 
 	"compiler/colors"
 	"compiler/ctx"
 	"compiler/internal/config"
+	"compiler/internal/deps"
 	"compiler/internal/frontend/parser"
 
 	//"compiler/internal/semantic"
 	// "strings"
 
-	"compiler/internal/semantic/analyzer"
+	"compiler/internal/semantic/cfg"
 	"compiler/internal/semantic/resolver"
 	"compiler/internal/semantic/typecheck"
 	//"compiler/internal/semantic/typecheck"
 )
 
-func Compile(filePath string, isDebugEnabled bool) *ctx.CompilerContext {
+const usage = "Usage: ferret <filename> [--debug] [--emit=cfg] [--locked] | ferret init [path] | ferret cache clean|list|verify | ferret add|remove|update <module>[@version] | ferret deps [--tree] | ferret vendor [--check] | ferret check [--workspace] [--locked] | ferret config migrate | ferret run <file|file.ferc> [--locked] | ferret build <file> --emit=bytecode|--target=c [--out=<path>] [--cc] [--locked] | ferret disasm <file.ferc>"
+
+// emitKinds are the debugging outputs --emit=<kind> can write next to each source file
+var emitKinds = []string{"cfg"}
+
+// Compile compiles a file and the modules it imports, printing the reports. It fails when the project
+// cannot be loaded, e.g. because its .ferret.json is invalid.
+func Compile(filePath string, isDebugEnabled bool, emit string, locked bool) (*ctx.CompilerContext, error) {
 	fullPath, err := filepath.Abs(filePath)
 	if err != nil {
-		panic(fmt.Errorf("failed to get absolute path: %w", err))
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
 	}
 
 	fullPath = filepath.ToSlash(fullPath) // Ensure forward slashes for consistency
 
-	context := ctx.NewCompilerContext(fullPath)
+	context, err := ctx.NewCompilerContext(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	prepareContext(context, locked)
 
 	defer func() {
 		context.Reports.DisplayAll()
@@ -44,18 +58,15 @@ func Compile(filePath string, isDebugEnabled bool) *ctx.CompilerContext {
 
 	if program == nil {
 		colors.RED.Println("Failed to parse the program.")
-		return context
+		return context, nil
 	}
 
 	if isDebugEnabled {
 		colors.BLUE.Printf("---------- [Parsing done] ----------\n")
 	}
 
-	// Run resolver
-	anz := analyzer.NewAnalyzerNode(program, context, isDebugEnabled)
-
-	// -- Resolve the program
-	resolver.ResolveProgram(anz)
+	// -- Resolve every module once, imports first
+	resolver.ResolveModules(context, isDebugEnabled)
 
 	if context.Reports.HasErrors() {
 		panic("Compilation stopped due to errors")
@@ -66,8 +77,7 @@ func Compile(filePath string, isDebugEnabled bool) *ctx.CompilerContext {
 	}
 
 	// --- Type Checking ---
-	// Type check the entry point program (which will handle imports internally)
-	typecheck.CheckProgram(anz)
+	typecheck.CheckModules(context, isDebugEnabled)
 
 	if context.Reports.HasErrors() {
 		panic("Compilation stopped due to type checking errors")
@@ -77,7 +87,57 @@ func Compile(filePath string, isDebugEnabled bool) *ctx.CompilerContext {
 		colors.GREEN.Println("---------- [Type Checking done] ----------")
 	}
 
-	return context
+	if err := context.SaveLockFile(); err != nil {
+		colors.RED.Printf("Failed to write the lockfile: %v\n", err)
+	}
+
+	if emit == "cfg" {
+		emitCFG(context)
+	}
+
+	return context, nil
+}
+
+// prepareContext sets how a compilation resolves remote imports
+func prepareContext(context *ctx.CompilerContext, locked bool) {
+	context.Locked = locked
+	// A project with a vendor directory compiles from it, without the network
+	context.Vendored = deps.HasVendor(context.ProjectRoot)
+	if context.Vendored {
+		if lock, err := context.LockFile(); err == nil {
+			for _, problem := range deps.VerifyVendor(context.ProjectRoot, lock) {
+				colors.YELLOW.Printf("Warning: %s\n", problem)
+			}
+		}
+	}
+}
+
+// emitCFG writes the control-flow graphs of every module file as Graphviz DOT to <file>.cfg.dot
+func emitCFG(context *ctx.CompilerContext) {
+	for _, name := range context.ModuleNames() {
+		for _, program := range context.Modules[name].Files {
+			path := program.FullPath + ".cfg.dot"
+			if err := os.WriteFile(filepath.FromSlash(path), []byte(cfg.DOT(cfg.BuildProgram(program))), 0644); err != nil {
+				colors.RED.Printf("Failed to write control-flow graph: %v\n", err)
+				continue
+			}
+			colors.BLUE.Printf("Control-flow graph written to %s\n", path)
+		}
+	}
+}
+
+// parseEmit returns the kind requested with --emit=<kind>, or "" when there is none
+func parseEmit(args []string) (string, error) {
+	emit := ""
+	for _, arg := range args {
+		if kind, found := strings.CutPrefix(arg, "--emit="); found {
+			if !slices.Contains(emitKinds, kind) {
+				return "", fmt.Errorf("unknown --emit kind '%s', expected one of: %s", kind, strings.Join(emitKinds, ", "))
+			}
+			emit = kind
+		}
+	}
+	return emit, nil
 }
 
 func parseArgs() (string, bool, bool, string) {
@@ -111,12 +171,26 @@ func parseArgs() (string, bool, bool, string) {
 
 func main() {
 	if len(os.Args) < 2 {
-		fmt.Println("Usage: ferret <filename> [--debug] | ferret init [path]")
+		fmt.Println(usage)
 		os.Exit(1)
 	}
 
+	if run, found := subcommands[os.Args[1]]; found {
+		if err := run(os.Args[2:]); err != nil {
+			colors.RED.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	filename, debug, initProject, initPath := parseArgs()
 
+	emit, err := parseEmit(os.Args[1:])
+	if err != nil {
+		colors.RED.Println(err)
+		os.Exit(1)
+	}
+
 	// Handle init command
 	if initProject {
 		projectRoot := initPath
@@ -140,7 +214,7 @@ func main() {
 
 	// Check for filename argument
 	if filename == "" {
-		fmt.Println("Usage: ferret <filename> [--debug] | ferret init [path]")
+		fmt.Println(usage)
 		os.Exit(1)
 	}
 
@@ -148,7 +222,11 @@ func main() {
 		colors.BLUE.Println("Debug mode enabled")
 	}
 
-	context := Compile(filename, debug)
+	context, err := Compile(filename, debug, emit, slices.Contains(os.Args[1:], "--locked"))
+	if err != nil {
+		colors.RED.Println(err)
+		os.Exit(1)
+	}
 
 	// Only destroy and print modules if context is not nil
 	if context != nil {
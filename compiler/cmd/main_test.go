@@ -4,6 +4,11 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"compiler/internal/cache"
+	"compiler/internal/config"
+	"compiler/internal/lockfile"
+	"compiler/internal/remote"
 )
 
 const (
@@ -200,3 +205,139 @@ func TestInitFunctionality(t *testing.T) {
 		t.Error("Config file should not exist yet (we only parsed args)")
 	}
 }
+
+func TestParseEmit(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantEmit string
+		wantErr  bool
+	}{
+		{name: "no emit flag", args: []string{TEST_FILE}, wantEmit: ""},
+		{name: "emit cfg", args: []string{TEST_FILE, "--emit=cfg"}, wantEmit: "cfg"},
+		{name: "emit before filename", args: []string{"--emit=cfg", TEST_FILE, DEBUG_FLAG}, wantEmit: "cfg"},
+		{name: "unknown kind", args: []string{TEST_FILE, "--emit=llvm"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emit, err := parseEmit(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseEmit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if emit != tt.wantEmit {
+				t.Errorf("parseEmit() = %q, want %q", emit, tt.wantEmit)
+			}
+		})
+	}
+}
+
+func TestRunCache(t *testing.T) {
+	projectDir := t.TempDir()
+	if err := config.CreateDefaultProjectConfig(projectDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(projectDir)
+
+	cachePath := filepath.Join(projectDir, ".ferret", "modules")
+	moduleCache, _ := cache.Open(cachePath)
+	path, err := moduleCache.Put("github.com/user/repo/lib.fer", []byte("fn lib() {}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{{"list"}, {"verify"}} {
+		if err := runCache(args); err != nil {
+			t.Errorf("runCache(%q) error = %v", args, err)
+		}
+	}
+
+	os.WriteFile(filepath.FromSlash(path), []byte("fn changed() {}"), 0644)
+	if err := runCache([]string{"verify"}); err == nil {
+		t.Error("expected verify to fail on an edited file")
+	}
+
+	if err := runCache([]string{"clean"}); err != nil {
+		t.Fatalf("runCache(clean) error = %v", err)
+	}
+	if _, err := os.Stat(cachePath); !os.IsNotExist(err) {
+		t.Error("expected the cache to be removed")
+	}
+
+	if err := runCache([]string{"prune"}); err == nil {
+		t.Error("expected an error for an unknown cache command")
+	}
+}
+
+// repoFetcher serves one repository, github.com/user/lib, on its default branch
+type repoFetcher map[string]string
+
+func (f repoFetcher) Fetch(repo, ref, file string) ([]byte, error) {
+	if content, ok := f[file]; ok && repo == "user/lib" && ref == remote.DefaultBranch {
+		return []byte(content), nil
+	}
+	return nil, remote.ErrNotFound
+}
+
+func TestDependencyCommands(t *testing.T) {
+	projectDir := t.TempDir()
+	if err := config.CreateDefaultProjectConfig(projectDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(projectDir)
+	defer func(previous func() remote.Fetcher) { newFetcher = previous }(newFetcher)
+	newFetcher = func() remote.Fetcher { return repoFetcher{".ferret.json": "{}"} }
+
+	if err := runAdd([]string{"github.com/user/lib"}); err != nil {
+		t.Fatalf("runAdd() error = %v", err)
+	}
+	cfg, _ := config.LoadProjectConfig(projectDir)
+	if len(cfg.Dependencies.Modules) != 1 || cfg.Dependencies.Modules[0] != "github.com/user/lib" {
+		t.Errorf("expected the dependency in .ferret.json, got %v", cfg.Dependencies.Modules)
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, lockfile.FILE)); err != nil {
+		t.Errorf("expected %s to be written: %v", lockfile.FILE, err)
+	}
+
+	for _, run := range []func() error{
+		func() error { return runDeps(nil) },
+		func() error { return runDeps([]string{"--tree"}) },
+		func() error { return runUpdate(nil) },
+		func() error { return runVendor(nil) },
+		func() error { return runVendor([]string{"--check"}) },
+		func() error { return runRemove([]string{"github.com/user/lib"}) },
+	} {
+		if err := run(); err != nil {
+			t.Errorf("command failed: %v", err)
+		}
+	}
+
+	if err := runAdd(nil); err == nil {
+		t.Error("expected add without a module to fail")
+	}
+	if err := runVendor([]string{"--check"}); err == nil {
+		t.Error("expected the vendored files of a removed dependency to be reported")
+	}
+	if err := runDeps([]string{"--graph"}); err == nil {
+		t.Error("expected an unknown deps flag to fail")
+	}
+}
+
+func TestRunConfig(t *testing.T) {
+	projectDir := t.TempDir()
+	os.WriteFile(filepath.Join(projectDir, config.CONFIG_FILE), []byte(`{"compiler": {"version": "0.1.0"}, "ProjectRoot": ""}`), 0644)
+	t.Chdir(projectDir)
+
+	if _, err := loadProject(); err == nil {
+		t.Fatal("expected the old config to be rejected")
+	}
+	if err := runConfig([]string{"migrate"}); err != nil {
+		t.Fatalf("runConfig(migrate) error = %v", err)
+	}
+	if _, err := loadProject(); err != nil {
+		t.Errorf("loadProject() after migrating error = %v", err)
+	}
+	if err := runConfig([]string{"check"}); err == nil {
+		t.Error("expected an unknown config command to fail")
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"compiler/ctx"
+	"compiler/internal/backend/bytecode"
+	"compiler/internal/backend/interp"
+)
+
+// runRun handles 'ferret run <file> [--locked]': the file and the modules it imports are compiled,
+// and the program is interpreted when they have no errors. A .ferc file built before runs on the VM.
+func runRun(args []string) error {
+	file, locked := "", false
+	for _, arg := range args {
+		switch {
+		case arg == "--locked":
+			locked = true
+		case !strings.HasPrefix(arg, "-") && file == "":
+			file = arg
+		default:
+			return fmt.Errorf("usage: ferret run <file> [--locked]")
+		}
+	}
+	if file == "" {
+		return fmt.Errorf("usage: ferret run <file> [--locked]")
+	}
+
+	if filepath.Ext(file) == bytecode.EXTENSION {
+		program, err := bytecode.Load(file)
+		if err != nil {
+			return err
+		}
+		return bytecode.New(program).Run()
+	}
+
+	fullPath, err := filepath.Abs(file)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	fullPath = filepath.ToSlash(fullPath)
+
+	context, err := ctx.NewCompilerContext(fullPath)
+	if err != nil {
+		return err
+	}
+	defer context.Destroy()
+	if !compileFiles(context, []string{fullPath}, locked) {
+		return fmt.Errorf("%s has errors, it was not run", file)
+	}
+	return interp.New(context).Run()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		args    []string
+		wantErr string
+	}{
+		{"runs", `let x = 6 * 7;`, []string{"main.fer"}, ""},
+		{"runtime error", `fn half(n: i32) -> i32 { return n / 0; }
+let x = half(4);`, []string{"main.fer"}, "runtime error: integer division by zero\n    at half"},
+		{"compile error", `let x: i32 = "six";`, []string{"main.fer"}, "main.fer has errors, it was not run"},
+		{"no file", ``, nil, "usage: ferret run"},
+		{"unknown flag", ``, []string{"main.fer", "--fast"}, "usage: ferret run"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, map[string]string{".ferret.json": `{"name": "app"}`, "main.fer": tt.source})
+			t.Chdir(root)
+
+			err := runRun(tt.args)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("runRun() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("runRun() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunInvalidConfig(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{".ferret.json": `{"name": "app", "target": "c"}`, "main.fer": `let x = 1;`})
+	t.Chdir(root)
+
+	// The located problems of the file are the error, instead of a panic
+	for _, err := range []error{runRun([]string{"main.fer"}), runBuild([]string{"main.fer", "--emit=bytecode"})} {
+		if err == nil || !strings.Contains(err.Error(), ".ferret.json:1:17: unknown key 'target'") {
+			t.Errorf("error = %v, want the config error", err)
+		}
+	}
+}
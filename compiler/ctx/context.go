@@ -2,23 +2,76 @@ package ctx
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"compiler/colors"
+	"compiler/internal/cache"
 	"compiler/internal/config"
+	"compiler/internal/deps"
 	"compiler/internal/frontend/ast"
+	"compiler/internal/lockfile"
+	"compiler/internal/remote"
 	"compiler/internal/report"
 	"compiler/internal/semantic"
+	"compiler/std"
 )
 
 var contextCreated = false
 
+// ModulePhase is the last analysis phase a module has been through
+type ModulePhase int
+
+const (
+	PHASE_PARSED ModulePhase = iota
+	PHASE_RESOLVED
+	PHASE_TYPECHECKED
+)
+
 type Module struct {
-	AST         *ast.Program
+	AST         *ast.Program   // the first file of the module
+	Files       []*ast.Program // every file of the module, a directory module has one per .fer file
+	Dir         string         // the directory of a module declared with 'mod', empty for a single-file module
 	SymbolTable *semantic.SymbolTable
+	Exports     *semantic.SymbolTable              // the public top-level symbols, all other modules can reach through module::name
+	ImportScope *semantic.SymbolTable              // names brought in by import lists, the parent scope of SymbolTable
+	Scopes      map[ast.Node]*semantic.SymbolTable // local scopes (function bodies, blocks) keyed by their owning node
+	ImportUses  map[string]int                     // import alias -> number of alias::name references
+	NameUses    map[string]int                     // imported name -> number of references
+	// ImportedFrom maps every name in ImportScope to the import statement that brought it in
+	ImportedFrom map[string]*ast.ImportStmt
+	// Order lists the top-level nodes in evaluation order, set by the resolver: imports and types, then
+	// statements with global initializers sorted by dependency, then functions and methods
+	Order []ast.Node
+	Phase ModulePhase // so every phase runs once per module, however many modules import it
+
+	fileOf map[ast.Node]*ast.Program // top-level node -> the file declaring it
+}
+
+// FullPath is the path imports of the module resolve to: its directory, or its only file
+func (m *Module) FullPath() string {
+	if m.Dir != "" {
+		return m.Dir
+	}
+	return m.AST.FullPath
+}
+
+// FileOf returns the file of the module a top-level node is declared in, or nil when it is not one of them
+func (m *Module) FileOf(node ast.Node) *ast.Program {
+	if m.fileOf == nil {
+		m.fileOf = make(map[ast.Node]*ast.Program)
+		for _, file := range m.Files {
+			for _, n := range file.Nodes {
+				m.fileOf[n] = file
+			}
+		}
+	}
+	return m.fileOf[node]
 }
 
 type CompilerContext struct {
@@ -37,6 +90,104 @@ type CompilerContext struct {
 	ProjectConfig *config.ProjectConfig
 	ProjectRoot   string
 	RemoteConfigs map[string]bool
+	// Fetcher downloads remote modules missing from the cache, nil uses remote.NewHTTPFetcher
+	Fetcher remote.Fetcher
+
+	// Locked refuses remote files that ferret.lock does not pin yet, instead of adding them
+	Locked bool
+	// Vendored resolves remote imports from the copies in vendor/ first, see deps.VENDOR_DIR
+	Vendored bool
+
+	moduleCache  *cache.Cache
+	lock         *lockfile.Lockfile
+	selection    *deps.Selection
+	selectionErr error
+	members      []*config.ProjectConfig
+	membersErr   error
+}
+
+// LockFile loads ferret.lock from the project root on first use
+func (c *CompilerContext) LockFile() (*lockfile.Lockfile, error) {
+	if c.lock != nil {
+		return c.lock, nil
+	}
+	lock, err := lockfile.Load(c.ProjectRoot)
+	if err != nil {
+		return nil, err
+	}
+	c.lock = lock
+	return lock, nil
+}
+
+// Store returns the store remote imports are fetched through, with the cache, lockfile and fetcher of the compilation
+func (c *CompilerContext) Store() (*deps.Store, error) {
+	moduleCache, err := c.ModuleCache()
+	if err != nil {
+		return nil, err
+	}
+	lock, err := c.LockFile()
+	if err != nil {
+		return nil, err
+	}
+	if c.Fetcher == nil {
+		c.Fetcher = remote.NewHTTPFetcher()
+	}
+	return &deps.Store{Cache: moduleCache, Fetcher: c.Fetcher, Lock: lock, Locked: c.Locked}, nil
+}
+
+// RemoteVersion returns the version a remote repository is fetched at. Versions are selected for the
+// whole dependency graph of the project, and of the other members of its workspace, on first use,
+// so every module of a repository is fetched at the same one.
+func (c *CompilerContext) RemoteVersion(repo remote.Module) (string, error) {
+	if c.selection == nil && c.selectionErr == nil {
+		store, err := c.Store()
+		if err != nil {
+			return "", err
+		}
+		roots := []deps.Root{{Name: c.ImportRoot()}}
+		if c.ProjectConfig != nil {
+			roots[0].Dependencies = c.ProjectConfig.Dependencies.List()
+		}
+		// Modules of other workspace members are built with their own dependencies
+		members, err := c.Members()
+		if err != nil {
+			return "", err
+		}
+		for _, member := range members {
+			if member.ProjectRoot != c.ProjectRoot {
+				roots = append(roots, deps.Root{Name: member.ImportRoot(), Dependencies: member.Dependencies.List()})
+			}
+		}
+		c.selection, c.selectionErr = store.SelectAll(roots, true)
+	}
+	if c.selectionErr != nil {
+		return "", c.selectionErr
+	}
+	return c.selection.Version(repo), nil
+}
+
+// SaveLockFile writes ferret.lock when the compilation pinned new files
+func (c *CompilerContext) SaveLockFile() error {
+	if c.lock == nil || c.Locked {
+		return nil
+	}
+	return c.lock.Save()
+}
+
+// ModuleCache opens the cache at CachePath on first use, it lasts between compilations
+func (c *CompilerContext) ModuleCache() (*cache.Cache, error) {
+	if c.moduleCache != nil {
+		return c.moduleCache, nil
+	}
+	if c.CachePath == "" {
+		return nil, fmt.Errorf("no cache path configured")
+	}
+	moduleCache, err := cache.Open(c.CachePath)
+	if err != nil {
+		return nil, err
+	}
+	c.moduleCache = moduleCache
+	return moduleCache, nil
 }
 
 func (c *CompilerContext) GetConfigFile(configFilepath string) *config.ProjectConfig {
@@ -59,10 +210,7 @@ func (c *CompilerContext) GetConfigFile(configFilepath string) *config.ProjectCo
 }
 
 func (c *CompilerContext) SetRemoteConfig(configFilepath string, data []byte) error {
-	if c.RemoteConfigs == nil {
-		c.RemoteConfigs = make(map[string]bool)
-	}
-	c.RemoteConfigs[configFilepath] = true
+	c.UseRemoteConfig(configFilepath)
 	err := os.MkdirAll(filepath.Dir(configFilepath), 0755)
 	if err != nil {
 		return err
@@ -75,6 +223,15 @@ func (c *CompilerContext) SetRemoteConfig(configFilepath string, data []byte) er
 	return nil
 }
 
+// UseRemoteConfig records the config file of a remote repository that is already in the cache
+func (c *CompilerContext) UseRemoteConfig(configFilepath string) {
+	if c.RemoteConfigs == nil {
+		c.RemoteConfigs = make(map[string]bool)
+	}
+	c.RemoteConfigs[configFilepath] = true
+}
+
+// FindNearestRemoteConfig returns the config of the remote repository a module import path belongs to
 func (c *CompilerContext) FindNearestRemoteConfig(logicalPath string) *config.ProjectConfig {
 
 	logicalPath = filepath.ToSlash(logicalPath)
@@ -86,19 +243,19 @@ func (c *CompilerContext) FindNearestRemoteConfig(logicalPath string) *config.Pr
 	logicalPath = filepath.ToSlash(logicalPath)
 	parts := strings.Split(logicalPath, "/")
 
+	// The cached configs by the path they configure, without the version they were fetched at
+	configs := make(map[string]string, len(c.RemoteConfigs))
+	for configPath := range c.RemoteConfigs {
+		if dir, ok := c.remoteImportPath(filepath.Dir(configPath)); ok {
+			configs[dir] = configPath
+		}
+	}
+
 	// Start from full path, walk up to github.com/user/repo
 	for i := len(parts); i >= 3; i-- {
 		prefix := strings.Join(parts[:i], "/")
-		if _, exists := c.RemoteConfigs[prefix]; exists {
-			data, err := os.ReadFile(filepath.FromSlash(prefix))
-			if err != nil {
-				continue
-			}
-			var cfg config.ProjectConfig
-			if err := json.Unmarshal(data, &cfg); err != nil {
-				continue
-			}
-			return &cfg
+		if cfg := c.GetConfigFile(configs[prefix]); cfg != nil {
+			return cfg
 		}
 	}
 	return nil
@@ -159,6 +316,45 @@ func (c *CompilerContext) ModuleNames() []string {
 	return names
 }
 
+// ModuleOrder returns the import paths of all modules, each one after the modules it imports
+func (c *CompilerContext) ModuleOrder() []string {
+	importPaths := make(map[string]string, len(c.Modules)) // full path -> import path
+	for importPath, module := range c.Modules {
+		importPaths[module.FullPath()] = importPath
+		for _, file := range module.Files {
+			importPaths[file.FullPath] = importPath
+		}
+	}
+
+	names := c.ModuleNames()
+	slices.Sort(names)
+
+	visited := make(map[string]bool, len(names))
+	order := make([]string, 0, len(names))
+	var visit func(importPath string)
+	visit = func(importPath string) {
+		if visited[importPath] {
+			return
+		}
+		// Marked before the imports are visited, so an import cycle cannot recurse forever.
+		// The parser reports cycles, so there should not be any here.
+		visited[importPath] = true
+		for _, file := range c.Modules[importPath].Files {
+			for _, dep := range c.DepGraph[file.FullPath] {
+				if depImportPath, ok := importPaths[dep]; ok && depImportPath != importPath {
+					visit(depImportPath)
+				}
+			}
+		}
+		order = append(order, importPath)
+	}
+
+	for _, name := range names {
+		visit(name)
+	}
+	return order
+}
+
 func (c *CompilerContext) HasModule(importPath string) bool {
 	if c.Modules == nil {
 		return false
@@ -167,17 +363,31 @@ func (c *CompilerContext) HasModule(importPath string) bool {
 	return exists
 }
 
+// AddModule adds a parsed file to the module at importPath, the first file creates the module.
+// Every file of a directory module shares the module's namespace.
 func (c *CompilerContext) AddModule(importPath string, module *ast.Program) {
 	if c.Modules == nil {
 		c.Modules = make(map[string]*Module)
 	}
-	if _, exists := c.Modules[importPath]; exists {
-		return
-	}
 	if module == nil {
 		panic(fmt.Sprintf("Cannot add nil module for '%s'\n", importPath))
 	}
-	c.Modules[importPath] = &Module{AST: module, SymbolTable: semantic.NewSymbolTable(c.Builtins)}
+	if existing, exists := c.Modules[importPath]; exists {
+		if slices.ContainsFunc(existing.Files, func(file *ast.Program) bool { return file.FullPath == module.FullPath }) {
+			return
+		}
+		existing.Files = append(existing.Files, module)
+		existing.fileOf = nil
+		return
+	}
+	importScope := semantic.NewSymbolTable(c.Builtins)
+	c.Modules[importPath] = &Module{
+		AST:          module,
+		Files:        []*ast.Program{module},
+		SymbolTable:  semantic.NewSymbolTable(importScope),
+		ImportScope:  importScope,
+		ImportedFrom: make(map[string]*ast.ImportStmt),
+	}
 }
 
 // IsModuleParsing checks if a module is currently being parsed
@@ -239,35 +449,39 @@ func (c *CompilerContext) FinishParsing(importPath string) {
 	}
 }
 
-func NewCompilerContext(entrypointFullpath string) *CompilerContext {
+// NewCompilerContext creates the context compiling the project the entry point belongs to. It fails when
+// the project has no .ferret.json or the file is invalid, the problems of an invalid file are ConfigErrors.
+func NewCompilerContext(entrypointFullpath string) (*CompilerContext, error) {
 	if contextCreated {
 		panic("CompilerContext already created, cannot create a new one")
 	}
-	contextCreated = true
 
 	// Load project configuration
 	root, err := config.FindProjectRoot(entrypointFullpath)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	projectConfig, err := config.LoadProjectConfig(root)
+	var configErrs config.ConfigErrors
+	if errors.As(err, &configErrs) {
+		return nil, configErrs // Located already, each on its own line
+	}
 	if err != nil {
-		panic(fmt.Errorf("failed to load project config: %w", err))
+		return nil, fmt.Errorf("failed to load project config: %w", err)
 	}
 
 	//get the entry point relative to the project root
 	entryPoint, err := filepath.Rel(root, entrypointFullpath)
 	if err != nil {
-		panic(fmt.Errorf("failed to get relative path for entry point: %w", err))
+		return nil, fmt.Errorf("failed to get relative path for entry point: %w", err)
 	}
 	entryPoint = filepath.ToSlash(entryPoint) // Ensure forward slashes for consistency
 
-	// Use cache path from project config
-	cachePath := filepath.Join(root, projectConfig.Cache.Path)
-	cachePath = filepath.ToSlash(cachePath)
+	cachePath := projectConfig.CachePath()
 	os.MkdirAll(cachePath, 0755)
 
+	contextCreated = true
 	return &CompilerContext{
 		EntryPoint:    entryPoint,
 		Builtins:      semantic.AddPreludeSymbols(semantic.NewSymbolTable(nil)), // Initialize built-in symbols
@@ -276,7 +490,7 @@ func NewCompilerContext(entrypointFullpath string) *CompilerContext {
 		CachePath:     cachePath,
 		ProjectConfig: projectConfig,
 		ProjectRoot:   root,
-	}
+	}, nil
 }
 
 func (c *CompilerContext) Destroy() {
@@ -288,11 +502,9 @@ func (c *CompilerContext) Destroy() {
 	c.Modules = nil
 	c.Reports = nil
 	c.DepGraph = nil
-
-	// Optionally, clear the cache directory
-	if c.CachePath != "" {
-		os.RemoveAll(c.CachePath)
-	}
+	c.moduleCache = nil
+	c.lock = nil
+	// The cache is kept for the next compilation, 'ferret cache clean' removes it
 }
 
 // AddDepEdge adds an edge from importer to imported in the dependency graph
@@ -365,20 +577,156 @@ func (c *CompilerContext) DetectCycle(start string) ([]string, bool) {
 	return cycle, found
 }
 
-func (c *CompilerContext) FullPathToImportPath(fullPath string) string {
-	relPath, err := filepath.Rel(c.ProjectRoot, fullPath)
+// StdPath is the directory the bundled standard library is written to when a module imports it,
+// in the cache when there is one
+func (c *CompilerContext) StdPath() string {
+	if c.CachePath != "" {
+		return filepath.ToSlash(filepath.Join(c.CachePath, std.Root))
+	}
+	return filepath.ToSlash(filepath.Join(os.TempDir(), "ferret", std.Root))
+}
+
+// cachedImportPath returns the import path of a standard library or remote module file written
+// to the cache, or "" for any other file
+func (c *CompilerContext) cachedImportPath(fullPath string) string {
+	if name, ok := modulePathIn(c.StdPath(), fullPath); ok {
+		return std.Root + "/" + name
+	}
+	if importPath, ok := c.remoteImportPath(fullPath); ok {
+		return importPath
+	}
+	return ""
+}
+
+// remoteImportPath returns the import path of a vendored or cached remote file, or of a directory
+// holding them. Remote files are cached by version, github.com/user/repo@v1/lib.fer is imported as
+// github.com/user/repo/lib, vendored files are not.
+func (c *CompilerContext) remoteImportPath(fullPath string) (string, bool) {
+	if c.Vendored {
+		if importPath, ok := modulePathIn(c.VendorDir(), fullPath); ok && remote.IsRemote(importPath) {
+			return importPath, true
+		}
+	}
+	if c.CachePath != "" {
+		if importPath, ok := modulePathIn(c.CachePath, fullPath); ok && remote.IsRemote(importPath) {
+			return remote.StripVersion(importPath), true
+		}
+	}
+	return "", false
+}
+
+// VendorDir is the directory remote files are vendored into
+func (c *CompilerContext) VendorDir() string {
+	return filepath.ToSlash(filepath.Join(c.ProjectRoot, deps.VENDOR_DIR))
+}
+
+// modulePathIn returns the slash separated path of a file inside dir, without the extension
+func modulePathIn(dir, fullPath string) (string, bool) {
+	relPath, err := filepath.Rel(dir, fullPath)
 	if err != nil || strings.HasPrefix(relPath, "..") {
-		return ""
+		return "", false
 	}
 	relPath = filepath.ToSlash(relPath)
-	moduleName := strings.TrimSuffix(relPath, filepath.Ext(relPath))
-	rootName := filepath.Base(c.ProjectRoot)
-	return rootName + "/" + moduleName
+	return strings.TrimSuffix(relPath, filepath.Ext(relPath)), true
+}
+
+// ImportRoot is the first part of the import paths of the project's own modules, see config.ProjectConfig.ImportRoot
+func (c *CompilerContext) ImportRoot() string {
+	if c.ProjectConfig != nil && c.ProjectConfig.Name != "" {
+		return c.ProjectConfig.Name
+	}
+	return filepath.Base(c.ProjectRoot)
+}
+
+// ExpandImportPath returns the import path a module is known by, with an alias from the imports of
+// .ferret.json replaced. A target in std/ or on the remote host is an import path, any other target is
+// a path inside the project. The aliases are the project's, imports in remote and standard library
+// files are left as written.
+func (c *CompilerContext) ExpandImportPath(importPath, importerFullPath string) string {
+	if c.cachedImportPath(importerFullPath) != "" {
+		return importPath
+	}
+	// A file of another workspace member uses that member's aliases
+	project, _, name, ok := c.projectOf(importerFullPath)
+	if !ok {
+		project, name = c.ProjectConfig, c.ImportRoot()
+	}
+	if project == nil {
+		return importPath
+	}
+	target, ok := project.ImportAlias(importPath)
+	if !ok {
+		return importPath
+	}
+	if remote.IsRemote(target) || target == std.Root || strings.HasPrefix(target, std.Root+"/") {
+		return target
+	}
+	// Cleaned as an absolute path, so a target cannot climb out of the project
+	if inner := strings.TrimPrefix(path.Clean("/"+target), "/"); inner != "" {
+		return name + "/" + inner
+	}
+	return name
+}
+
+// Members returns the configs of the projects in the workspace of the project, nil outside a workspace
+func (c *CompilerContext) Members() ([]*config.ProjectConfig, error) {
+	if c.ProjectConfig == nil || c.ProjectConfig.Workspace == nil {
+		return nil, nil
+	}
+	if c.members == nil && c.membersErr == nil {
+		c.members, c.membersErr = c.ProjectConfig.Workspace.LoadMembers()
+	}
+	return c.members, c.membersErr
+}
+
+// ProjectRootOf returns the root of the project whose modules start with importRoot, the current
+// project or another member of its workspace
+func (c *CompilerContext) ProjectRootOf(importRoot string) (string, bool) {
+	if importRoot == c.ImportRoot() {
+		return c.ProjectRoot, true
+	}
+	members, _ := c.Members()
+	for _, member := range members {
+		if member.ImportRoot() == importRoot {
+			return member.ProjectRoot, true
+		}
+	}
+	return "", false
+}
+
+// projectOf returns the project a file belongs to, the current one or another member of its workspace,
+// with its root and import root. A member nested in another project owns the files below it.
+func (c *CompilerContext) projectOf(fullPath string) (project *config.ProjectConfig, root, name string, ok bool) {
+	if _, inside := modulePathIn(c.ProjectRoot, fullPath); inside && c.ProjectRoot != "" {
+		project, root, name, ok = c.ProjectConfig, c.ProjectRoot, c.ImportRoot(), true
+	}
+	members, _ := c.Members()
+	for _, member := range members {
+		if _, inside := modulePathIn(member.ProjectRoot, fullPath); inside && len(member.ProjectRoot) > len(root) {
+			project, root, name, ok = member, member.ProjectRoot, member.ImportRoot(), true
+		}
+	}
+	return project, root, name, ok
+}
+
+func (c *CompilerContext) FullPathToImportPath(fullPath string) string {
+	// The cache can be inside the project, so cached modules are checked first
+	if importPath := c.cachedImportPath(fullPath); importPath != "" {
+		return importPath
+	}
+	_, root, name, ok := c.projectOf(fullPath)
+	if !ok {
+		return ""
+	}
+	moduleName, _ := modulePathIn(root, fullPath)
+	return name + "/" + moduleName
 }
 
 func (c *CompilerContext) FullPathToModuleName(fullPath string) string {
-	relPath, err := filepath.Rel(c.ProjectRoot, fullPath)
-	if err != nil || strings.HasPrefix(relPath, "..") {
+	if importPath := c.cachedImportPath(fullPath); importPath != "" {
+		return path.Base(importPath)
+	}
+	if _, _, _, ok := c.projectOf(fullPath); !ok {
 		return ""
 	}
 	filename := filepath.Base(fullPath)
@@ -134,6 +134,25 @@ func TestCycleDetection(t *testing.T) {
 	}
 }
 
+func TestModuleOrder(t *testing.T) {
+	ctx := &CompilerContext{}
+	for _, name := range []string{"app/a", "app/b", "app/c", "app/d"} {
+		ctx.AddModule(name, &ast.Program{FullPath: "/" + name + ".fer", ImportPath: name})
+	}
+
+	// Diamond: a imports b and c, both import d
+	ctx.DepGraph = map[string][]string{
+		"/app/a.fer": {"/app/b.fer", "/app/c.fer"},
+		"/app/b.fer": {"/app/d.fer"},
+		"/app/c.fer": {"/app/d.fer"},
+	}
+
+	expected := []string{"app/d", "app/b", "app/c", "app/a"}
+	if order := ctx.ModuleOrder(); !reflect.DeepEqual(order, expected) {
+		t.Errorf("Expected order %v, got %v", expected, order)
+	}
+}
+
 func TestFullPathToImportPath(t *testing.T) {
 	ctx := &CompilerContext{
 		ProjectRoot: "/project/root",
@@ -0,0 +1,80 @@
+package bytecode
+
+import (
+	"io"
+	"testing"
+
+	"compiler/internal/backend/interp"
+)
+
+var benchmarks = []struct {
+	name  string
+	input string
+}{
+	{"fib", `fn fib(n: i32) -> i32 {
+    if n < 2 {
+        return n;
+    }
+    return fib(n - 1) + fib(n - 2);
+}
+let x = fib(20);`},
+	{"loops", `let total: i64 = 0;
+for let i = 0; i < 200; i++ {
+    for let j = 0; j < 100; j++ {
+        let rest = (i + j) % 3;
+        if rest == 0 {
+            continue;
+        }
+        total = total + i * j;
+    }
+}`},
+	{"closures", `fn counter() -> fn() -> i32 {
+    let count = 0;
+    return fn() -> i32 {
+        count = count + 1;
+        return count;
+    };
+}
+let next = counter();
+let last = 0;
+while last < 10000 {
+    last = next();
+}`},
+	{"arrays", `let a = [0, 0, 0, 0, 0, 0, 0, 0, 0, 0];
+for let round = 0; round < 1000; round++ {
+    for let i = 0; i < 10; i++ {
+        a[i] = a[i] + round;
+    }
+}`},
+}
+
+// BenchmarkPrograms runs each program with the interpreter walking the AST and on the VM, the
+// compilation to bytecode is not timed as a program is compiled once
+func BenchmarkPrograms(b *testing.B) {
+	for _, bm := range benchmarks {
+		context := checkProject(b, map[string]string{"main.fer": bm.input})
+		program, err := Compile(context)
+		if err != nil {
+			b.Fatalf("Compile() error = %v", err)
+		}
+
+		b.Run(bm.name+"/ast", func(b *testing.B) {
+			for b.Loop() {
+				in := interp.New(context)
+				in.Stdout = io.Discard
+				if err := in.Run(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(bm.name+"/bytecode", func(b *testing.B) {
+			for b.Loop() {
+				vm := New(program)
+				vm.Stdout = io.Discard
+				if err := vm.Run(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
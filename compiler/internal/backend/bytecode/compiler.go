@@ -0,0 +1,429 @@
+// Package bytecode lowers the type checked AST of a compiler context to a compact instruction set,
+// and runs it on a stack based virtual machine. Values and their operators are the ones of the
+// interp package, so a program prints the same under both.
+package bytecode
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"compiler/ctx"
+	"compiler/internal/backend/interp"
+	"compiler/internal/frontend/ast"
+	"compiler/internal/semantic"
+	"compiler/internal/source"
+	"compiler/internal/types"
+)
+
+// CompileError is a construct the compiler cannot lower, the type checker rejects most of them first
+type CompileError struct {
+	File     string
+	Position source.Position
+	Message  string
+}
+
+func (e *CompileError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Position.Line, e.Position.Column, e.Message)
+}
+
+// local is a variable in a stack slot of the function being compiled
+type local struct {
+	name     string
+	depth    int
+	typ      int  // values stored into the variable are converted to it
+	captured bool // a closure captures it, so it is closed instead of popped at the end of its scope
+}
+
+// loop is a loop being compiled, break and continue jump out of it
+type loop struct {
+	locals    int   // the number of locals when the body started, break and continue pop the others
+	breaks    []int // the jumps to patch to the end of the loop
+	continues []int // the jumps to patch to the post statement of a for loop
+	start     int   // where a while loop checks its condition again
+	isFor     bool
+}
+
+// funcState is a function being compiled
+type funcState struct {
+	enclosing    *funcState
+	function     *Function
+	locals       []local
+	upvalueTypes []int
+	depth        int
+	loops        []*loop
+	constants    map[interp.Value]int
+}
+
+type compiler struct {
+	ctx        *ctx.CompilerContext
+	program    *Program
+	globals    map[string]map[string]int // import path -> name -> index in Program.Globals
+	typeIndex  map[any]int
+	files      map[string]int
+	module     *ctx.Module
+	importPath string // the import path of module
+	file       *ast.Program
+	fn         *funcState
+}
+
+// Compile lowers every module of a context the type checker found no errors in
+func Compile(context *ctx.CompilerContext) (program *Program, err error) {
+	c := &compiler{
+		ctx:       context,
+		program:   &Program{},
+		globals:   make(map[string]map[string]int),
+		typeIndex: make(map[any]int),
+		files:     make(map[string]int),
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			compileErr, ok := r.(*CompileError)
+			if !ok {
+				panic(r)
+			}
+			program, err = nil, compileErr
+		}
+	}()
+
+	order := context.ModuleOrder()
+	// Every global exists before any code is compiled, functions may use the ones declared after them
+	for _, importPath := range order {
+		c.declareGlobals(importPath, context.Modules[importPath])
+	}
+	for _, importPath := range order {
+		c.compileModule(importPath, context.Modules[importPath])
+	}
+	return c.program, nil
+}
+
+// topLevel returns the top-level nodes of a module in the order the resolver decided they run in
+func topLevel(m *ctx.Module) []ast.Node {
+	if m.Order != nil {
+		return m.Order
+	}
+	var nodes []ast.Node
+	for _, file := range m.Files {
+		nodes = append(nodes, file.Nodes...)
+	}
+	return nodes
+}
+
+// enter makes the file a top-level node of the module is written in the current one
+func (c *compiler) enter(importPath string, m *ctx.Module, node ast.Node) {
+	c.module, c.importPath = m, importPath
+	c.file = m.FileOf(node)
+	if c.file == nil {
+		c.file = m.AST
+	}
+}
+
+func (c *compiler) declareGlobals(importPath string, m *ctx.Module) {
+	c.globals[importPath] = make(map[string]int)
+	for _, node := range topLevel(m) {
+		c.enter(importPath, m, node)
+		switch n := node.(type) {
+		case *ast.FunctionDecl:
+			global := Global{Kind: GlobalFunction, Module: importPath, Name: n.Identifier.Name}
+			if n.Function.Body == nil {
+				global.Kind = GlobalNative
+				for _, param := range n.Function.Params {
+					global.Params = append(global.Params, c.typeOf(param.Type))
+				}
+			}
+			c.declareGlobal(global)
+		case *ast.VarDeclStmt:
+			for _, v := range n.Variables {
+				typ := NoType
+				if v.ExplicitType != nil {
+					typ = c.typeOf(v.ExplicitType)
+				}
+				c.declareGlobal(Global{Kind: GlobalVar, Module: importPath, Name: v.Identifier.Name, Type: typ})
+			}
+		}
+	}
+}
+
+func (c *compiler) declareGlobal(global Global) {
+	c.globals[global.Module][global.Name] = len(c.program.Globals)
+	c.program.Globals = append(c.program.Globals, global)
+}
+
+// compileModule compiles the functions and methods of a module, and its other top-level nodes into its Init function
+func (c *compiler) compileModule(importPath string, m *ctx.Module) {
+	init := &Function{Name: "<" + importPath + ">"}
+	initIndex := c.addFunction(init)
+	c.fn = &funcState{function: init, locals: []local{{typ: NoType}}, constants: make(map[interp.Value]int)}
+
+	for _, node := range topLevel(m) {
+		c.enter(importPath, m, node)
+		switch n := node.(type) {
+		case *ast.FunctionDecl:
+			if n.Function.Body != nil {
+				c.program.Globals[c.globals[importPath][n.Identifier.Name]].Function = c.function(n.Identifier.Name, n.Function, "", true)
+			}
+		case *ast.MethodDecl:
+			c.method(n)
+		default:
+			c.statement(node)
+		}
+	}
+	c.enter(importPath, m, m.AST)
+	c.emit(m.AST, OpReturn, 0)
+	c.program.Modules = append(c.program.Modules, Module{ImportPath: importPath, Init: initIndex})
+}
+
+func (c *compiler) method(n *ast.MethodDecl) {
+	if n.Receiver == nil {
+		return
+	}
+	receiver := c.typeOf(n.Receiver.Type)
+	structType, ok := c.program.Types[receiver].(*semantic.StructType)
+	if !ok {
+		return
+	}
+	c.program.Methods = append(c.program.Methods, Method{
+		Type:     receiver,
+		Name:     n.Method.Name,
+		Function: c.function(string(structType.Name)+"."+n.Method.Name, n.Function, n.Receiver.Identifier.Name, true),
+		RRef:     n.IsRRef,
+	})
+}
+
+func (c *compiler) addFunction(fn *Function) int {
+	c.program.Functions = append(c.program.Functions, fn)
+	return len(c.program.Functions) - 1
+}
+
+// function compiles a function literal. Slot 0 of its frame holds the receiver of a method and the
+// closure itself otherwise. A top-level function captures nothing, it reads the globals.
+func (c *compiler) function(name string, lit *ast.FunctionLiteral, receiver string, topLevel bool) int {
+	fn := &Function{Name: name}
+	index := c.addFunction(fn)
+	fs := &funcState{enclosing: c.fn, function: fn, depth: 1, constants: make(map[interp.Value]int)}
+	if topLevel {
+		fs.enclosing = nil
+	}
+	fs.locals = append(fs.locals, local{name: receiver, depth: 1, typ: NoType})
+	for _, param := range lit.Params {
+		typ := c.typeOf(param.Type)
+		fn.Params = append(fn.Params, typ)
+		fs.locals = append(fs.locals, local{name: param.Identifier.Name, depth: 1, typ: typ})
+	}
+	for _, result := range lit.ReturnType {
+		fn.Results = append(fn.Results, c.typeOf(result))
+	}
+
+	saved := c.fn
+	c.fn = fs
+	if lit.Body != nil {
+		for _, node := range lit.Body.Nodes {
+			c.statement(node)
+		}
+	}
+	c.emit(lit, OpReturn, 0)
+	c.fn = saved
+	return index
+}
+
+// fail stops the compilation with an error at node
+func (c *compiler) fail(node ast.Node, format string, args ...any) {
+	err := &CompileError{Message: fmt.Sprintf(format, args...)}
+	if c.file != nil {
+		err.File = c.file.FullPath
+	}
+	if node != nil && node.Loc() != nil && node.Loc().Start != nil {
+		err.Position = *node.Loc().Start
+	}
+	panic(err)
+}
+
+// emit appends an instruction compiled from node to the current function
+func (c *compiler) emit(node ast.Node, op Opcode, operands ...int) int {
+	chunk := &c.fn.function.Chunk
+	offset := len(chunk.Code)
+	c.mark(node, offset)
+	chunk.Code = append(chunk.Code, byte(op))
+	for _, operand := range operands {
+		if operand < 0 || operand > math.MaxUint16 {
+			c.fail(node, "%s operand %d does not fit in 16 bits, the function is too large", op, operand)
+		}
+		chunk.Code = binary.BigEndian.AppendUint16(chunk.Code, uint16(operand))
+	}
+	return offset
+}
+
+// mark adds a line for the instruction at offset when its position differs from the previous one
+func (c *compiler) mark(node ast.Node, offset int) {
+	if node == nil || node.Loc() == nil || node.Loc().Start == nil {
+		return
+	}
+	line := Line{Offset: offset, File: c.fileIndex(c.file.FullPath), Position: *node.Loc().Start}
+	chunk := &c.fn.function.Chunk
+	if n := len(chunk.Lines); n > 0 {
+		last := &chunk.Lines[n-1]
+		if last.File == line.File && last.Position == line.Position {
+			return
+		}
+		if last.Offset == offset {
+			*last = line
+			return
+		}
+	}
+	chunk.Lines = append(chunk.Lines, line)
+}
+
+func (c *compiler) fileIndex(path string) int {
+	if i, found := c.files[path]; found {
+		return i
+	}
+	c.files[path] = len(c.program.Files)
+	c.program.Files = append(c.program.Files, path)
+	return c.files[path]
+}
+
+// constant returns the index of a value in the constants of the current function, adding it once
+func (c *compiler) constant(node ast.Node, v interp.Value) int {
+	if i, found := c.fn.constants[v]; found {
+		return i
+	}
+	chunk := &c.fn.function.Chunk
+	if len(chunk.Constants) > math.MaxUint16 {
+		c.fail(node, "too many constants in one function")
+	}
+	c.fn.constants[v] = len(chunk.Constants)
+	chunk.Constants = append(chunk.Constants, v)
+	return len(chunk.Constants) - 1
+}
+
+// emitJump emits a jump to patch later and returns its offset
+func (c *compiler) emitJump(node ast.Node, op Opcode) int {
+	return c.emit(node, op, 0)
+}
+
+// patchJump makes the jump at offset land on the next instruction
+func (c *compiler) patchJump(node ast.Node, offset int) {
+	code := c.fn.function.Code
+	distance := len(code) - (offset + OpJump.Size())
+	if distance > math.MaxUint16 {
+		c.fail(node, "the jump is too long, the function is too large")
+	}
+	binary.BigEndian.PutUint16(code[offset+1:], uint16(distance))
+}
+
+// emitLoop jumps back to start
+func (c *compiler) emitLoop(node ast.Node, start int) {
+	c.emit(node, OpLoop, len(c.fn.function.Code)+OpLoop.Size()-start)
+}
+
+func (c *compiler) beginScope() {
+	c.fn.depth++
+}
+
+// endScope pops the locals of the scope, closing the ones closures captured
+func (c *compiler) endScope(node ast.Node) {
+	c.fn.depth--
+	fs := c.fn
+	for len(fs.locals) > 0 && fs.locals[len(fs.locals)-1].depth > fs.depth {
+		c.popLocal(node, fs.locals[len(fs.locals)-1])
+		fs.locals = fs.locals[:len(fs.locals)-1]
+	}
+}
+
+func (c *compiler) popLocal(node ast.Node, l local) {
+	if l.captured {
+		c.emit(node, OpCloseUpvalue)
+	} else {
+		c.emit(node, OpPop)
+	}
+}
+
+// isGlobalScope reports whether a declaration is a global of the module
+func (c *compiler) isGlobalScope() bool {
+	return c.fn.enclosing == nil && c.fn.depth == 0
+}
+
+func (c *compiler) declareLocal(node ast.Node, name string, typ int) {
+	if len(c.fn.locals) > math.MaxUint16 {
+		c.fail(node, "too many local variables in one function")
+	}
+	c.fn.locals = append(c.fn.locals, local{name: name, depth: c.fn.depth, typ: typ})
+}
+
+// typeOf returns the index of a type written in the current file
+func (c *compiler) typeOf(dataType ast.DataType) int {
+	if scoped, ok := dataType.(*ast.TypeScopeResolution); ok {
+		if importPath, found := c.file.ModulenameToImportpath[scoped.Module.Name]; found && c.ctx.Modules[importPath] != nil {
+			return c.addType(&semantic.UserType{Name: scoped.Type()}, c.ctx.Modules[importPath])
+		}
+	}
+	return c.addType(semantic.ASTToSemanticType(dataType), c.module)
+}
+
+// addType resolves a type named in a module and returns its index in Program.Types, adding it once
+func (c *compiler) addType(t semantic.Type, m *ctx.Module) int {
+	t = resolveType(t, m)
+	var key any = t
+	switch t := t.(type) {
+	case *semantic.PrimitiveType:
+		key = t.Name
+	case *semantic.ArrayType:
+		key = [1]int{c.addType(t.ElementType, m)}
+	}
+	if i, found := c.typeIndex[key]; found {
+		return i
+	}
+	if len(c.program.Types) >= NoType {
+		c.fail(nil, "too many types in one program")
+	}
+
+	index := len(c.program.Types)
+	c.typeIndex[key] = index
+	c.program.Types = append(c.program.Types, t)
+	switch t := t.(type) {
+	case *semantic.ArrayType:
+		c.program.Types[index] = &semantic.ArrayType{ElementType: c.program.Types[key.([1]int)[0]], Name: t.Name}
+	case *semantic.StructType:
+		// Registered before its fields, so a field can refer to the struct type again
+		resolved := &semantic.StructType{Name: t.Name, Module: t.Module, Fields: make(map[string]semantic.Type, len(t.Fields))}
+		c.program.Types[index] = resolved
+		owner := c.ctx.Modules[t.Module]
+		if owner == nil {
+			owner = m
+		}
+		for name, fieldType := range t.Fields {
+			resolved.Fields[name] = c.program.Types[c.addType(fieldType, owner)]
+		}
+	}
+	return index
+}
+
+// resolveType follows a type name to the type it is declared as, in the module the name is written in
+func resolveType(t semantic.Type, m *ctx.Module) semantic.Type {
+	// An alias cycle is reported by the type checker, the bound only keeps a bug from hanging the compiler
+	for range 64 {
+		user, ok := t.(*semantic.UserType)
+		if !ok {
+			return t
+		}
+		if user.Definition != nil {
+			t = user.Definition
+			continue
+		}
+		sym, found := m.SymbolTable.Lookup(string(user.Name))
+		if !found || sym.Kind != semantic.SymbolType {
+			return t
+		}
+		t = sym.Type
+	}
+	return t
+}
+
+// structType returns the index of the struct type a struct literal names
+func (c *compiler) structType(e *ast.StructLiteralExpr) int {
+	index := c.addType(&semantic.UserType{Name: types.TYPE_NAME(e.StructName.Name)}, c.module)
+	if _, ok := c.program.Types[index].(*semantic.StructType); !ok {
+		c.fail(e.StructName, "'%s' is not a struct type", e.StructName.Name)
+	}
+	return index
+}
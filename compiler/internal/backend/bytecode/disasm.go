@@ -0,0 +1,90 @@
+package bytecode
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"compiler/internal/backend/interp"
+)
+
+// Disassemble prints every function of a program, an instruction per line with its offset, the
+// source position it was compiled from and what its operands refer to
+func Disassemble(w io.Writer, p *Program) error {
+	var b strings.Builder
+	for i, m := range p.Modules {
+		fmt.Fprintf(&b, "module %s, init function %d\n", m.ImportPath, m.Init)
+		if i == len(p.Modules)-1 {
+			b.WriteString("\n")
+		}
+	}
+	for i, fn := range p.Functions {
+		fmt.Fprintf(&b, "function %d %s: %d params, %d upvalues, %d constants\n", i, fn.Name, len(fn.Params), len(fn.Upvalues), len(fn.Constants))
+		p.disassembleFunction(&b, fn)
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (p *Program) disassembleFunction(b *strings.Builder, fn *Function) {
+	previous := ""
+	for offset := 0; offset < len(fn.Code); offset += Opcode(fn.Code[offset]).Size() {
+		op := Opcode(fn.Code[offset])
+		position := ""
+		if line, found := fn.LineAt(offset); found {
+			position = fmt.Sprintf("%s:%d:%d", filepath.Base(p.Files[line.File]), line.Position.Line, line.Position.Column)
+		}
+		// A position is printed once for the instructions compiled from the same expression
+		shown := position
+		if position == previous {
+			shown = "|"
+		}
+		previous = position
+
+		operands := make([]string, op.Operands())
+		for i := range operands {
+			operands[i] = fmt.Sprint(fn.operand(offset + 1 + 2*i))
+		}
+		line := fmt.Sprintf("  %04d  %-16s %-14s %-6s", offset, shown, op, strings.Join(operands, " "))
+		if comment := p.describe(fn, op, offset); comment != "" {
+			line += " ; " + comment
+		}
+		b.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+}
+
+// describe explains what the operand of an instruction refers to
+func (p *Program) describe(fn *Function, op Opcode, offset int) string {
+	if op.Operands() == 0 {
+		return ""
+	}
+	operand := fn.operand(offset + 1)
+	switch op {
+	case OpConstant:
+		return interp.Display(fn.constantAt(operand))
+	case OpGetField, OpSetField:
+		return fmt.Sprint(fn.constantAt(operand))
+	case OpGetGlobal, OpSetGlobal:
+		if operand < len(p.Globals) {
+			return p.Globals[operand].Module + "::" + p.Globals[operand].Name
+		}
+	case OpStore, OpZero, OpStruct:
+		if operand == NoType {
+			return "inferred type"
+		}
+		if t := p.typeAt(operand); t != nil {
+			return t.String()
+		}
+	case OpClosure:
+		if operand < len(p.Functions) {
+			return p.Functions[operand].Name
+		}
+	case OpJump, OpJumpIfFalse:
+		return fmt.Sprintf("to %04d", offset+op.Size()+operand)
+	case OpLoop:
+		return fmt.Sprintf("to %04d", offset+op.Size()-operand)
+	}
+	return ""
+}
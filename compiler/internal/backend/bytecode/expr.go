@@ -0,0 +1,233 @@
+package bytecode
+
+import (
+	"math"
+
+	"compiler/internal/backend/interp"
+	"compiler/internal/frontend/ast"
+	"compiler/internal/types"
+)
+
+var binaryOps = map[string]Opcode{
+	"+":  OpAdd,
+	"-":  OpSub,
+	"*":  OpMul,
+	"/":  OpDiv,
+	"%":  OpMod,
+	"==": OpEqual,
+	"!=": OpNotEqual,
+	"<":  OpLess,
+	"<=": OpLessEqual,
+	">":  OpGreater,
+	">=": OpGreaterEqual,
+}
+
+// expr compiles an expression, which pushes its value
+func (c *compiler) expr(expr ast.Expression) {
+	switch e := expr.(type) {
+	case *ast.IntLiteral:
+		// Literals are i32, as the type checker infers them, unless the value needs i64
+		kind := types.INT32
+		if e.Value < math.MinInt32 || e.Value > math.MaxInt32 {
+			kind = types.INT64
+		}
+		c.emit(e, OpConstant, c.constant(e, interp.NewInt(e.Value, kind)))
+	case *ast.FloatLiteral:
+		c.emit(e, OpConstant, c.constant(e, interp.NewFloat(e.Value, types.FLOAT64)))
+	case *ast.StringLiteral:
+		c.emit(e, OpConstant, c.constant(e, interp.Str(e.Value)))
+	case *ast.BoolLiteral:
+		c.emit(e, OpConstant, c.constant(e, interp.Bool(e.Value)))
+	case *ast.ByteLiteral:
+		c.emit(e, OpConstant, c.constant(e, interp.NewInt(int64(e.Value[0]), types.BYTE)))
+	case *ast.IdentifierExpr:
+		get, _, index, _ := c.resolve(e, e.Name)
+		c.emit(e, get, index)
+	case *ast.VarScopeResolution:
+		c.emit(e, OpGetGlobal, c.scopedGlobal(e))
+	case *ast.BinaryExpr:
+		c.binary(e)
+	case *ast.UnaryExpr:
+		c.expr(*e.Operand)
+		if e.Operator.Value == "!" {
+			c.emit(e, OpNot)
+		} else {
+			c.emit(e, OpNegate)
+		}
+	case *ast.PrefixExpr:
+		c.expr(*e.Operand)
+		c.emit(e, incrementOp(e.Operator.Value))
+		c.emit(e, OpDup)
+		c.assign(*e.Operand, true)
+	case *ast.PostfixExpr:
+		c.expr(*e.Operand)
+		c.emit(e, OpDup)
+		c.emit(e, incrementOp(e.Operator.Value))
+		c.assign(*e.Operand, true)
+	case *ast.FunctionCallExpr:
+		c.expr(*e.Caller)
+		for _, arg := range e.Arguments {
+			c.expr(arg)
+		}
+		c.emit(e, OpCall, len(e.Arguments))
+	case *ast.FieldAccessExpr:
+		c.expr(*e.Object)
+		c.emit(e.Field, OpGetField, c.constant(e.Field, interp.Str(e.Field.Name)))
+	case *ast.IndexableExpr:
+		c.expr(*e.Indexable)
+		c.expr(*e.Index)
+		c.emit(e, OpIndex)
+	case *ast.ArrayLiteralExpr:
+		for _, elem := range e.Elements {
+			c.expr(elem)
+		}
+		c.emit(e, OpArray, len(e.Elements))
+	case *ast.StructLiteralExpr:
+		c.structLiteral(e)
+	case *ast.FunctionLiteral:
+		c.emit(e, OpClosure, c.function("fn", e, "", false))
+	default:
+		c.fail(expr, "cannot compile %T", expr)
+	}
+}
+
+func incrementOp(operator string) Opcode {
+	if operator == "--" {
+		return OpDecrement
+	}
+	return OpIncrement
+}
+
+func (c *compiler) binary(e *ast.BinaryExpr) {
+	c.expr(*e.Left)
+	switch e.Operator.Value {
+	case "&&":
+		skipRight := c.emitJump(e, OpJumpIfFalse)
+		c.expr(*e.Right)
+		end := c.emitJump(e, OpJump)
+		c.patchJump(e, skipRight)
+		c.emit(e, OpConstant, c.constant(e, interp.Bool(false)))
+		c.patchJump(e, end)
+		return
+	case "||":
+		evalRight := c.emitJump(e, OpJumpIfFalse)
+		c.emit(e, OpConstant, c.constant(e, interp.Bool(true)))
+		end := c.emitJump(e, OpJump)
+		c.patchJump(e, evalRight)
+		c.expr(*e.Right)
+		c.patchJump(e, end)
+		return
+	}
+	c.expr(*e.Right)
+	op, found := binaryOps[e.Operator.Value]
+	if !found {
+		c.fail(e, "unknown operator %s", e.Operator.Value)
+	}
+	c.emit(e, op)
+}
+
+func (c *compiler) structLiteral(e *ast.StructLiteralExpr) {
+	count := 0
+	for _, field := range e.Fields {
+		if field.FieldValue == nil {
+			continue
+		}
+		c.emit(field.FieldIdentifier, OpConstant, c.constant(field.FieldIdentifier, interp.Str(field.FieldIdentifier.Name)))
+		c.expr(*field.FieldValue)
+		count++
+	}
+	if e.IsAnonymous {
+		c.emit(e, OpAnonStruct, count)
+		return
+	}
+	c.emit(e, OpStruct, c.structType(e), count)
+}
+
+// resolve finds a name in the current function, then in the functions around it, then in the
+// globals of the module and the names it imported. It returns the instructions reading and writing
+// the variable, its index for them and its type.
+func (c *compiler) resolve(node ast.Node, name string) (get, set Opcode, index, typ int) {
+	if slot, found := c.fn.local(name); found {
+		return OpGetLocal, OpSetLocal, slot, c.fn.locals[slot].typ
+	}
+	if upvalue, found := c.fn.upvalue(name); found {
+		return OpGetUpvalue, OpSetUpvalue, upvalue, c.fn.upvalueTypes[upvalue]
+	}
+	if global, found := c.global(c.importPath, name); found {
+		return OpGetGlobal, OpSetGlobal, global, c.program.Globals[global].Type
+	}
+	c.fail(node, "'%s' is not defined", name)
+	return
+}
+
+// local returns the slot of the innermost local named name
+func (fs *funcState) local(name string) (int, bool) {
+	for i := len(fs.locals) - 1; i >= 0; i-- {
+		if fs.locals[i].name == name && name != "" {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// upvalue returns the index of the upvalue capturing a variable of an enclosing function, adding it once
+func (fs *funcState) upvalue(name string) (int, bool) {
+	if fs.enclosing == nil {
+		return 0, false
+	}
+	if slot, found := fs.enclosing.local(name); found {
+		fs.enclosing.locals[slot].captured = true
+		return fs.addUpvalue(Upvalue{Local: true, Index: slot}, fs.enclosing.locals[slot].typ), true
+	}
+	if index, found := fs.enclosing.upvalue(name); found {
+		return fs.addUpvalue(Upvalue{Index: index}, fs.enclosing.upvalueTypes[index]), true
+	}
+	return 0, false
+}
+
+func (fs *funcState) addUpvalue(upvalue Upvalue, typ int) int {
+	for i, existing := range fs.function.Upvalues {
+		if existing == upvalue {
+			return i
+		}
+	}
+	fs.function.Upvalues = append(fs.function.Upvalues, upvalue)
+	fs.upvalueTypes = append(fs.upvalueTypes, typ)
+	return len(fs.function.Upvalues) - 1
+}
+
+// global finds a top-level name of a module, which may be one it imported with an import list or
+// re-exports with 'pub import'
+func (c *compiler) global(importPath, name string) (int, bool) {
+	if index, found := c.globals[importPath][name]; found {
+		return index, true
+	}
+	m := c.ctx.Modules[importPath]
+	if m == nil {
+		return 0, false
+	}
+	importStmt := m.ImportedFrom[name]
+	if importStmt == nil || c.globals[importStmt.ModulePath] == nil {
+		return 0, false
+	}
+	original := name
+	for _, imported := range importStmt.Names {
+		if imported.LocalName().Name == name {
+			original = imported.Name.Name
+		}
+	}
+	return c.global(importStmt.ModulePath, original)
+}
+
+// scopedGlobal returns the global module::name refers to
+func (c *compiler) scopedGlobal(e *ast.VarScopeResolution) int {
+	importPath, found := c.file.ModulenameToImportpath[e.Module.Name]
+	if !found {
+		c.fail(e.Module, "module '%s' is not imported", e.Module.Name)
+	}
+	index, found := c.global(importPath, e.Var.Name)
+	if !found {
+		c.fail(e.Var, "'%s' is not defined in module '%s'", e.Var.Name, e.Module.Name)
+	}
+	return index
+}
@@ -0,0 +1,732 @@
+package bytecode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"compiler/internal/backend/interp"
+	"compiler/internal/semantic"
+	"compiler/internal/source"
+	"compiler/internal/types"
+)
+
+// EXTENSION is the extension of a compiled program file
+const EXTENSION = ".ferc"
+
+// FORMAT_VERSION is written into every .ferc file, a file of another version is refused
+const FORMAT_VERSION = 1
+
+// MAGIC starts every .ferc file
+const MAGIC = "\x7fFERC"
+
+// A .ferc file is MAGIC, the format version as a uint16, then the sections of the program in this
+// order: files, types, the constant pool, functions, globals, methods, modules and the line tables
+// of the functions. It ends with the CRC-32 of everything before it. Numbers are uvarints and
+// strings are a length followed by their bytes, unless noted otherwise.
+
+const (
+	typePrimitive byte = iota
+	typeArray
+	typeStruct
+	typeOther // function and interface types, nothing converts to them
+)
+
+const (
+	constInt byte = iota
+	constFloat
+	constStr
+	constBool
+)
+
+type encoder struct {
+	bytes.Buffer
+}
+
+func (e *encoder) uint(n int) {
+	e.Write(binary.AppendUvarint(nil, uint64(n)))
+}
+
+func (e *encoder) str(s string) {
+	e.uint(len(s))
+	e.WriteString(s)
+}
+
+func (e *encoder) ints(list []int) {
+	e.uint(len(list))
+	for _, n := range list {
+		e.uint(n)
+	}
+}
+
+func (e *encoder) bool(b bool) {
+	if b {
+		e.WriteByte(1)
+	} else {
+		e.WriteByte(0)
+	}
+}
+
+// Encode serializes a program to the .ferc format
+func Encode(p *Program) ([]byte, error) {
+	e := &encoder{}
+	e.WriteString(MAGIC)
+	e.Write(binary.BigEndian.AppendUint16(nil, FORMAT_VERSION))
+
+	e.uint(len(p.Files))
+	for _, file := range p.Files {
+		e.str(file)
+	}
+
+	typeIndex := make(map[semantic.Type]int, len(p.Types))
+	for i, t := range p.Types {
+		typeIndex[t] = i
+	}
+	indexOf := func(t semantic.Type) (int, error) {
+		if i, found := typeIndex[t]; found {
+			return i, nil
+		}
+		return 0, fmt.Errorf("type %s is not in the type table", t)
+	}
+	e.uint(len(p.Types))
+	for _, t := range p.Types {
+		switch t := t.(type) {
+		case *semantic.PrimitiveType:
+			e.WriteByte(typePrimitive)
+			e.str(string(t.Name))
+		case *semantic.ArrayType:
+			elem, err := indexOf(t.ElementType)
+			if err != nil {
+				return nil, err
+			}
+			e.WriteByte(typeArray)
+			e.uint(elem)
+		case *semantic.StructType:
+			e.WriteByte(typeStruct)
+			e.str(string(t.Name))
+			e.str(t.Module)
+			names := slices.Sorted(maps.Keys(t.Fields))
+			e.uint(len(names))
+			for _, name := range names {
+				field, err := indexOf(t.Fields[name])
+				if err != nil {
+					return nil, err
+				}
+				e.str(name)
+				e.uint(field)
+			}
+		default:
+			e.WriteByte(typeOther)
+			e.str(string(t.TypeName()))
+		}
+	}
+
+	// The constants of every function go into one pool, each function lists the ones it uses
+	var pool []interp.Value
+	poolIndex := make(map[interp.Value]int)
+	for _, fn := range p.Functions {
+		for _, v := range fn.Constants {
+			if _, found := poolIndex[v]; !found {
+				poolIndex[v] = len(pool)
+				pool = append(pool, v)
+			}
+		}
+	}
+	e.uint(len(pool))
+	for _, v := range pool {
+		switch v := v.(type) {
+		case interp.Int:
+			e.WriteByte(constInt)
+			e.str(string(v.Kind))
+			e.Write(binary.AppendVarint(nil, v.V))
+		case interp.Float:
+			e.WriteByte(constFloat)
+			e.str(string(v.Kind))
+			e.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(v.V)))
+		case interp.Str:
+			e.WriteByte(constStr)
+			e.str(string(v))
+		case interp.Bool:
+			e.WriteByte(constBool)
+			e.bool(bool(v))
+		default:
+			return nil, fmt.Errorf("cannot encode the constant %s", interp.Display(v))
+		}
+	}
+
+	e.uint(len(p.Functions))
+	for _, fn := range p.Functions {
+		e.str(fn.Name)
+		e.ints(fn.Params)
+		e.ints(fn.Results)
+		e.uint(len(fn.Upvalues))
+		for _, uv := range fn.Upvalues {
+			e.bool(uv.Local)
+			e.uint(uv.Index)
+		}
+		constants := make([]int, len(fn.Constants))
+		for i, v := range fn.Constants {
+			constants[i] = poolIndex[v]
+		}
+		e.ints(constants)
+		e.uint(len(fn.Code))
+		e.Write(fn.Code)
+	}
+
+	e.uint(len(p.Globals))
+	for _, global := range p.Globals {
+		e.WriteByte(byte(global.Kind))
+		e.str(global.Module)
+		e.str(global.Name)
+		e.uint(global.Type)
+		e.uint(global.Function)
+		e.ints(global.Params)
+	}
+
+	e.uint(len(p.Methods))
+	for _, m := range p.Methods {
+		e.uint(m.Type)
+		e.str(m.Name)
+		e.uint(m.Function)
+		e.bool(m.RRef)
+	}
+
+	e.uint(len(p.Modules))
+	for _, m := range p.Modules {
+		e.str(m.ImportPath)
+		e.uint(m.Init)
+	}
+
+	for _, fn := range p.Functions {
+		e.uint(len(fn.Lines))
+		for _, line := range fn.Lines {
+			e.uint(line.Offset)
+			e.uint(line.File)
+			e.uint(line.Position.Line)
+			e.uint(line.Position.Column)
+			e.uint(line.Position.Index)
+		}
+	}
+
+	e.Write(binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE(e.Bytes())))
+	return e.Bytes(), nil
+}
+
+// Save writes a program to a .ferc file
+func Save(p *Program, path string) error {
+	data, err := Encode(p)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.FromSlash(path), data, 0644)
+}
+
+// Load reads a program from a .ferc file
+func Load(path string) (*Program, error) {
+	data, err := os.ReadFile(filepath.FromSlash(path))
+	if err != nil {
+		return nil, err
+	}
+	p, err := Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// FormatError is a file that is not a valid .ferc file: of another format or version, truncated or corrupted
+type FormatError struct {
+	Message string
+}
+
+func (e *FormatError) Error() string {
+	return "invalid bytecode file: " + e.Message
+}
+
+var errTruncated = &FormatError{Message: "the file is truncated"}
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) fail(format string, args ...any) {
+	panic(&FormatError{Message: fmt.Sprintf(format, args...)})
+}
+
+func (d *decoder) byte() byte {
+	if d.pos >= len(d.data) {
+		panic(errTruncated)
+	}
+	d.pos++
+	return d.data[d.pos-1]
+}
+
+func (d *decoder) bytes(n int) []byte {
+	if n > len(d.data)-d.pos {
+		panic(errTruncated)
+	}
+	d.pos += n
+	return d.data[d.pos-n : d.pos]
+}
+
+func (d *decoder) uint() int {
+	n, size := binary.Uvarint(d.data[d.pos:])
+	if size == 0 {
+		panic(errTruncated)
+	}
+	if size < 0 || n > math.MaxInt32 {
+		d.fail("a number at offset %d is out of range", d.pos)
+	}
+	d.pos += size
+	return int(n)
+}
+
+// count reads the length of a list, every element takes a byte at least so it cannot exceed what is left
+func (d *decoder) count() int {
+	n := d.uint()
+	if n > len(d.data)-d.pos {
+		panic(errTruncated)
+	}
+	return n
+}
+
+func (d *decoder) str() string {
+	return string(d.bytes(d.count()))
+}
+
+func (d *decoder) ints() []int {
+	n := d.count()
+	if n == 0 {
+		return nil
+	}
+	list := make([]int, n)
+	for i := range list {
+		list[i] = d.uint()
+	}
+	return list
+}
+
+func (d *decoder) bool() bool {
+	switch b := d.byte(); b {
+	case 0:
+		return false
+	case 1:
+		return true
+	default:
+		d.fail("invalid bool %d at offset %d", b, d.pos-1)
+		return false
+	}
+}
+
+// Decode reads a program in the .ferc format. It checks the file is complete and intact, and that
+// every index the program holds is in range, so the VM can run it without checking them.
+func Decode(data []byte) (p *Program, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			formatErr, ok := r.(*FormatError)
+			if !ok {
+				panic(r)
+			}
+			p, err = nil, formatErr
+		}
+	}()
+
+	if !bytes.HasPrefix(data, []byte(MAGIC)) {
+		return nil, &FormatError{Message: "not a Ferret bytecode file"}
+	}
+	if len(data) < len(MAGIC)+2+4 {
+		return nil, errTruncated
+	}
+	if version := binary.BigEndian.Uint16(data[len(MAGIC):]); version != FORMAT_VERSION {
+		return nil, &FormatError{Message: fmt.Sprintf("format version %d, this compiler reads version %d", version, FORMAT_VERSION)}
+	}
+	body, checksum := data[:len(data)-4], binary.BigEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(body) != checksum {
+		return nil, &FormatError{Message: "the checksum does not match, the file is truncated or corrupted"}
+	}
+
+	d := &decoder{data: body, pos: len(MAGIC) + 2}
+	p = &Program{}
+	p.Files = make([]string, d.count())
+	for i := range p.Files {
+		p.Files[i] = d.str()
+	}
+	d.types(p)
+
+	pool := make([]interp.Value, d.count())
+	for i := range pool {
+		pool[i] = d.constant()
+	}
+
+	p.Functions = make([]*Function, d.count())
+	for i := range p.Functions {
+		fn := &Function{Name: d.str(), Params: d.ints(), Results: d.ints()}
+		if n := d.count(); n > 0 {
+			fn.Upvalues = make([]Upvalue, n)
+			for j := range fn.Upvalues {
+				fn.Upvalues[j] = Upvalue{Local: d.bool(), Index: d.uint()}
+			}
+		}
+		for _, k := range d.ints() {
+			if k >= len(pool) {
+				d.fail("function %s uses constant %d of a pool of %d", fn.Name, k, len(pool))
+			}
+			fn.Constants = append(fn.Constants, pool[k])
+		}
+		fn.Code = d.bytes(d.count())
+		p.Functions[i] = fn
+	}
+
+	p.Globals = make([]Global, d.count())
+	for i := range p.Globals {
+		kind := GlobalKind(d.byte())
+		if kind > GlobalNative {
+			d.fail("global %d has an invalid kind %d", i, kind)
+		}
+		p.Globals[i] = Global{Kind: kind, Module: d.str(), Name: d.str(), Type: d.uint(), Function: d.uint(), Params: d.ints()}
+	}
+
+	p.Methods = make([]Method, d.count())
+	for i := range p.Methods {
+		p.Methods[i] = Method{Type: d.uint(), Name: d.str(), Function: d.uint(), RRef: d.bool()}
+	}
+
+	p.Modules = make([]Module, d.count())
+	for i := range p.Modules {
+		p.Modules[i] = Module{ImportPath: d.str(), Init: d.uint()}
+	}
+
+	for _, fn := range p.Functions {
+		fn.Lines = make([]Line, d.count())
+		for i := range fn.Lines {
+			fn.Lines[i] = Line{Offset: d.uint(), File: d.uint(), Position: source.Position{Line: d.uint(), Column: d.uint(), Index: d.uint()}}
+		}
+	}
+	if d.pos != len(body) {
+		d.fail("%d unexpected bytes after the line tables", len(body)-d.pos)
+	}
+
+	if err := p.validate(); err != nil {
+		return nil, &FormatError{Message: err.Error()}
+	}
+	return p, nil
+}
+
+// types reads the type table, in two passes as an array or a struct can refer to a type after it
+func (d *decoder) types(p *Program) {
+	type link struct {
+		elem   int
+		fields map[string]int
+	}
+	p.Types = make([]semantic.Type, d.count())
+	links := make([]link, len(p.Types))
+	for i := range p.Types {
+		switch tag := d.byte(); tag {
+		case typePrimitive, typeOther:
+			p.Types[i] = &semantic.PrimitiveType{Name: types.TYPE_NAME(d.str())}
+		case typeArray:
+			p.Types[i] = &semantic.ArrayType{Name: types.ARRAY}
+			links[i].elem = d.uint()
+		case typeStruct:
+			t := &semantic.StructType{Name: types.TYPE_NAME(d.str()), Module: d.str()}
+			n := d.count()
+			t.Fields = make(map[string]semantic.Type, n)
+			links[i].fields = make(map[string]int, n)
+			for range n {
+				name := d.str()
+				links[i].fields[name] = d.uint()
+			}
+			p.Types[i] = t
+		default:
+			d.fail("type %d has an invalid tag %d", i, tag)
+		}
+	}
+
+	at := func(index int) semantic.Type {
+		if index >= len(p.Types) {
+			d.fail("a type refers to type %d of %d", index, len(p.Types))
+		}
+		return p.Types[index]
+	}
+	for i, t := range p.Types {
+		switch t := t.(type) {
+		case *semantic.ArrayType:
+			t.ElementType = at(links[i].elem)
+		case *semantic.StructType:
+			for name, field := range links[i].fields {
+				t.Fields[name] = at(field)
+			}
+		}
+	}
+}
+
+func (d *decoder) constant() interp.Value {
+	switch tag := d.byte(); tag {
+	case constInt:
+		kind := types.TYPE_NAME(d.str())
+		n, size := binary.Varint(d.data[d.pos:])
+		if size <= 0 {
+			panic(errTruncated)
+		}
+		d.pos += size
+		return interp.NewInt(n, kind)
+	case constFloat:
+		kind := types.TYPE_NAME(d.str())
+		return interp.NewFloat(math.Float64frombits(binary.BigEndian.Uint64(d.bytes(8))), kind)
+	case constStr:
+		return interp.Str(d.str())
+	case constBool:
+		return interp.Bool(d.bool())
+	default:
+		d.fail("a constant has an invalid tag %d", tag)
+		return nil
+	}
+}
+
+// validate checks every index of a program is in range and every function is well formed code
+func (p *Program) validate() error {
+	typeOK := func(t int) bool { return t == NoType || t < len(p.Types) }
+	for _, global := range p.Globals {
+		if !typeOK(global.Type) || global.Kind == GlobalFunction && global.Function >= len(p.Functions) {
+			return fmt.Errorf("global %s::%s refers to a type or function out of range", global.Module, global.Name)
+		}
+		for _, param := range global.Params {
+			if !typeOK(param) {
+				return fmt.Errorf("global %s::%s has a parameter type out of range", global.Module, global.Name)
+			}
+		}
+	}
+	for _, m := range p.Methods {
+		if m.Type >= len(p.Types) || m.Function >= len(p.Functions) {
+			return fmt.Errorf("method %s refers to a type or function out of range", m.Name)
+		}
+		if _, ok := p.Types[m.Type].(*semantic.StructType); !ok {
+			return fmt.Errorf("method %s has a receiver that is not a struct type", m.Name)
+		}
+	}
+	for _, m := range p.Modules {
+		if m.Init >= len(p.Functions) {
+			return fmt.Errorf("module %s has an init function out of range", m.ImportPath)
+		}
+	}
+	// Globals, methods and module inits run as closures that capture nothing
+	for _, global := range p.Globals {
+		if global.Kind == GlobalFunction && len(p.Functions[global.Function].Upvalues) > 0 {
+			return fmt.Errorf("global %s::%s is a function with upvalues", global.Module, global.Name)
+		}
+	}
+	for _, m := range p.Methods {
+		if len(p.Functions[m.Function].Upvalues) > 0 {
+			return fmt.Errorf("method %s is a function with upvalues", m.Name)
+		}
+	}
+	for _, m := range p.Modules {
+		if len(p.Functions[m.Init].Upvalues) > 0 {
+			return fmt.Errorf("module %s has an init function with upvalues", m.ImportPath)
+		}
+	}
+	for _, fn := range p.Functions {
+		if err := p.validateFunction(fn); err != nil {
+			return fmt.Errorf("function %s: %w", fn.Name, err)
+		}
+	}
+	return nil
+}
+
+func (p *Program) validateFunction(fn *Function) error {
+	for _, t := range append(append([]int(nil), fn.Params...), fn.Results...) {
+		if t != NoType && t >= len(p.Types) {
+			return fmt.Errorf("type %d out of range", t)
+		}
+	}
+	for i, line := range fn.Lines {
+		if line.File >= len(p.Files) || line.Offset >= len(fn.Code) || i > 0 && line.Offset <= fn.Lines[i-1].Offset {
+			return fmt.Errorf("line %d is out of order or out of range", i)
+		}
+	}
+
+	// The first pass finds where instructions start, jumps must land on one
+	starts := make(map[int]bool)
+	var last Opcode
+	for offset := 0; offset < len(fn.Code); {
+		op := Opcode(fn.Code[offset])
+		if op >= opCount {
+			return fmt.Errorf("invalid opcode %d at %d", op, offset)
+		}
+		if offset+op.Size() > len(fn.Code) {
+			return fmt.Errorf("%s at %d is cut off", op, offset)
+		}
+		starts[offset] = true
+		last = op
+		offset += op.Size()
+	}
+	if last != OpReturn {
+		return errors.New("the code does not end with a return")
+	}
+
+	for offset := 0; offset < len(fn.Code); {
+		op := Opcode(fn.Code[offset])
+		next := offset + op.Size()
+		var operand int
+		if op.Operands() > 0 {
+			operand = fn.operand(offset + 1)
+		}
+		ok := true
+		switch op {
+		case OpConstant:
+			ok = operand < len(fn.Constants)
+		case OpGetField, OpSetField:
+			_, isStr := fn.constantAt(operand).(interp.Str)
+			ok = isStr
+		case OpGetGlobal, OpSetGlobal:
+			ok = operand < len(p.Globals)
+		case OpStore, OpZero:
+			ok = operand == NoType || operand < len(p.Types)
+		case OpStruct:
+			_, ok = p.typeAt(operand).(*semantic.StructType)
+		case OpClosure:
+			ok = operand < len(p.Functions)
+		case OpGetUpvalue, OpSetUpvalue:
+			ok = operand < len(fn.Upvalues)
+		case OpJump, OpJumpIfFalse:
+			ok = starts[next+operand]
+		case OpLoop:
+			ok = starts[next-operand]
+		}
+		if !ok {
+			return fmt.Errorf("%s at %d has an invalid operand %d", op, offset, operand)
+		}
+		offset = next
+	}
+	return p.checkStack(fn)
+}
+
+// stackEffect is the number of values an instruction needs on the stack and the number it leaves
+// in their place
+func (c *Chunk) stackEffect(offset int) (pops, pushes int) {
+	op := Opcode(c.Code[offset])
+	var operand int
+	if op.Operands() > 0 {
+		operand = c.operand(offset + 1)
+	}
+	switch op {
+	case OpConstant, OpNil, OpGetLocal, OpGetGlobal, OpGetUpvalue, OpZero, OpClosure:
+		return 0, 1
+	case OpPop, OpSetLocal, OpSetGlobal, OpSetUpvalue, OpCloseUpvalue, OpJumpIfFalse:
+		return 1, 0
+	case OpDup:
+		return 1, 2
+	case OpStore, OpNegate, OpNot, OpIncrement, OpDecrement, OpGetField:
+		return 1, 1
+	case OpStoreLike, OpAdd, OpSub, OpMul, OpDiv, OpMod, OpEqual, OpNotEqual, OpLess, OpLessEqual,
+		OpGreater, OpGreaterEqual, OpIndex:
+		return 2, 1
+	case OpSetField:
+		return 2, 0
+	case OpSetIndex:
+		return 3, 0
+	case OpCall:
+		return operand + 1, 1
+	case OpReturn:
+		return operand, 0
+	case OpArray:
+		return operand, 1
+	case OpStruct:
+		return 2 * c.operand(offset+3), 1
+	case OpAnonStruct:
+		return 2 * operand, 1
+	case OpSpread:
+		return 1, operand
+	}
+	return 0, 0
+}
+
+// checkStack follows every path through the code with the number of values the frame holds before
+// each instruction, the callee and its arguments at the start. Locals are slots of the frame, so the
+// slot of a local, or of a local a closure captures, must be below that depth. An instruction cannot
+// pop more than the frame holds, and every path to an instruction must reach it with the same depth.
+func (p *Program) checkStack(fn *Function) error {
+	depths := map[int]int{0: 1 + len(fn.Params)}
+	work := []int{0}
+	for len(work) > 0 {
+		offset := work[len(work)-1]
+		work = work[:len(work)-1]
+		depth := depths[offset]
+
+		op := Opcode(fn.Code[offset])
+		next := offset + op.Size()
+		var operand int
+		if op.Operands() > 0 {
+			operand = fn.operand(offset + 1)
+		}
+		pops, pushes := fn.stackEffect(offset)
+		if pops > depth {
+			return fmt.Errorf("%s at %d pops %d of %d values", op, offset, pops, depth)
+		}
+		switch op {
+		case OpGetLocal:
+			if operand >= depth {
+				return fmt.Errorf("%s at %d reads slot %d of a frame of %d", op, offset, operand, depth)
+			}
+		case OpSetLocal:
+			if operand >= depth-1 {
+				return fmt.Errorf("%s at %d writes slot %d of a frame of %d", op, offset, operand, depth-1)
+			}
+		case OpClosure:
+			for _, uv := range p.Functions[operand].Upvalues {
+				// A local function captures itself, in the slot the closure is pushed to
+				if uv.Local && uv.Index > depth {
+					return fmt.Errorf("%s at %d captures slot %d of a frame of %d", op, offset, uv.Index, depth)
+				}
+				if !uv.Local && uv.Index >= len(fn.Upvalues) {
+					return fmt.Errorf("%s at %d captures upvalue %d of %d", op, offset, uv.Index, len(fn.Upvalues))
+				}
+			}
+		}
+		depth += pushes - pops
+
+		var succs []int
+		switch op {
+		case OpJump:
+			succs = []int{next + operand}
+		case OpJumpIfFalse:
+			succs = []int{next, next + operand}
+		case OpLoop:
+			succs = []int{next - operand}
+		case OpReturn:
+		default:
+			succs = []int{next}
+		}
+		for _, succ := range succs {
+			if known, seen := depths[succ]; !seen {
+				depths[succ] = depth
+				work = append(work, succ)
+			} else if known != depth {
+				return fmt.Errorf("the stack depth at %d is %d on one path and %d on another", succ, known, depth)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Chunk) constantAt(index int) interp.Value {
+	if index < len(c.Constants) {
+		return c.Constants[index]
+	}
+	return nil
+}
+
+func (p *Program) typeAt(index int) semantic.Type {
+	if index < len(p.Types) {
+		return p.Types[index]
+	}
+	return nil
+}
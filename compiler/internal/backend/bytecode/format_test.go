@@ -0,0 +1,216 @@
+package bytecode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"compiler/internal/backend/interp"
+	"compiler/internal/types"
+)
+
+var formatProgram = map[string]string{
+	"main.fer": `import "std/fmt";
+import "app/shapes";
+fn scaler(factor: f64) -> fn() -> f64 {
+    return fn() -> f64 { return 1.25 * factor; };
+}
+let double = scaler(2.0);
+let r = shapes::newRect(2, 3);
+let big: i64 = 3000000000;
+fmt::println(fmt::formatFloat(double()) + " " + fmt::formatInt(r.area()) + " " + fmt::formatInt(big + 1));`,
+	"shapes.fer": `type Rect struct { w: i32, h: i32, tags: []str };
+fn (r: Rect) area() -> i32 {
+    return r.w * r.h;
+}
+fn newRect(w: i32, h: i32) -> Rect {
+    return @Rect{w: w, h: h, tags: ["rect"]};
+}`,
+}
+
+// compileProgram type checks and compiles a project
+func compileProgram(t *testing.T, files map[string]string) *Program {
+	t.Helper()
+	program, err := Compile(checkProject(t, files))
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	return program
+}
+
+func run(t *testing.T, p *Program) string {
+	t.Helper()
+	var out bytes.Buffer
+	vm := New(p)
+	vm.Stdout = &out
+	if err := vm.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	return out.String()
+}
+
+func disassemble(t *testing.T, p *Program) string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := Disassemble(&out, p); err != nil {
+		t.Fatalf("Disassemble() error = %v", err)
+	}
+	return out.String()
+}
+
+func TestEncodeDecode(t *testing.T) {
+	program := compileProgram(t, formatProgram)
+	path := filepath.ToSlash(filepath.Join(t.TempDir(), "main"+EXTENSION))
+	if err := Save(program, path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if got, want := run(t, loaded), "2.5 6 3000000001\n"; got != want {
+		t.Errorf("the loaded program printed %q, want %q", got, want)
+	}
+	if got, want := disassemble(t, loaded), disassemble(t, program); got != want {
+		t.Errorf("the loaded program disassembles to\n%s\nwant\n%s", got, want)
+	}
+}
+
+// reseal replaces the checksum of an encoded program after a test changed it
+func reseal(data []byte) []byte {
+	body := data[:len(data)-4]
+	return binary.BigEndian.AppendUint32(append([]byte(nil), body...), crc32.ChecksumIEEE(body))
+}
+
+func TestDecodeErrors(t *testing.T) {
+	program := compileProgram(t, formatProgram)
+	data, err := Encode(program)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	// corrupt encodes the program with a change to the functions the validation must find, the file
+	// is intact otherwise
+	corrupt := func(change func(fn *Function)) []byte {
+		t.Helper()
+		p := compileProgram(t, formatProgram)
+		for _, fn := range p.Functions {
+			change(fn)
+		}
+		data, err := Encode(p)
+		if err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+		return data
+	}
+	prepend := func(name string, code ...byte) []byte {
+		return corrupt(func(fn *Function) {
+			if fn.Name == name {
+				fn.Code = append(code, fn.Code...)
+			}
+		})
+	}
+	capture := func(local bool, index int) []byte {
+		return corrupt(func(fn *Function) {
+			for i := range fn.Upvalues {
+				fn.Upvalues[i] = Upvalue{Local: local, Index: index}
+			}
+		})
+	}
+
+	versioned := append([]byte(nil), data...)
+	binary.BigEndian.PutUint16(versioned[len(MAGIC):], FORMAT_VERSION+1)
+	corrupted := append([]byte(nil), data...)
+	corrupted[len(corrupted)/2] ^= 0xFF
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"not bytecode", []byte("let x = 1;"), "not a Ferret bytecode file"},
+		{"empty", nil, "not a Ferret bytecode file"},
+		{"other version", reseal(versioned), "format version 2, this compiler reads version 1"},
+		{"truncated", data[:len(data)-10], "the checksum does not match"},
+		{"truncated header", data[:len(MAGIC)+3], "the file is truncated"},
+		{"corrupted", corrupted, "the checksum does not match"},
+		{"truncated with a valid checksum", reseal(data[:len(data)/2]), "the file is truncated"},
+		{"trailing bytes", reseal(append(append([]byte(nil), data[:len(data)-4]...), 0, 0, 0, 0, 0)), "unexpected bytes"},
+		{"operand out of range", prepend("Rect.area", byte(OpConstant), 0, 99), "function Rect.area: CONSTANT at 0 has an invalid operand 99"},
+		{"local out of range", prepend("Rect.area", byte(OpGetLocal), 0, 200), "function Rect.area: GET_LOCAL at 0 reads slot 200 of a frame of 1"},
+		{"local written out of range", prepend("Rect.area", byte(OpNil), byte(OpSetLocal), 0, 1), "function Rect.area: SET_LOCAL at 1 writes slot 1 of a frame of 1"},
+		{"stack underflow", prepend("Rect.area", byte(OpPop), byte(OpPop)), "function Rect.area: POP at 1 pops 1 of 0 values"},
+		{"uneven stack", prepend("Rect.area", byte(OpConstant), 0, 0, byte(OpJumpIfFalse), 0, 1, byte(OpNil)), "the stack depth at 7 is 1 on one path and 2 on another"},
+		{"captured local out of range", capture(true, 40), "captures slot 40 of a frame of"},
+		{"captured upvalue out of range", capture(false, 3), "captures upvalue 3 of 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(tt.data)
+			var formatErr *FormatError
+			if !errors.As(err, &formatErr) {
+				t.Fatalf("Decode() error = %v, want a FormatError", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Decode() error = %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunInvalidValues(t *testing.T) {
+	// Decode checks the constant index of the field name, not that the constant is a string
+	program := compileProgram(t, formatProgram)
+	for _, fn := range program.Functions {
+		if fn.Name == "newRect" {
+			fn.Constants[0] = interp.Int{V: 7, Kind: types.INT32}
+		}
+	}
+	data, err := Encode(program)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	loaded, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	vm := New(loaded)
+	vm.Stdout = io.Discard
+	err = vm.Run()
+	var formatErr *FormatError
+	if !errors.As(err, &formatErr) {
+		t.Fatalf("Run() error = %v, want a FormatError", err)
+	}
+	if want := "function newRect, the instruction before 26: the field name 7 is not a string"; !strings.Contains(err.Error(), want) {
+		t.Errorf("Run() error = %q, want %q", err, want)
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	program := compileProgram(t, map[string]string{"main.fer": `fn add(a: i32, b: i32) -> i32 {
+    return a + b;
+}
+let x = add(1, 2);`})
+	got := disassemble(t, program)
+	for _, want := range []string{
+		"module app/main, init function 0",
+		"function 1 add: 2 params, 0 upvalues, 0 constants",
+		"  0000  main.fer:2:12    GET_LOCAL      1",
+		"  0007  main.fer:2:5     RETURN         1",
+		"main.fer:4:9     GET_GLOBAL     1      ; app/main::add",
+		"main.fer:4:13    CONSTANT       0      ; 1",
+		"SET_GLOBAL     0      ; app/main::x",
+		"  0012  |                STORE          65535  ; inferred type",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Disassemble() does not print %q:\n%s", want, got)
+		}
+	}
+}
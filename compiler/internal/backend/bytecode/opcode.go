@@ -0,0 +1,122 @@
+package bytecode
+
+import "fmt"
+
+// Opcode is the first byte of an instruction, its operands follow as big endian uint16s
+type Opcode byte
+
+const (
+	OpConstant     Opcode = iota // k: push constant k of the chunk
+	OpNil                        // push the value of a call that returns nothing
+	OpPop                        // drop the top of the stack
+	OpDup                        // push the top of the stack again
+	OpGetLocal                   // s: push local slot s of the frame
+	OpSetLocal                   // s: pop into local slot s
+	OpGetGlobal                  // g: push global g
+	OpSetGlobal                  // g: pop into global g
+	OpGetUpvalue                 // u: push upvalue u of the closure
+	OpSetUpvalue                 // u: pop into upvalue u
+	OpCloseUpvalue               // move the local on top of the stack into the closures capturing it, and pop it
+	OpStore                      // t: convert the top of the stack to type t and copy it, as a variable of type t owns it
+	OpStoreLike                  // pop a variable's old value, convert the value below it to the same type and copy it
+	OpZero                       // t: push the zero value of type t
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpMod
+	OpEqual
+	OpNotEqual
+	OpLess
+	OpLessEqual
+	OpGreater
+	OpGreaterEqual
+	OpNegate
+	OpNot
+	OpIncrement   // add one to the number on top of the stack, in its own kind
+	OpDecrement   // subtract one from the number on top of the stack, in its own kind
+	OpJump        // n: skip n bytes forward
+	OpJumpIfFalse // n: pop a bool, skip n bytes forward when it is false
+	OpLoop        // n: jump n bytes back
+	OpCall        // n: call the value below n arguments
+	OpReturn      // n: return the n values on top of the stack
+	OpClosure     // f: push a closure of function f, capturing its upvalues
+	OpArray       // n: pop n elements into a new array
+	OpStruct      // t n: pop n field name and value pairs into a new struct of type t
+	OpAnonStruct  // n: pop n field name and value pairs into a new anonymous struct
+	OpGetField    // k: replace a struct with its field or method named by constant k
+	OpSetField    // k: pop a struct and a value, and set the field named by constant k
+	OpIndex       // pop an array and an index, push the element
+	OpSetIndex    // pop a value, an array and an index, and set the element
+	OpSpread      // n: replace a tuple of n values with its values
+	opCount
+)
+
+var opcodes = [opCount]struct {
+	name     string
+	operands int
+}{
+	OpConstant:     {"CONSTANT", 1},
+	OpNil:          {"NIL", 0},
+	OpPop:          {"POP", 0},
+	OpDup:          {"DUP", 0},
+	OpGetLocal:     {"GET_LOCAL", 1},
+	OpSetLocal:     {"SET_LOCAL", 1},
+	OpGetGlobal:    {"GET_GLOBAL", 1},
+	OpSetGlobal:    {"SET_GLOBAL", 1},
+	OpGetUpvalue:   {"GET_UPVALUE", 1},
+	OpSetUpvalue:   {"SET_UPVALUE", 1},
+	OpCloseUpvalue: {"CLOSE_UPVALUE", 0},
+	OpStore:        {"STORE", 1},
+	OpStoreLike:    {"STORE_LIKE", 0},
+	OpZero:         {"ZERO", 1},
+	OpAdd:          {"ADD", 0},
+	OpSub:          {"SUB", 0},
+	OpMul:          {"MUL", 0},
+	OpDiv:          {"DIV", 0},
+	OpMod:          {"MOD", 0},
+	OpEqual:        {"EQUAL", 0},
+	OpNotEqual:     {"NOT_EQUAL", 0},
+	OpLess:         {"LESS", 0},
+	OpLessEqual:    {"LESS_EQUAL", 0},
+	OpGreater:      {"GREATER", 0},
+	OpGreaterEqual: {"GREATER_EQUAL", 0},
+	OpNegate:       {"NEGATE", 0},
+	OpNot:          {"NOT", 0},
+	OpIncrement:    {"INCREMENT", 0},
+	OpDecrement:    {"DECREMENT", 0},
+	OpJump:         {"JUMP", 1},
+	OpJumpIfFalse:  {"JUMP_IF_FALSE", 1},
+	OpLoop:         {"LOOP", 1},
+	OpCall:         {"CALL", 1},
+	OpReturn:       {"RETURN", 1},
+	OpClosure:      {"CLOSURE", 1},
+	OpArray:        {"ARRAY", 1},
+	OpStruct:       {"STRUCT", 2},
+	OpAnonStruct:   {"ANON_STRUCT", 1},
+	OpGetField:     {"GET_FIELD", 1},
+	OpSetField:     {"SET_FIELD", 1},
+	OpIndex:        {"INDEX", 0},
+	OpSetIndex:     {"SET_INDEX", 0},
+	OpSpread:       {"SPREAD", 1},
+}
+
+func (op Opcode) String() string {
+	if op < opCount {
+		return opcodes[op].name
+	}
+	return fmt.Sprintf("OP_%d", byte(op))
+}
+
+// Operands is the number of uint16 operands following the opcode
+func (op Opcode) Operands() int {
+	if op < opCount {
+		return opcodes[op].operands
+	}
+	return 0
+}
+
+// Size is the number of bytes of an instruction with the opcode
+func (op Opcode) Size() int {
+	return 1 + 2*op.Operands()
+}
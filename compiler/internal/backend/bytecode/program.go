@@ -0,0 +1,103 @@
+package bytecode
+
+import (
+	"encoding/binary"
+	"sort"
+
+	"compiler/internal/backend/interp"
+	"compiler/internal/semantic"
+	"compiler/internal/source"
+)
+
+// NoType stands for a type the compiler does not know, a variable declared without one takes the
+// type of its first value at run time
+const NoType = 0xFFFF
+
+// Program is a compiled Ferret program, every module of a compiler context lowered to functions
+type Program struct {
+	Files []string // the full paths of the source files, the line tables index them
+	// Types are the types instructions and declarations refer to by index. They are resolved: there
+	// are no type names left in them, and the fields of a struct type are resolved too.
+	Types     []semantic.Type
+	Functions []*Function
+	Globals   []Global
+	Methods   []Method
+	Modules   []Module // in the order they run, each after the modules it imports
+}
+
+// GlobalKind is what a global of a module holds
+type GlobalKind byte
+
+const (
+	GlobalVar GlobalKind = iota
+	GlobalFunction
+	GlobalNative
+)
+
+// Global is a top-level variable or function of a module
+type Global struct {
+	Kind     GlobalKind
+	Module   string // the import path of the declaring module
+	Name     string
+	Type     int   // the declared type of a variable, NoType when it is inferred
+	Function int   // the function of a GlobalFunction
+	Params   []int // the parameter types of a GlobalNative, its arguments are converted to them
+}
+
+// Method is a function called on a value of a struct type
+type Method struct {
+	Type     int // the receiver struct type
+	Name     string
+	Function int
+	RRef     bool // the method shares its receiver instead of copying it
+}
+
+// Module runs its top-level code when Init is called
+type Module struct {
+	ImportPath string
+	Init       int
+}
+
+// Function is the code of a function, a method, a function literal or the top-level code of a module
+type Function struct {
+	Name     string
+	Params   []int // the parameter types, arguments are converted to them
+	Results  []int // the return types, returned values are converted to them
+	Upvalues []Upvalue
+	Chunk
+}
+
+// Upvalue is a variable a closure captures when it is created: a local of the enclosing function,
+// or an upvalue the enclosing function captured itself
+type Upvalue struct {
+	Local bool
+	Index int
+}
+
+// Chunk is the code of a function with the constants it uses and its line table
+type Chunk struct {
+	Code      []byte
+	Constants []interp.Value // ints, floats, strings and bools
+	Lines     []Line         // sorted by offset
+}
+
+// Line maps the instructions from Offset up to the next line to the source position they were compiled from
+type Line struct {
+	Offset   int
+	File     int // an index in Program.Files
+	Position source.Position
+}
+
+// operand reads the uint16 operand at offset
+func (c *Chunk) operand(offset int) int {
+	return int(binary.BigEndian.Uint16(c.Code[offset:]))
+}
+
+// LineAt returns the line of the instruction at offset
+func (c *Chunk) LineAt(offset int) (Line, bool) {
+	i := sort.Search(len(c.Lines), func(i int) bool { return c.Lines[i].Offset > offset })
+	if i == 0 {
+		return Line{}, false
+	}
+	return c.Lines[i-1], true
+}
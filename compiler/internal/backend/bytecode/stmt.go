@@ -0,0 +1,254 @@
+package bytecode
+
+import (
+	"compiler/internal/backend/interp"
+	"compiler/internal/frontend/ast"
+)
+
+// statement compiles a statement, it leaves the stack as it found it but for the locals it declares
+func (c *compiler) statement(node ast.Node) {
+	switch n := node.(type) {
+	case *ast.VarDeclStmt:
+		c.varDecl(n)
+	case *ast.AssignmentStmt:
+		c.valueList(*n.Right, len(*n.Left))
+		// The values are on the stack in order, the last one on top
+		for i := len(*n.Left) - 1; i >= 0; i-- {
+			c.assign((*n.Left)[i], false)
+		}
+	case *ast.ExpressionStmt:
+		for _, expr := range *n.Expressions {
+			c.expr(expr)
+			c.emit(expr, OpPop)
+		}
+	case *ast.FunctionDecl:
+		// Declared before the function is compiled, so it can call itself
+		c.declareLocal(n, n.Identifier.Name, NoType)
+		c.emit(n, OpClosure, c.function(n.Identifier.Name, n.Function, "", false))
+	case *ast.Block:
+		c.beginScope()
+		c.block(n)
+		c.endScope(n)
+	case *ast.IfStmt:
+		c.ifStmt(n)
+	case *ast.WhileStmt:
+		c.whileStmt(n)
+	case *ast.ForStmt:
+		c.forStmt(n)
+	case *ast.BreakStmt:
+		c.jumpOut(n, true)
+	case *ast.ContinueStmt:
+		c.jumpOut(n, false)
+	case *ast.ReturnStmt:
+		count := 0
+		if n.Values != nil {
+			count = c.valueList(*n.Values, len(c.fn.function.Results))
+		}
+		c.emit(n, OpReturn, count)
+	case *ast.ImportStmt, *ast.TypeDeclStmt, *ast.ModuleDeclStmt, *ast.MethodDecl:
+		// Declarations the resolver and compileModule handled
+	default:
+		c.fail(node, "cannot compile %T", node)
+	}
+}
+
+func (c *compiler) block(block *ast.Block) {
+	for _, node := range block.Nodes {
+		c.statement(node)
+	}
+}
+
+// scopedBlock compiles a block in a scope of its own
+func (c *compiler) scopedBlock(block *ast.Block) {
+	c.beginScope()
+	c.block(block)
+	c.endScope(block)
+}
+
+func (c *compiler) varDecl(n *ast.VarDeclStmt) {
+	types := make([]int, len(n.Variables))
+	for i, v := range n.Variables {
+		types[i] = NoType
+		if v.ExplicitType != nil {
+			types[i] = c.typeOf(v.ExplicitType)
+		}
+	}
+
+	// Each value is stored as its variable's type, unless a call spreads them all at once
+	spread := len(n.Initializers) == 1 && len(n.Variables) > 1
+	for i, init := range n.Initializers {
+		c.expr(init)
+		if spread {
+			c.emit(init, OpSpread, len(n.Variables))
+		} else if i < len(types) {
+			c.emit(init, OpStore, types[i])
+		}
+	}
+	count := len(n.Initializers)
+	if spread {
+		count = len(n.Variables)
+	}
+	for i := count; i < len(n.Variables); i++ {
+		c.emit(n.Variables[i].Identifier, OpZero, types[i])
+	}
+
+	if c.isGlobalScope() {
+		for i := len(n.Variables) - 1; i >= 0; i-- {
+			v := n.Variables[i]
+			if spread {
+				c.emit(v.Identifier, OpStore, types[i])
+			}
+			c.emit(v.Identifier, OpSetGlobal, c.globals[c.importPath][v.Identifier.Name])
+		}
+		return
+	}
+	// The values are where the locals live now
+	first := len(c.fn.locals)
+	for i, v := range n.Variables {
+		c.declareLocal(v.Identifier, v.Identifier.Name, types[i])
+	}
+	if spread {
+		for i, v := range n.Variables {
+			if types[i] != NoType {
+				c.emit(v.Identifier, OpGetLocal, first+i)
+				c.emit(v.Identifier, OpStore, types[i])
+				c.emit(v.Identifier, OpSetLocal, first+i)
+			}
+		}
+	}
+}
+
+// valueList compiles the values of a declaration, an assignment or a return, and returns how many
+// there are on the stack. A single call returning several values spreads them over the want targets.
+func (c *compiler) valueList(exprs []ast.Expression, want int) int {
+	for _, expr := range exprs {
+		c.expr(expr)
+	}
+	if len(exprs) == 1 && want > 1 {
+		c.emit(exprs[0], OpSpread, want)
+		return want
+	}
+	return len(exprs)
+}
+
+// assign pops the value on top of the stack into a variable, a field or an array element. A value
+// of the variable's own type, as ++ computes, needs no conversion.
+func (c *compiler) assign(target ast.Expression, sameType bool) {
+	switch t := target.(type) {
+	case *ast.IdentifierExpr:
+		get, set, index, typ := c.resolve(t, t.Name)
+		c.storeAs(t, typ, sameType, get, index)
+		c.emit(t, set, index)
+	case *ast.VarScopeResolution:
+		index := c.scopedGlobal(t)
+		c.storeAs(t, c.program.Globals[index].Type, sameType, OpGetGlobal, index)
+		c.emit(t, OpSetGlobal, index)
+	case *ast.FieldAccessExpr:
+		c.expr(*t.Object)
+		c.emit(t.Field, OpSetField, c.constant(t.Field, interp.Str(t.Field.Name)))
+	case *ast.IndexableExpr:
+		c.expr(*t.Indexable)
+		c.expr(*t.Index)
+		c.emit(t, OpSetIndex)
+	default:
+		c.fail(target, "cannot assign to %T", target)
+	}
+}
+
+// storeAs converts the value on top of the stack to the type of a variable, the type of its old
+// value when it was declared without one
+func (c *compiler) storeAs(node ast.Node, typ int, sameType bool, get Opcode, index int) {
+	switch {
+	case sameType:
+	case typ != NoType:
+		c.emit(node, OpStore, typ)
+	default:
+		c.emit(node, get, index)
+		c.emit(node, OpStoreLike)
+	}
+}
+
+func (c *compiler) ifStmt(n *ast.IfStmt) {
+	c.expr(*n.Condition)
+	skipBody := c.emitJump(*n.Condition, OpJumpIfFalse)
+	c.scopedBlock(n.Body)
+	if n.Alternative == nil {
+		c.patchJump(n, skipBody)
+		return
+	}
+	skipAlternative := c.emitJump(n, OpJump)
+	c.patchJump(n, skipBody)
+	c.statement(n.Alternative)
+	c.patchJump(n, skipAlternative)
+}
+
+func (c *compiler) whileStmt(n *ast.WhileStmt) {
+	l := &loop{start: len(c.fn.function.Code), locals: len(c.fn.locals)}
+	c.expr(*n.Condition)
+	exit := c.emitJump(*n.Condition, OpJumpIfFalse)
+	c.loopBody(n.Body, l)
+	c.emitLoop(n, l.start)
+	c.patchJump(n, exit)
+	c.endLoop(n, l)
+}
+
+func (c *compiler) forStmt(n *ast.ForStmt) {
+	// The variables of the init statement live as long as the loop
+	c.beginScope()
+	if n.Init != nil {
+		c.statement(n.Init)
+	}
+	l := &loop{start: len(c.fn.function.Code), locals: len(c.fn.locals), isFor: true}
+	exit := -1
+	if n.Condition != nil {
+		c.expr(*n.Condition)
+		exit = c.emitJump(*n.Condition, OpJumpIfFalse)
+	}
+	c.loopBody(n.Body, l)
+	for _, jump := range l.continues {
+		c.patchJump(n, jump)
+	}
+	if n.Post != nil {
+		c.statement(n.Post)
+	}
+	c.emitLoop(n, l.start)
+	if exit >= 0 {
+		c.patchJump(n, exit)
+	}
+	c.endLoop(n, l)
+	c.endScope(n)
+}
+
+// loopBody compiles the body of a loop in a scope of its own, so a closure created in one iteration
+// captures the variables of that iteration
+func (c *compiler) loopBody(body *ast.Block, l *loop) {
+	c.fn.loops = append(c.fn.loops, l)
+	c.scopedBlock(body)
+	c.fn.loops = c.fn.loops[:len(c.fn.loops)-1]
+}
+
+func (c *compiler) endLoop(node ast.Node, l *loop) {
+	for _, jump := range l.breaks {
+		c.patchJump(node, jump)
+	}
+}
+
+// jumpOut compiles break and continue: the locals of the loop body are popped, then the jump leaves
+// the loop or goes to its next iteration
+func (c *compiler) jumpOut(node ast.Node, isBreak bool) {
+	if len(c.fn.loops) == 0 {
+		c.fail(node, "break and continue must be in a loop")
+	}
+	l := c.fn.loops[len(c.fn.loops)-1]
+	for i := len(c.fn.locals) - 1; i >= l.locals; i-- {
+		c.popLocal(node, c.fn.locals[i])
+	}
+	switch {
+	case isBreak:
+		l.breaks = append(l.breaks, c.emitJump(node, OpJump))
+	case l.isFor:
+		l.continues = append(l.continues, c.emitJump(node, OpJump))
+	default:
+		c.emitLoop(node, l.start)
+	}
+}
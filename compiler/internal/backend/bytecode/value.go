@@ -0,0 +1,110 @@
+package bytecode
+
+import (
+	"compiler/internal/backend/interp"
+	"compiler/internal/semantic"
+	"compiler/internal/types"
+)
+
+// Closure is a function with the variables it captured
+type Closure struct {
+	Function *Function
+	Upvalues []*upvalue
+}
+
+// BoundMethod is a method read from a struct value, called with it as its receiver
+type BoundMethod struct {
+	Receiver interp.Value
+	Method   *Closure
+}
+
+// upvalue is a captured variable. It is open while the variable is in its stack slot, and closed
+// over a copy when the variable goes out of scope.
+type upvalue struct {
+	slot   int
+	open   bool
+	closed interp.Value
+}
+
+func (c *Closure) Type() semantic.Type { return semantic.CreatePrimitiveType(types.FUNCTION) }
+func (c *Closure) String() string      { return "fn " + c.Function.Name }
+
+func (m *BoundMethod) Type() semantic.Type { return semantic.CreatePrimitiveType(types.FUNCTION) }
+func (m *BoundMethod) String() string      { return "fn " + m.Method.Function.Name }
+
+// convert gives a value the type it is stored as, the implicit conversions the type checker allows:
+// numbers widen to the kind of the target, and arrays and structs take the element and field types
+// of the target. The types of a program are resolved already.
+func convert(v interp.Value, t semantic.Type) interp.Value {
+	if v == nil || t == nil {
+		return v
+	}
+	switch t := t.(type) {
+	case *semantic.PrimitiveType:
+		if i, ok := v.(interp.Int); ok && i.Kind == t.Name {
+			return v
+		}
+		if interp.IsNumeric(v) && types.GetNumberBitSize(t.Name) > 0 {
+			return interp.ToKind(v, t.Name)
+		}
+	case *semantic.ArrayType:
+		array, ok := v.(*interp.Array)
+		if !ok || t.ElementType == nil || array.Elem != nil && array.Elem.Equals(t.ElementType) {
+			return v
+		}
+		// An array of another element type is a new array, its elements converted
+		converted := &interp.Array{Elems: make([]interp.Value, len(array.Elems)), Elem: t.ElementType}
+		for i, e := range array.Elems {
+			converted.Elems[i] = store(e, t.ElementType)
+		}
+		return converted
+	case *semantic.StructType:
+		s, ok := v.(*interp.Struct)
+		if !ok || s.Def == t {
+			return v
+		}
+		// A struct of another type with the same fields, an anonymous one for one, becomes a value of t
+		return newStruct(t, s.Fields)
+	}
+	return v
+}
+
+// newStruct creates a value of a struct type from the values of its fields, a missing field is zero
+func newStruct(t *semantic.StructType, fields map[string]interp.Value) *interp.Struct {
+	s := &interp.Struct{Def: t, Fields: make(map[string]interp.Value, len(t.Fields))}
+	for name, fieldType := range t.Fields {
+		if field, found := fields[name]; found {
+			s.Fields[name] = store(field, fieldType)
+		} else {
+			s.Fields[name] = zero(fieldType)
+		}
+	}
+	return s
+}
+
+// store converts a value to the type of the variable, field or element it is stored into, and copies it
+func store(v interp.Value, t semantic.Type) interp.Value {
+	return interp.CopyValue(convert(v, t))
+}
+
+// zero is the value of a variable declared without an initializer
+func zero(t semantic.Type) interp.Value {
+	switch t := t.(type) {
+	case *semantic.PrimitiveType:
+		switch {
+		case t.Name == types.FLOAT32 || t.Name == types.FLOAT64:
+			return interp.NewFloat(0, t.Name)
+		case types.GetNumberBitSize(t.Name) > 0:
+			return interp.NewInt(0, t.Name)
+		case t.Name == types.STRING:
+			return interp.Str("")
+		case t.Name == types.BOOL:
+			return interp.Bool(false)
+		}
+	case *semantic.ArrayType:
+		return &interp.Array{Elem: t.ElementType}
+	case *semantic.StructType:
+		return newStruct(t, nil)
+	}
+	return nil
+}
@@ -0,0 +1,582 @@
+package bytecode
+
+import (
+	"fmt"
+	"os"
+
+	"compiler/internal/backend/interp"
+	"compiler/internal/semantic"
+	"compiler/internal/source"
+	"compiler/internal/types"
+)
+
+// maxCallDepth is the deepest a program can call before it stops with a stack overflow
+const maxCallDepth = 10000
+
+// callFrame is a call in progress: the closure running, the offset of its next instruction and the
+// stack slot of its slot 0
+type callFrame struct {
+	closure *Closure
+	ip      int
+	base    int
+}
+
+// method is a method of a struct type while the program runs
+type method struct {
+	closure *Closure
+	rref    bool
+}
+
+// VM runs a compiled program
+type VM struct {
+	interp.System
+
+	program *Program
+	globals []interp.Value
+	methods map[*semantic.StructType]map[string]method
+	stack   []interp.Value
+	frames  []callFrame
+	open    []*upvalue // the open upvalues, by stack slot
+}
+
+// New creates a virtual machine for a program
+func New(program *Program) *VM {
+	return &VM{
+		System:  interp.System{Stdout: os.Stdout, Stdin: os.Stdin},
+		program: program,
+		methods: make(map[*semantic.StructType]map[string]method),
+	}
+}
+
+// Run runs the top-level code of every module in order. A runtime error stops the program and is
+// returned as an *interp.RuntimeError, with the same message and trace the interpreter gives.
+// Decode checks indexes but not the kind of the values they find, so a loaded file holding a value
+// where the code expects another kind stops the program with a *FormatError instead of a crash.
+func (vm *VM) Run() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			switch r := r.(type) {
+			case *interp.RuntimeError:
+				err = r
+			case *FormatError:
+				err = r
+			default:
+				err = vm.invalid(r)
+			}
+		}
+	}()
+
+	p := vm.program
+	vm.globals = make([]interp.Value, len(p.Globals))
+	for i, global := range p.Globals {
+		switch global.Kind {
+		case GlobalFunction:
+			vm.globals[i] = &Closure{Function: p.Functions[global.Function]}
+		case GlobalNative:
+			native := &interp.Native{Name: global.Module + "::" + global.Name, Fn: interp.LookupNative(global.Module, global.Name)}
+			for _, param := range global.Params {
+				native.Params = append(native.Params, vm.typeAt(param))
+			}
+			vm.globals[i] = native
+		}
+	}
+	for _, m := range p.Methods {
+		structType := p.Types[m.Type].(*semantic.StructType)
+		if vm.methods[structType] == nil {
+			vm.methods[structType] = make(map[string]method)
+		}
+		vm.methods[structType][m.Name] = method{closure: &Closure{Function: p.Functions[m.Function]}, rref: m.RRef}
+	}
+
+	for _, m := range p.Modules {
+		init := &Closure{Function: p.Functions[m.Init]}
+		vm.push(init)
+		vm.call(init, 0)
+		vm.run()
+		vm.pop()
+	}
+	return nil
+}
+
+// typeAt returns a type of the program, nil for NoType
+func (vm *VM) typeAt(index int) semantic.Type {
+	if index == NoType {
+		return nil
+	}
+	return vm.program.Types[index]
+}
+
+func (vm *VM) push(v interp.Value) {
+	vm.stack = append(vm.stack, v)
+}
+
+func (vm *VM) pop() interp.Value {
+	v := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return v
+}
+
+func (vm *VM) peek() interp.Value {
+	return vm.stack[len(vm.stack)-1]
+}
+
+// fail stops the program with a runtime error at the current instruction of every frame
+func (vm *VM) fail(format string, args ...any) {
+	err := &interp.RuntimeError{Message: fmt.Sprintf(format, args...)}
+	for i := len(vm.frames) - 1; i >= 0; i-- {
+		f := vm.frames[i]
+		trace := interp.Frame{Function: f.closure.Function.Name}
+		// ip is past the instruction already, any of its bytes finds its line
+		if line, found := f.closure.Function.LineAt(f.ip - 1); found {
+			pos := line.Position
+			trace.File = vm.program.Files[line.File]
+			trace.Location = source.Location{Start: &pos, End: &pos}
+		}
+		err.Trace = append(err.Trace, trace)
+	}
+	panic(err)
+}
+
+// invalid returns the error for code of a loaded file that uses a value of the wrong kind, found by
+// the instruction running in the top frame
+func (vm *VM) invalid(problem any) *FormatError {
+	if len(vm.frames) == 0 {
+		return &FormatError{Message: fmt.Sprint(problem)}
+	}
+	f := vm.frames[len(vm.frames)-1]
+	return &FormatError{Message: fmt.Sprintf("function %s, the instruction before %d: %v", f.closure.Function.Name, f.ip, problem)}
+}
+
+// run executes instructions until the frame on top when it was called returns
+func (vm *VM) run() {
+	depth := len(vm.frames)
+	f := &vm.frames[depth-1]
+	chunk := &f.closure.Function.Chunk
+	for {
+		op := Opcode(chunk.Code[f.ip])
+		f.ip++
+		operand := 0
+		if op.Operands() > 0 {
+			operand = chunk.operand(f.ip)
+			f.ip += 2 * op.Operands()
+		}
+
+		switch op {
+		case OpConstant:
+			vm.push(chunk.Constants[operand])
+		case OpNil:
+			vm.push(nil)
+		case OpPop:
+			vm.pop()
+		case OpDup:
+			vm.push(vm.peek())
+		case OpGetLocal:
+			vm.push(vm.stack[f.base+operand])
+		case OpSetLocal:
+			vm.stack[f.base+operand] = vm.pop()
+		case OpGetGlobal:
+			vm.push(vm.globals[operand])
+		case OpSetGlobal:
+			vm.globals[operand] = vm.pop()
+		case OpGetUpvalue:
+			vm.push(vm.upvalueValue(f.closure.Upvalues[operand]))
+		case OpSetUpvalue:
+			if uv := f.closure.Upvalues[operand]; uv.open {
+				vm.stack[uv.slot] = vm.pop()
+			} else {
+				uv.closed = vm.pop()
+			}
+		case OpCloseUpvalue:
+			vm.closeUpvalues(len(vm.stack) - 1)
+			vm.pop()
+		case OpStore:
+			top := len(vm.stack) - 1
+			vm.stack[top] = store(vm.stack[top], vm.typeAt(operand))
+		case OpStoreLike:
+			old := vm.pop()
+			top := len(vm.stack) - 1
+			vm.stack[top] = store(vm.stack[top], interp.TypeOfValue(old))
+		case OpZero:
+			vm.push(zero(vm.typeAt(operand)))
+		case OpAdd, OpSub, OpMul, OpDiv, OpMod:
+			right := vm.pop()
+			top := len(vm.stack) - 1
+			vm.stack[top] = vm.arithmetic(op, vm.stack[top], right)
+		case OpEqual, OpNotEqual, OpLess, OpLessEqual, OpGreater, OpGreaterEqual:
+			right := vm.pop()
+			top := len(vm.stack) - 1
+			vm.stack[top] = vm.comparison(op, vm.stack[top], right)
+		case OpNegate:
+			top := len(vm.stack) - 1
+			vm.stack[top] = interp.Negate(vm.stack[top])
+		case OpNot:
+			top := len(vm.stack) - 1
+			vm.stack[top] = !vm.boolean(vm.stack[top])
+		case OpIncrement, OpDecrement:
+			top := len(vm.stack) - 1
+			vm.stack[top] = vm.increment(op, vm.stack[top])
+		case OpJump:
+			f.ip += operand
+		case OpJumpIfFalse:
+			if !vm.boolean(vm.pop()) {
+				f.ip += operand
+			}
+		case OpLoop:
+			f.ip -= operand
+		case OpCall:
+			vm.call(vm.stack[len(vm.stack)-1-operand], operand)
+			f = &vm.frames[len(vm.frames)-1]
+			chunk = &f.closure.Function.Chunk
+		case OpReturn:
+			vm.ret(f, operand)
+			if len(vm.frames) < depth {
+				return
+			}
+			f = &vm.frames[len(vm.frames)-1]
+			chunk = &f.closure.Function.Chunk
+		case OpClosure:
+			vm.push(vm.closure(f, vm.program.Functions[operand]))
+		case OpArray:
+			vm.array(operand)
+		case OpStruct:
+			count := chunk.operand(f.ip - 2)
+			fields := vm.popFields(count)
+			vm.push(newStruct(vm.program.Types[operand].(*semantic.StructType), fields))
+		case OpAnonStruct:
+			fields := vm.popFields(operand)
+			def := &semantic.StructType{Name: types.STRUCT, Fields: make(map[string]semantic.Type, len(fields))}
+			for name, field := range fields {
+				def.Fields[name] = interp.TypeOfValue(field)
+				fields[name] = interp.CopyValue(field)
+			}
+			vm.push(&interp.Struct{Def: def, Fields: fields})
+		case OpGetField:
+			top := len(vm.stack) - 1
+			vm.stack[top] = vm.field(vm.stack[top], string(chunk.Constants[operand].(interp.Str)))
+		case OpSetField:
+			object := vm.pop()
+			vm.setField(object, string(chunk.Constants[operand].(interp.Str)), vm.pop())
+		case OpIndex:
+			array, index := vm.element()
+			vm.push(array.Elems[index])
+		case OpSetIndex:
+			array, index := vm.element()
+			array.Elems[index] = store(vm.pop(), array.Elem)
+		case OpSpread:
+			vm.spread(operand)
+		default:
+			vm.fail("invalid instruction %s", op)
+		}
+	}
+}
+
+// call calls a callee below argc arguments. A closure gets a frame the run loop continues in,
+// a native function returns its value at once.
+func (vm *VM) call(callee interp.Value, argc int) {
+	base := len(vm.stack) - 1 - argc
+	switch c := callee.(type) {
+	case *Closure:
+		vm.callClosure(c, base, argc)
+	case *BoundMethod:
+		vm.stack[base] = c.Receiver
+		vm.callClosure(c.Method, base, argc)
+	case *interp.Native:
+		args := make([]interp.Value, argc)
+		copy(args, vm.stack[base+1:])
+		for i := range args {
+			if i < len(c.Params) {
+				args[i] = convert(args[i], c.Params[i])
+			}
+		}
+		result, err := c.Fn(&vm.System, args)
+		if err != nil {
+			vm.fail("%s", err)
+		}
+		vm.stack = vm.stack[:base]
+		vm.push(result)
+	default:
+		vm.fail("cannot call %s", interp.Display(callee))
+	}
+}
+
+func (vm *VM) callClosure(c *Closure, base, argc int) {
+	fn := c.Function
+	if argc != len(fn.Params) {
+		vm.fail("%s expects %d arguments, got %d", fn.Name, len(fn.Params), argc)
+	}
+	if len(vm.frames) >= maxCallDepth {
+		vm.fail("stack overflow, more than %d nested calls", maxCallDepth)
+	}
+	for i, param := range fn.Params {
+		vm.stack[base+1+i] = store(vm.stack[base+1+i], vm.typeAt(param))
+	}
+	vm.frames = append(vm.frames, callFrame{closure: c, base: base})
+}
+
+// ret returns from the frame f with the count values on top of the stack, converted to its return types
+func (vm *VM) ret(f *callFrame, count int) {
+	results := vm.stack[len(vm.stack)-count:]
+	for i := range results {
+		if i < len(f.closure.Function.Results) {
+			results[i] = store(results[i], vm.typeAt(f.closure.Function.Results[i]))
+		}
+	}
+	var result interp.Value
+	switch count {
+	case 0:
+	case 1:
+		result = results[0]
+	default:
+		result = interp.Tuple(append([]interp.Value(nil), results...))
+	}
+	vm.closeUpvalues(f.base)
+	vm.stack = vm.stack[:f.base]
+	vm.push(result)
+	vm.frames = vm.frames[:len(vm.frames)-1]
+}
+
+// closure creates a closure of fn in the frame f, capturing its locals and upvalues
+func (vm *VM) closure(f *callFrame, fn *Function) *Closure {
+	c := &Closure{Function: fn, Upvalues: make([]*upvalue, len(fn.Upvalues))}
+	for i, uv := range fn.Upvalues {
+		if uv.Local {
+			c.Upvalues[i] = vm.capture(f.base + uv.Index)
+		} else {
+			c.Upvalues[i] = f.closure.Upvalues[uv.Index]
+		}
+	}
+	return c
+}
+
+// capture returns the open upvalue of a stack slot, closures capturing the same variable share it
+func (vm *VM) capture(slot int) *upvalue {
+	for _, uv := range vm.open {
+		if uv.slot == slot {
+			return uv
+		}
+	}
+	uv := &upvalue{slot: slot, open: true}
+	vm.open = append(vm.open, uv)
+	return uv
+}
+
+// closeUpvalues closes the upvalues of the stack slots from slot up, their variables go out of scope
+func (vm *VM) closeUpvalues(slot int) {
+	open := vm.open[:0]
+	for _, uv := range vm.open {
+		if uv.slot >= slot {
+			uv.closed, uv.open = vm.stack[uv.slot], false
+		} else {
+			open = append(open, uv)
+		}
+	}
+	vm.open = open
+}
+
+func (vm *VM) upvalueValue(uv *upvalue) interp.Value {
+	if uv.open {
+		return vm.stack[uv.slot]
+	}
+	return uv.closed
+}
+
+// boolean returns the value of a condition
+func (vm *VM) boolean(v interp.Value) interp.Bool {
+	b, ok := v.(interp.Bool)
+	if !ok {
+		vm.fail("expected a bool, got %s", interp.Display(v))
+	}
+	return b
+}
+
+var arithmeticOps = map[Opcode]string{OpAdd: "+", OpSub: "-", OpMul: "*", OpDiv: "/", OpMod: "%"}
+
+func (vm *VM) arithmetic(op Opcode, left, right interp.Value) interp.Value {
+	// Integers of the same kind are the common case, they need no conversion
+	if l, ok := left.(interp.Int); ok {
+		if r, ok := right.(interp.Int); ok && l.Kind == r.Kind {
+			switch op {
+			case OpAdd:
+				return interp.NewInt(l.V+r.V, l.Kind)
+			case OpSub:
+				return interp.NewInt(l.V-r.V, l.Kind)
+			case OpMul:
+				return interp.NewInt(l.V*r.V, l.Kind)
+			}
+		}
+	}
+
+	symbol := arithmeticOps[op]
+	// Numbers of different types are converted to their common type first, as i32 + i64 is an i64
+	if interp.IsNumeric(left) && interp.IsNumeric(right) {
+		kind := interp.CommonKind(left, right)
+		left, right = interp.ToKind(left, kind), interp.ToKind(right, kind)
+	} else if l, ok := left.(interp.Str); ok && op == OpAdd {
+		if r, ok := right.(interp.Str); ok {
+			return l + r
+		}
+	}
+	if !interp.IsNumeric(left) || !interp.IsNumeric(right) {
+		vm.fail("invalid operands for %s: %s and %s", symbol, interp.Display(left), interp.Display(right))
+	}
+	result, err := interp.Arithmetic(symbol, left, right)
+	if err != nil {
+		vm.fail("%s", err)
+	}
+	return result
+}
+
+func (vm *VM) comparison(op Opcode, left, right interp.Value) interp.Value {
+	if l, ok := left.(interp.Int); ok {
+		if r, ok := right.(interp.Int); ok && l.Kind == r.Kind && !l.Unsigned() {
+			switch op {
+			case OpEqual:
+				return interp.Bool(l.V == r.V)
+			case OpNotEqual:
+				return interp.Bool(l.V != r.V)
+			case OpLess:
+				return interp.Bool(l.V < r.V)
+			case OpLessEqual:
+				return interp.Bool(l.V <= r.V)
+			case OpGreater:
+				return interp.Bool(l.V > r.V)
+			case OpGreaterEqual:
+				return interp.Bool(l.V >= r.V)
+			}
+		}
+	}
+
+	if interp.IsNumeric(left) && interp.IsNumeric(right) {
+		kind := interp.CommonKind(left, right)
+		left, right = interp.ToKind(left, kind), interp.ToKind(right, kind)
+	}
+	switch op {
+	case OpEqual:
+		return interp.Bool(interp.Equal(left, right))
+	case OpNotEqual:
+		return interp.Bool(!interp.Equal(left, right))
+	}
+	_, isStr := left.(interp.Str)
+	if !interp.IsNumeric(left) && !isStr || left.Type().TypeName() != right.Type().TypeName() {
+		vm.fail("cannot compare %s and %s", interp.Display(left), interp.Display(right))
+	}
+	c := interp.Compare(left, right)
+	return interp.Bool(op == OpLess && c < 0 || op == OpLessEqual && c <= 0 || op == OpGreater && c > 0 || op == OpGreaterEqual && c >= 0)
+}
+
+// increment adds or subtracts one for ++ and --, in the kind of the value
+func (vm *VM) increment(op Opcode, v interp.Value) interp.Value {
+	symbol := "++"
+	delta := int64(1)
+	if op == OpDecrement {
+		symbol, delta = "--", -1
+	}
+	switch v := v.(type) {
+	case interp.Int:
+		return interp.NewInt(v.V+delta, v.Kind)
+	case interp.Float:
+		return interp.NewFloat(v.V+float64(delta), v.Kind)
+	}
+	vm.fail("cannot apply %s to %s", symbol, interp.Display(v))
+	return nil
+}
+
+// array pops count elements into a new array, of the common type of the elements
+func (vm *VM) array(count int) {
+	elems := make([]interp.Value, count)
+	copy(elems, vm.stack[len(vm.stack)-count:])
+	vm.stack = vm.stack[:len(vm.stack)-count]
+
+	array := &interp.Array{Elems: elems}
+	for _, elem := range elems {
+		t := interp.TypeOfValue(elem)
+		if array.Elem == nil {
+			array.Elem = t
+		} else if t != nil {
+			if common := semantic.GetCommonType(array.Elem, t); common != nil {
+				array.Elem = common
+			}
+		}
+	}
+	for i, elem := range elems {
+		elems[i] = store(elem, array.Elem)
+	}
+	vm.push(array)
+}
+
+// popFields pops count field name and value pairs
+func (vm *VM) popFields(count int) map[string]interp.Value {
+	fields := make(map[string]interp.Value, count)
+	pairs := vm.stack[len(vm.stack)-2*count:]
+	for i := 0; i < len(pairs); i += 2 {
+		name, ok := pairs[i].(interp.Str)
+		if !ok {
+			panic(vm.invalid(fmt.Sprintf("the field name %v is not a string", pairs[i])))
+		}
+		fields[string(name)] = pairs[i+1]
+	}
+	vm.stack = vm.stack[:len(vm.stack)-2*count]
+	return fields
+}
+
+// field returns a field of a struct, or one of its methods bound to a copy of it
+func (vm *VM) field(object interp.Value, name string) interp.Value {
+	s, ok := object.(*interp.Struct)
+	if !ok {
+		vm.fail("cannot read '%s' of %s", name, interp.Display(object))
+	}
+	if field, found := s.Fields[name]; found {
+		return field
+	}
+	m, found := vm.methods[s.Def][name]
+	if !found {
+		vm.fail("%s has no field or method '%s'", s.Def.Name, name)
+	}
+	if m.rref {
+		return &BoundMethod{Receiver: s, Method: m.closure}
+	}
+	return &BoundMethod{Receiver: interp.CopyValue(s), Method: m.closure}
+}
+
+func (vm *VM) setField(object interp.Value, name string, v interp.Value) {
+	s, ok := object.(*interp.Struct)
+	if !ok {
+		vm.fail("cannot set '%s' of %s", name, interp.Display(object))
+	}
+	s.Fields[name] = store(v, s.Def.Fields[name])
+}
+
+// element pops an array and an index, and checks the index
+func (vm *VM) element() (*interp.Array, int) {
+	indexValue := vm.pop()
+	indexable := vm.pop()
+	array, ok := indexable.(*interp.Array)
+	if !ok {
+		vm.fail("cannot index %s", interp.Display(indexable))
+	}
+	index, ok := indexValue.(interp.Int)
+	if !ok {
+		vm.fail("an array index must be an integer")
+	}
+	// A u64 above the int64 range reads as negative, and is out of range too
+	if index.V < 0 || index.V >= int64(len(array.Elems)) {
+		vm.fail("index %s out of range for an array of length %d", index, len(array.Elems))
+	}
+	return array, int(index.V)
+}
+
+// spread replaces the tuple a call returned with its count values
+func (vm *VM) spread(count int) {
+	tuple, ok := vm.peek().(interp.Tuple)
+	if !ok || len(tuple) < count {
+		got := 1
+		if ok {
+			got = len(tuple)
+		}
+		vm.fail("expected %d values, got %d", count, got)
+	}
+	vm.pop()
+	for _, v := range tuple[:count] {
+		vm.push(v)
+	}
+}
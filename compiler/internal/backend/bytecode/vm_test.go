@@ -0,0 +1,124 @@
+package bytecode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"compiler/ctx"
+	"compiler/internal/backend/interp"
+	"compiler/internal/semantic/resolver"
+	"compiler/internal/semantic/typecheck"
+	"compiler/internal/testutil/fixture"
+)
+
+// checkProject writes the files into a project named "app" and type checks main.fer with the modules it imports
+func checkProject(tb testing.TB, files map[string]string) *ctx.CompilerContext {
+	tb.Helper()
+	return fixture.Check(tb, files, resolver.ResolveModules, typecheck.CheckModules)
+}
+
+// runBoth compiles and runs a project on the VM, and interprets it too. It returns what the VM printed
+// and its runtime error, after checking the interpreter printed the same.
+func runBoth(t *testing.T, files map[string]string, stdin string) (string, error) {
+	t.Helper()
+	context := checkProject(t, files)
+
+	program, err := Compile(context)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	// What the compiler writes passes the checks a loaded program gets
+	if err := program.validate(); err != nil {
+		t.Fatalf("validate() error = %v", err)
+	}
+	var out bytes.Buffer
+	vm := New(program)
+	vm.Stdout = &out
+	vm.Stdin = strings.NewReader(stdin)
+	runErr := vm.Run()
+
+	var interpOut bytes.Buffer
+	in := interp.New(context)
+	in.Stdout = &interpOut
+	in.Stdin = strings.NewReader(stdin)
+	interpErr := in.Run()
+	if out.String() != interpOut.String() || fmt.Sprint(runErr) != fmt.Sprint(interpErr) {
+		t.Errorf("the VM printed %q and returned %v, the interpreter printed %q and returned %v", out.String(), runErr, interpOut.String(), interpErr)
+	}
+	return out.String(), runErr
+}
+
+func TestPrograms(t *testing.T) {
+	for _, program := range fixture.Programs {
+		t.Run(program.Name, func(t *testing.T) {
+			out, err := runBoth(t, program.Files, program.Stdin)
+			if out != program.Stdout {
+				t.Errorf("Run() printed %q, want %q", out, program.Stdout)
+			}
+			if program.Err == "" {
+				if err != nil {
+					t.Fatalf("Run() error = %v", err)
+				}
+				return
+			}
+
+			var runtimeErr *interp.RuntimeError
+			if !errors.As(err, &runtimeErr) {
+				t.Fatalf("Run() error = %v, want a runtime error", err)
+			}
+			if !strings.Contains(runtimeErr.Message, program.Err) {
+				t.Errorf("Message = %q, want %q", runtimeErr.Message, program.Err)
+			}
+			if program.Trace == nil {
+				return
+			}
+			var trace []string
+			for _, frame := range runtimeErr.Trace {
+				trace = append(trace, fmt.Sprintf("%s %d:%d", frame.Function, frame.Location.Start.Line, frame.Location.Start.Column))
+			}
+			if strings.Join(trace, ", ") != strings.Join(program.Trace, ", ") {
+				t.Errorf("Trace = %v, want %v", trace, program.Trace)
+			}
+		})
+	}
+}
+
+func TestLineTable(t *testing.T) {
+	context := checkProject(t, map[string]string{"main.fer": `fn add(a: i32, b: i32) -> i32 {
+    let sum = a + b;
+    return sum;
+}`})
+	program, err := Compile(context)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	var add *Function
+	for _, fn := range program.Functions {
+		if fn.Name == "add" {
+			add = fn
+		}
+	}
+	if add == nil {
+		t.Fatal("add was not compiled")
+	}
+
+	// Every instruction maps to the position of the expression or statement it was compiled from
+	var got []string
+	for offset := 0; offset < len(add.Code); offset += Opcode(add.Code[offset]).Size() {
+		line, found := add.LineAt(offset)
+		if !found {
+			t.Fatalf("no line for the instruction at %d", offset)
+		}
+		got = append(got, fmt.Sprintf("%s %d:%d", Opcode(add.Code[offset]), line.Position.Line, line.Position.Column))
+	}
+	want := []string{"GET_LOCAL 2:15", "GET_LOCAL 2:19", "ADD 2:15", "STORE 2:15", "GET_LOCAL 3:12", "RETURN 3:5", "RETURN 1:1"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("instructions = %v, want %v", got, want)
+	}
+	if !strings.HasSuffix(program.Files[add.Lines[0].File], "/app/main.fer") {
+		t.Errorf("the lines are in %s, want main.fer", program.Files[add.Lines[0].File])
+	}
+}
@@ -0,0 +1,522 @@
+package cgen
+
+import (
+	"math"
+	"strconv"
+
+	"compiler/internal/frontend/ast"
+	"compiler/internal/semantic"
+	"compiler/internal/types"
+)
+
+// function is a C function the generator writes: a Ferret function, a method, a closure or the
+// top-level code of a module
+type function struct {
+	name     string // the name runtime errors show
+	cname    string
+	module   *module
+	file     *ast.Program
+	parent   *function // the function a closure is created in
+	lit      *ast.FunctionLiteral
+	sig      *semantic.FunctionType
+	receiver *variable // the receiver of a method, a pointer to the struct it is called on
+	params   []*variable
+	captures []*variable          // the variables of the functions around it a closure uses
+	fields   map[*variable]string // the field of the closure environment holding each capture
+	names    map[string]bool      // the C names of its locals
+	value    string               // the static closure object of a closure capturing nothing
+	written  bool
+}
+
+// variable is a local variable or a parameter
+type variable struct {
+	name     string
+	cname    string
+	owner    *function
+	typ      semantic.Type
+	boxed    bool // it lives in memory the C variable points to, so closures and methods taking it by reference share it
+	captured bool // a closure uses it
+	param    bool // boxed parameters are copied into their box when the function starts
+}
+
+// local returns a C name for a local of fn no other local of it uses
+func (fn *function) local(name string) string {
+	candidate := name
+	for i := 2; fn.names[candidate]; i++ {
+		candidate = name + "_" + strconv.Itoa(i)
+	}
+	fn.names[candidate] = true
+	return candidate
+}
+
+// scope is a scope of local variables, as the interpreter opens them: a function body shares the
+// scope of its parameters, a block, a branch or a loop body opens a new one
+type scope struct {
+	vars   map[string]*variable
+	parent *scope
+	fn     *function
+}
+
+func newScope(parent *scope, fn *function) *scope {
+	return &scope{vars: make(map[string]*variable), parent: parent, fn: fn}
+}
+
+func (s *scope) lookup(name string) *variable {
+	for sc := s; sc != nil; sc = sc.parent {
+		if v, found := sc.vars[name]; found {
+			return v
+		}
+	}
+	return nil
+}
+
+// analyze finds which variable every identifier of a module refers to, infers the type of every
+// local and finds the variables closures capture, which are boxed. Names no scope declares are globals.
+func (g *generator) analyze(m *module) {
+	for _, node := range topLevel(m.info) {
+		g.enter(m, node)
+		switch n := node.(type) {
+		case *ast.FunctionDecl:
+			if n.Function.Body != nil {
+				g.analyzeFunction(n.Function, n.Identifier.Name, nil, nil, m.globals[n.Identifier.Name].cname)
+			}
+		case *ast.MethodDecl:
+			if mt := g.methodOf(n); mt != nil && n.Function.Body != nil {
+				g.analyzeFunction(n.Function, string(mt.owner.Name)+"."+n.Method.Name, nil, n, mt.cname)
+			}
+		case *ast.VarDeclStmt:
+			// A top-level declaration declares globals, the functions in its initializers capture nothing
+			g.analyzeExprs(n.Initializers, newScope(nil, m.init))
+		default:
+			g.analyzeStmt(node, newScope(nil, m.init))
+		}
+	}
+}
+
+// methodOf returns the method a method declaration declares
+func (g *generator) methodOf(n *ast.MethodDecl) *method {
+	if n.Receiver == nil {
+		return nil
+	}
+	owner, ok := g.astType(n.Receiver.Type).(*semantic.StructType)
+	if !ok {
+		return nil
+	}
+	return g.methods[owner][n.Method.Name]
+}
+
+// analyzeFunction analyzes a function literal created in the scope outer, which is nil for a
+// top-level function or method
+func (g *generator) analyzeFunction(lit *ast.FunctionLiteral, name string, outer *scope, decl *ast.MethodDecl, cname string) *function {
+	fn := &function{name: name, cname: cname, module: g.module, file: g.file, lit: lit, fields: make(map[*variable]string), names: make(map[string]bool)}
+	if outer != nil {
+		fn.parent = outer.fn
+	}
+	g.functions[lit] = fn
+	fn.sig = g.signature(lit)
+	sc := newScope(outer, fn)
+	if decl != nil {
+		// Every method takes a pointer to its receiver: the variable of a method taking it by reference,
+		// or a copy, which a bound method keeps from one call to the next
+		fn.receiver = g.declareVar(sc, decl.Receiver.Identifier, g.astType(decl.Receiver.Type))
+		fn.receiver.param, fn.receiver.boxed = true, true
+	}
+	for _, param := range lit.Params {
+		v := g.declareVar(sc, param.Identifier, g.astType(param.Type))
+		v.param = true
+		fn.params = append(fn.params, v)
+	}
+	if lit.Body != nil {
+		for _, node := range lit.Body.Nodes {
+			g.analyzeStmt(node, sc)
+		}
+	}
+	return fn
+}
+
+func (g *generator) declareVar(sc *scope, ident *ast.IdentifierExpr, t semantic.Type) *variable {
+	v := &variable{name: ident.Name, cname: sc.fn.local(mangle(ident.Name)), owner: sc.fn, typ: t}
+	sc.vars[ident.Name] = v
+	g.vars[ident] = v
+	return v
+}
+
+func (g *generator) analyzeStmt(node ast.Node, sc *scope) {
+	switch n := node.(type) {
+	case *ast.VarDeclStmt:
+		g.analyzeExprs(n.Initializers, sc)
+		var results []semantic.Type
+		if len(n.Initializers) == 1 && len(n.Variables) > 1 {
+			if tuple, ok := g.typeOf(n.Initializers[0]).(*tupleType); ok {
+				results = tuple.elems
+			}
+		} else {
+			for _, init := range n.Initializers {
+				results = append(results, g.typeOf(init))
+			}
+		}
+		for i, v := range n.Variables {
+			var t semantic.Type
+			switch {
+			case v.ExplicitType != nil:
+				t = g.astType(v.ExplicitType)
+			case i < len(results):
+				t = results[i]
+			default:
+				g.fail(v.Identifier, "cannot infer the type of '%s'", v.Identifier.Name)
+			}
+			g.declareVar(sc, v.Identifier, t)
+		}
+	case *ast.AssignmentStmt:
+		g.analyzeExprs(*n.Right, sc)
+		g.analyzeExprs(*n.Left, sc)
+	case *ast.ExpressionStmt:
+		g.analyzeExprs(*n.Expressions, sc)
+	case *ast.FunctionDecl:
+		// Declared before its body is analyzed, so it can call itself
+		g.declareVar(sc, n.Identifier, g.typeOf(n.Function))
+		g.analyzeFunction(n.Function, n.Identifier.Name, sc, nil, g.unique(sc.fn.cname+"__"+mangle(n.Identifier.Name)))
+	case *ast.Block:
+		g.analyzeBlock(n, sc)
+	case *ast.IfStmt:
+		g.analyzeExpr(*n.Condition, sc)
+		g.analyzeBlock(n.Body, sc)
+		if n.Alternative != nil {
+			g.analyzeStmt(n.Alternative, sc)
+		}
+	case *ast.WhileStmt:
+		g.analyzeExpr(*n.Condition, sc)
+		g.analyzeBlock(n.Body, sc)
+	case *ast.ForStmt:
+		loop := newScope(sc, sc.fn)
+		if n.Init != nil {
+			g.analyzeStmt(n.Init, loop)
+		}
+		if n.Condition != nil {
+			g.analyzeExpr(*n.Condition, loop)
+		}
+		g.analyzeBlock(n.Body, loop)
+		if n.Post != nil {
+			g.analyzeStmt(n.Post, loop)
+		}
+	case *ast.ReturnStmt:
+		if n.Values != nil {
+			g.analyzeExprs(*n.Values, sc)
+		}
+	}
+}
+
+func (g *generator) analyzeBlock(block *ast.Block, sc *scope) {
+	inner := newScope(sc, sc.fn)
+	for _, node := range block.Nodes {
+		g.analyzeStmt(node, inner)
+	}
+}
+
+func (g *generator) analyzeExprs(exprs []ast.Expression, sc *scope) {
+	for _, expr := range exprs {
+		g.analyzeExpr(expr, sc)
+	}
+}
+
+func (g *generator) analyzeExpr(expr ast.Expression, sc *scope) {
+	switch e := expr.(type) {
+	case *ast.IdentifierExpr:
+		v := sc.lookup(e.Name)
+		if v == nil {
+			return
+		}
+		g.vars[e] = v
+		// A variable of another function is captured by every closure between the two
+		for fn := sc.fn; fn != v.owner && fn != nil; fn = fn.parent {
+			if _, found := fn.fields[v]; !found {
+				fn.captures = append(fn.captures, v)
+				fn.fields[v] = fn.local(v.cname)
+			}
+			v.boxed, v.captured = true, true
+		}
+	case *ast.BinaryExpr:
+		g.analyzeExpr(*e.Left, sc)
+		g.analyzeExpr(*e.Right, sc)
+	case *ast.UnaryExpr:
+		g.analyzeExpr(*e.Operand, sc)
+	case *ast.PrefixExpr:
+		g.analyzeExpr(*e.Operand, sc)
+	case *ast.PostfixExpr:
+		g.analyzeExpr(*e.Operand, sc)
+	case *ast.FunctionCallExpr:
+		g.analyzeExpr(*e.Caller, sc)
+		g.analyzeExprs(e.Arguments, sc)
+	case *ast.FieldAccessExpr:
+		g.analyzeExpr(*e.Object, sc)
+		// A method taking its receiver by reference may keep a pointer to it in a closure, so the
+		// variable holding the struct is boxed
+		if structType, ok := g.typeOf(*e.Object).(*semantic.StructType); ok {
+			if mt := g.methods[structType][e.Field.Name]; mt != nil && mt.decl.IsRRef && structType.Fields[e.Field.Name] == nil {
+				if v := g.vars[rootVariable(*e.Object)]; v != nil {
+					v.boxed = true
+				}
+			}
+		}
+	case *ast.IndexableExpr:
+		g.analyzeExpr(*e.Indexable, sc)
+		g.analyzeExpr(*e.Index, sc)
+	case *ast.ArrayLiteralExpr:
+		g.analyzeExprs(e.Elements, sc)
+	case *ast.StructLiteralExpr:
+		for _, field := range e.Fields {
+			if field.FieldValue != nil {
+				g.analyzeExpr(*field.FieldValue, sc)
+			}
+		}
+	case *ast.FunctionLiteral:
+		g.analyzeFunction(e, "fn", sc, nil, g.unique(sc.fn.cname+"__fn"))
+	}
+}
+
+// rootVariable returns the identifier a chain of field accesses starts with, or nil
+func rootVariable(expr ast.Expression) *ast.IdentifierExpr {
+	for {
+		switch e := expr.(type) {
+		case *ast.IdentifierExpr:
+			return e
+		case *ast.FieldAccessExpr:
+			expr = *e.Object
+		default:
+			return nil
+		}
+	}
+}
+
+// typeOf returns the static type of an expression, with the types the interpreter gives its values
+func (g *generator) typeOf(expr ast.Expression) semantic.Type {
+	if t, found := g.exprTypes[expr]; found {
+		return t
+	}
+	t := g.inferType(expr)
+	g.exprTypes[expr] = t
+	return t
+}
+
+func (g *generator) inferType(expr ast.Expression) semantic.Type {
+	switch e := expr.(type) {
+	case *ast.IntLiteral:
+		// Literals are i32, as the type checker infers them, unless the value needs i64
+		if e.Value < math.MinInt32 || e.Value > math.MaxInt32 {
+			return primitive(types.INT64)
+		}
+		return primitive(types.INT32)
+	case *ast.FloatLiteral:
+		return primitive(types.FLOAT64)
+	case *ast.StringLiteral:
+		return primitive(types.STRING)
+	case *ast.BoolLiteral:
+		return primitive(types.BOOL)
+	case *ast.ByteLiteral:
+		return primitive(types.BYTE)
+	case *ast.IdentifierExpr:
+		if v := g.vars[e]; v != nil {
+			return v.typ
+		}
+		gl := g.global(g.module, e.Name)
+		if gl == nil {
+			g.fail(e, "'%s' is not defined", e.Name)
+		}
+		return g.globalType(gl)
+	case *ast.VarScopeResolution:
+		return g.globalType(g.scopedGlobal(e))
+	case *ast.BinaryExpr:
+		switch e.Operator.Value {
+		case "&&", "||", "==", "!=", "<", "<=", ">", ">=":
+			return primitive(types.BOOL)
+		}
+		left, right := g.typeOf(*e.Left), g.typeOf(*e.Right)
+		if common := semantic.GetCommonType(left, right); common != nil {
+			return common
+		}
+		return left
+	case *ast.UnaryExpr:
+		if e.Operator.Value == "!" {
+			return primitive(types.BOOL)
+		}
+		return g.typeOf(*e.Operand)
+	case *ast.PrefixExpr:
+		return g.typeOf(*e.Operand)
+	case *ast.PostfixExpr:
+		return g.typeOf(*e.Operand)
+	case *ast.FunctionCallExpr:
+		fnType, ok := g.typeOf(*e.Caller).(*semantic.FunctionType)
+		if !ok {
+			g.fail(*e.Caller, "cannot call a value that is not a function")
+		}
+		return results(fnType)
+	case *ast.FieldAccessExpr:
+		if iface, ok := g.typeOf(*e.Object).(*semantic.InterfaceType); ok {
+			if sig := iface.Methods[e.Field.Name]; sig != nil {
+				return sig
+			}
+			g.fail(e.Field, "%s has no method '%s'", iface.Name, e.Field.Name)
+		}
+		structType, ok := g.typeOf(*e.Object).(*semantic.StructType)
+		if !ok {
+			g.fail(e.Field, "cannot read '%s' of a value that is not a struct", e.Field.Name)
+		}
+		if field, found := structType.Fields[e.Field.Name]; found {
+			return field
+		}
+		if mt := g.methods[structType][e.Field.Name]; mt != nil {
+			return g.methodType(mt)
+		}
+		g.fail(e.Field, "%s has no field or method '%s'", structType.Name, e.Field.Name)
+	case *ast.IndexableExpr:
+		array, ok := g.typeOf(*e.Indexable).(*semantic.ArrayType)
+		if !ok {
+			g.fail(*e.Indexable, "cannot index a value that is not an array")
+		}
+		return array.ElementType
+	case *ast.ArrayLiteralExpr:
+		// The element type is the common type of the elements, [1, 2.5] is an array of f64
+		var elem semantic.Type
+		for _, element := range e.Elements {
+			t := g.typeOf(element)
+			if elem == nil {
+				elem = t
+			} else if common := semantic.GetCommonType(elem, t); common != nil {
+				elem = common
+			}
+		}
+		if elem == nil {
+			elem = primitive(types.UNKNOWN_TYPE)
+		}
+		return &semantic.ArrayType{ElementType: elem, Name: types.ARRAY}
+	case *ast.StructLiteralExpr:
+		if e.IsAnonymous {
+			g.fail(e, "anonymous struct literals are not supported")
+		}
+		structType, ok := g.resolve(&semantic.UserType{Name: types.TYPE_NAME(e.StructName.Name)}, g.module.info).(*semantic.StructType)
+		if !ok {
+			g.fail(e.StructName, "'%s' is not a struct type", e.StructName.Name)
+		}
+		return structType
+	case *ast.FunctionLiteral:
+		return g.signature(e)
+	}
+	g.fail(expr, "cannot translate %T", expr)
+	return nil
+}
+
+// globalType returns the type of a global, a variable declared without one has the type of its initializer
+func (g *generator) globalType(gl *global) semantic.Type {
+	if gl.typ != nil {
+		return gl.typ
+	}
+	module, file, current := g.module, g.file, g.current
+	defer func() { g.module, g.file, g.current = module, file, current }()
+	g.module, g.file, g.current = gl.module, gl.file, gl.decl
+	if gl.fn != nil {
+		g.current = gl.fn
+	}
+
+	switch {
+	case gl.fn != nil:
+		gl.typ = g.signature(gl.fn.Function)
+	case gl.decl.Variables[gl.index].ExplicitType != nil:
+		gl.typ = g.astType(gl.decl.Variables[gl.index].ExplicitType)
+	default:
+		if gl.typing {
+			g.fail(gl.decl, "the type of '%s' depends on itself", gl.name)
+		}
+		gl.typing = true
+		inits := gl.decl.Initializers
+		switch {
+		case len(inits) == 1 && len(gl.decl.Variables) > 1:
+			if tuple, ok := g.typeOf(inits[0]).(*tupleType); ok && gl.index < len(tuple.elems) {
+				gl.typ = tuple.elems[gl.index]
+			}
+		case gl.index < len(inits):
+			gl.typ = g.typeOf(inits[gl.index])
+		}
+		if gl.typ == nil {
+			g.fail(gl.decl, "cannot infer the type of '%s'", gl.name)
+		}
+	}
+	return gl.typ
+}
+
+// signature returns the type of a function literal, its parameter and result types written in the current file
+func (g *generator) signature(lit *ast.FunctionLiteral) *semantic.FunctionType {
+	fnType := &semantic.FunctionType{Name: types.FUNCTION}
+	for _, param := range lit.Params {
+		fnType.Parameters = append(fnType.Parameters, g.astType(param.Type))
+	}
+	for _, result := range lit.ReturnType {
+		fnType.ReturnTypes = append(fnType.ReturnTypes, g.astType(result))
+	}
+	return fnType
+}
+
+// methodType returns the type of a method bound to its receiver, written in the file of the method
+func (g *generator) methodType(mt *method) *semantic.FunctionType {
+	module, file := g.module, g.file
+	defer func() { g.module, g.file = module, file }()
+	g.module, g.file = mt.mod, mt.file
+	return g.signature(mt.decl.Function)
+}
+
+// results returns the type a call of a function evaluates to: nil when it returns nothing and a
+// tuple when it returns more than one value
+func results(fnType *semantic.FunctionType) semantic.Type {
+	switch len(fnType.ReturnTypes) {
+	case 0:
+		return nil
+	case 1:
+		return fnType.ReturnTypes[0]
+	}
+	return &tupleType{elems: fnType.ReturnTypes}
+}
+
+// isPure reports whether evaluating an expression can neither change a variable, call a function nor
+// fail, so it can be evaluated in any order with the expressions around it
+func (g *generator) isPure(expr ast.Expression) bool {
+	switch e := expr.(type) {
+	case *ast.FunctionCallExpr, *ast.PrefixExpr, *ast.PostfixExpr, *ast.IndexableExpr:
+		return false
+	case *ast.BinaryExpr:
+		if (e.Operator.Value == "/" || e.Operator.Value == "%") && isInteger(g.typeOf(e)) {
+			return false
+		}
+		return g.isPure(*e.Left) && g.isPure(*e.Right)
+	case *ast.UnaryExpr:
+		return g.isPure(*e.Operand)
+	case *ast.FieldAccessExpr:
+		// Reading a method of a zero interface value fails
+		if _, ok := g.typeOf(*e.Object).(*semantic.InterfaceType); ok {
+			return false
+		}
+		return g.isPure(*e.Object)
+	case *ast.ArrayLiteralExpr:
+		for _, element := range e.Elements {
+			if !g.isPure(element) {
+				return false
+			}
+		}
+	case *ast.StructLiteralExpr:
+		for _, field := range e.Fields {
+			if field.FieldValue != nil && !g.isPure(*field.FieldValue) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isConstant reports whether an expression always has the same value, so it is never evaluated into a temporary
+func isConstant(expr ast.Expression) bool {
+	switch e := expr.(type) {
+	case *ast.IntLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.BoolLiteral, *ast.ByteLiteral:
+		return true
+	case *ast.UnaryExpr:
+		return isConstant(*e.Operand)
+	}
+	return false
+}
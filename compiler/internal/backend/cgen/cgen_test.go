@@ -0,0 +1,147 @@
+package cgen
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"compiler/ctx"
+	"compiler/internal/backend/interp"
+	"compiler/internal/semantic/resolver"
+	"compiler/internal/semantic/typecheck"
+	"compiler/internal/testutil/fixture"
+)
+
+// checkProject writes the files into a project named "app" and type checks main.fer with the modules it imports
+func checkProject(tb testing.TB, files map[string]string) *ctx.CompilerContext {
+	tb.Helper()
+	return fixture.Check(tb, files, resolver.ResolveModules, typecheck.CheckModules)
+}
+
+// compileC generates the C file of a project and compiles it with the system C compiler, it returns
+// the path of the executable
+func compileC(t *testing.T, context *ctx.CompilerContext) string {
+	t.Helper()
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("no C compiler found")
+	}
+	code, err := Generate(context)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	dir := t.TempDir()
+	source, executable := filepath.Join(dir, "main.c"), filepath.Join(dir, "main")
+	if err := os.WriteFile(source, code, 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(cc, "-std=c99", "-o", executable, source, "-lm").CombinedOutput()
+	if err != nil {
+		t.Fatalf("cc failed: %v\n%s", err, out)
+	}
+	return executable
+}
+
+// runNative compiles a project to an executable and runs it, and interprets it too. It returns what
+// the executable printed to stdout and stderr, after checking the interpreter printed the same and
+// reported the same runtime error.
+func runNative(t *testing.T, files map[string]string, stdin string) (string, string) {
+	t.Helper()
+	context := checkProject(t, files)
+	executable := compileC(t, context)
+
+	var out, errOut bytes.Buffer
+	cmd := exec.Command(executable)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout, cmd.Stderr = &out, &errOut
+	runErr := cmd.Run()
+	var exitErr *exec.ExitError
+	if runErr != nil && !errors.As(runErr, &exitErr) {
+		t.Fatalf("running the executable failed: %v", runErr)
+	}
+
+	var interpOut bytes.Buffer
+	in := interp.New(context)
+	in.Stdout = &interpOut
+	in.Stdin = strings.NewReader(stdin)
+	wantErr := ""
+	if err := in.Run(); err != nil {
+		wantErr = err.Error() + "\n"
+	}
+	if out.String() != interpOut.String() || errOut.String() != wantErr || (runErr == nil) != (wantErr == "") {
+		t.Errorf("the executable printed %q and %q, the interpreter printed %q and %q", out.String(), errOut.String(), interpOut.String(), wantErr)
+	}
+	return out.String(), errOut.String()
+}
+
+func TestPrograms(t *testing.T) {
+	for _, program := range fixture.Programs {
+		t.Run(program.Name, func(t *testing.T) {
+			out, errOut := runNative(t, program.Files, program.Stdin)
+			if out != program.Stdout {
+				t.Errorf("the executable printed %q, want %q", out, program.Stdout)
+			}
+			failed := strings.HasPrefix(errOut, "runtime error: "+program.Err)
+			if program.Err == "" && errOut != "" || program.Err != "" && !failed {
+				t.Errorf("the executable printed %q to stderr, want the error %q", errOut, program.Err)
+			}
+		})
+	}
+}
+
+func TestLineDirectives(t *testing.T) {
+	context := checkProject(t, map[string]string{"main.fer": `fn add(a: i32, b: i32) -> i32 {
+    let sum = a + b;
+    return sum;
+}`})
+	code, err := Generate(context)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	path := context.Modules["app/main"].AST.FullPath
+	for _, want := range []string{
+		"#line 1 " + cString(path) + "\nint32_t app_main__add(fr_fn fr_self, int32_t a, int32_t b) {",
+		"\n    int32_t sum = fr_add_i32(a, b);\n",
+		"#line 3 " + cString(path) + "\n    return sum;",
+	} {
+		if !strings.Contains(string(code), want) {
+			t.Errorf("Generate() does not write %q:\n%s", want, code)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		passes []fixture.Pass
+		want   string // file:line:column: message, the file relative to the project
+	}{
+		// The type checker rejects these programs before, the C backend must still not write C that cannot compile
+		{"anonymous struct", `let p = @struct{x: 1, y: 2};
+let q = p.x;`, []fixture.Pass{resolver.ResolveModules}, "main.fer:1:9: anonymous struct literals are not supported"},
+		{"recursive type", `type Pair struct { a: i32, b: i32 };
+type Node struct { value: i32, next: Node };
+fn size(n: Node) -> i32 {
+    return n.value;
+}`, []fixture.Pass{resolver.ResolveModules}, "main.fer:2:1: invalid recursive type: 'Node' contains itself"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			context := fixture.Check(t, map[string]string{"main.fer": tt.input}, tt.passes...)
+			_, err := Generate(context)
+			var compileErr *CompileError
+			if !errors.As(err, &compileErr) {
+				t.Fatalf("Generate() error = %v, want a CompileError", err)
+			}
+			if got := strings.TrimPrefix(err.Error(), context.ProjectRoot+"/"); got != tt.want {
+				t.Errorf("Generate() error = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,602 @@
+package cgen
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"compiler/internal/frontend/ast"
+	"compiler/internal/semantic"
+	"compiler/internal/types"
+)
+
+// operand is one of the expressions of a call, an operator or a literal, which the interpreter
+// evaluates left to right and C in any order
+type operand struct {
+	expr      ast.Expression
+	ctype     string // the C type it is evaluated into a temporary as
+	translate func() string
+	spill     bool // always evaluated into a temporary
+	address   bool // the temporary is passed by its address
+}
+
+// valueOperand is an operand translated as the value of its expression
+func (g *generator) valueOperand(expr ast.Expression) operand {
+	return operand{expr: expr, ctype: g.cType(g.typeOf(expr)), translate: func() string { return g.expr(expr) }}
+}
+
+// operands translates expressions so C evaluates them in the order the interpreter does. An operand is
+// evaluated into a temporary first when it or one after it has an effect, unless the other one is a
+// constant. The impure operands of a call are all evaluated first, as they may call functions
+// themselves and move the position of the frame away from the call.
+func (g *generator) operands(ops []operand, call bool) []string {
+	impure := make([]bool, len(ops))
+	constant := make([]bool, len(ops))
+	for i, op := range ops {
+		impure[i], constant[i] = !g.isPure(op.expr), isConstant(op.expr)
+	}
+	codes := make([]string, len(ops))
+	for i, op := range ops {
+		code := op.translate()
+		spill := op.spill || op.address || call && impure[i]
+		for j := i + 1; j < len(ops) && !spill; j++ {
+			spill = impure[i] && !constant[j] || impure[j] && !constant[i]
+		}
+		if spill && (!constant[i] || op.address) {
+			code = g.spill(code, op.ctype)
+		}
+		if op.address {
+			code = "&" + code
+		}
+		codes[i] = code
+	}
+	return codes
+}
+
+// expr translates an expression to a C expression, the statements it needs evaluated first are added
+// to the current function
+func (g *generator) expr(expr ast.Expression) string {
+	switch e := expr.(type) {
+	case *ast.IntLiteral:
+		return g.intLiteral(e.Value, g.typeOf(e))
+	case *ast.FloatLiteral:
+		return floatLiteral(e.Value)
+	case *ast.StringLiteral:
+		return "FR_STR(" + cString(e.Value) + ")"
+	case *ast.BoolLiteral:
+		return strconv.FormatBool(e.Value)
+	case *ast.ByteLiteral:
+		return fmt.Sprintf("((uint8_t)%d)", e.Value[0])
+	case *ast.IdentifierExpr:
+		if v := g.vars[e]; v != nil {
+			return g.varCode(v)
+		}
+		return g.globalCode(g.global(g.module, e.Name))
+	case *ast.VarScopeResolution:
+		return g.globalCode(g.scopedGlobal(e))
+	case *ast.BinaryExpr:
+		return g.binary(e)
+	case *ast.UnaryExpr:
+		return g.unary(e)
+	case *ast.PrefixExpr:
+		return g.increment(*e.Operand, e.Operator.Value, "pre")
+	case *ast.PostfixExpr:
+		return g.increment(*e.Operand, e.Operator.Value, "post")
+	case *ast.FunctionCallExpr:
+		pos, call := g.call(e)
+		return fmt.Sprintf("(fr_at.pos = %d, %s)", pos, call)
+	case *ast.FieldAccessExpr:
+		return g.fieldAccess(e)
+	case *ast.IndexableExpr:
+		return g.index(e)
+	case *ast.ArrayLiteralExpr:
+		return g.arrayLiteral(e)
+	case *ast.StructLiteralExpr:
+		return g.structLiteral(e)
+	case *ast.FunctionLiteral:
+		return g.closure(e)
+	}
+	g.fail(expr, "cannot translate %T", expr)
+	return ""
+}
+
+// intLiteral writes an integer constant of an integer type
+func (g *generator) intLiteral(value int64, t semantic.Type) string {
+	switch kindOf(t) {
+	case types.INT64:
+		if value == math.MinInt64 {
+			return "INT64_MIN"
+		}
+		return "INT64_C(" + strconv.FormatInt(value, 10) + ")"
+	case types.INT32:
+		// An int may only have 16 bits
+		if value < -32767 || value > 32767 {
+			return "INT32_C(" + strconv.FormatInt(value, 10) + ")"
+		}
+		return strconv.FormatInt(value, 10)
+	}
+	return "((" + g.cType(t) + ")" + strconv.FormatInt(value, 10) + ")"
+}
+
+// floatLiteral writes a double constant with the digits that read back as the same value
+func floatLiteral(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "HUGE_VAL"
+	case math.IsInf(value, -1):
+		return "(-HUGE_VAL)"
+	}
+	s := strconv.FormatFloat(value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".en") {
+		s += ".0"
+	}
+	return s
+}
+
+// varCode returns the C lvalue of a local variable in the current function
+func (g *generator) varCode(v *variable) string {
+	fn := g.body.fn
+	if v.owner != fn {
+		return "(*fr_env->" + fn.fields[v] + ")"
+	}
+	if v.boxed {
+		return "(*" + v.cname + ")"
+	}
+	return v.cname
+}
+
+// globalCode returns the C variable of a global, or the static closure of a function
+func (g *generator) globalCode(gl *global) string {
+	if gl == nil {
+		g.fail(nil, "undefined global")
+	}
+	if gl.kind == globalVar {
+		return gl.cname
+	}
+	if gl.value == "" {
+		gl.value = g.staticClosure(gl.cname)
+	}
+	return gl.value
+}
+
+// staticClosure declares the closure object of a function capturing nothing
+func (g *generator) staticClosure(cname string) string {
+	name := g.unique(cname + "_closure")
+	g.globals = append(g.globals, fmt.Sprintf("static fr_closure %s = {(void (*)(void))%s};", name, cname))
+	return "(&" + name + ")"
+}
+
+// closure writes the function of a function literal and returns the C expression creating its value.
+// A closure capturing variables holds pointers to them.
+func (g *generator) closure(lit *ast.FunctionLiteral) string {
+	fn := g.functions[lit]
+	if fn == nil {
+		g.fail(lit, "the function literal was not analyzed")
+	}
+	if !fn.written {
+		g.function(fn)
+	}
+	if len(fn.captures) == 0 {
+		if fn.value == "" {
+			fn.value = g.staticClosure(fn.cname)
+		}
+		return fn.value
+	}
+
+	constructor := g.helper("new "+fn.cname, fn.cname+"_new", func(name string) *helper {
+		var params []string
+		var b strings.Builder
+		fmt.Fprintf(&b, "struct %s_env {\n    fr_closure fr_base;\n", fn.cname)
+		for _, v := range fn.captures {
+			param := declare(g.cType(v.typ)+" *", fn.fields[v])
+			params = append(params, param)
+			fmt.Fprintf(&b, "    %s;\n", param)
+		}
+		b.WriteString("};\n\n")
+		proto := fmt.Sprintf("static fr_fn %s(%s)", name, strings.Join(params, ", "))
+		fmt.Fprintf(&b, "%s {\n    struct %s_env *env = fr_alloc(sizeof *env);\n", proto, fn.cname)
+		fmt.Fprintf(&b, "    env->fr_base.code = (void (*)(void))%s;\n", fn.cname)
+		for _, v := range fn.captures {
+			fmt.Fprintf(&b, "    env->%s = %s;\n", fn.fields[v], fn.fields[v])
+		}
+		b.WriteString("    return &env->fr_base;\n}\n")
+		return &helper{proto: proto, body: b.String()}
+	})
+
+	// The pointers to the variables, which the current function holds or captured itself
+	current := g.body.fn
+	args := make([]string, len(fn.captures))
+	for i, v := range fn.captures {
+		if v.owner == current {
+			args[i] = v.cname
+		} else {
+			args[i] = "fr_env->" + current.fields[v]
+		}
+	}
+	return constructor + "(" + strings.Join(args, ", ") + ")"
+}
+
+func (g *generator) binary(e *ast.BinaryExpr) string {
+	op := e.Operator.Value
+	switch op {
+	case "&&", "||":
+		left := g.expr(*e.Left)
+		pre, right := g.capture(1, func() string { return g.expr(*e.Right) })
+		if len(pre) == 0 {
+			return "(" + left + " " + op + " " + right + ")"
+		}
+		// The right operand needs statements evaluated first, which only run when it is evaluated
+		t := g.spill(left, "bool")
+		if op == "&&" {
+			g.emit("if (%s) {", t)
+		} else {
+			g.emit("if (!%s) {", t)
+		}
+		g.body.lines = append(g.body.lines, pre...)
+		g.body.depth++
+		g.emit("%s = %s;", t, right)
+		g.body.depth--
+		g.emit("}")
+		return t
+	}
+
+	leftType, rightType := g.typeOf(*e.Left), g.typeOf(*e.Right)
+	codes := g.operands([]operand{g.valueOperand(*e.Left), g.valueOperand(*e.Right)}, false)
+	left, right := codes[0], codes[1]
+	t := leftType
+	if isNumeric(leftType) && isNumeric(rightType) {
+		// Numbers of different types are converted to their common type first, as i32 + i64 is an i64
+		if common := semantic.GetCommonType(leftType, rightType); common != nil {
+			t = common
+		}
+		left, right = g.convert(left, leftType, t), g.convert(right, rightType, t)
+	} else {
+		right = g.convert(right, rightType, leftType)
+	}
+
+	switch op {
+	case "==":
+		return g.equal(left, right, t)
+	case "!=":
+		return "(!" + g.equal(left, right, t) + ")"
+	case "<", "<=", ">", ">=":
+		if kindOf(t) == types.STRING {
+			return "(fr_str_cmp(" + left + ", " + right + ") " + op + " 0)"
+		}
+		// Comparing with NaN orders neither way, so NaN <= x holds as in the interpreter
+		switch {
+		case op == "<=" && isFloat(t):
+			return "(!(" + left + " > " + right + "))"
+		case op == ">=" && isFloat(t):
+			return "(!(" + left + " < " + right + "))"
+		}
+		return "(" + left + " " + op + " " + right + ")"
+	case "+":
+		if kindOf(t) == types.STRING {
+			return "fr_concat(" + left + ", " + right + ")"
+		}
+	}
+	if !isNumeric(t) {
+		g.fail(e, "invalid operands for %s: %s and %s", op, leftType, rightType)
+	}
+
+	switch op {
+	case "+", "-", "*":
+		if isFloat(t) {
+			return g.floatResult("("+left+" "+op+" "+right+")", t)
+		}
+		name := map[string]string{"+": "add", "-": "sub", "*": "mul"}[op]
+		return fmt.Sprintf("fr_%s_%s(%s, %s)", name, suffix(t), left, right)
+	case "/":
+		if isFloat(t) {
+			return g.floatResult("("+left+" / "+right+")", t)
+		}
+		return fmt.Sprintf("fr_div_%s(%s, %s, %d)", suffix(t), left, right, g.pos(e))
+	case "%":
+		if isFloat(t) {
+			return fmt.Sprintf("fr_mod_%s(%s, %s)", suffix(t), left, right)
+		}
+		return fmt.Sprintf("fr_mod_%s(%s, %s, %d)", suffix(t), left, right, g.pos(e))
+	}
+	g.fail(e, "the operator %s is not supported", op)
+	return ""
+}
+
+// floatResult rounds the result of an f32 operation to float, C may compute it with more precision
+func (g *generator) floatResult(code string, t semantic.Type) string {
+	if kindOf(t) == types.FLOAT32 {
+		return "((float)" + code + ")"
+	}
+	return code
+}
+
+func (g *generator) unary(e *ast.UnaryExpr) string {
+	t := g.typeOf(e)
+	if e.Operator.Value == "!" {
+		return "(!" + g.expr(*e.Operand) + ")"
+	}
+	switch operand := (*e.Operand).(type) {
+	case *ast.IntLiteral:
+		return g.intLiteral(-operand.Value, t)
+	case *ast.FloatLiteral:
+		return "(" + floatLiteral(-operand.Value) + ")"
+	}
+	if isFloat(t) {
+		return "(-" + g.expr(*e.Operand) + ")"
+	}
+	return "fr_neg_" + suffix(t) + "(" + g.expr(*e.Operand) + ")"
+}
+
+// increment translates ++ and -- before or after a variable, a field or an element
+func (g *generator) increment(target ast.Expression, op, when string) string {
+	t := g.typeOf(target)
+	if !isNumeric(t) {
+		g.fail(target, "cannot apply %s to a value of type %s", op, t)
+	}
+	delta := "1"
+	switch {
+	case isFloat(t) && op == "--":
+		delta = "-1.0"
+	case isFloat(t):
+		delta = "1.0"
+	case op == "--":
+		delta = "(" + g.cType(t) + ")-1"
+	}
+	return fmt.Sprintf("fr_%s_%s(&%s, %s)", when, suffix(t), g.lvalue(target), delta)
+}
+
+// isAddressable reports whether an expression names memory a pointer can point to
+func (g *generator) isAddressable(expr ast.Expression) bool {
+	switch e := expr.(type) {
+	case *ast.IdentifierExpr:
+		if g.vars[e] != nil {
+			return true
+		}
+		gl := g.global(g.module, e.Name)
+		return gl != nil && gl.kind == globalVar
+	case *ast.VarScopeResolution:
+		return g.scopedGlobal(e).kind == globalVar
+	case *ast.IndexableExpr:
+		return true
+	case *ast.FieldAccessExpr:
+		structType, ok := g.typeOf(*e.Object).(*semantic.StructType)
+		return ok && structType.Fields[e.Field.Name] != nil && g.isAddressable(*e.Object)
+	}
+	return false
+}
+
+// method returns the method a field access names, or nil when it reads a field
+func (g *generator) method(e *ast.FieldAccessExpr) *method {
+	structType, ok := g.typeOf(*e.Object).(*semantic.StructType)
+	if !ok || structType.Fields[e.Field.Name] != nil {
+		return nil
+	}
+	return g.methods[structType][e.Field.Name]
+}
+
+func (g *generator) fieldAccess(e *ast.FieldAccessExpr) string {
+	if iface, ok := g.typeOf(*e.Object).(*semantic.InterfaceType); ok {
+		return fmt.Sprintf("%s(%s, %d)", g.interfaceBinder(iface, e.Field.Name), g.expr(*e.Object), g.pos(e.Field))
+	}
+	structType, ok := g.typeOf(*e.Object).(*semantic.StructType)
+	if !ok {
+		g.fail(e.Field, "cannot read '%s' of a value that is not a struct", e.Field.Name)
+	}
+	if structType.Fields[e.Field.Name] != nil {
+		return g.expr(*e.Object) + "." + g.field(structType, e.Field.Name)
+	}
+	mt := g.method(e)
+	if mt == nil {
+		g.fail(e.Field, "%s has no field or method '%s'", structType.Name, e.Field.Name)
+	}
+	// A bound method holds a copy of its receiver, or a pointer to it when it takes it by reference
+	switch {
+	case !mt.decl.IsRRef:
+		return g.binder(mt) + "(" + g.expr(*e.Object) + ")"
+	case g.isAddressable(*e.Object):
+		return g.binder(mt) + "(&" + g.expr(*e.Object) + ")"
+	}
+	return g.binder(mt) + "(" + g.boxer(structType) + "(" + g.expr(*e.Object) + "))"
+}
+
+// receiver is the operand passing the receiver of a method call: the address of the variable for a
+// method taking it by reference, or of a copy, allocated when a closure of the method may keep it
+func (g *generator) receiver(mt *method, object ast.Expression) operand {
+	ctype := g.cType(mt.owner)
+	if mt.decl.IsRRef && g.isAddressable(object) {
+		return operand{expr: object, ctype: ctype + " *", translate: func() string { return "&" + g.expr(object) }}
+	}
+	if fn := g.functions[mt.decl.Function]; fn != nil && fn.receiver.captured {
+		return operand{expr: object, ctype: ctype + " *", translate: func() string { return g.boxer(mt.owner) + "(" + g.expr(object) + ")" }}
+	}
+	return operand{expr: object, ctype: ctype, translate: func() string { return g.expr(object) }, address: true}
+}
+
+// call translates a call, it returns the position of the call and the C call. A global function is
+// called directly, a method with a pointer to its receiver, a method of an interface value through
+// the table of the value and any other function value through its closure.
+func (g *generator) call(e *ast.FunctionCallExpr) (int, string) {
+	callee := *e.Caller
+	var fnType *semantic.FunctionType
+	var ops []operand
+	var build func(codes []string) string
+
+	if gl := g.directCallee(callee); gl != nil {
+		fnType = g.globalType(gl).(*semantic.FunctionType)
+		build = func(args []string) string {
+			return gl.cname + "(" + strings.Join(append([]string{"NULL"}, args...), ", ") + ")"
+		}
+	} else if field, ok := callee.(*ast.FieldAccessExpr); ok && g.method(field) != nil {
+		mt := g.method(field)
+		fnType = g.methodType(mt)
+		ops = append(ops, g.receiver(mt, *field.Object))
+		build = func(args []string) string {
+			return mt.cname + "(" + strings.Join(append([]string{"NULL"}, args...), ", ") + ")"
+		}
+	} else if iface, field := g.interfaceMethod(callee); iface != nil {
+		fnType = iface.Methods[field.Field.Name]
+		ops = append(ops, g.valueOperand(*field.Object))
+		name, pos := g.dispatcher(iface, field.Field.Name), strconv.Itoa(g.pos(field.Field))
+		build = func(args []string) string {
+			return name + "(" + strings.Join(append([]string{args[0], pos}, args[1:]...), ", ") + ")"
+		}
+	} else {
+		t, ok := g.typeOf(callee).(*semantic.FunctionType)
+		if !ok {
+			g.fail(callee, "cannot call a value that is not a function")
+		}
+		fnType = t
+		ops = append(ops, operand{expr: callee, ctype: "fr_fn", translate: func() string { return g.expr(callee) }})
+		name := g.caller(fnType)
+		build = func(args []string) string { return name + "(" + strings.Join(args, ", ") + ")" }
+	}
+	if len(e.Arguments) != len(fnType.Parameters) {
+		g.fail(e, "expected %d arguments, got %d", len(fnType.Parameters), len(e.Arguments))
+	}
+	for i, arg := range e.Arguments {
+		param := fnType.Parameters[i]
+		ops = append(ops, operand{expr: arg, ctype: g.cType(param), translate: func() string {
+			return g.convert(g.expr(arg), g.typeOf(arg), param)
+		}})
+	}
+	return g.pos(e), build(g.operands(ops, true))
+}
+
+// interfaceMethod returns the interface type of the value a callee reads a method of, or nil when it
+// is not a method of an interface value
+func (g *generator) interfaceMethod(callee ast.Expression) (*semantic.InterfaceType, *ast.FieldAccessExpr) {
+	field, ok := callee.(*ast.FieldAccessExpr)
+	if !ok {
+		return nil, nil
+	}
+	iface, _ := g.typeOf(*field.Object).(*semantic.InterfaceType)
+	return iface, field
+}
+
+// directCallee returns the global function a callee names, or nil when it is another value
+func (g *generator) directCallee(callee ast.Expression) *global {
+	var gl *global
+	switch c := callee.(type) {
+	case *ast.IdentifierExpr:
+		if g.vars[c] != nil {
+			return nil
+		}
+		gl = g.global(g.module, c.Name)
+	case *ast.VarScopeResolution:
+		gl = g.scopedGlobal(c)
+	}
+	if gl == nil || gl.kind == globalVar {
+		return nil
+	}
+	return gl
+}
+
+func (g *generator) index(e *ast.IndexableExpr) string {
+	arrayType, ok := g.typeOf(*e.Indexable).(*semantic.ArrayType)
+	if !ok {
+		g.fail(*e.Indexable, "cannot index a value that is not an array")
+	}
+	indexType := g.typeOf(*e.Index)
+	if !isInteger(indexType) {
+		g.fail(*e.Index, "an array index must be an integer")
+	}
+	codes := g.operands([]operand{g.valueOperand(*e.Indexable), g.valueOperand(*e.Index)}, false)
+	elem := g.cType(arrayType.ElementType)
+	if kindOf(indexType) == types.UINT64 {
+		return fmt.Sprintf("(*(%s *)fr_elem_u(%s, %s, sizeof(%s), %d))", elem, codes[0], codes[1], elem, g.pos(e))
+	}
+	return fmt.Sprintf("(*(%s *)fr_elem(%s, (int64_t)%s, sizeof(%s), %d))", elem, codes[0], codes[1], elem, g.pos(e))
+}
+
+func (g *generator) arrayLiteral(e *ast.ArrayLiteralExpr) string {
+	arrayType := g.typeOf(e).(*semantic.ArrayType)
+	if len(e.Elements) == 0 {
+		return "NULL"
+	}
+	elem := arrayType.ElementType
+	ops := make([]operand, len(e.Elements))
+	for i, element := range e.Elements {
+		ops[i] = operand{expr: element, ctype: g.cType(elem), translate: func() string {
+			return g.convert(g.expr(element), g.typeOf(element), elem)
+		}}
+	}
+	ctype := g.cType(elem)
+	return fmt.Sprintf("fr_array_of(%d, sizeof(%s), (%s[]){%s})", len(e.Elements), ctype, ctype, strings.Join(g.operands(ops, false), ", "))
+}
+
+// structLiteral creates a struct from its fields, the fields it does not set are zero
+func (g *generator) structLiteral(e *ast.StructLiteralExpr) string {
+	structType := g.typeOf(e).(*semantic.StructType)
+	var names []string
+	var ops []operand
+	for _, field := range e.Fields {
+		if field.FieldValue == nil {
+			continue
+		}
+		fieldType := structType.Fields[field.FieldIdentifier.Name]
+		if fieldType == nil {
+			g.fail(field.FieldIdentifier, "%s has no field '%s'", structType.Name, field.FieldIdentifier.Name)
+		}
+		value := *field.FieldValue
+		names = append(names, g.field(structType, field.FieldIdentifier.Name))
+		ops = append(ops, operand{expr: value, ctype: g.cType(fieldType), translate: func() string {
+			return g.convert(g.expr(value), g.typeOf(value), fieldType)
+		}})
+	}
+	if len(ops) == 0 {
+		return g.zero(structType)
+	}
+	codes := g.operands(ops, false)
+	inits := make([]string, len(codes))
+	for i, code := range codes {
+		inits[i] = "." + names[i] + " = " + code
+	}
+	return "((" + g.cType(structType) + "){" + strings.Join(inits, ", ") + "})"
+}
+
+// boxer returns the helper copying a value into memory of its own
+func (g *generator) boxer(t semantic.Type) string {
+	return g.helper("box "+typeKey(t), "fr_box", func(name string) *helper {
+		ctype := g.cType(t)
+		proto := fmt.Sprintf("static %s *%s(%s v)", ctype, name, ctype)
+		body := fmt.Sprintf("%s {\n    %s *p = fr_alloc(sizeof(%s));\n    *p = v;\n    return p;\n}\n", proto, ctype, ctype)
+		return &helper{proto: proto, body: body}
+	})
+}
+
+// binder returns the helper binding a method to a receiver, the function value it creates calls the
+// method with a pointer to the copy it holds or to the variable it was bound to
+func (g *generator) binder(mt *method) string {
+	return g.helper("bind "+mt.cname, mt.cname+"_bind", func(name string) *helper {
+		ctype := g.cType(mt.owner)
+		fnType := g.methodType(mt)
+		receiver, recv := ctype+" recv", "&env->recv"
+		if mt.decl.IsRRef {
+			receiver, recv = ctype+" *recv", "env->recv"
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "struct %s_env {\n    fr_closure fr_base;\n    %s;\n};\n\n", name, receiver)
+
+		params := []string{"fr_fn fr_self"}
+		args := []string{"NULL", recv}
+		for i, param := range fnType.Parameters {
+			arg := "a" + strconv.Itoa(i)
+			params = append(params, g.decl(param, arg))
+			args = append(args, arg)
+		}
+		result := results(fnType)
+		call := fmt.Sprintf("%s(%s)", mt.cname, strings.Join(args, ", "))
+		if result != nil {
+			call = "return " + call
+		}
+		fmt.Fprintf(&b, "static %s %s_code(%s) {\n", g.cType(result), name, strings.Join(params, ", "))
+		fmt.Fprintf(&b, "    struct %s_env *env = (struct %s_env *)fr_self;\n    %s;\n}\n\n", name, name, call)
+
+		proto := fmt.Sprintf("static fr_fn %s(%s)", name, receiver)
+		fmt.Fprintf(&b, "%s {\n    struct %s_env *env = fr_alloc(sizeof *env);\n", proto, name)
+		fmt.Fprintf(&b, "    env->fr_base.code = (void (*)(void))%s_code;\n", name)
+		b.WriteString("    env->recv = recv;\n    return &env->fr_base;\n}\n")
+		return &helper{proto: proto, body: b.String()}
+	})
+}
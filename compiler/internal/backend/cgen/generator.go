@@ -0,0 +1,485 @@
+// Package cgen translates the type checked AST of a compiler context to a single portable C99 file.
+// Integers map to the <stdint.h> types and wrap as they do in the interpreter, strings and arrays are
+// runtime structs, structs are C structs, interface values point to a copy of their value and to a
+// table of its methods, and functions are C functions taking their closure first.
+// The generated functions keep a stack of frames, so a runtime error prints the trace the interpreter
+// prints, and #line directives map C compiler errors and debugger locations back to the .fer sources.
+package cgen
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"compiler/ctx"
+	"compiler/internal/frontend/ast"
+	"compiler/internal/semantic"
+	"compiler/internal/source"
+	"compiler/std"
+)
+
+//go:embed runtime.h
+var runtime string
+
+// natives are the standard library functions without a body the runtime implements, a native
+// function is named fr_<module>_<name> in it
+var natives = map[string][]string{
+	std.Root + "/fmt":     {"print", "println", "formatInt", "formatFloat", "formatBool"},
+	std.Root + "/io":      {"readLine", "readFile", "writeFile"},
+	std.Root + "/math":    {"sqrt", "floor", "ceil", "pow"},
+	std.Root + "/strings": {"len", "contains", "indexOf", "hasPrefix", "hasSuffix", "toUpper", "toLower", "trim"},
+}
+
+// CompileError is a construct the C backend cannot translate, the type checker rejects most of them first
+type CompileError struct {
+	File     string
+	Position source.Position
+	Message  string
+}
+
+func (e *CompileError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Position.Line, e.Position.Column, e.Message)
+}
+
+// module is a module of the program being translated
+type module struct {
+	importPath string
+	info       *ctx.Module
+	cname      string // the import path as a C identifier
+	globals    map[string]*global
+	init       *function // the function running the top-level code of the module
+}
+
+type globalKind int
+
+const (
+	globalVar globalKind = iota
+	globalFunction
+	globalNative
+)
+
+// global is a top-level variable, constant or function of a module
+type global struct {
+	kind   globalKind
+	module *module
+	file   *ast.Program
+	name   string
+	cname  string
+	decl   *ast.VarDeclStmt // the declaration of a variable, which is its index-th
+	index  int
+	fn     *ast.FunctionDecl
+	typ    semantic.Type // resolved when it is first needed
+	typing bool          // the type is being inferred, an initializer refers to the variable again
+	value  string        // the static closure object of a function, created when it is used as a value
+}
+
+// method is a method of a struct type
+type method struct {
+	decl  *ast.MethodDecl
+	owner *semantic.StructType
+	cname string
+	file  *ast.Program
+	mod   *module
+	bind  string // the helper binding it to a receiver, created when it is used as a value
+}
+
+// position is an entry of the table of positions a runtime error can report
+type position struct {
+	function string
+	file     string
+	line     int
+	column   int
+}
+
+// cLine is a line of a generated C function, with the line of the .fer file it was translated from
+type cLine struct {
+	text string
+	file string
+	line int
+}
+
+// helper is a C function the generated code needs, with its prototype when it has one
+type helper struct {
+	proto string
+	body  string
+}
+
+type generator struct {
+	ctx     *ctx.CompilerContext
+	modules map[string]*module
+	order   []string
+	methods map[*semantic.StructType]map[string]*method
+
+	// The analysis of every function, see analysis.go
+	vars      map[*ast.IdentifierExpr]*variable
+	functions map[*ast.FunctionLiteral]*function
+	exprTypes map[ast.Expression]semantic.Type
+
+	// The types, see types.go
+	resolved map[*semantic.StructType]*semantic.StructType
+	canon    map[string]*semantic.StructType
+	structs  map[string]*cStruct
+	structOf []*cStruct
+
+	// The interfaces, see interfaces.go
+	ifaces      map[*semantic.InterfaceType]*semantic.InterfaceType
+	canonIfaces map[string]*semantic.InterfaceType
+	tables      map[string]string          // the table of a type for an interface, by both keys
+	holders     map[string][]semantic.Type // the types with a table for an interface, by its key
+	casts       []*cast
+
+	// The module and file being translated, the statement for errors without a node and the C function
+	// being written, see stmt.go
+	module  *module
+	file    *ast.Program
+	current ast.Node
+	body    *body
+
+	names       map[string]bool // the C names declared at file scope
+	protos      []string
+	globals     []string
+	helpers     []*helper
+	helperNames map[string]string
+	bodies      [][]cLine
+	positions   []position
+	positionOf  map[position]int
+}
+
+// Generate translates every module of a context the type checker found no errors in to one C file
+func Generate(context *ctx.CompilerContext) (code []byte, err error) {
+	g := &generator{
+		ctx:         context,
+		modules:     make(map[string]*module),
+		methods:     make(map[*semantic.StructType]map[string]*method),
+		vars:        make(map[*ast.IdentifierExpr]*variable),
+		functions:   make(map[*ast.FunctionLiteral]*function),
+		exprTypes:   make(map[ast.Expression]semantic.Type),
+		resolved:    make(map[*semantic.StructType]*semantic.StructType),
+		canon:       make(map[string]*semantic.StructType),
+		structs:     make(map[string]*cStruct),
+		ifaces:      make(map[*semantic.InterfaceType]*semantic.InterfaceType),
+		canonIfaces: make(map[string]*semantic.InterfaceType),
+		tables:      make(map[string]string),
+		holders:     make(map[string][]semantic.Type),
+		names:       make(map[string]bool),
+		helperNames: make(map[string]string),
+		positionOf:  make(map[position]int),
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			compileErr, ok := r.(*CompileError)
+			if !ok {
+				panic(r)
+			}
+			code, err = nil, compileErr
+		}
+	}()
+
+	g.order = context.ModuleOrder()
+	for _, importPath := range g.order {
+		m := &module{importPath: importPath, info: context.Modules[importPath], cname: mangle(importPath), globals: make(map[string]*global)}
+		g.modules[importPath] = m
+	}
+	// Every global, function and method has a C name before any code is translated, functions may use
+	// the ones declared after them
+	for _, importPath := range g.order {
+		g.declare(g.modules[importPath])
+	}
+	for _, importPath := range g.order {
+		g.analyze(g.modules[importPath])
+	}
+	for _, importPath := range g.order {
+		g.translate(g.modules[importPath])
+	}
+	g.writeCasts()
+	return []byte(g.render()), nil
+}
+
+// topLevel returns the top-level nodes of a module in the order the resolver decided they run in
+func topLevel(m *ctx.Module) []ast.Node {
+	if m.Order != nil {
+		return m.Order
+	}
+	var nodes []ast.Node
+	for _, file := range m.Files {
+		nodes = append(nodes, file.Nodes...)
+	}
+	return nodes
+}
+
+// enter makes the file a top-level node of the module is written in the current one
+func (g *generator) enter(m *module, node ast.Node) {
+	g.module, g.current = m, node
+	g.file = m.info.FileOf(node)
+	if g.file == nil {
+		g.file = m.info.AST
+	}
+}
+
+// fail stops the translation with an error at node, or at the statement being translated
+func (g *generator) fail(node ast.Node, format string, args ...any) {
+	err := &CompileError{Message: fmt.Sprintf(format, args...)}
+	if g.file != nil {
+		err.File = g.file.FullPath
+	}
+	if node == nil {
+		node = g.current
+	}
+	if node != nil && node.Loc() != nil && node.Loc().Start != nil {
+		err.Position = *node.Loc().Start
+	}
+	panic(err)
+}
+
+// declare names the globals, functions and methods of a module
+func (g *generator) declare(m *module) {
+	m.init = &function{name: "<" + m.importPath + ">", cname: g.unique("fr_init_" + m.cname), module: m, file: m.info.AST, names: make(map[string]bool)}
+	for _, node := range topLevel(m.info) {
+		g.enter(m, node)
+		switch n := node.(type) {
+		case *ast.FunctionDecl:
+			gl := &global{kind: globalFunction, module: m, file: g.file, name: n.Identifier.Name, fn: n}
+			if n.Function.Body == nil {
+				gl.kind = globalNative
+			}
+			gl.cname = g.unique(m.cname + "__" + mangle(n.Identifier.Name))
+			m.globals[gl.name] = gl
+		case *ast.VarDeclStmt:
+			for i, v := range n.Variables {
+				gl := &global{kind: globalVar, module: m, file: g.file, name: v.Identifier.Name, decl: n, index: i}
+				gl.cname = g.unique(m.cname + "__" + mangle(v.Identifier.Name))
+				m.globals[gl.name] = gl
+			}
+		case *ast.MethodDecl:
+			if n.Receiver == nil {
+				continue
+			}
+			owner, ok := g.astType(n.Receiver.Type).(*semantic.StructType)
+			if !ok {
+				continue
+			}
+			if g.methods[owner] == nil {
+				g.methods[owner] = make(map[string]*method)
+			}
+			cname := g.unique(m.cname + "__" + mangle(string(owner.Name)) + "__" + mangle(n.Method.Name))
+			g.methods[owner][n.Method.Name] = &method{decl: n, owner: owner, cname: cname, file: g.file, mod: m}
+		}
+	}
+}
+
+// global finds a top-level name of a module, which may be one it imported with an import list or
+// re-exports with 'pub import'
+func (g *generator) global(m *module, name string) *global {
+	if gl := m.globals[name]; gl != nil {
+		return gl
+	}
+	importStmt := m.info.ImportedFrom[name]
+	if importStmt == nil || g.modules[importStmt.ModulePath] == nil {
+		return nil
+	}
+	original := name
+	for _, imported := range importStmt.Names {
+		if imported.LocalName().Name == name {
+			original = imported.Name.Name
+		}
+	}
+	return g.global(g.modules[importStmt.ModulePath], original)
+}
+
+// scopedGlobal returns the global module::name refers to in the current file
+func (g *generator) scopedGlobal(e *ast.VarScopeResolution) *global {
+	importPath, found := g.file.ModulenameToImportpath[e.Module.Name]
+	if !found || g.modules[importPath] == nil {
+		g.fail(e.Module, "module '%s' is not imported", e.Module.Name)
+	}
+	gl := g.global(g.modules[importPath], e.Var.Name)
+	if gl == nil {
+		g.fail(e.Var, "'%s' is not defined in module '%s'", e.Var.Name, e.Module.Name)
+	}
+	return gl
+}
+
+// unique returns a C name declared at file scope no other declaration uses
+func (g *generator) unique(name string) string {
+	candidate := name
+	for i := 2; g.names[candidate]; i++ {
+		candidate = name + "_" + strconv.Itoa(i)
+	}
+	g.names[candidate] = true
+	return candidate
+}
+
+// position returns the index of a position in the table runtime errors read, adding it once
+func (g *generator) position(function string, file *ast.Program, node ast.Node) int {
+	p := position{function: function}
+	if file != nil {
+		p.file = file.FullPath
+	}
+	if node != nil && node.Loc() != nil && node.Loc().Start != nil {
+		p.line, p.column = node.Loc().Start.Line, node.Loc().Start.Column
+	}
+	if i, found := g.positionOf[p]; found {
+		return i
+	}
+	g.positionOf[p] = len(g.positions)
+	g.positions = append(g.positions, p)
+	return len(g.positions) - 1
+}
+
+// helper returns the name of the helper function registered under key, creating it with build the first time
+func (g *generator) helper(key, base string, build func(name string) *helper) string {
+	if name, found := g.helperNames[key]; found {
+		return name
+	}
+	name := g.unique(base)
+	g.helperNames[key] = name
+	// Registered before it is built, a helper converting a struct may need itself for a field
+	h := &helper{}
+	g.helpers = append(g.helpers, h)
+	*h = *build(name)
+	return name
+}
+
+// render puts the generated declarations and functions together, in the order C needs them declared
+func (g *generator) render() string {
+	var b strings.Builder
+	if g.ctx.EntryPoint != "" {
+		fmt.Fprintf(&b, "/* Generated by the Ferret compiler from %s, do not edit. */\n\n", g.ctx.EntryPoint)
+	} else {
+		b.WriteString("/* Generated by the Ferret compiler, do not edit. */\n\n")
+	}
+	b.WriteString(runtime)
+
+	b.WriteString("\n/* Types */\n\n")
+	g.renderStructs(&b)
+
+	b.WriteString("\n/* Functions */\n\n")
+	for _, helper := range g.helpers {
+		if helper.proto != "" {
+			b.WriteString(helper.proto + ";\n")
+		}
+	}
+	for _, proto := range g.protos {
+		b.WriteString(proto + ";\n")
+	}
+
+	b.WriteString("\n/* Globals */\n\n")
+	for _, global := range g.globals {
+		b.WriteString(global + "\n")
+	}
+
+	b.WriteString("\nconst fr_pos fr_positions[] = {\n")
+	for _, p := range g.positions {
+		fmt.Fprintf(&b, "    {%s, %s, %d, %d},\n", cString(p.function), cString(p.file), p.line, p.column)
+	}
+	b.WriteString("};\n")
+
+	for _, helper := range g.helpers {
+		if helper.body != "" {
+			b.WriteString("\n" + helper.body)
+		}
+	}
+
+	b.WriteString("\nint main(void) {\n")
+	for _, importPath := range g.order {
+		fmt.Fprintf(&b, "    %s();\n", g.modules[importPath].init.cname)
+	}
+	b.WriteString("    return 0;\n}\n")
+
+	// The lines of the .fer files the functions were translated from, a #line directive is written
+	// when the next C line does not follow the previous one
+	file, line := "", 0
+	for _, body := range g.bodies {
+		b.WriteString("\n")
+		line++
+		for _, l := range body {
+			if l.file != "" && (l.file != file || l.line != line) {
+				fmt.Fprintf(&b, "#line %d %s\n", l.line, cString(l.file))
+				file, line = l.file, l.line
+			}
+			b.WriteString(l.text + "\n")
+			line++
+		}
+	}
+	return b.String()
+}
+
+// reserved are the identifiers a Ferret name cannot keep in C: the keywords and the names the
+// runtime headers define as macros
+var reserved = map[string]bool{}
+
+func init() {
+	for _, name := range strings.Fields(`auto break case char const continue default do double else enum extern
+		float for goto if inline int long register restrict return short signed sizeof static struct switch
+		typedef union unsigned void volatile while bool true false errno stdin stdout stderr math_errhandling
+		int8_t int16_t int32_t int64_t uint8_t uint16_t uint32_t uint64_t intptr_t uintptr_t intmax_t
+		uintmax_t size_t ptrdiff_t wchar_t va_list main`) {
+		reserved[name] = true
+	}
+}
+
+// mangle turns a Ferret name or an import path into a C identifier. Double underscores separate the
+// parts of the names generated at file scope, the fr_ and FR_ prefixes belong to the runtime and
+// upper case names may be macros, so none of them is kept as is.
+func mangle(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	s := b.String()
+	for strings.Contains(s, "__") {
+		s = strings.ReplaceAll(s, "__", "_")
+	}
+	switch {
+	case s == "" || s[0] >= '0' && s[0] <= '9' || s[0] == '_':
+		s = "x" + s
+	case strings.HasPrefix(s, "fr_") || strings.HasPrefix(s, "FR_"):
+		s = "x_" + s
+	}
+	if reserved[s] || strings.ToUpper(s) == s {
+		s += "_"
+	}
+	return s
+}
+
+// cString quotes s as a C string literal, in octal escapes the bytes that are not printable ASCII
+func cString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == '?':
+			// A ? before another one could start a trigraph
+			b.WriteString(`\?`)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\t':
+			b.WriteString(`\t`)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// sortedFields returns the names of the fields of a struct type in the order its C struct declares them
+func sortedFields(t *semantic.StructType) []string {
+	names := make([]string, 0, len(t.Fields))
+	for name := range t.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,261 @@
+package cgen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"compiler/ctx"
+	"compiler/internal/frontend/ast"
+	"compiler/internal/semantic"
+	"compiler/internal/types"
+)
+
+// An interface value is a fr_iface: a pointer to a copy of the value it holds and the table of the
+// type of that value for the interface. The table starts with the function comparing two values of
+// the type, the methods of the interface follow in the order of their names. A method in a table
+// takes the copy as a void pointer, so a call through the table does not need to know the type.
+
+// cast is a conversion between two interface types, written once every table is known
+type cast struct {
+	from, to *semantic.InterfaceType
+	proto    string
+}
+
+// canonicalInterface returns the interface type every type naming the same interface resolves to,
+// its method signatures resolved in the module declaring it
+func (g *generator) canonicalInterface(t *semantic.InterfaceType, m *ctx.Module) *semantic.InterfaceType {
+	if c := g.ifaces[t]; c != nil {
+		return c
+	}
+	owner := m
+	if declaring := g.ctx.Modules[t.Module]; declaring != nil {
+		owner = declaring
+	}
+	c := &semantic.InterfaceType{Name: t.Name, Module: t.Module, Methods: make(map[string]*semantic.FunctionType, len(t.Methods))}
+	if t.Module != "" {
+		key := typeKey(c)
+		if existing := g.canonIfaces[key]; existing != nil {
+			g.ifaces[t] = existing
+			return existing
+		}
+		// Registered before its methods, so a method can take or return the interface again
+		g.canonIfaces[key] = c
+		g.ifaces[t] = c
+	}
+	declared := g.inDeclaration(t.Module, t.Name, func(base ast.DataType) bool {
+		iface, ok := base.(*ast.InterfaceType)
+		if !ok {
+			return false
+		}
+		for _, method := range iface.Methods {
+			sig := &semantic.FunctionType{Name: types.FUNCTION}
+			for _, param := range method.Params {
+				sig.Parameters = append(sig.Parameters, g.astType(param.Type))
+			}
+			for _, result := range method.ReturnType {
+				sig.ReturnTypes = append(sig.ReturnTypes, g.astType(result))
+			}
+			c.Methods[method.Name.Name] = sig
+		}
+		return true
+	})
+	if !declared {
+		for name, sig := range t.Methods {
+			c.Methods[name] = g.resolve(sig, owner).(*semantic.FunctionType)
+		}
+	}
+	if t.Module == "" {
+		// An interface literal is known by its methods, which cannot refer to it
+		key := typeKey(c)
+		if existing := g.canonIfaces[key]; existing != nil {
+			c = existing
+		}
+		g.canonIfaces[key] = c
+		g.ifaces[t] = c
+	}
+	return c
+}
+
+// boxing returns the helper converting a value to an interface, which points to a copy of it and to
+// the table of its type
+func (g *generator) boxing(from semantic.Type, to *semantic.InterfaceType) string {
+	return g.helper("conv "+typeKey(from)+" "+typeKey(to), "fr_conv", func(name string) *helper {
+		proto := fmt.Sprintf("static fr_iface %s(%s)", name, g.decl(from, "v"))
+		body := fmt.Sprintf("%s {\n    fr_iface r;\n    r.table = %s;\n    r.data = %s(v);\n    return r;\n}\n",
+			proto, g.table(from, to), g.boxer(from))
+		return &helper{proto: proto, body: body}
+	})
+}
+
+// cast returns the helper converting a value of an interface to another interface. It looks the table
+// of the held type for the other interface up by the table the value has, see writeCasts.
+func (g *generator) cast(from, to *semantic.InterfaceType) string {
+	return g.helper("conv "+typeKey(from)+" "+typeKey(to), "fr_conv", func(name string) *helper {
+		proto := fmt.Sprintf("static fr_iface %s(fr_iface v)", name)
+		g.casts = append(g.casts, &cast{from: from, to: to, proto: proto})
+		return &helper{proto: proto}
+	})
+}
+
+// writeCasts writes the conversions between interfaces once the code is translated. A value of the
+// interface converted from may hold any type that has a table for it, and declaring the tables of
+// those types for the other interface can add types another conversion has to know.
+func (g *generator) writeCasts() {
+	for tables, casts := -1, -1; tables != len(g.tables) || casts != len(g.casts); {
+		tables, casts = len(g.tables), len(g.casts)
+		for i := 0; i < len(g.casts); i++ {
+			c := g.casts[i]
+			for _, t := range g.holders[typeKey(c.from)] {
+				g.table(t, c.to)
+			}
+		}
+	}
+	for _, c := range g.casts {
+		var b strings.Builder
+		b.WriteString(c.proto + " {\n    fr_iface r;\n    r.table = NULL;\n    r.data = v.data;\n")
+		for _, t := range g.holders[typeKey(c.from)] {
+			fmt.Fprintf(&b, "    if (v.table == %s) r.table = %s;\n", g.table(t, c.from), g.table(t, c.to))
+		}
+		b.WriteString("    return r;\n}\n")
+		g.helpers = append(g.helpers, &helper{body: b.String()})
+	}
+}
+
+// table returns the table of a type for an interface, declaring it the first time
+func (g *generator) table(t semantic.Type, iface *semantic.InterfaceType) string {
+	key := typeKey(t) + " " + typeKey(iface)
+	if name, found := g.tables[key]; found {
+		return name
+	}
+	name := g.unique("fr_table")
+	g.tables[key] = name
+	g.holders[typeKey(iface)] = append(g.holders[typeKey(iface)], t)
+
+	entries := []string{"(void (*)(void))" + g.erasedEquality(t)}
+	for _, methodName := range iface.MethodNames() {
+		entries = append(entries, "(void (*)(void))"+g.thunk(t, methodName, iface.Methods[methodName]))
+	}
+	g.globals = append(g.globals, fmt.Sprintf("static void (*const %s[])(void) = {%s};", name, strings.Join(entries, ", ")))
+	return name
+}
+
+// erasedEquality returns the function comparing two values of a type given by void pointers
+func (g *generator) erasedEquality(t semantic.Type) string {
+	return g.helper("eq void "+typeKey(t), "fr_eq", func(name string) *helper {
+		ctype := g.cType(t)
+		proto := fmt.Sprintf("static bool %s(void *a, void *b)", name)
+		body := fmt.Sprintf("%s {\n    return %s;\n}\n", proto, g.equal("*("+ctype+" *)a", "*("+ctype+" *)b", t))
+		return &helper{proto: proto, body: body}
+	})
+}
+
+// thunk returns the function of a table calling a method of a struct. The method gets a copy of the
+// value, as a call on a struct does, unless it takes its receiver by reference.
+func (g *generator) thunk(t semantic.Type, methodName string, sig *semantic.FunctionType) string {
+	structType, _ := t.(*semantic.StructType)
+	mt := g.methods[structType][methodName]
+	if mt == nil {
+		g.fail(nil, "%s has no method '%s'", t, methodName)
+	}
+	return g.helper("thunk "+mt.cname+" "+typeKey(sig), mt.cname+"_thunk", func(name string) *helper {
+		ctype := g.cType(structType)
+		fnType := g.methodType(mt)
+		recv := "&self"
+		switch fn := g.functions[mt.decl.Function]; {
+		case mt.decl.IsRRef:
+			recv = "(" + ctype + " *)recv"
+		case fn != nil && fn.receiver.captured:
+			recv = g.boxer(structType) + "(*(" + ctype + " *)recv)"
+		}
+
+		params := []string{"fr_fn fr_self", "void *recv"}
+		args := []string{"NULL", recv}
+		for i, param := range sig.Parameters {
+			arg := "a" + strconv.Itoa(i)
+			params = append(params, g.decl(param, arg))
+			args = append(args, g.convert(arg, param, fnType.Parameters[i]))
+		}
+		proto := fmt.Sprintf("static %s %s(%s)", g.cType(results(sig)), name, strings.Join(params, ", "))
+		var b strings.Builder
+		b.WriteString(proto + " {\n")
+		if recv == "&self" {
+			fmt.Fprintf(&b, "    %s self = *(%s *)recv;\n", ctype, ctype)
+		}
+		g.returnCall(&b, mt.cname+"("+strings.Join(args, ", ")+")", results(fnType), results(sig))
+		b.WriteString("}\n")
+		return &helper{proto: proto, body: b.String()}
+	})
+}
+
+// methodPointer returns the C type of a pointer to a method in a table
+func (g *generator) methodPointer(sig *semantic.FunctionType) string {
+	params := []string{"fr_fn", "void *"}
+	for _, param := range sig.Parameters {
+		params = append(params, g.cType(param))
+	}
+	return g.cType(results(sig)) + " (*)(" + strings.Join(params, ", ") + ")"
+}
+
+// dispatcher returns the helper calling a method of an interface value through its table, which
+// fails at pos when the value is zero
+func (g *generator) dispatcher(iface *semantic.InterfaceType, methodName string) string {
+	return g.helper("call "+typeKey(iface)+" "+methodName, "fr_call", func(name string) *helper {
+		sig := iface.Methods[methodName]
+		entry := 1
+		for _, other := range iface.MethodNames() {
+			if other < methodName {
+				entry++
+			}
+		}
+		params := []string{"fr_iface v", "int pos"}
+		args := []string{"NULL", "v.data"}
+		for i, param := range sig.Parameters {
+			arg := "a" + strconv.Itoa(i)
+			params = append(params, g.decl(param, arg))
+			args = append(args, arg)
+		}
+		result := results(sig)
+		proto := fmt.Sprintf("static %s %s(%s)", g.cType(result), name, strings.Join(params, ", "))
+		call := fmt.Sprintf("((%s)v.table[%d])(%s)", g.methodPointer(sig), entry, strings.Join(args, ", "))
+		if result != nil {
+			call = "return " + call
+		}
+		body := fmt.Sprintf("%s {\n    if (v.table == NULL) fr_fail(pos, %s);\n    %s;\n}\n",
+			proto, cString("cannot read '"+methodName+"' of <none>"), call)
+		return &helper{proto: proto, body: body}
+	})
+}
+
+// interfaceBinder returns the helper binding a method of an interface value, which fails at pos when
+// the value is zero. The function value it creates calls the method through the table.
+func (g *generator) interfaceBinder(iface *semantic.InterfaceType, methodName string) string {
+	return g.helper("bind "+typeKey(iface)+" "+methodName, "fr_bind", func(name string) *helper {
+		sig := iface.Methods[methodName]
+		dispatcher := g.dispatcher(iface, methodName)
+		var b strings.Builder
+		fmt.Fprintf(&b, "struct %s_env {\n    fr_closure fr_base;\n    fr_iface recv;\n};\n\n", name)
+
+		params := []string{"fr_fn fr_self"}
+		args := []string{"((struct " + name + "_env *)fr_self)->recv", "-1"}
+		for i, param := range sig.Parameters {
+			arg := "a" + strconv.Itoa(i)
+			params = append(params, g.decl(param, arg))
+			args = append(args, arg)
+		}
+		result := results(sig)
+		call := fmt.Sprintf("%s(%s)", dispatcher, strings.Join(args, ", "))
+		if result != nil {
+			call = "return " + call
+		}
+		fmt.Fprintf(&b, "static %s %s_code(%s) {\n    %s;\n}\n\n", g.cType(result), name, strings.Join(params, ", "), call)
+
+		proto := fmt.Sprintf("static fr_fn %s(fr_iface recv, int pos)", name)
+		fmt.Fprintf(&b, "%s {\n    struct %s_env *env;\n", proto, name)
+		fmt.Fprintf(&b, "    if (recv.table == NULL) fr_fail(pos, %s);\n", cString("cannot read '"+methodName+"' of <none>"))
+		b.WriteString("    env = fr_alloc(sizeof *env);\n")
+		fmt.Fprintf(&b, "    env->fr_base.code = (void (*)(void))%s_code;\n", name)
+		b.WriteString("    env->recv = recv;\n    return &env->fr_base;\n}\n")
+		return &helper{proto: proto, body: b.String()}
+	})
+}
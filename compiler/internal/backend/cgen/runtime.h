@@ -0,0 +1,472 @@
+/* The runtime of the Ferret C backend, every generated program starts with it. Memory is allocated
+   and never freed, values live until the program exits. */
+
+#include <ctype.h>
+#include <errno.h>
+#include <inttypes.h>
+#include <math.h>
+#include <stdarg.h>
+#include <stdbool.h>
+#include <stddef.h>
+#include <stdint.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+/* A str, immutable and shared. data is not terminated by a NUL. */
+typedef struct fr_str {
+    const char *data;
+    int64_t len;
+} fr_str;
+
+#define FR_STR(literal) ((fr_str){literal, sizeof(literal) - 1})
+
+/* An array is shared by every variable it is assigned to, NULL is an empty array */
+typedef struct fr_array {
+    int64_t len;
+    void *data;
+} fr_array;
+
+/* A function value points to the code of the function, a closure is a larger struct starting with
+   one and holding pointers to the variables it captured */
+typedef struct fr_closure {
+    void (*code)(void);
+} fr_closure;
+
+typedef fr_closure *fr_fn;
+
+/* An interface value points to a copy of the value it holds and to the table of its type for the
+   interface: the function comparing two values of the type, then the methods of the interface in the
+   order of their names. The zero value has no table. */
+typedef struct fr_iface {
+    void (*const *table)(void);
+    void *data;
+} fr_iface;
+
+/* A position a runtime error can report, in the table the generated program defines */
+typedef struct fr_pos {
+    const char *function;
+    const char *file;
+    int line;
+    int column;
+} fr_pos;
+
+extern const fr_pos fr_positions[];
+
+/* A call in progress. pos is where it is evaluating, the call site while it calls another function. */
+typedef struct fr_frame {
+    struct fr_frame *caller;
+    int pos;
+} fr_frame;
+
+#define FR_MAX_DEPTH 10000
+
+static fr_frame *fr_top;
+static int fr_depth;
+
+/* A trace prints the innermost FR_TRACE_HEAD and the outermost FR_TRACE_GROUPS - FR_TRACE_HEAD runs of
+   identical frames, the calls between are counted instead */
+#define FR_TRACE_GROUPS 50
+#define FR_TRACE_HEAD 40
+
+static int fr_same_pos(const fr_pos *a, const fr_pos *b) {
+    return a->line == b->line && a->column == b->column && strcmp(a->function, b->function) == 0 &&
+           strcmp(a->file, b->file) == 0;
+}
+
+/* fr_fail stops the program with a runtime error at pos, or where the current call is when pos is -1,
+   and prints the calls that led to it innermost first, a run of identical frames as one */
+void fr_fail(int pos, const char *format, ...) {
+    static const fr_pos *runs[FR_MAX_DEPTH + 1];
+    static int counts[FR_MAX_DEPTH + 1];
+    int n = 0, i, j;
+    va_list args;
+    fr_frame *frame;
+
+    fflush(stdout);
+    if (pos >= 0 && fr_top != NULL) {
+        fr_top->pos = pos;
+    }
+    fputs("runtime error: ", stderr);
+    va_start(args, format);
+    vfprintf(stderr, format, args);
+    va_end(args);
+    for (frame = fr_top; frame != NULL; frame = frame->caller) {
+        const fr_pos *p = &fr_positions[frame->pos];
+        if (n > 0 && fr_same_pos(runs[n - 1], p)) {
+            counts[n - 1]++;
+        } else {
+            runs[n] = p;
+            counts[n++] = 1;
+        }
+    }
+    for (i = 0; i < n; i++) {
+        if (n > FR_TRACE_GROUPS && i == FR_TRACE_HEAD) {
+            int64_t hidden = 0;
+            for (j = FR_TRACE_HEAD; j < n - (FR_TRACE_GROUPS - FR_TRACE_HEAD); j++) {
+                hidden += counts[j];
+            }
+            fprintf(stderr, "\n    ... %" PRId64 " more calls", hidden);
+            i = n - (FR_TRACE_GROUPS - FR_TRACE_HEAD) - 1;
+            continue;
+        }
+        fprintf(stderr, "\n    at %s (%s:%d:%d)", runs[i]->function, runs[i]->file, runs[i]->line, runs[i]->column);
+        if (counts[i] > 1) {
+            fprintf(stderr, "\n    ... the call above repeats %d more times", counts[i] - 1);
+        }
+    }
+    fputc('\n', stderr);
+    exit(EXIT_FAILURE);
+}
+
+static inline void fr_enter(fr_frame *frame, int pos) {
+    if (fr_depth >= FR_MAX_DEPTH) {
+        fr_fail(-1, "stack overflow, more than %d nested calls", FR_MAX_DEPTH);
+    }
+    frame->caller = fr_top;
+    frame->pos = pos;
+    fr_top = frame;
+    fr_depth++;
+}
+
+static inline void fr_leave(fr_frame *frame) {
+    fr_top = frame->caller;
+    fr_depth--;
+}
+
+void *fr_alloc(size_t size) {
+    void *p = calloc(1, size == 0 ? 1 : size);
+    if (p == NULL) {
+        fr_fail(-1, "out of memory");
+    }
+    return p;
+}
+
+/* Two interface values are equal when they hold equal values of the same type, the table tells the type */
+bool fr_iface_eq(fr_iface a, fr_iface b) {
+    if (a.table != b.table) {
+        return false;
+    }
+    return a.table == NULL || ((bool (*)(void *, void *))a.table[0])(a.data, b.data);
+}
+
+/* Integers wrap at the width of their type: the arithmetic is done on unsigned integers at least as
+   wide as int, which wrap in C, and converted back */
+#define FR_INTEGER(T, S, W)                                                          \
+    static inline S fr_add_##T(S a, S b) { return (S)((W)a + (W)b); }                \
+    static inline S fr_sub_##T(S a, S b) { return (S)((W)a - (W)b); }                \
+    static inline S fr_mul_##T(S a, S b) { return (S)((W)a * (W)b); }                \
+    static inline S fr_neg_##T(S a) { return (S)((W)0 - (W)a); }                     \
+    static inline S fr_pre_##T(S *p, S d) { return *p = fr_add_##T(*p, d); }         \
+    static inline S fr_post_##T(S *p, S d) {                                         \
+        S old = *p;                                                                  \
+        *p = fr_add_##T(*p, d);                                                      \
+        return old;                                                                  \
+    }
+
+/* The most negative value divided by -1 overflows back to itself, as in two's complement */
+#define FR_SIGNED(T, S, W)                                                           \
+    FR_INTEGER(T, S, W)                                                              \
+    static inline S fr_div_##T(S a, S b, int pos) {                                  \
+        if (b == 0) fr_fail(pos, "integer division by zero");                        \
+        return b == -1 ? fr_neg_##T(a) : (S)(a / b);                                 \
+    }                                                                                \
+    static inline S fr_mod_##T(S a, S b, int pos) {                                  \
+        if (b == 0) fr_fail(pos, "integer division by zero");                        \
+        return b == -1 ? 0 : (S)(a % b);                                             \
+    }
+
+#define FR_UNSIGNED(T, S, W)                                                         \
+    FR_INTEGER(T, S, W)                                                              \
+    static inline S fr_div_##T(S a, S b, int pos) {                                  \
+        if (b == 0) fr_fail(pos, "integer division by zero");                        \
+        return (S)(a / b);                                                           \
+    }                                                                                \
+    static inline S fr_mod_##T(S a, S b, int pos) {                                  \
+        if (b == 0) fr_fail(pos, "integer division by zero");                        \
+        return (S)(a % b);                                                           \
+    }
+
+FR_SIGNED(i8, int8_t, uint32_t)
+FR_SIGNED(i16, int16_t, uint32_t)
+FR_SIGNED(i32, int32_t, uint32_t)
+FR_SIGNED(i64, int64_t, uint64_t)
+FR_UNSIGNED(u8, uint8_t, uint32_t)
+FR_UNSIGNED(u16, uint16_t, uint32_t)
+FR_UNSIGNED(u32, uint32_t, uint32_t)
+FR_UNSIGNED(u64, uint64_t, uint64_t)
+
+#define FR_FLOAT(T, S)                                                               \
+    static inline S fr_pre_##T(S *p, S d) { return *p = (S)(*p + d); }               \
+    static inline S fr_post_##T(S *p, S d) {                                         \
+        S old = *p;                                                                  \
+        *p = (S)(*p + d);                                                            \
+        return old;                                                                  \
+    }
+
+FR_FLOAT(f32, float)
+FR_FLOAT(f64, double)
+
+static inline float fr_mod_f32(float a, float b) { return (float)fmod(a, b); }
+static inline double fr_mod_f64(double a, double b) { return fmod(a, b); }
+
+/* A float converts to an integer truncated toward zero, a value out of range becomes the most negative
+   int64 before it wraps to the width of the integer, as on the machines the interpreter runs on */
+static inline int64_t fr_ftoi(double f) {
+    if (f >= -9223372036854775808.0 && f < 9223372036854775808.0) {
+        return (int64_t)f;
+    }
+    return INT64_MIN;
+}
+
+static inline uint64_t fr_ftou(double f) {
+    if (f < 9223372036854775808.0) {
+        return (uint64_t)fr_ftoi(f);
+    }
+    if (f < 18446744073709551616.0) {
+        return (uint64_t)fr_ftoi(f - 9223372036854775808.0) ^ UINT64_C(0x8000000000000000);
+    }
+    return UINT64_C(0x8000000000000000);
+}
+
+fr_str fr_copy(const char *data, int64_t len) {
+    char *copy = fr_alloc((size_t)len);
+    memcpy(copy, data, (size_t)len);
+    return (fr_str){copy, len};
+}
+
+fr_str fr_concat(fr_str a, fr_str b) {
+    char *data = fr_alloc((size_t)(a.len + b.len));
+    if (a.len > 0) memcpy(data, a.data, (size_t)a.len);
+    if (b.len > 0) memcpy(data + a.len, b.data, (size_t)b.len);
+    return (fr_str){data, a.len + b.len};
+}
+
+/* fr_str_cmp orders strings by their bytes, it returns -1, 0 or 1 */
+int fr_str_cmp(fr_str a, fr_str b) {
+    int64_t n = a.len < b.len ? a.len : b.len;
+    int c = n > 0 ? memcmp(a.data, b.data, (size_t)n) : 0;
+    if (c != 0) return c < 0 ? -1 : 1;
+    return a.len < b.len ? -1 : a.len > b.len ? 1 : 0;
+}
+
+bool fr_str_eq(fr_str a, fr_str b) {
+    return a.len == b.len && (a.len == 0 || memcmp(a.data, b.data, (size_t)a.len) == 0);
+}
+
+/* fr_cstr returns a copy of s terminated by a NUL, for the C library */
+char *fr_cstr(fr_str s) {
+    char *c = fr_alloc((size_t)s.len + 1);
+    if (s.len > 0) memcpy(c, s.data, (size_t)s.len);
+    return c;
+}
+
+fr_array *fr_array_of(int64_t len, size_t size, const void *elems) {
+    fr_array *a = fr_alloc(sizeof(fr_array));
+    a->len = len;
+    a->data = fr_alloc((size_t)len * size);
+    memcpy(a->data, elems, (size_t)len * size);
+    return a;
+}
+
+fr_array *fr_array_new(int64_t len, size_t size) {
+    fr_array *a = fr_alloc(sizeof(fr_array));
+    a->len = len;
+    a->data = fr_alloc((size_t)len * size);
+    return a;
+}
+
+static inline int64_t fr_len(const fr_array *a) {
+    return a == NULL ? 0 : a->len;
+}
+
+/* fr_elem returns the address of an element, after checking the index is in range */
+void *fr_elem(fr_array *a, int64_t i, size_t size, int pos) {
+    if (i < 0 || i >= fr_len(a)) {
+        fr_fail(pos, "index %" PRId64 " out of range for an array of length %" PRId64, i, fr_len(a));
+    }
+    return (char *)a->data + (size_t)i * size;
+}
+
+/* fr_elem_u is fr_elem for a u64 index, which can be above the int64 range */
+void *fr_elem_u(fr_array *a, uint64_t i, size_t size, int pos) {
+    if (i >= (uint64_t)fr_len(a)) {
+        fr_fail(pos, "index %" PRIu64 " out of range for an array of length %" PRId64, i, fr_len(a));
+    }
+    return (char *)a->data + (size_t)i * size;
+}
+
+/* std/fmt */
+
+void fr_std_fmt_print(fr_str s) {
+    fwrite(s.data, 1, (size_t)s.len, stdout);
+}
+
+void fr_std_fmt_println(fr_str s) {
+    fwrite(s.data, 1, (size_t)s.len, stdout);
+    fputc('\n', stdout);
+}
+
+fr_str fr_std_fmt_formatInt(int64_t n) {
+    char buf[32];
+    sprintf(buf, "%" PRId64, n);
+    return fr_copy(buf, (int64_t)strlen(buf));
+}
+
+/* fr_std_fmt_formatFloat writes the shortest digits that read back as f, without an exponent */
+fr_str fr_std_fmt_formatFloat(double f) {
+    char digits[32], out[400];
+    char *p = digits;
+    int precision, point, n = 0, k = 0, i;
+    char mantissa[20];
+
+    if (f != f) return FR_STR("NaN");
+    if (isinf(f)) return f > 0 ? FR_STR("+Inf") : FR_STR("-Inf");
+    for (precision = 1; precision <= 17; precision++) {
+        sprintf(digits, "%.*e", precision - 1, f);
+        if (strtod(digits, NULL) == f) break;
+    }
+
+    /* digits is [-]d.ddde±xx */
+    if (*p == '-') {
+        out[k++] = '-';
+        p++;
+    }
+    for (; *p != 'e'; p++) {
+        if (*p != '.') mantissa[n++] = *p;
+    }
+    point = atoi(p + 1) + 1;
+    if (point <= 0) {
+        out[k++] = '0';
+        out[k++] = '.';
+        for (i = point; i < 0; i++) out[k++] = '0';
+        for (i = 0; i < n; i++) out[k++] = mantissa[i];
+    } else {
+        for (i = 0; i < point; i++) out[k++] = i < n ? mantissa[i] : '0';
+        if (n > point) {
+            out[k++] = '.';
+            for (i = point; i < n; i++) out[k++] = mantissa[i];
+        }
+    }
+    return fr_copy(out, k);
+}
+
+fr_str fr_std_fmt_formatBool(bool b) {
+    return b ? FR_STR("true") : FR_STR("false");
+}
+
+/* std/io */
+
+fr_str fr_std_io_readLine(void) {
+    size_t len = 0, size = 64;
+    char *line = fr_alloc(size);
+    int c;
+
+    while ((c = getchar()) != EOF && c != '\n') {
+        if (len == size) {
+            char *grown = fr_alloc(size * 2);
+            memcpy(grown, line, len);
+            line = grown;
+            size *= 2;
+        }
+        line[len++] = (char)c;
+    }
+    while (len > 0 && (line[len - 1] == '\r' || line[len - 1] == '\n')) len--;
+    return (fr_str){line, (int64_t)len};
+}
+
+/* fr_fail_errno stops the program with the error of a C library call on a file */
+static void fr_fail_errno(const char *operation, const char *path) {
+    char message[256];
+    snprintf(message, sizeof message, "%s", strerror(errno));
+    message[0] = (char)tolower((unsigned char)message[0]);
+    fr_fail(-1, "%s %s: %s", operation, path, message);
+}
+
+fr_str fr_std_io_readFile(fr_str path) {
+    char *name = fr_cstr(path);
+    FILE *f = fopen(name, "rb");
+    size_t len = 0, size = 4096, n;
+    char *data;
+
+    if (f == NULL) fr_fail_errno("open", name);
+    data = fr_alloc(size);
+    while ((n = fread(data + len, 1, size - len, f)) > 0) {
+        len += n;
+        if (len == size) {
+            char *grown = fr_alloc(size * 2);
+            memcpy(grown, data, len);
+            data = grown;
+            size *= 2;
+        }
+    }
+    if (ferror(f)) fr_fail_errno("read", name);
+    fclose(f);
+    return (fr_str){data, (int64_t)len};
+}
+
+void fr_std_io_writeFile(fr_str path, fr_str content) {
+    char *name = fr_cstr(path);
+    FILE *f = fopen(name, "wb");
+
+    if (f == NULL) fr_fail_errno("open", name);
+    if (content.len > 0 && fwrite(content.data, 1, (size_t)content.len, f) != (size_t)content.len) {
+        fr_fail_errno("write", name);
+    }
+    if (fclose(f) != 0) fr_fail_errno("write", name);
+}
+
+/* std/math */
+
+double fr_std_math_sqrt(double x) { return sqrt(x); }
+double fr_std_math_floor(double x) { return floor(x); }
+double fr_std_math_ceil(double x) { return ceil(x); }
+double fr_std_math_pow(double x, double y) { return pow(x, y); }
+
+/* std/strings, letters and white space are the ASCII ones */
+
+int32_t fr_std_strings_len(fr_str s) {
+    return (int32_t)(uint32_t)s.len;
+}
+
+int32_t fr_std_strings_indexOf(fr_str s, fr_str sub) {
+    int64_t i;
+    for (i = 0; i + sub.len <= s.len; i++) {
+        if (sub.len == 0 || memcmp(s.data + i, sub.data, (size_t)sub.len) == 0) return (int32_t)i;
+    }
+    return -1;
+}
+
+bool fr_std_strings_contains(fr_str s, fr_str sub) {
+    return fr_std_strings_indexOf(s, sub) >= 0;
+}
+
+bool fr_std_strings_hasPrefix(fr_str s, fr_str prefix) {
+    return s.len >= prefix.len && (prefix.len == 0 || memcmp(s.data, prefix.data, (size_t)prefix.len) == 0);
+}
+
+bool fr_std_strings_hasSuffix(fr_str s, fr_str suffix) {
+    return s.len >= suffix.len &&
+           (suffix.len == 0 || memcmp(s.data + s.len - suffix.len, suffix.data, (size_t)suffix.len) == 0);
+}
+
+fr_str fr_std_strings_toUpper(fr_str s) {
+    char *data = fr_alloc((size_t)s.len);
+    int64_t i;
+    for (i = 0; i < s.len; i++) data[i] = (char)toupper((unsigned char)s.data[i]);
+    return (fr_str){data, s.len};
+}
+
+fr_str fr_std_strings_toLower(fr_str s) {
+    char *data = fr_alloc((size_t)s.len);
+    int64_t i;
+    for (i = 0; i < s.len; i++) data[i] = (char)tolower((unsigned char)s.data[i]);
+    return (fr_str){data, s.len};
+}
+
+fr_str fr_std_strings_trim(fr_str s) {
+    int64_t start = 0, end = s.len;
+    while (start < end && isspace((unsigned char)s.data[start])) start++;
+    while (end > start && isspace((unsigned char)s.data[end - 1])) end--;
+    return (fr_str){s.data + start, end - start};
+}
@@ -0,0 +1,510 @@
+package cgen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"compiler/internal/frontend/ast"
+	"compiler/internal/semantic"
+)
+
+// body is a C function being written
+type body struct {
+	fn    *function
+	lines []cLine
+	depth int // the indentation of the next line
+	line  int // the line of the .fer file the next lines are translated from
+	loops []*loop
+}
+
+// loop is a loop being translated, a for loop with a post statement continues with a goto to it
+type loop struct {
+	label string
+	post  bool
+	used  bool
+}
+
+// emit adds a line to the function being written
+func (g *generator) emit(format string, args ...any) {
+	b := g.body
+	file := ""
+	if g.file != nil {
+		file = g.file.FullPath
+	}
+	b.lines = append(b.lines, cLine{text: strings.Repeat("    ", b.depth) + fmt.Sprintf(format, args...), file: file, line: b.line})
+}
+
+// capture translates an expression into code evaluated depth levels deeper than the current line, it
+// returns the lines it needs evaluated first instead of adding them
+func (g *generator) capture(depth int, translate func() string) ([]cLine, string) {
+	b := g.body
+	start := len(b.lines)
+	b.depth += depth
+	code := translate()
+	b.depth -= depth
+	pre := append([]cLine(nil), b.lines[start:]...)
+	b.lines = b.lines[:start]
+	return pre, code
+}
+
+// pos returns the index of the position of a node in the current function
+func (g *generator) pos(node ast.Node) int {
+	return g.position(g.body.fn.name, g.file, node)
+}
+
+// spill evaluates code into a new temporary, so it is evaluated before the expressions translated after it
+func (g *generator) spill(code, ctype string) string {
+	name := g.body.fn.local("fr_t")
+	g.emit("%s = %s;", declare(ctype, name), code)
+	return name
+}
+
+// declare declares a C variable of a C type
+func declare(ctype, name string) string {
+	if strings.HasSuffix(ctype, "*") {
+		return ctype + name
+	}
+	return ctype + " " + name
+}
+
+// translate writes the functions of a module and the function running its top-level code
+func (g *generator) translate(m *module) {
+	g.body = &body{fn: m.init}
+	proto := fmt.Sprintf("void %s(void)", m.init.cname)
+	g.protos = append(g.protos, proto)
+	g.file = nil
+	g.emit("%s {", proto)
+	g.body.depth++
+	g.emit("fr_frame fr_at;")
+	g.emit("fr_enter(&fr_at, %d);", g.position(m.init.name, m.info.AST, nil))
+
+	for _, node := range topLevel(m.info) {
+		g.enter(m, node)
+		switch n := node.(type) {
+		case *ast.FunctionDecl:
+			gl := m.globals[n.Identifier.Name]
+			if gl.kind == globalNative {
+				g.native(gl)
+			} else {
+				g.function(g.functions[n.Function])
+			}
+		case *ast.MethodDecl:
+			if fn := g.functions[n.Function]; fn != nil {
+				g.function(fn)
+			}
+		case *ast.VarDeclStmt:
+			g.globalDecl(m, n)
+		case *ast.TypeDeclStmt, *ast.ImportStmt, *ast.ModuleDeclStmt:
+		default:
+			g.stmt(node)
+		}
+	}
+
+	g.file = nil
+	g.emit("fr_leave(&fr_at);")
+	g.body.depth--
+	g.emit("}")
+	g.bodies = append(g.bodies, g.body.lines)
+}
+
+// globalDecl declares the globals of a top-level declaration and assigns their initial values
+func (g *generator) globalDecl(m *module, n *ast.VarDeclStmt) {
+	g.body.line = n.Loc().Start.Line
+	codes, from := g.values(n.Initializers, len(n.Variables), false)
+	for i, v := range n.Variables {
+		gl := m.globals[v.Identifier.Name]
+		t := g.globalType(gl)
+		g.globals = append(g.globals, "static "+g.decl(t, gl.cname)+";")
+		if i < len(codes) {
+			g.emit("%s = %s;", gl.cname, g.convert(codes[i], from[i], t))
+		}
+	}
+}
+
+// function writes the C function of a Ferret function, method or closure
+func (g *generator) function(fn *function) {
+	fn.written = true
+	saved, module, file, current := g.body, g.module, g.file, g.current
+	defer func() { g.body, g.module, g.file, g.current = saved, module, file, current }()
+	g.body, g.module, g.file, g.current = &body{fn: fn, line: fn.lit.Loc().Start.Line}, fn.module, fn.file, fn.lit
+
+	params := []string{"fr_fn fr_self"}
+	if fn.receiver != nil {
+		params = append(params, g.cType(fn.receiver.typ)+" *"+fn.receiver.cname)
+	}
+	var boxed []*variable
+	args := make(map[*variable]string)
+	for _, param := range fn.params {
+		if param.boxed {
+			args[param] = fn.local(param.cname + "_arg")
+			boxed = append(boxed, param)
+			params = append(params, g.decl(param.typ, args[param]))
+		} else {
+			params = append(params, g.decl(param.typ, param.cname))
+		}
+	}
+	result := results(fn.sig)
+	proto := fmt.Sprintf("%s %s(%s)", g.cType(result), fn.cname, strings.Join(params, ", "))
+	g.protos = append(g.protos, proto)
+
+	g.emit("%s {", proto)
+	g.body.depth++
+	g.emit("fr_frame fr_at;")
+	if len(fn.captures) > 0 {
+		g.emit("struct %s_env *fr_env = (struct %s_env *)fr_self;", fn.cname, fn.cname)
+	}
+	g.emit("fr_enter(&fr_at, %d);", g.pos(fn.lit))
+	for _, param := range boxed {
+		g.emit("%s = fr_alloc(sizeof(%s));", declare(g.cType(param.typ)+" *", param.cname), g.cType(param.typ))
+		g.emit("*%s = %s;", param.cname, args[param])
+	}
+
+	nodes := fn.lit.Body.Nodes
+	for _, node := range nodes {
+		g.stmt(node)
+	}
+	if len(nodes) == 0 {
+		g.emit("fr_leave(&fr_at);")
+	} else if _, returns := nodes[len(nodes)-1].(*ast.ReturnStmt); !returns {
+		if end := fn.lit.Body.Loc().End; end != nil {
+			g.body.line = end.Line
+		}
+		g.emit("fr_leave(&fr_at);")
+		if result != nil {
+			g.emit("return %s;", g.zero(result))
+		}
+	}
+	g.body.depth--
+	g.emit("}")
+	g.bodies = append(g.bodies, g.body.lines)
+}
+
+// native writes the function of a standard library function the runtime implements, or one failing
+// when it is called
+func (g *generator) native(gl *global) {
+	fnType := g.globalType(gl).(*semantic.FunctionType)
+	params := []string{"fr_fn fr_self"}
+	var args []string
+	for i, param := range fnType.Parameters {
+		arg := "a" + strconv.Itoa(i)
+		params = append(params, g.decl(param, arg))
+		args = append(args, arg)
+	}
+	result := results(fnType)
+	proto := fmt.Sprintf("%s %s(%s)", g.cType(result), gl.cname, strings.Join(params, ", "))
+	g.protos = append(g.protos, proto)
+
+	saved := g.body
+	defer func() { g.body = saved }()
+	g.body = &body{fn: gl.module.init, line: gl.fn.Loc().Start.Line}
+	g.emit("%s {", proto)
+	g.body.depth++
+	g.emit("(void)fr_self;")
+	implemented := false
+	for _, name := range natives[gl.module.importPath] {
+		implemented = implemented || name == gl.name
+	}
+	call := fmt.Sprintf("fr_%s_%s(%s)", mangle(gl.module.importPath), gl.name, strings.Join(args, ", "))
+	switch {
+	case !implemented:
+		g.emit("fr_fail(-1, %s);", cString(strings.ReplaceAll(fmt.Sprintf("%s::%s has no native implementation", gl.module.importPath, gl.name), "%", "%%")))
+		if result != nil {
+			g.emit("return %s;", g.zero(result))
+		}
+	case result != nil:
+		g.emit("return %s;", call)
+	default:
+		g.emit("%s;", call)
+	}
+	g.body.depth--
+	g.emit("}")
+	g.bodies = append(g.bodies, g.body.lines)
+}
+
+// stmt translates a statement
+func (g *generator) stmt(node ast.Node) {
+	if loc := node.Loc(); loc != nil && loc.Start != nil {
+		g.body.line = loc.Start.Line
+	}
+	switch n := node.(type) {
+	case *ast.VarDeclStmt:
+		codes, from := g.values(n.Initializers, len(n.Variables), false)
+		for i, v := range n.Variables {
+			variable := g.vars[v.Identifier]
+			code := g.zero(variable.typ)
+			if i < len(codes) {
+				code = g.convert(codes[i], from[i], variable.typ)
+			}
+			g.declareLocal(variable, code)
+		}
+	case *ast.AssignmentStmt:
+		g.assignment(n)
+	case *ast.ExpressionStmt:
+		for _, expr := range *n.Expressions {
+			g.exprStmt(expr)
+		}
+	case *ast.FunctionDecl:
+		// Declared before the closure is created, so the function can call itself
+		v := g.vars[n.Identifier]
+		g.declareLocal(v, "NULL")
+		g.emit("%s = %s;", g.varCode(v), g.closure(n.Function))
+	case *ast.Block:
+		g.emit("{")
+		g.block(n)
+		g.emit("}")
+	case *ast.IfStmt:
+		g.ifStmt(n)
+	case *ast.WhileStmt:
+		g.whileStmt(n)
+	case *ast.ForStmt:
+		g.forStmt(n)
+	case *ast.ReturnStmt:
+		g.returnStmt(n)
+	case *ast.BreakStmt:
+		g.emit("break;")
+	case *ast.ContinueStmt:
+		if l := g.body.loops[len(g.body.loops)-1]; l.post {
+			l.used = true
+			g.emit("goto %s;", l.label)
+		} else {
+			g.emit("continue;")
+		}
+	case *ast.TypeDeclStmt, *ast.ImportStmt, *ast.ModuleDeclStmt:
+	default:
+		g.fail(node, "cannot translate %T", node)
+	}
+}
+
+// block translates the statements of a block one level deeper than the current line
+func (g *generator) block(block *ast.Block) {
+	g.body.depth++
+	for _, node := range block.Nodes {
+		g.stmt(node)
+	}
+	g.body.depth--
+}
+
+// declareLocal declares a local variable, a boxed one is allocated
+func (g *generator) declareLocal(v *variable, code string) {
+	ctype := g.cType(v.typ)
+	if v.boxed {
+		g.emit("%s = fr_alloc(sizeof(%s));", declare(ctype+" *", v.cname), ctype)
+		g.emit("*%s = %s;", v.cname, code)
+		return
+	}
+	g.emit("%s = %s;", declare(ctype, v.cname), code)
+}
+
+// values translates the values of a declaration, an assignment or a return, with their types. A
+// single call returning several values is spread over want targets. Every value is evaluated into a
+// temporary when spillAll is set, as assigning one may change the variable another one reads.
+func (g *generator) values(exprs []ast.Expression, want int, spillAll bool) ([]string, []semantic.Type) {
+	if len(exprs) == 1 && want > 1 {
+		if tuple, ok := g.typeOf(exprs[0]).(*tupleType); ok {
+			t := g.spill(g.expr(exprs[0]), g.cType(tuple))
+			codes := make([]string, len(tuple.elems))
+			for i := range tuple.elems {
+				codes[i] = t + ".r" + strconv.Itoa(i)
+			}
+			return codes, tuple.elems
+		}
+	}
+	ops := make([]operand, len(exprs))
+	ts := make([]semantic.Type, len(exprs))
+	for i, expr := range exprs {
+		ts[i] = g.typeOf(expr)
+		ops[i] = g.valueOperand(expr)
+		ops[i].spill = spillAll
+	}
+	return g.operands(ops, false), ts
+}
+
+func (g *generator) assignment(n *ast.AssignmentStmt) {
+	targets, exprs := *n.Left, *n.Right
+	spillAll := len(targets) > 1
+	for _, target := range targets {
+		spillAll = spillAll || !g.isPure(target)
+	}
+	codes, from := g.values(exprs, len(targets), spillAll)
+	for i, target := range targets {
+		if i < len(codes) {
+			g.emit("%s = %s;", g.lvalue(target), g.convert(codes[i], from[i], g.typeOf(target)))
+		}
+	}
+}
+
+// lvalue translates the target of an assignment
+func (g *generator) lvalue(target ast.Expression) string {
+	if field, ok := target.(*ast.FieldAccessExpr); ok && !g.isAddressable(*field.Object) {
+		// The field of a struct no variable holds, the assignment changes a copy
+		structType := g.typeOf(*field.Object).(*semantic.StructType)
+		return g.spill(g.expr(*field.Object), g.cType(structType)) + "." + g.field(structType, field.Field.Name)
+	}
+	switch target.(type) {
+	case *ast.IdentifierExpr, *ast.VarScopeResolution, *ast.FieldAccessExpr, *ast.IndexableExpr:
+		return g.expr(target)
+	}
+	g.fail(target, "cannot assign to %T", target)
+	return ""
+}
+
+// exprStmt translates an expression evaluated for its effects
+func (g *generator) exprStmt(expr ast.Expression) {
+	switch e := expr.(type) {
+	case *ast.FunctionCallExpr:
+		pos, call := g.call(e)
+		g.emit("fr_at.pos = %d;", pos)
+		g.emit("%s;", call)
+	case *ast.PrefixExpr, *ast.PostfixExpr:
+		g.emit("%s;", g.expr(expr))
+	default:
+		g.emit("(void)%s;", g.expr(expr))
+	}
+}
+
+func (g *generator) ifStmt(n *ast.IfStmt) {
+	g.emit("if (%s) {", g.expr(*n.Condition))
+	for {
+		g.block(n.Body)
+		switch alt := n.Alternative.(type) {
+		case *ast.IfStmt:
+			pre, cond := g.capture(1, func() string { return g.expr(*alt.Condition) })
+			if len(pre) > 0 {
+				// The condition needs statements evaluated first, so the else branch holds them and an if
+				g.emit("} else {")
+				g.body.lines = append(g.body.lines, pre...)
+				g.body.depth++
+				g.emit("if (%s) {", cond)
+				g.ifTail(alt)
+				g.body.depth--
+				g.emit("}")
+				return
+			}
+			g.body.line = alt.Loc().Start.Line
+			g.emit("} else if (%s) {", cond)
+			n = alt
+			continue
+		case *ast.Block:
+			g.emit("} else {")
+			g.block(alt)
+		}
+		g.emit("}")
+		return
+	}
+}
+
+// ifTail translates the branches of an if statement whose condition is already written
+func (g *generator) ifTail(n *ast.IfStmt) {
+	g.block(n.Body)
+	switch alt := n.Alternative.(type) {
+	case *ast.IfStmt:
+		g.emit("} else {")
+		g.body.depth++
+		g.ifStmt(alt)
+		g.body.depth--
+	case *ast.Block:
+		g.emit("} else {")
+		g.block(alt)
+	}
+	g.emit("}")
+}
+
+// loopHead opens a loop running while a condition holds, a condition needing statements evaluated
+// first is checked in the loop
+func (g *generator) loopHead(condition *ast.Expression) {
+	if condition == nil {
+		g.emit("for (;;) {")
+		return
+	}
+	pre, cond := g.capture(1, func() string { return g.expr(*condition) })
+	if len(pre) == 0 {
+		g.emit("while (%s) {", cond)
+		return
+	}
+	g.emit("for (;;) {")
+	g.body.lines = append(g.body.lines, pre...)
+	g.body.depth++
+	g.emit("if (!%s) break;", cond)
+	g.body.depth--
+}
+
+func (g *generator) whileStmt(n *ast.WhileStmt) {
+	g.loopHead(n.Condition)
+	g.body.loops = append(g.body.loops, &loop{})
+	g.block(n.Body)
+	g.body.loops = g.body.loops[:len(g.body.loops)-1]
+	g.emit("}")
+}
+
+// forStmt translates a for loop. The variables of the init statement are declared once for the whole
+// loop, the body is a block of its own, and continue jumps to the post statement.
+func (g *generator) forStmt(n *ast.ForStmt) {
+	g.emit("{")
+	g.body.depth++
+	if n.Init != nil {
+		g.stmt(n.Init)
+	}
+	g.body.line = n.Loc().Start.Line
+	g.loopHead(n.Condition)
+	l := &loop{post: n.Post != nil}
+	if l.post {
+		l.label = g.body.fn.local("fr_continue")
+	}
+	g.body.loops = append(g.body.loops, l)
+	g.body.depth++
+	if l.post {
+		g.emit("{")
+		g.block(n.Body)
+		g.emit("}")
+		if l.used {
+			g.emit("%s:;", l.label)
+		}
+		g.stmt(n.Post)
+	} else {
+		g.body.depth--
+		g.block(n.Body)
+		g.body.depth++
+	}
+	g.body.depth--
+	g.body.loops = g.body.loops[:len(g.body.loops)-1]
+	g.body.line = n.Loc().Start.Line
+	g.emit("}")
+	g.body.depth--
+	g.emit("}")
+}
+
+// returnStmt evaluates the results before the frame is left, and converts them to the result types
+func (g *generator) returnStmt(n *ast.ReturnStmt) {
+	sig := g.body.fn.sig
+	result := results(sig)
+	var exprs []ast.Expression
+	if n.Values != nil {
+		exprs = *n.Values
+	}
+	if len(exprs) == 0 || result == nil {
+		for _, expr := range exprs {
+			g.exprStmt(expr)
+		}
+		g.emit("fr_leave(&fr_at);")
+		g.emit("return;")
+		return
+	}
+
+	codes, from := g.values(exprs, len(sig.ReturnTypes), false)
+	var code string
+	if tuple, ok := result.(*tupleType); ok {
+		code = g.body.fn.local("fr_t")
+		g.emit("%s;", g.decl(tuple, code))
+		for i := range tuple.elems {
+			g.emit("%s.r%d = %s;", code, i, g.convert(codes[i], from[i], tuple.elems[i]))
+		}
+	} else {
+		code = g.convert(codes[0], from[0], result)
+		if !g.isPure(exprs[0]) {
+			code = g.spill(code, g.cType(result))
+		}
+	}
+	g.emit("fr_leave(&fr_at);")
+	g.emit("return %s;", code)
+}
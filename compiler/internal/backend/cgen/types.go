@@ -0,0 +1,661 @@
+package cgen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"compiler/ctx"
+	"compiler/internal/frontend/ast"
+	"compiler/internal/semantic"
+	"compiler/internal/types"
+)
+
+// tupleType is the type of a call to a function returning several values, a C struct with a field per value
+type tupleType struct {
+	elems []semantic.Type
+}
+
+func (t *tupleType) TypeName() types.TYPE_NAME { return "tuple" }
+
+func (t *tupleType) String() string {
+	names := make([]string, len(t.elems))
+	for i, elem := range t.elems {
+		names[i] = elem.String()
+	}
+	return "(" + strings.Join(names, ", ") + ")"
+}
+
+func (t *tupleType) Equals(other semantic.Type) bool {
+	o, ok := other.(*tupleType)
+	return ok && typeKey(t) == typeKey(o)
+}
+
+// cStruct is a C struct the generated code declares, for a struct type or a tuple
+type cStruct struct {
+	typ    semantic.Type // the struct type or the tuple
+	name   string
+	fields []string // the C names of the fields, in the order they are declared
+	types  []semantic.Type
+	byName map[string]string // the C name of each field of a struct type
+}
+
+func primitive(name types.TYPE_NAME) semantic.Type {
+	return &semantic.PrimitiveType{Name: name}
+}
+
+// kindOf returns the name of a primitive type, or "" for any other type
+func kindOf(t semantic.Type) types.TYPE_NAME {
+	if p, ok := t.(*semantic.PrimitiveType); ok {
+		return p.Name
+	}
+	return ""
+}
+
+func isFloat(t semantic.Type) bool {
+	kind := kindOf(t)
+	return kind == types.FLOAT32 || kind == types.FLOAT64
+}
+
+func isInteger(t semantic.Type) bool {
+	return types.GetNumberBitSize(kindOf(t)) > 0 && !isFloat(t)
+}
+
+func isNumeric(t semantic.Type) bool {
+	return types.GetNumberBitSize(kindOf(t)) > 0
+}
+
+// resolve follows the type names in a type written in a module to the types they are declared as.
+// Every struct type resolves to one canonical struct, its field types resolved in the module declaring
+// it, and every interface type to one canonical interface the same way.
+func (g *generator) resolve(t semantic.Type, m *ctx.Module) semantic.Type {
+	// An alias cycle is reported by the type checker, the bound only keeps a bug from hanging the compiler
+	for range 64 {
+		user, ok := t.(*semantic.UserType)
+		if !ok {
+			break
+		}
+		if user.Definition != nil {
+			t = user.Definition
+			continue
+		}
+		sym, found := m.SymbolTable.Lookup(string(user.Name))
+		if !found || sym.Kind != semantic.SymbolType {
+			g.fail(nil, "unknown type '%s'", user.Name)
+		}
+		t = sym.Type
+	}
+
+	switch t := t.(type) {
+	case *semantic.ArrayType:
+		return &semantic.ArrayType{ElementType: g.resolve(t.ElementType, m), Name: types.ARRAY}
+	case *semantic.FunctionType:
+		resolved := &semantic.FunctionType{Name: types.FUNCTION}
+		for _, param := range t.Parameters {
+			resolved.Parameters = append(resolved.Parameters, g.resolve(param, m))
+		}
+		for _, result := range t.ReturnTypes {
+			resolved.ReturnTypes = append(resolved.ReturnTypes, g.resolve(result, m))
+		}
+		return resolved
+	case *semantic.StructType:
+		return g.canonical(t, m)
+	case *semantic.InterfaceType:
+		return g.canonicalInterface(t, m)
+	case *semantic.UserType:
+		g.fail(nil, "unknown type '%s'", t.Name)
+	}
+	return t
+}
+
+// canonical returns the struct type every type naming the same struct resolves to
+func (g *generator) canonical(t *semantic.StructType, m *ctx.Module) *semantic.StructType {
+	if c := g.resolved[t]; c != nil {
+		return c
+	}
+	owner := m
+	if declaring := g.ctx.Modules[t.Module]; declaring != nil {
+		owner = declaring
+	}
+	c := &semantic.StructType{Name: t.Name, Module: t.Module, Fields: make(map[string]semantic.Type, len(t.Fields))}
+	if t.Module != "" {
+		key := typeKey(c)
+		if existing := g.canon[key]; existing != nil {
+			g.resolved[t] = existing
+			return existing
+		}
+		// Registered before its fields, so a field can refer to the struct type again
+		g.canon[key] = c
+		g.resolved[t] = c
+	}
+	declared := g.inDeclaration(t.Module, t.Name, func(base ast.DataType) bool {
+		structType, ok := base.(*ast.StructType)
+		if !ok {
+			return false
+		}
+		for _, field := range structType.Fields {
+			if field.FieldType != nil {
+				c.Fields[field.FieldIdentifier.Name] = g.astType(field.FieldType)
+			}
+		}
+		return true
+	})
+	if !declared {
+		for name, fieldType := range t.Fields {
+			c.Fields[name] = g.resolve(fieldType, owner)
+		}
+	}
+	if t.Module == "" {
+		// An anonymous struct is known by its fields, which cannot refer to it
+		key := typeKey(c)
+		if existing := g.canon[key]; existing != nil {
+			c = existing
+		}
+		g.canon[key] = c
+		g.resolved[t] = c
+	}
+	return c
+}
+
+// astType resolves a type written in the current file
+func (g *generator) astType(dataType ast.DataType) semantic.Type {
+	if dataType == nil {
+		return nil
+	}
+	if scoped, ok := dataType.(*ast.TypeScopeResolution); ok {
+		importPath, found := g.file.ModulenameToImportpath[scoped.Module.Name]
+		if !found || g.ctx.Modules[importPath] == nil {
+			g.fail(scoped.Module, "module '%s' is not imported", scoped.Module.Name)
+		}
+		return g.resolve(&semantic.UserType{Name: scoped.Type()}, g.ctx.Modules[importPath])
+	}
+	switch t := dataType.(type) {
+	case *ast.ArrayType:
+		return &semantic.ArrayType{ElementType: g.astType(t.ElementType), Name: types.ARRAY}
+	case *ast.FunctionType:
+		fnType := &semantic.FunctionType{Name: types.FUNCTION}
+		for _, param := range t.Parameters {
+			fnType.Parameters = append(fnType.Parameters, g.astType(param))
+		}
+		for _, result := range t.ReturnTypes {
+			fnType.ReturnTypes = append(fnType.ReturnTypes, g.astType(result))
+		}
+		return fnType
+	}
+	return g.resolve(semantic.ASTToSemanticType(dataType), g.module.info)
+}
+
+// typeKey identifies a resolved type, two types with the same key have the same C representation
+func typeKey(t semantic.Type) string {
+	switch t := t.(type) {
+	case nil:
+		return "void"
+	case *semantic.PrimitiveType:
+		if t.Name == types.BYTE {
+			// A byte is a u8, the conversion between them changes nothing
+			return string(types.UINT8)
+		}
+		return string(t.Name)
+	case *semantic.ArrayType:
+		return "[]" + typeKey(t.ElementType)
+	case *semantic.FunctionType:
+		return "fn(" + typeKeys(t.Parameters) + ") -> (" + typeKeys(t.ReturnTypes) + ")"
+	case *semantic.StructType:
+		if t.Module != "" {
+			return "struct " + t.Module + "." + string(t.Name)
+		}
+		fields := make([]string, 0, len(t.Fields))
+		for _, name := range sortedFields(t) {
+			fields = append(fields, name+": "+typeKey(t.Fields[name]))
+		}
+		return "struct {" + strings.Join(fields, ", ") + "}"
+	case *semantic.InterfaceType:
+		if t.Module != "" {
+			return "interface " + t.Module + "." + string(t.Name)
+		}
+		methods := make([]string, 0, len(t.Methods))
+		for _, name := range t.MethodNames() {
+			methods = append(methods, name+": "+typeKey(t.Methods[name]))
+		}
+		return "interface {" + strings.Join(methods, ", ") + "}"
+	case *tupleType:
+		return "(" + typeKeys(t.elems) + ")"
+	}
+	return t.String()
+}
+
+func typeKeys(ts []semantic.Type) string {
+	keys := make([]string, len(ts))
+	for i, t := range ts {
+		keys[i] = typeKey(t)
+	}
+	return strings.Join(keys, ", ")
+}
+
+// cType returns the C type the values of a resolved type have
+func (g *generator) cType(t semantic.Type) string {
+	switch t := t.(type) {
+	case nil:
+		return "void"
+	case *semantic.PrimitiveType:
+		switch t.Name {
+		case types.INT8, types.INT16, types.INT32, types.INT64:
+			return fmt.Sprintf("int%d_t", types.GetNumberBitSize(t.Name))
+		case types.UINT8, types.UINT16, types.UINT32, types.UINT64, types.BYTE:
+			return fmt.Sprintf("uint%d_t", types.GetNumberBitSize(t.Name))
+		case types.FLOAT32:
+			return "float"
+		case types.FLOAT64:
+			return "double"
+		case types.BOOL:
+			return "bool"
+		case types.STRING:
+			return "fr_str"
+		}
+	case *semantic.ArrayType:
+		return "fr_array *"
+	case *semantic.FunctionType:
+		return "fr_fn"
+	case *semantic.InterfaceType:
+		return "fr_iface"
+	case *semantic.StructType, *tupleType:
+		return g.cStruct(t).name
+	}
+	g.fail(nil, "the C backend cannot represent values of type %s", t)
+	return ""
+}
+
+// decl declares a C variable or parameter of a resolved type
+func (g *generator) decl(t semantic.Type, name string) string {
+	ctype := g.cType(t)
+	if strings.HasSuffix(ctype, "*") {
+		return ctype + name
+	}
+	return ctype + " " + name
+}
+
+// suffix names the runtime functions for the arithmetic of a numeric type, fr_add_i32 adds two i32s
+func suffix(t semantic.Type) string {
+	kind := kindOf(t)
+	switch {
+	case kind == types.FLOAT32:
+		return "f32"
+	case kind == types.FLOAT64:
+		return "f64"
+	case types.IsSigned(kind):
+		return "i" + strconv.Itoa(int(types.GetNumberBitSize(kind)))
+	}
+	return "u" + strconv.Itoa(int(types.GetNumberBitSize(kind)))
+}
+
+// cStruct returns the C struct of a struct type or a tuple, declaring it the first time
+func (g *generator) cStruct(t semantic.Type) *cStruct {
+	key := typeKey(t)
+	if s := g.structs[key]; s != nil {
+		return s
+	}
+	s := &cStruct{typ: t, byName: make(map[string]string)}
+	g.structs[key] = s
+	g.structOf = append(g.structOf, s)
+	switch t := t.(type) {
+	case *semantic.StructType:
+		if t.Module != "" {
+			s.name = g.unique(mangle(t.Module) + "__" + mangle(string(t.Name)))
+		} else {
+			s.name = g.unique("fr_struct")
+		}
+		used := make(map[string]bool)
+		for _, name := range sortedFields(t) {
+			field := mangle(name)
+			for i := 2; used[field]; i++ {
+				field = mangle(name) + "_" + strconv.Itoa(i)
+			}
+			used[field] = true
+			s.byName[name] = field
+			s.fields = append(s.fields, field)
+			s.types = append(s.types, t.Fields[name])
+		}
+	case *tupleType:
+		s.name = g.unique("fr_tuple")
+		for i, elem := range t.elems {
+			s.fields = append(s.fields, "r"+strconv.Itoa(i))
+			s.types = append(s.types, elem)
+		}
+	}
+	return s
+}
+
+// field returns the C name of a field of a struct type
+func (g *generator) field(t *semantic.StructType, name string) string {
+	return g.cStruct(t).byName[name]
+}
+
+// renderStructs declares the C structs, a struct after the ones it holds by value
+func (g *generator) renderStructs(b *strings.Builder) {
+	// The types of the fields are only known once every struct is, declaring a struct can find more
+	for i := 0; i < len(g.structOf); i++ {
+		for _, t := range g.structOf[i].types {
+			g.cType(t)
+		}
+	}
+	for _, s := range g.structOf {
+		fmt.Fprintf(b, "typedef struct %s %s;\n", s.name, s.name)
+	}
+
+	deps := make(map[*cStruct][]*cStruct)
+	for _, s := range g.structOf {
+		for _, t := range s.types {
+			switch t.(type) {
+			case *semantic.StructType, *tupleType:
+				deps[s] = append(deps[s], g.cStruct(t))
+			}
+		}
+	}
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[*cStruct]int)
+	var define func(s *cStruct)
+	define = func(s *cStruct) {
+		switch state[s] {
+		case done:
+			return
+		case visiting:
+			// The type checker rejects these types, this keeps a bug from writing C that cannot compile
+			name := s.name
+			if t, ok := s.typ.(*semantic.StructType); ok {
+				name = string(t.Name)
+				if m, decl := g.typeDecl(t.Module, t.Name); decl != nil {
+					g.enter(m, decl)
+				}
+			}
+			g.fail(nil, "invalid recursive type: '%s' contains itself", name)
+		}
+		state[s] = visiting
+		for _, dep := range deps[s] {
+			define(dep)
+		}
+		state[s] = done
+		fmt.Fprintf(b, "\nstruct %s {\n", s.name)
+		if len(s.fields) == 0 {
+			b.WriteString("    char fr_empty;\n")
+		}
+		for i, field := range s.fields {
+			fmt.Fprintf(b, "    %s;\n", g.decl(s.types[i], field))
+		}
+		b.WriteString("};\n")
+	}
+	for _, s := range g.structOf {
+		define(s)
+	}
+}
+
+// zero returns the value of a variable of a resolved type declared without an initializer
+func (g *generator) zero(t semantic.Type) string {
+	switch t := t.(type) {
+	case *semantic.PrimitiveType:
+		switch {
+		case isFloat(t):
+			return "0.0"
+		case isInteger(t):
+			return "0"
+		case t.Name == types.BOOL:
+			return "false"
+		case t.Name == types.STRING:
+			return "((fr_str){NULL, 0})"
+		}
+	case *semantic.ArrayType, *semantic.FunctionType:
+		return "NULL"
+	case *semantic.InterfaceType:
+		return "((fr_iface){NULL, NULL})"
+	case *semantic.StructType, *tupleType:
+		return "((" + g.cType(t) + "){0})"
+	}
+	g.fail(nil, "the C backend cannot represent values of type %s", t)
+	return ""
+}
+
+// convert converts a C expression of type from to the type it is stored as. Numbers take the kind of
+// the target, arrays and structs of other element and field types are copied into new ones, a
+// function of another signature is wrapped in one converting its arguments and results and a value
+// stored in an interface is copied and given the table of its type.
+func (g *generator) convert(code string, from, to semantic.Type) string {
+	if from == nil || to == nil || typeKey(from) == typeKey(to) {
+		return code
+	}
+	switch to := to.(type) {
+	case *semantic.PrimitiveType:
+		if !isNumeric(from) || !isNumeric(to) {
+			return code
+		}
+		switch {
+		case to.Name == types.FLOAT32 && isInteger(from):
+			// Through a double, as the interpreter rounds an integer to f64 first
+			return "((float)(double)(" + code + "))"
+		case isFloat(to):
+			return "((" + g.cType(to) + ")(" + code + "))"
+		case isFloat(from) && types.IsUnsigned(to.Name):
+			return "((" + g.cType(to) + ")fr_ftou(" + code + "))"
+		case isFloat(from):
+			return "((" + g.cType(to) + ")fr_ftoi(" + code + "))"
+		}
+		return "((" + g.cType(to) + ")(" + code + "))"
+	case *semantic.ArrayType:
+		if from, ok := from.(*semantic.ArrayType); ok {
+			return g.arrayConversion(from, to) + "(" + code + ")"
+		}
+	case *semantic.StructType:
+		if from, ok := from.(*semantic.StructType); ok {
+			return g.structConversion(from, to) + "(" + code + ")"
+		}
+	case *semantic.FunctionType:
+		if from, ok := from.(*semantic.FunctionType); ok {
+			return g.adapter(from, to) + "(" + code + ")"
+		}
+	case *semantic.InterfaceType:
+		if from, ok := from.(*semantic.InterfaceType); ok {
+			return g.cast(from, to) + "(" + code + ")"
+		}
+		return g.boxing(from, to) + "(" + code + ")"
+	}
+	return code
+}
+
+// arrayConversion returns the helper copying an array into a new one of another element type
+func (g *generator) arrayConversion(from, to *semantic.ArrayType) string {
+	return g.helper("conv "+typeKey(from)+" "+typeKey(to), "fr_conv", func(name string) *helper {
+		proto := fmt.Sprintf("static fr_array *%s(fr_array *a)", name)
+		elemFrom, elemTo := g.cType(from.ElementType), g.cType(to.ElementType)
+		var b strings.Builder
+		b.WriteString(proto + " {\n")
+		b.WriteString("    fr_array *r;\n    int64_t i;\n")
+		b.WriteString("    if (a == NULL) return NULL;\n")
+		fmt.Fprintf(&b, "    r = fr_array_new(a->len, sizeof(%s));\n", elemTo)
+		b.WriteString("    for (i = 0; i < a->len; i++) {\n")
+		fmt.Fprintf(&b, "        ((%s *)r->data)[i] = %s;\n", elemTo,
+			g.convert(fmt.Sprintf("((%s *)a->data)[i]", elemFrom), from.ElementType, to.ElementType))
+		b.WriteString("    }\n    return r;\n}\n")
+		return &helper{proto: proto, body: b.String()}
+	})
+}
+
+// structConversion returns the helper copying a struct into one of another struct type, a field the
+// value does not have is zero
+func (g *generator) structConversion(from, to *semantic.StructType) string {
+	return g.helper("conv "+typeKey(from)+" "+typeKey(to), "fr_conv", func(name string) *helper {
+		proto := fmt.Sprintf("static %s %s(%s s)", g.cType(to), name, g.cType(from))
+		var b strings.Builder
+		b.WriteString(proto + " {\n")
+		fmt.Fprintf(&b, "    %s r;\n", g.cType(to))
+		b.WriteString("    memset(&r, 0, sizeof r);\n")
+		for _, fieldName := range sortedFields(to) {
+			fieldFrom, found := from.Fields[fieldName]
+			if !found {
+				continue
+			}
+			fmt.Fprintf(&b, "    r.%s = %s;\n", g.field(to, fieldName),
+				g.convert("s."+g.field(from, fieldName), fieldFrom, to.Fields[fieldName]))
+		}
+		b.WriteString("    return r;\n}\n")
+		return &helper{proto: proto, body: b.String()}
+	})
+}
+
+// adapter returns the helper wrapping a function in one of another signature, which converts the
+// arguments to the parameter types of the function and its results to the result types of the signature
+func (g *generator) adapter(from, to *semantic.FunctionType) string {
+	return g.helper("adapt "+typeKey(from)+" "+typeKey(to), "fr_adapt", func(name string) *helper {
+		var b strings.Builder
+		fmt.Fprintf(&b, "struct %s_env {\n    fr_closure fr_base;\n    fr_fn inner;\n};\n\n", name)
+
+		params := []string{"fr_fn fr_self"}
+		args := []string{"inner"}
+		for i, param := range to.Parameters {
+			arg := "a" + strconv.Itoa(i)
+			params = append(params, g.decl(param, arg))
+			args = append(args, g.convert(arg, param, from.Parameters[i]))
+		}
+		toResult, fromResult := results(to), results(from)
+		fmt.Fprintf(&b, "static %s %s_code(%s) {\n", g.cType(toResult), name, strings.Join(params, ", "))
+		fmt.Fprintf(&b, "    fr_fn inner = ((struct %s_env *)fr_self)->inner;\n", name)
+		g.returnCall(&b, fmt.Sprintf("((%s)inner->code)(%s)", g.codePointer(from), strings.Join(args, ", ")), fromResult, toResult)
+		b.WriteString("}\n\n")
+
+		proto := fmt.Sprintf("static fr_fn %s(fr_fn f)", name)
+		b.WriteString(proto + " {\n")
+		fmt.Fprintf(&b, "    struct %s_env *env;\n", name)
+		b.WriteString("    if (f == NULL) return NULL;\n")
+		b.WriteString("    env = fr_alloc(sizeof *env);\n")
+		fmt.Fprintf(&b, "    env->fr_base.code = (void (*)(void))%s_code;\n", name)
+		b.WriteString("    env->inner = f;\n    return &env->fr_base;\n}\n")
+		return &helper{proto: proto, body: b.String()}
+	})
+}
+
+// returnCall writes the statements of a function returning the results of a call converted to its
+// result types
+func (g *generator) returnCall(b *strings.Builder, call string, from, to semantic.Type) {
+	switch fromTuple := from.(type) {
+	case nil:
+		fmt.Fprintf(b, "    %s;\n", call)
+	case *tupleType:
+		toTuple := to.(*tupleType)
+		fmt.Fprintf(b, "    %s = %s;\n", g.decl(fromTuple, "t"), call)
+		fmt.Fprintf(b, "    %s;\n", g.decl(toTuple, "r"))
+		for i := range toTuple.elems {
+			fmt.Fprintf(b, "    r.r%d = %s;\n", i, g.convert("t.r"+strconv.Itoa(i), fromTuple.elems[i], toTuple.elems[i]))
+		}
+		b.WriteString("    return r;\n")
+	default:
+		fmt.Fprintf(b, "    return %s;\n", g.convert(call, from, to))
+	}
+}
+
+// codePointer returns the C type of a pointer to the code of a function of a signature
+func (g *generator) codePointer(t *semantic.FunctionType) string {
+	params := []string{"fr_fn"}
+	for _, param := range t.Parameters {
+		params = append(params, g.cType(param))
+	}
+	return g.cType(results(t)) + " (*)(" + strings.Join(params, ", ") + ")"
+}
+
+// caller returns the helper calling a function value of a signature, which fails when it is nil
+func (g *generator) caller(t *semantic.FunctionType) string {
+	return g.helper("call "+typeKey(t), "fr_call", func(name string) *helper {
+		params := []string{"fr_fn f"}
+		args := []string{"f"}
+		for i, param := range t.Parameters {
+			arg := "a" + strconv.Itoa(i)
+			params = append(params, g.decl(param, arg))
+			args = append(args, arg)
+		}
+		result := results(t)
+		proto := fmt.Sprintf("static %s %s(%s)", g.cType(result), name, strings.Join(params, ", "))
+		call := fmt.Sprintf("((%s)f->code)(%s)", g.codePointer(t), strings.Join(args, ", "))
+		if result != nil {
+			call = "return " + call
+		}
+		body := fmt.Sprintf("%s {\n    if (f == NULL) fr_fail(-1, \"cannot call <none>\");\n    %s;\n}\n", proto, call)
+		return &helper{proto: proto, body: body}
+	})
+}
+
+// equal returns a C expression comparing two values of a resolved type as == does
+func (g *generator) equal(a, b string, t semantic.Type) string {
+	switch t := t.(type) {
+	case *semantic.PrimitiveType:
+		if t.Name == types.STRING {
+			return "fr_str_eq(" + a + ", " + b + ")"
+		}
+	case *semantic.ArrayType:
+		return g.arrayEquality(t) + "(" + a + ", " + b + ")"
+	case *semantic.StructType:
+		return g.structEquality(t) + "(" + a + ", " + b + ")"
+	case *semantic.InterfaceType:
+		return "fr_iface_eq(" + a + ", " + b + ")"
+	}
+	return "(" + a + " == " + b + ")"
+}
+
+// arrayEquality returns the helper comparing two arrays element by element
+func (g *generator) arrayEquality(t *semantic.ArrayType) string {
+	return g.helper("eq "+typeKey(t), "fr_eq", func(name string) *helper {
+		proto := fmt.Sprintf("static bool %s(fr_array *a, fr_array *b)", name)
+		elem := g.cType(t.ElementType)
+		var b strings.Builder
+		b.WriteString(proto + " {\n    int64_t i;\n")
+		b.WriteString("    if (fr_len(a) != fr_len(b)) return false;\n")
+		b.WriteString("    for (i = 0; i < fr_len(a); i++) {\n")
+		fmt.Fprintf(&b, "        if (!%s) return false;\n",
+			g.equal(fmt.Sprintf("((%s *)a->data)[i]", elem), fmt.Sprintf("((%s *)b->data)[i]", elem), t.ElementType))
+		b.WriteString("    }\n    return true;\n}\n")
+		return &helper{proto: proto, body: b.String()}
+	})
+}
+
+// structEquality returns the helper comparing two structs field by field
+func (g *generator) structEquality(t *semantic.StructType) string {
+	return g.helper("eq "+typeKey(t), "fr_eq", func(name string) *helper {
+		proto := fmt.Sprintf("static bool %s(%s a, %s b)", name, g.cType(t), g.cType(t))
+		comparisons := []string{"true"}
+		for _, fieldName := range sortedFields(t) {
+			field := g.field(t, fieldName)
+			comparisons = append(comparisons, g.equal("a."+field, "b."+field, t.Fields[fieldName]))
+		}
+		if len(comparisons) > 1 {
+			comparisons = comparisons[1:]
+		}
+		body := fmt.Sprintf("%s {\n    return %s;\n}\n", proto, strings.Join(comparisons, " && "))
+		return &helper{proto: proto, body: body}
+	})
+}
+
+// typeDecl finds the declaration of a named type in the module declaring it
+func (g *generator) typeDecl(importPath string, name types.TYPE_NAME) (*module, *ast.TypeDeclStmt) {
+	m := g.modules[importPath]
+	if m == nil {
+		return nil, nil
+	}
+	for _, node := range topLevel(m.info) {
+		if decl, ok := node.(*ast.TypeDeclStmt); ok && decl.Alias != nil && decl.Alias.Name == string(name) {
+			return m, decl
+		}
+	}
+	return m, nil
+}
+
+// inDeclaration calls resolve with the type a named type is declared as, in the file declaring it. The
+// semantic type only knows a type of another module by its name, the declaration writes its module.
+// It reports whether resolve found the type it expected.
+func (g *generator) inDeclaration(importPath string, name types.TYPE_NAME, resolve func(base ast.DataType) bool) bool {
+	m, decl := g.typeDecl(importPath, name)
+	if decl == nil {
+		return false
+	}
+	module, file, current := g.module, g.file, g.current
+	defer func() { g.module, g.file, g.current = module, file, current }()
+	g.enter(m, decl)
+	return resolve(decl.BaseType)
+}
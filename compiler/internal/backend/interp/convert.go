@@ -0,0 +1,105 @@
+package interp
+
+import (
+	"compiler/internal/semantic"
+	"compiler/internal/types"
+)
+
+// isNumericKind reports whether a type name is an integer, byte or float type
+func isNumericKind(kind types.TYPE_NAME) bool {
+	return types.GetNumberBitSize(kind) > 0
+}
+
+// convert gives a value the type it is stored as, the implicit conversions the type checker allows:
+// numbers widen to the kind of the target, and arrays and structs take the element and field types
+// of the target. t is resolved in m.
+func (in *Interpreter) convert(v Value, t semantic.Type, m *module) Value {
+	if v == nil || t == nil {
+		return v
+	}
+	switch t := in.resolveType(t, m).(type) {
+	case *semantic.PrimitiveType:
+		if IsNumeric(v) && isNumericKind(t.Name) {
+			return ToKind(v, t.Name)
+		}
+	case *semantic.ArrayType:
+		array, ok := v.(*Array)
+		if !ok || t.ElementType == nil {
+			return v
+		}
+		elem := in.resolveType(t.ElementType, m)
+		if array.Elem != nil && array.Elem.Equals(elem) {
+			return array
+		}
+		// An array of another element type is a new array, its elements converted
+		converted := &Array{Elems: make([]Value, len(array.Elems)), Elem: elem}
+		for i, e := range array.Elems {
+			converted.Elems[i] = in.store(e, elem, m)
+		}
+		return converted
+	case *semantic.StructType:
+		s, ok := v.(*Struct)
+		if !ok || s.Def == t {
+			return v
+		}
+		// A struct of another type with the same fields, an anonymous one for one, becomes a value of t
+		return in.newStruct(t, s.Fields)
+	}
+	return v
+}
+
+// newStruct creates a value of a struct type from the values of its fields, a missing field is zero
+func (in *Interpreter) newStruct(t *semantic.StructType, fields map[string]Value) *Struct {
+	owner := in.moduleOf(t)
+	s := &Struct{Def: t, Fields: make(map[string]Value, len(t.Fields))}
+	for name, fieldType := range t.Fields {
+		if field, found := fields[name]; found {
+			s.Fields[name] = in.store(field, fieldType, owner)
+		} else {
+			s.Fields[name] = in.zero(fieldType, owner)
+		}
+	}
+	return s
+}
+
+// store converts a value to the type of the variable, field or element it is stored into, and copies it
+func (in *Interpreter) store(v Value, t semantic.Type, m *module) Value {
+	return CopyValue(in.convert(v, t, m))
+}
+
+// zero is the value of a variable declared without an initializer
+func (in *Interpreter) zero(t semantic.Type, m *module) Value {
+	switch t := in.resolveType(t, m).(type) {
+	case *semantic.PrimitiveType:
+		switch {
+		case t.Name == types.FLOAT32 || t.Name == types.FLOAT64:
+			return NewFloat(0, t.Name)
+		case isNumericKind(t.Name):
+			return NewInt(0, t.Name)
+		case t.Name == types.STRING:
+			return Str("")
+		case t.Name == types.BOOL:
+			return Bool(false)
+		}
+	case *semantic.ArrayType:
+		return &Array{Elem: in.resolveType(t.ElementType, m)}
+	case *semantic.StructType:
+		return in.newStruct(t, nil)
+	}
+	return nil
+}
+
+// TypeOfValue is the type a variable declared without one takes from its initializer
+func TypeOfValue(v Value) semantic.Type {
+	switch v := v.(type) {
+	case Int, Float, Str, Bool:
+		return v.Type()
+	case *Array:
+		if v.Elem != nil {
+			return v.Type()
+		}
+	case *Struct:
+		return v.Def
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package interp
+
+import (
+	"fmt"
+	"strings"
+
+	"compiler/internal/source"
+)
+
+// Frame is one call on the stack of a runtime error, where the function was when the error happened
+type Frame struct {
+	Function string // the function name, "<import path>" for the top-level code of a module
+	File     string // the full path of the file the function is declared in
+	Location source.Location
+}
+
+func (f Frame) String() string {
+	return fmt.Sprintf("%s (%s:%d:%d)", f.Function, f.File, f.Location.Start.Line, f.Location.Start.Column)
+}
+
+// RuntimeError stops a program, with the stack of calls it happened in, innermost first
+type RuntimeError struct {
+	Message string
+	Trace   []Frame
+}
+
+// traceGroups is how many runs of identical frames a trace prints: the innermost traceHead and the
+// outermost traceGroups-traceHead, the calls between are counted instead
+const (
+	traceGroups = 50
+	traceHead   = 40
+)
+
+// Error prints the trace with a run of identical frames, deep recursion, as one frame and a count
+func (e *RuntimeError) Error() string {
+	type run struct {
+		frame string
+		count int
+	}
+	var runs []run
+	for _, frame := range e.Trace {
+		if text := frame.String(); len(runs) > 0 && runs[len(runs)-1].frame == text {
+			runs[len(runs)-1].count++
+		} else {
+			runs = append(runs, run{frame: text, count: 1})
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("runtime error: " + e.Message)
+	for i := 0; i < len(runs); i++ {
+		if len(runs) > traceGroups && i == traceHead {
+			hidden := 0
+			for _, r := range runs[traceHead : len(runs)-(traceGroups-traceHead)] {
+				hidden += r.count
+			}
+			fmt.Fprintf(&b, "\n    ... %d more calls", hidden)
+			i = len(runs) - (traceGroups - traceHead) - 1
+			continue
+		}
+		b.WriteString("\n    at " + runs[i].frame)
+		if runs[i].count > 1 {
+			fmt.Fprintf(&b, "\n    ... the call above repeats %d more times", runs[i].count-1)
+		}
+	}
+	return b.String()
+}
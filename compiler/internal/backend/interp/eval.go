@@ -0,0 +1,239 @@
+package interp
+
+import (
+	"math"
+
+	"compiler/internal/frontend/ast"
+	"compiler/internal/semantic"
+	"compiler/internal/types"
+)
+
+// eval evaluates an expression in a scope of the current frame
+func (in *Interpreter) eval(expr ast.Expression, scope *env) Value {
+	switch e := expr.(type) {
+	case *ast.IntLiteral:
+		// Literals are i32, as the type checker infers them, unless the value needs i64
+		if e.Value < math.MinInt32 || e.Value > math.MaxInt32 {
+			return NewInt(e.Value, types.INT64)
+		}
+		return NewInt(e.Value, types.INT32)
+	case *ast.FloatLiteral:
+		return NewFloat(e.Value, types.FLOAT64)
+	case *ast.StringLiteral:
+		return Str(e.Value)
+	case *ast.BoolLiteral:
+		return Bool(e.Value)
+	case *ast.ByteLiteral:
+		return NewInt(int64(e.Value[0]), types.BYTE)
+	case *ast.IdentifierExpr:
+		b, found := in.lookup(e.Name, scope, in.top().module)
+		if !found {
+			in.fail(e, "'%s' is not defined", e.Name)
+		}
+		return b.value
+	case *ast.VarScopeResolution:
+		return in.scopedBinding(e).value
+	case *ast.BinaryExpr:
+		return in.evalBinary(e, scope)
+	case *ast.UnaryExpr:
+		operand := in.eval(*e.Operand, scope)
+		if e.Operator.Value == "!" {
+			return !in.boolean(*e.Operand, operand)
+		}
+		return Negate(operand)
+	case *ast.PrefixExpr:
+		_, updated := in.increment(*e.Operand, e.Operator.Value, scope)
+		return updated
+	case *ast.PostfixExpr:
+		old, _ := in.increment(*e.Operand, e.Operator.Value, scope)
+		return old
+	case *ast.FunctionCallExpr:
+		return in.evalCall(e, scope)
+	case *ast.FieldAccessExpr:
+		return in.evalFieldAccess(e, scope)
+	case *ast.IndexableExpr:
+		array, index := in.element(e, scope)
+		return array.Elems[index]
+	case *ast.ArrayLiteralExpr:
+		return in.evalArrayLiteral(e, scope)
+	case *ast.StructLiteralExpr:
+		return in.evalStructLiteral(e, scope)
+	case *ast.FunctionLiteral:
+		f := in.top()
+		return &Function{Name: "fn", Literal: e, Env: scope, Module: f.module, File: f.file}
+	}
+	in.fail(expr, "cannot evaluate %T", expr)
+	return nil
+}
+
+// boolean returns the value of a condition
+func (in *Interpreter) boolean(expr ast.Expression, v Value) Bool {
+	b, ok := v.(Bool)
+	if !ok {
+		in.fail(expr, "expected a bool, got %s", Display(v))
+	}
+	return b
+}
+
+func (in *Interpreter) evalBinary(e *ast.BinaryExpr, scope *env) Value {
+	op := e.Operator.Value
+	left := in.eval(*e.Left, scope)
+	switch op {
+	case "&&":
+		if !in.boolean(*e.Left, left) {
+			return Bool(false)
+		}
+		return in.boolean(*e.Right, in.eval(*e.Right, scope))
+	case "||":
+		if in.boolean(*e.Left, left) {
+			return Bool(true)
+		}
+		return in.boolean(*e.Right, in.eval(*e.Right, scope))
+	}
+	right := in.eval(*e.Right, scope)
+
+	// Numbers of different types are converted to their common type first, as i32 + i64 is an i64
+	if IsNumeric(left) && IsNumeric(right) {
+		kind := CommonKind(left, right)
+		left, right = ToKind(left, kind), ToKind(right, kind)
+	}
+
+	switch op {
+	case "==":
+		return Bool(Equal(left, right))
+	case "!=":
+		return Bool(!Equal(left, right))
+	case "<", "<=", ">", ">=":
+		if !IsNumeric(left) && !isString(left) || left.Type().TypeName() != right.Type().TypeName() {
+			in.fail(e, "cannot compare %s and %s", Display(left), Display(right))
+		}
+		c := Compare(left, right)
+		return Bool(op == "<" && c < 0 || op == "<=" && c <= 0 || op == ">" && c > 0 || op == ">=" && c >= 0)
+	case "+":
+		if l, ok := left.(Str); ok {
+			if r, ok := right.(Str); ok {
+				return l + r
+			}
+		}
+	}
+	if !IsNumeric(left) || !IsNumeric(right) {
+		in.fail(e, "invalid operands for %s: %s and %s", op, Display(left), Display(right))
+	}
+	result, err := Arithmetic(op, left, right)
+	if err != nil {
+		in.fail(e, "%s", err)
+	}
+	return result
+}
+
+func isString(v Value) bool {
+	_, ok := v.(Str)
+	return ok
+}
+
+// increment adds or subtracts one from a variable, field or element for ++ and --, and returns
+// its value before and after
+func (in *Interpreter) increment(target ast.Expression, op string, scope *env) (Value, Value) {
+	old := in.eval(target, scope)
+	if !IsNumeric(old) {
+		in.fail(target, "cannot apply %s to %s", op, Display(old))
+	}
+	one := ToKind(NewInt(1, types.INT32), old.Type().TypeName())
+	updated, _ := Arithmetic(op[:1], old, one)
+	in.assign(target, updated, scope)
+	return old, updated
+}
+
+// scopedBinding returns the binding of module::name
+func (in *Interpreter) scopedBinding(e *ast.VarScopeResolution) *binding {
+	target := in.moduleNamed(e.Module.Name)
+	if target == nil {
+		in.fail(e.Module, "module '%s' is not imported", e.Module.Name)
+	}
+	b, found := in.lookupGlobal(target, e.Var.Name)
+	if !found {
+		in.fail(e.Var, "'%s' is not defined in module '%s'", e.Var.Name, e.Module.Name)
+	}
+	return b
+}
+
+func (in *Interpreter) evalFieldAccess(e *ast.FieldAccessExpr, scope *env) Value {
+	object := in.eval(*e.Object, scope)
+	s, ok := object.(*Struct)
+	if !ok {
+		in.fail(e.Field, "cannot read '%s' of %s", e.Field.Name, Display(object))
+	}
+	if field, found := s.Fields[e.Field.Name]; found {
+		return field
+	}
+	method := in.methods[s.Def][e.Field.Name]
+	if method == nil {
+		in.fail(e.Field, "%s has no field or method '%s'", s.Def.Name, e.Field.Name)
+	}
+	bound := *method
+	bound.Receiver = s
+	if !method.isRRef {
+		bound.Receiver = CopyValue(s)
+	}
+	return &bound
+}
+
+// element returns the array and the checked index of an index expression
+func (in *Interpreter) element(e *ast.IndexableExpr, scope *env) (*Array, int) {
+	indexable := in.eval(*e.Indexable, scope)
+	array, ok := indexable.(*Array)
+	if !ok {
+		in.fail(*e.Indexable, "cannot index %s", Display(indexable))
+	}
+	index, ok := in.eval(*e.Index, scope).(Int)
+	if !ok {
+		in.fail(*e.Index, "an array index must be an integer")
+	}
+	// A u64 above the int64 range reads as negative, and is out of range too
+	if index.V < 0 || index.V >= int64(len(array.Elems)) {
+		in.fail(e, "index %s out of range for an array of length %d", index, len(array.Elems))
+	}
+	return array, int(index.V)
+}
+
+func (in *Interpreter) evalArrayLiteral(e *ast.ArrayLiteralExpr, scope *env) Value {
+	array := &Array{Elems: make([]Value, len(e.Elements))}
+	for i, elem := range e.Elements {
+		array.Elems[i] = in.eval(elem, scope)
+		// The element type is the common type of the elements, [1, 2.5] is an array of f64
+		t := TypeOfValue(array.Elems[i])
+		if array.Elem == nil {
+			array.Elem = t
+		} else if t != nil {
+			if common := semantic.GetCommonType(array.Elem, t); common != nil {
+				array.Elem = common
+			}
+		}
+	}
+	for i, elem := range array.Elems {
+		array.Elems[i] = in.store(elem, array.Elem, in.top().module)
+	}
+	return array
+}
+
+func (in *Interpreter) evalStructLiteral(e *ast.StructLiteralExpr, scope *env) Value {
+	fields := make(map[string]Value, len(e.Fields))
+	for _, field := range e.Fields {
+		if field.FieldValue != nil {
+			fields[field.FieldIdentifier.Name] = in.eval(*field.FieldValue, scope)
+		}
+	}
+	if !e.IsAnonymous {
+		structType, ok := in.resolveType(&semantic.UserType{Name: types.TYPE_NAME(e.StructName.Name)}, in.top().module).(*semantic.StructType)
+		if !ok {
+			in.fail(e.StructName, "'%s' is not a struct type", e.StructName.Name)
+		}
+		return in.newStruct(structType, fields)
+	}
+	def := &semantic.StructType{Name: types.STRUCT, Fields: make(map[string]semantic.Type, len(fields))}
+	for name, field := range fields {
+		def.Fields[name] = TypeOfValue(field)
+		fields[name] = CopyValue(field)
+	}
+	return &Struct{Def: def, Fields: fields}
+}
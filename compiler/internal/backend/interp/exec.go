@@ -0,0 +1,241 @@
+package interp
+
+import (
+	"compiler/internal/frontend/ast"
+	"compiler/internal/semantic"
+)
+
+// control is how a statement ends, the loop or call around it handles anything but next
+type control int
+
+const (
+	next control = iota
+	breaking
+	continuing
+	returning
+)
+
+// exec executes a statement in a scope of the current frame
+func (in *Interpreter) exec(node ast.Node, scope *env) control {
+	switch n := node.(type) {
+	case *ast.VarDeclStmt:
+		in.execVarDecl(n, scope)
+	case *ast.AssignmentStmt:
+		values := in.evalList(*n.Right, len(*n.Left), scope)
+		for i, target := range *n.Left {
+			in.assign(target, values[i], scope)
+		}
+	case *ast.ExpressionStmt:
+		for _, expr := range *n.Expressions {
+			in.eval(expr, scope)
+		}
+	case *ast.FunctionDecl:
+		// Bound before the function is created, so it can call itself
+		b := &binding{}
+		scope.vars[n.Identifier.Name] = b
+		b.value = in.function(n, scope)
+	case *ast.Block:
+		return in.execBlock(n, newEnv(scope))
+	case *ast.IfStmt:
+		if in.boolean(*n.Condition, in.eval(*n.Condition, scope)) {
+			return in.execBlock(n.Body, newEnv(scope))
+		}
+		if n.Alternative != nil {
+			return in.exec(n.Alternative, scope)
+		}
+	case *ast.WhileStmt:
+		for in.boolean(*n.Condition, in.eval(*n.Condition, scope)) {
+			if ctl := in.execBlock(n.Body, newEnv(scope)); ctl == breaking {
+				break
+			} else if ctl == returning {
+				return ctl
+			}
+		}
+	case *ast.ForStmt:
+		return in.execFor(n, scope)
+	case *ast.BreakStmt:
+		return breaking
+	case *ast.ContinueStmt:
+		return continuing
+	case *ast.ReturnStmt:
+		in.top().result = nil
+		if n.Values != nil {
+			in.top().result = in.evalList(*n.Values, 0, scope)
+		}
+		return returning
+	case *ast.ImportStmt, *ast.TypeDeclStmt, *ast.ModuleDeclStmt, *ast.MethodDecl:
+		// Declarations the resolver and declareFunctions handled
+	default:
+		in.fail(node, "cannot execute %T", node)
+	}
+	return next
+}
+
+// execBlock runs the statements of a block until one of them breaks, continues or returns
+func (in *Interpreter) execBlock(block *ast.Block, scope *env) control {
+	for _, node := range block.Nodes {
+		if ctl := in.exec(node, scope); ctl != next {
+			return ctl
+		}
+	}
+	return next
+}
+
+func (in *Interpreter) execFor(n *ast.ForStmt, scope *env) control {
+	// The variables of the init statement live as long as the loop
+	loop := newEnv(scope)
+	if n.Init != nil {
+		in.exec(n.Init, loop)
+	}
+	for n.Condition == nil || in.boolean(*n.Condition, in.eval(*n.Condition, loop)) {
+		ctl := in.execBlock(n.Body, newEnv(loop))
+		if ctl == breaking {
+			break
+		}
+		if ctl == returning {
+			return ctl
+		}
+		if n.Post != nil {
+			in.exec(n.Post, loop)
+		}
+	}
+	return next
+}
+
+func (in *Interpreter) execVarDecl(n *ast.VarDeclStmt, scope *env) {
+	var values []Value
+	if len(n.Initializers) > 0 {
+		values = in.evalList(n.Initializers, len(n.Variables), scope)
+	}
+	for i, v := range n.Variables {
+		t := in.typeOf(v.ExplicitType)
+		var value Value
+		if i < len(values) {
+			value = values[i]
+			if t == nil {
+				t = TypeOfValue(value)
+			}
+		} else {
+			value = in.zero(t, in.top().module)
+		}
+		scope.vars[v.Identifier.Name] = &binding{value: in.store(value, t, in.top().module), typ: t}
+	}
+}
+
+// evalList evaluates the expressions of a declaration, an assignment or a return. A single call
+// returning several values spreads them over the want targets.
+func (in *Interpreter) evalList(exprs []ast.Expression, want int, scope *env) []Value {
+	if len(exprs) == 0 {
+		return nil
+	}
+	values := make([]Value, len(exprs))
+	for i, expr := range exprs {
+		values[i] = in.eval(expr, scope)
+	}
+	if tuple, ok := values[0].(Tuple); ok && len(values) == 1 && want > 1 {
+		return tuple
+	}
+	if len(values) < want {
+		in.fail(exprs[0], "expected %d values, got %d", want, len(values))
+	}
+	return values
+}
+
+// assign stores a value into a variable, a field or an array element
+func (in *Interpreter) assign(target ast.Expression, v Value, scope *env) {
+	m := in.top().module
+	switch t := target.(type) {
+	case *ast.IdentifierExpr:
+		b, found := in.lookup(t.Name, scope, m)
+		if !found {
+			in.fail(t, "'%s' is not defined", t.Name)
+		}
+		b.value = in.store(v, b.typ, m)
+	case *ast.VarScopeResolution:
+		b := in.scopedBinding(t)
+		b.value = in.store(v, b.typ, m)
+	case *ast.FieldAccessExpr:
+		object := in.eval(*t.Object, scope)
+		s, ok := object.(*Struct)
+		if !ok {
+			in.fail(t.Field, "cannot set '%s' of %s", t.Field.Name, Display(object))
+		}
+		var fieldType semantic.Type
+		if s.Def.Fields != nil {
+			fieldType = s.Def.Fields[t.Field.Name]
+		}
+		s.Fields[t.Field.Name] = in.store(v, fieldType, in.moduleOf(s.Def))
+	case *ast.IndexableExpr:
+		array, index := in.element(t, scope)
+		array.Elems[index] = in.store(v, array.Elem, m)
+	default:
+		in.fail(target, "cannot assign to %T", target)
+	}
+}
+
+func (in *Interpreter) evalCall(e *ast.FunctionCallExpr, scope *env) Value {
+	callee := in.eval(*e.Caller, scope)
+	args := make([]Value, len(e.Arguments))
+	for i, arg := range e.Arguments {
+		args[i] = in.eval(arg, scope)
+	}
+	in.top().loc = e.Loc()
+	return in.call(callee, args, e)
+}
+
+// call calls a function with the values of its arguments, converted to its parameter types.
+// A function returning several values returns them as a Tuple.
+func (in *Interpreter) call(callee Value, args []Value, site ast.Node) Value {
+	switch f := callee.(type) {
+	case *Native:
+		for i := range args {
+			if i < len(f.Params) {
+				args[i] = in.convert(args[i], f.Params[i], in.top().module)
+			}
+		}
+		result, err := f.Fn(&in.System, args)
+		if err != nil {
+			in.fail(site, "%s", err)
+		}
+		return result
+	case *Function:
+		return in.callFunction(f, args)
+	}
+	in.fail(site, "cannot call %s", Display(callee))
+	return nil
+}
+
+func (in *Interpreter) callFunction(f *Function, args []Value) Value {
+	in.push(&frame{function: f.Name, file: f.File, module: f.Module, loc: f.Literal.Loc()})
+	defer in.pop()
+
+	scope := newEnv(f.Env)
+	if f.Receiver != nil {
+		scope.vars[f.ReceiverName] = &binding{value: f.Receiver}
+	}
+	for i, param := range f.Literal.Params {
+		t := in.typeOf(param.Type)
+		scope.vars[param.Identifier.Name] = &binding{value: in.store(args[i], t, f.Module), typ: t}
+	}
+	if f.Literal.Body == nil || in.execBlock(f.Literal.Body, scope) != returning {
+		return nil
+	}
+
+	results := in.top().result
+	if len(results) == 0 {
+		return nil
+	}
+	if tuple, ok := results[0].(Tuple); ok && len(results) == 1 {
+		results = tuple
+	}
+	returnTypes := f.Literal.ReturnType
+	for i := range results {
+		if i < len(returnTypes) {
+			results[i] = in.store(results[i], in.typeOf(returnTypes[i]), f.Module)
+		}
+	}
+	if len(results) == 1 {
+		return results[0]
+	}
+	return Tuple(results)
+}
@@ -0,0 +1,300 @@
+// Package interp is the reference interpreter of Ferret. It walks the type checked AST of every
+// module of a compiler context and evaluates it, with the fixed width integer semantics of the static
+// types, so the other backends have something to be compared against.
+package interp
+
+import (
+	"fmt"
+	"os"
+
+	"compiler/ctx"
+	"compiler/internal/frontend/ast"
+	"compiler/internal/semantic"
+	"compiler/internal/source"
+)
+
+// maxCallDepth is the deepest a program can call before it stops with a stack overflow
+const maxCallDepth = 10000
+
+// binding is a variable, a function or a constant
+type binding struct {
+	value Value
+	typ   semantic.Type // values stored into the binding are converted to it, nil stores them as they are
+}
+
+// env is a scope of bindings, the outermost one of a module holds its globals
+type env struct {
+	vars   map[string]*binding
+	parent *env
+}
+
+func newEnv(parent *env) *env {
+	return &env{vars: make(map[string]*binding), parent: parent}
+}
+
+func (e *env) lookup(name string) (*binding, bool) {
+	for scope := e; scope != nil; scope = scope.parent {
+		if b, found := scope.vars[name]; found {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// module is a module of the program while it runs
+type module struct {
+	importPath string
+	info       *ctx.Module
+	globals    *env
+}
+
+// frame is a call in progress, loc is where it is evaluating
+type frame struct {
+	function string
+	file     *ast.Program
+	module   *module
+	loc      *source.Location
+	result   []Value // the values of the return statement that ended the call
+}
+
+// Interpreter runs the modules of a type checked compiler context
+type Interpreter struct {
+	System
+
+	ctx     *ctx.CompilerContext
+	modules map[string]*module
+	methods map[*semantic.StructType]map[string]*Function
+	frames  []*frame
+}
+
+// New creates an interpreter for a context the type checker found no errors in
+func New(context *ctx.CompilerContext) *Interpreter {
+	return &Interpreter{
+		System:  System{Stdout: os.Stdout, Stdin: os.Stdin},
+		ctx:     context,
+		modules: make(map[string]*module),
+		methods: make(map[*semantic.StructType]map[string]*Function),
+	}
+}
+
+// Run evaluates the top-level code of every module, each after the modules it imports, so the entry
+// file runs last. A runtime error stops the program and is returned as a *RuntimeError.
+func (in *Interpreter) Run() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			runtimeErr, ok := r.(*RuntimeError)
+			if !ok {
+				panic(r)
+			}
+			err = runtimeErr
+		}
+	}()
+
+	order := in.ctx.ModuleOrder()
+	for _, importPath := range order {
+		in.modules[importPath] = &module{importPath: importPath, info: in.ctx.Modules[importPath], globals: newEnv(nil)}
+	}
+	// Functions and methods exist before any code runs, global initializers may call them
+	for _, importPath := range order {
+		in.declareFunctions(in.modules[importPath])
+	}
+	for _, importPath := range order {
+		in.runModule(in.modules[importPath])
+	}
+	return nil
+}
+
+// topLevel returns the top-level nodes of a module in the order the resolver decided they run in
+func topLevel(m *module) []ast.Node {
+	if m.info.Order != nil {
+		return m.info.Order
+	}
+	var nodes []ast.Node
+	for _, file := range m.info.Files {
+		nodes = append(nodes, file.Nodes...)
+	}
+	return nodes
+}
+
+// fileOf returns the file of a module a top-level node is written in
+func fileOf(m *module, node ast.Node) *ast.Program {
+	if file := m.info.FileOf(node); file != nil {
+		return file
+	}
+	return m.info.AST
+}
+
+// declareFunctions binds the functions of a module and registers its methods on their receiver types
+func (in *Interpreter) declareFunctions(m *module) {
+	in.push(&frame{function: "<" + m.importPath + ">", file: m.info.AST, module: m})
+	defer in.pop()
+
+	for _, node := range topLevel(m) {
+		in.top().file = fileOf(m, node)
+		switch n := node.(type) {
+		case *ast.FunctionDecl:
+			m.globals.vars[n.Identifier.Name] = &binding{value: in.function(n, m.globals)}
+		case *ast.MethodDecl:
+			if n.Receiver == nil {
+				continue
+			}
+			structType, ok := in.typeOf(n.Receiver.Type).(*semantic.StructType)
+			if !ok {
+				continue
+			}
+			if in.methods[structType] == nil {
+				in.methods[structType] = make(map[string]*Function)
+			}
+			in.methods[structType][n.Method.Name] = &Function{
+				Name:         string(structType.Name) + "." + n.Method.Name,
+				Literal:      n.Function,
+				Env:          m.globals,
+				Module:       m,
+				File:         in.top().file,
+				ReceiverName: n.Receiver.Identifier.Name,
+				isRRef:       n.IsRRef,
+			}
+		}
+	}
+}
+
+// function creates the value of a function declaration, a standard library function without a body is native
+func (in *Interpreter) function(decl *ast.FunctionDecl, scope *env) Value {
+	f := in.top()
+	if decl.Function.Body == nil {
+		return in.native(f.module, decl)
+	}
+	return &Function{Name: decl.Identifier.Name, Literal: decl.Function, Env: scope, Module: f.module, File: f.file}
+}
+
+// runModule evaluates the global declarations and statements of a module
+func (in *Interpreter) runModule(m *module) {
+	in.push(&frame{function: "<" + m.importPath + ">", file: m.info.AST, module: m})
+	defer in.pop()
+
+	for _, node := range topLevel(m) {
+		switch node.(type) {
+		case *ast.FunctionDecl, *ast.MethodDecl:
+			continue // declared already
+		}
+		in.top().file = fileOf(m, node)
+		in.exec(node, m.globals)
+	}
+}
+
+func (in *Interpreter) push(f *frame) {
+	if len(in.frames) >= maxCallDepth {
+		in.fail(nil, "stack overflow, more than %d nested calls", maxCallDepth)
+	}
+	in.frames = append(in.frames, f)
+}
+
+func (in *Interpreter) pop() {
+	in.frames = in.frames[:len(in.frames)-1]
+}
+
+func (in *Interpreter) top() *frame {
+	return in.frames[len(in.frames)-1]
+}
+
+// fail stops the program with a runtime error at node, which is nil for the current location
+func (in *Interpreter) fail(node ast.Node, format string, args ...any) {
+	if node != nil && len(in.frames) > 0 {
+		in.top().loc = node.Loc()
+	}
+	err := &RuntimeError{Message: fmt.Sprintf(format, args...)}
+	for i := len(in.frames) - 1; i >= 0; i-- {
+		f := in.frames[i]
+		trace := Frame{Function: f.function, File: f.file.FullPath}
+		if f.loc != nil {
+			trace.Location = *f.loc
+		}
+		err.Trace = append(err.Trace, trace)
+	}
+	panic(err)
+}
+
+// lookup finds a name in a scope, then in the names the module imported with an import list
+func (in *Interpreter) lookup(name string, scope *env, m *module) (*binding, bool) {
+	if b, found := scope.lookup(name); found {
+		return b, true
+	}
+	return in.lookupImported(m, name)
+}
+
+// lookupGlobal finds a top-level name of a module, which may be one it re-exports with 'pub import'
+func (in *Interpreter) lookupGlobal(m *module, name string) (*binding, bool) {
+	if b, found := m.globals.vars[name]; found {
+		return b, true
+	}
+	return in.lookupImported(m, name)
+}
+
+func (in *Interpreter) lookupImported(m *module, name string) (*binding, bool) {
+	importStmt := m.info.ImportedFrom[name]
+	if importStmt == nil {
+		return nil, false
+	}
+	target := in.modules[importStmt.ModulePath]
+	if target == nil {
+		return nil, false
+	}
+	original := name
+	for _, imported := range importStmt.Names {
+		if imported.LocalName().Name == name {
+			original = imported.Name.Name
+		}
+	}
+	return in.lookupGlobal(target, original)
+}
+
+// moduleNamed returns the module an alias of the current file refers to, as in alias::name
+func (in *Interpreter) moduleNamed(alias string) *module {
+	importPath, found := in.top().file.ModulenameToImportpath[alias]
+	if !found {
+		return nil
+	}
+	return in.modules[importPath]
+}
+
+// typeOf converts a type written in the current file to the type it is declared as
+func (in *Interpreter) typeOf(dataType ast.DataType) semantic.Type {
+	if dataType == nil {
+		return nil
+	}
+	if scoped, ok := dataType.(*ast.TypeScopeResolution); ok {
+		if target := in.moduleNamed(scoped.Module.Name); target != nil {
+			return in.resolveType(&semantic.UserType{Name: scoped.Type()}, target)
+		}
+	}
+	return in.resolveType(semantic.ASTToSemanticType(dataType), in.top().module)
+}
+
+// resolveType follows a type name to the type it is declared as, in the module the name is written in
+func (in *Interpreter) resolveType(t semantic.Type, m *module) semantic.Type {
+	// An alias cycle is reported by the type checker, the bound only keeps a bug from hanging the program
+	for range 64 {
+		user, ok := t.(*semantic.UserType)
+		if !ok {
+			return t
+		}
+		if user.Definition != nil {
+			t = user.Definition
+			continue
+		}
+		sym, found := m.info.SymbolTable.Lookup(string(user.Name))
+		if !found || sym.Kind != semantic.SymbolType {
+			return t
+		}
+		t = sym.Type
+	}
+	return t
+}
+
+// moduleOf returns the module a struct type is declared in, types are resolved there
+func (in *Interpreter) moduleOf(structType *semantic.StructType) *module {
+	if m := in.modules[structType.Module]; m != nil {
+		return m
+	}
+	return in.top().module
+}
@@ -0,0 +1,89 @@
+package interp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"compiler/internal/semantic/resolver"
+	"compiler/internal/semantic/typecheck"
+	"compiler/internal/source"
+	"compiler/internal/testutil/fixture"
+)
+
+// runProgram compiles a program of the shared table and interprets it. It returns what the program
+// printed and its runtime error.
+func runProgram(t *testing.T, program fixture.Program) (string, error) {
+	t.Helper()
+	context := fixture.Check(t, program.Files, resolver.ResolveModules, typecheck.CheckModules)
+
+	var out bytes.Buffer
+	in := New(context)
+	in.Stdout = &out
+	in.Stdin = strings.NewReader(program.Stdin)
+	err := in.Run()
+	return out.String(), err
+}
+
+func TestPrograms(t *testing.T) {
+	for _, program := range fixture.Programs {
+		t.Run(program.Name, func(t *testing.T) {
+			out, err := runProgram(t, program)
+			if out != program.Stdout {
+				t.Errorf("Run() printed %q, want %q", out, program.Stdout)
+			}
+			if program.Err == "" {
+				if err != nil {
+					t.Fatalf("Run() error = %v", err)
+				}
+				return
+			}
+
+			var runtimeErr *RuntimeError
+			if !errors.As(err, &runtimeErr) {
+				t.Fatalf("Run() error = %v, want a runtime error", err)
+			}
+			if !strings.Contains(runtimeErr.Message, program.Err) {
+				t.Errorf("Message = %q, want %q", runtimeErr.Message, program.Err)
+			}
+			if program.Trace == nil {
+				return
+			}
+			var trace []string
+			for _, frame := range runtimeErr.Trace {
+				if !strings.HasSuffix(frame.File, "/app/main.fer") {
+					t.Errorf("frame %s is in %s, want main.fer", frame.Function, frame.File)
+				}
+				trace = append(trace, fmt.Sprintf("%s %d:%d", frame.Function, frame.Location.Start.Line, frame.Location.Start.Column))
+			}
+			if strings.Join(trace, ", ") != strings.Join(program.Trace, ", ") {
+				t.Errorf("Trace = %v, want %v", trace, program.Trace)
+			}
+		})
+	}
+}
+
+func TestRuntimeErrorTrace(t *testing.T) {
+	frame := func(function string, line int) Frame {
+		pos := source.Position{Line: line, Column: 5}
+		return Frame{Function: function, File: "main.fer", Location: source.Location{Start: &pos, End: &pos}}
+	}
+
+	recursion := &RuntimeError{Message: "stack overflow", Trace: []Frame{frame("rec", 2), frame("rec", 2), frame("rec", 2), frame("<app/main>", 4)}}
+	want := "runtime error: stack overflow\n    at rec (main.fer:2:5)\n    ... the call above repeats 2 more times\n    at <app/main> (main.fer:4:5)"
+	if got := recursion.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	// Mutual recursion has no identical neighbours, the middle of the trace is counted instead
+	mutual := &RuntimeError{Message: "stack overflow"}
+	for i := 0; i < 100; i++ {
+		mutual.Trace = append(mutual.Trace, frame("even", 2), frame("odd", 7))
+	}
+	lines := strings.Split(mutual.Error(), "\n")
+	if len(lines) != 1+traceGroups+1 || lines[1+traceHead] != "    ... 150 more calls" || lines[len(lines)-1] != "    at odd (main.fer:7:5)" {
+		t.Errorf("Error() = %q, want %d frames around the count of the others", lines, traceGroups)
+	}
+}
@@ -0,0 +1,134 @@
+package interp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"compiler/internal/frontend/ast"
+	"compiler/internal/types"
+	"compiler/std"
+)
+
+// System is what the standard library reads from and writes to
+type System struct {
+	Stdout io.Writer
+	Stdin  io.Reader
+	stdin  *bufio.Reader
+}
+
+// NativeFunc implements a standard library function declared without a body
+type NativeFunc func(sys *System, args []Value) (Value, error)
+
+// natives implement the standard library functions declared without a body, by module and name
+var natives = map[string]map[string]NativeFunc{
+	std.Root + "/fmt": {
+		"print": func(sys *System, args []Value) (Value, error) {
+			_, err := io.WriteString(sys.Stdout, str(args[0]))
+			return nil, err
+		},
+		"println": func(sys *System, args []Value) (Value, error) {
+			_, err := io.WriteString(sys.Stdout, str(args[0])+"\n")
+			return nil, err
+		},
+		"formatInt": func(sys *System, args []Value) (Value, error) {
+			return Str(strconv.FormatInt(args[0].(Int).V, 10)), nil
+		},
+		"formatFloat": func(sys *System, args []Value) (Value, error) {
+			return Str(strconv.FormatFloat(args[0].(Float).V, 'f', -1, 64)), nil
+		},
+		"formatBool": func(sys *System, args []Value) (Value, error) {
+			return Str(strconv.FormatBool(bool(args[0].(Bool)))), nil
+		},
+	},
+	std.Root + "/io": {
+		"readLine": func(sys *System, args []Value) (Value, error) {
+			if sys.stdin == nil {
+				sys.stdin = bufio.NewReader(sys.Stdin)
+			}
+			line, err := sys.stdin.ReadString('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			return Str(strings.TrimRight(line, "\r\n")), nil
+		},
+		"readFile": func(sys *System, args []Value) (Value, error) {
+			data, err := os.ReadFile(str(args[0]))
+			return Str(data), err
+		},
+		"writeFile": func(sys *System, args []Value) (Value, error) {
+			return nil, os.WriteFile(str(args[0]), []byte(str(args[1])), 0644)
+		},
+	},
+	std.Root + "/math": {
+		"sqrt":  float1(math.Sqrt),
+		"floor": float1(math.Floor),
+		"ceil":  float1(math.Ceil),
+		"pow": func(sys *System, args []Value) (Value, error) {
+			return NewFloat(math.Pow(args[0].(Float).V, args[1].(Float).V), types.FLOAT64), nil
+		},
+	},
+	std.Root + "/strings": {
+		"len": func(sys *System, args []Value) (Value, error) {
+			return NewInt(int64(len(str(args[0]))), types.INT32), nil
+		},
+		"contains": str2Bool(strings.Contains),
+		"indexOf": func(sys *System, args []Value) (Value, error) {
+			return NewInt(int64(strings.Index(str(args[0]), str(args[1]))), types.INT32), nil
+		},
+		"hasPrefix": str2Bool(strings.HasPrefix),
+		"hasSuffix": str2Bool(strings.HasSuffix),
+		"toUpper":   str1(strings.ToUpper),
+		"toLower":   str1(strings.ToLower),
+		"trim":      str1(strings.TrimSpace),
+	},
+}
+
+func str(v Value) string {
+	return string(v.(Str))
+}
+
+func float1(f func(float64) float64) NativeFunc {
+	return func(sys *System, args []Value) (Value, error) {
+		return NewFloat(f(args[0].(Float).V), types.FLOAT64), nil
+	}
+}
+
+func str1(f func(string) string) NativeFunc {
+	return func(sys *System, args []Value) (Value, error) {
+		return Str(f(str(args[0]))), nil
+	}
+}
+
+func str2Bool(f func(string, string) bool) NativeFunc {
+	return func(sys *System, args []Value) (Value, error) {
+		return Bool(f(str(args[0]), str(args[1]))), nil
+	}
+}
+
+// LookupNative returns the implementation of the function name of a standard library module, a
+// function without one fails when it is called
+func LookupNative(importPath, name string) NativeFunc {
+	if fn := natives[importPath][name]; fn != nil {
+		return fn
+	}
+	return func(*System, []Value) (Value, error) {
+		return nil, fmt.Errorf("%s::%s has no native implementation", importPath, name)
+	}
+}
+
+// native returns the implementation of a function declared without a body
+func (in *Interpreter) native(m *module, decl *ast.FunctionDecl) *Native {
+	name := m.importPath + "::" + decl.Identifier.Name
+	fn := LookupNative(m.importPath, decl.Identifier.Name)
+	native := &Native{Name: name, Fn: fn}
+	for _, param := range decl.Function.Params {
+		native.Params = append(native.Params, in.typeOf(param.Type))
+	}
+	return native
+}
@@ -0,0 +1,146 @@
+package interp
+
+import (
+	"errors"
+	"math"
+
+	"compiler/internal/semantic"
+	"compiler/internal/types"
+)
+
+var errDivisionByZero = errors.New("integer division by zero")
+
+// IsNumeric reports whether a value is an integer, byte or float
+func IsNumeric(v Value) bool {
+	switch v.(type) {
+	case Int, Float:
+		return true
+	}
+	return false
+}
+
+// ToKind converts a number to another numeric kind: integers wrap to the width of the kind and
+// floats are truncated toward zero when converted to an integer
+func ToKind(v Value, kind types.TYPE_NAME) Value {
+	isFloat := kind == types.FLOAT32 || kind == types.FLOAT64
+	switch v := v.(type) {
+	case Int:
+		if !isFloat {
+			return wrapInt(uint64(v.V), kind)
+		}
+		if v.Unsigned() {
+			return NewFloat(float64(uint64(v.V)), kind)
+		}
+		return NewFloat(float64(v.V), kind)
+	case Float:
+		if isFloat {
+			return NewFloat(v.V, kind)
+		}
+		if types.IsUnsigned(kind) {
+			return wrapInt(uint64(v.V), kind)
+		}
+		return wrapInt(uint64(int64(v.V)), kind)
+	}
+	return v
+}
+
+// CommonKind is the kind both operands of an arithmetic operator are converted to, as the type checker decided
+func CommonKind(a, b Value) types.TYPE_NAME {
+	if common, ok := semantic.GetCommonType(a.Type(), b.Type()).(*semantic.PrimitiveType); ok {
+		return common.Name
+	}
+	return a.Type().TypeName()
+}
+
+// Arithmetic applies + - * / % to two numbers of the same kind
+func Arithmetic(op string, a, b Value) (Value, error) {
+	switch x := a.(type) {
+	case Int:
+		return intArithmetic(op, x, b.(Int))
+	case Float:
+		y := b.(Float)
+		switch op {
+		case "+":
+			return NewFloat(x.V+y.V, x.Kind), nil
+		case "-":
+			return NewFloat(x.V-y.V, x.Kind), nil
+		case "*":
+			return NewFloat(x.V*y.V, x.Kind), nil
+		case "/":
+			return NewFloat(x.V/y.V, x.Kind), nil
+		case "%":
+			return NewFloat(math.Mod(x.V, y.V), x.Kind), nil
+		}
+	}
+	return nil, errors.New("invalid operands for " + op)
+}
+
+// intArithmetic computes in two's complement and wraps the result to the width of the kind,
+// division and remainder are signed or unsigned as the kind is
+func intArithmetic(op string, x, y Int) (Value, error) {
+	a, b := uint64(x.V), uint64(y.V)
+	switch op {
+	case "+":
+		return wrapInt(a+b, x.Kind), nil
+	case "-":
+		return wrapInt(a-b, x.Kind), nil
+	case "*":
+		return wrapInt(a*b, x.Kind), nil
+	case "/", "%":
+		if b == 0 {
+			return nil, errDivisionByZero
+		}
+		if x.Unsigned() {
+			if op == "/" {
+				return wrapInt(a/b, x.Kind), nil
+			}
+			return wrapInt(a%b, x.Kind), nil
+		}
+		// The most negative value divided by -1 overflows back to itself, as in two's complement
+		if op == "/" {
+			return wrapInt(uint64(x.V/y.V), x.Kind), nil
+		}
+		return wrapInt(uint64(x.V%y.V), x.Kind), nil
+	}
+	return nil, errors.New("invalid operands for " + op)
+}
+
+// Compare orders two numbers of the same kind or two strings, it returns -1, 0 or 1
+func Compare(a, b Value) int {
+	switch x := a.(type) {
+	case Int:
+		y := b.(Int)
+		if x.Unsigned() {
+			return cmp3(uint64(x.V) < uint64(y.V), uint64(x.V) > uint64(y.V))
+		}
+		return cmp3(x.V < y.V, x.V > y.V)
+	case Float:
+		y := b.(Float)
+		return cmp3(x.V < y.V, x.V > y.V)
+	case Str:
+		y := b.(Str)
+		return cmp3(x < y, x > y)
+	}
+	return 0
+}
+
+func cmp3(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+// Negate returns -v in the kind of v, the most negative integer negates to itself
+func Negate(v Value) Value {
+	switch v := v.(type) {
+	case Int:
+		return wrapInt(-uint64(v.V), v.Kind)
+	case Float:
+		return NewFloat(-v.V, v.Kind)
+	}
+	return v
+}
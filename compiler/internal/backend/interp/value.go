@@ -0,0 +1,221 @@
+package interp
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"compiler/internal/frontend/ast"
+	"compiler/internal/semantic"
+	"compiler/internal/types"
+)
+
+// Value is a runtime value, it knows its own type so operators and conversions follow the static types
+type Value interface {
+	Type() semantic.Type
+	String() string
+}
+
+// Int is a value of an integer type or byte. V holds the bits of the value: signed kinds are sign
+// extended, unsigned kinds are zero extended and u64 above the int64 range reads back through uint64.
+type Int struct {
+	V    int64
+	Kind types.TYPE_NAME
+}
+
+// Float is an f32 or f64, an f32 is rounded to float32 precision after every operation
+type Float struct {
+	V    float64
+	Kind types.TYPE_NAME
+}
+
+type Str string
+
+type Bool bool
+
+// Array is shared by every variable it is assigned to, as its elements are
+type Array struct {
+	Elems []Value
+	Elem  semantic.Type // the element type, values stored into the array are converted to it
+}
+
+// Struct is copied when it is assigned, passed or returned
+type Struct struct {
+	Def    *semantic.StructType
+	Fields map[string]Value
+}
+
+// Function is a Ferret function or closure, with the environment it was created in
+type Function struct {
+	Name    string
+	Literal *ast.FunctionLiteral
+	Env     *env
+	Module  *module
+	File    *ast.Program
+	// Receiver is the value a method was read from, bound to the parameter named ReceiverName
+	Receiver     Value
+	ReceiverName string
+	isRRef       bool // the method shares its receiver instead of copying it
+}
+
+// Native is a standard library function implemented in Go
+type Native struct {
+	Name   string
+	Params []semantic.Type // the arguments are converted to the parameter types of the declaration
+	Fn     NativeFunc
+}
+
+// Tuple holds the values of a function returning more than one
+type Tuple []Value
+
+// NewInt returns the value n of an integer kind, wrapped to the width of the kind
+func NewInt(n int64, kind types.TYPE_NAME) Int {
+	return wrapInt(uint64(n), kind)
+}
+
+// NewFloat returns the value f of a float kind, rounded to its precision
+func NewFloat(f float64, kind types.TYPE_NAME) Float {
+	if kind == types.FLOAT32 {
+		f = float64(float32(f))
+	}
+	return Float{V: f, Kind: kind}
+}
+
+// wrapInt truncates two's complement bits to the width of kind, as a fixed width integer overflows
+func wrapInt(bits uint64, kind types.TYPE_NAME) Int {
+	size := types.GetNumberBitSize(kind)
+	if size == 0 || size >= 64 {
+		return Int{V: int64(bits), Kind: kind}
+	}
+	shift := 64 - size
+	if types.IsSigned(kind) {
+		return Int{V: int64(bits<<shift) >> shift, Kind: kind}
+	}
+	return Int{V: int64(bits << shift >> shift), Kind: kind}
+}
+
+// Unsigned reports whether the value is of an unsigned kind, whose bits read as uint64
+func (i Int) Unsigned() bool { return types.IsUnsigned(i.Kind) }
+
+func (i Int) Type() semantic.Type { return semantic.CreatePrimitiveType(i.Kind) }
+func (i Int) String() string {
+	if i.Unsigned() {
+		return strconv.FormatUint(uint64(i.V), 10)
+	}
+	return strconv.FormatInt(i.V, 10)
+}
+
+func (f Float) Type() semantic.Type { return semantic.CreatePrimitiveType(f.Kind) }
+func (f Float) String() string {
+	return strconv.FormatFloat(f.V, 'g', -1, int(types.GetNumberBitSize(f.Kind)))
+}
+
+func (s Str) Type() semantic.Type { return semantic.CreatePrimitiveType(types.STRING) }
+func (s Str) String() string      { return string(s) }
+
+func (b Bool) Type() semantic.Type { return semantic.CreatePrimitiveType(types.BOOL) }
+func (b Bool) String() string      { return strconv.FormatBool(bool(b)) }
+
+func (a *Array) Type() semantic.Type {
+	if a.Elem == nil {
+		return semantic.CreateArrayType(semantic.CreatePrimitiveType(types.UNKNOWN_TYPE))
+	}
+	return semantic.CreateArrayType(a.Elem)
+}
+func (a *Array) String() string {
+	elems := make([]string, len(a.Elems))
+	for i, elem := range a.Elems {
+		elems[i] = Display(elem)
+	}
+	return "[" + strings.Join(elems, ", ") + "]"
+}
+
+func (s *Struct) Type() semantic.Type { return s.Def }
+func (s *Struct) String() string {
+	names := make([]string, 0, len(s.Fields))
+	for name := range s.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fields := make([]string, len(names))
+	for i, name := range names {
+		fields[i] = fmt.Sprintf("%s: %s", name, Display(s.Fields[name]))
+	}
+	return fmt.Sprintf("%s { %s }", s.Def.Name, strings.Join(fields, ", "))
+}
+
+func (f *Function) Type() semantic.Type { return semantic.FunctionLiteralToSemanticType(f.Literal) }
+func (f *Function) String() string      { return "fn " + f.Name }
+
+func (n *Native) Type() semantic.Type { return semantic.CreatePrimitiveType(types.FUNCTION) }
+func (n *Native) String() string      { return "fn " + n.Name }
+
+func (t Tuple) Type() semantic.Type { return semantic.CreatePrimitiveType(types.UNKNOWN_TYPE) }
+func (t Tuple) String() string {
+	elems := make([]string, len(t))
+	for i, elem := range t {
+		elems[i] = Display(elem)
+	}
+	return "(" + strings.Join(elems, ", ") + ")"
+}
+
+// Display shows a value inside another one, strings quoted
+func Display(v Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "<none>"
+	case Str:
+		return strconv.Quote(string(v))
+	}
+	return v.String()
+}
+
+// CopyValue returns a value a new variable can own: structs are values and are copied, with the
+// structs in their fields, arrays and functions are shared
+func CopyValue(v Value) Value {
+	s, ok := v.(*Struct)
+	if !ok {
+		return v
+	}
+	fields := make(map[string]Value, len(s.Fields))
+	for name, field := range s.Fields {
+		fields[name] = CopyValue(field)
+	}
+	return &Struct{Def: s.Def, Fields: fields}
+}
+
+// Equal compares two values of comparable types, structs and arrays element by element
+func Equal(a, b Value) bool {
+	switch a := a.(type) {
+	case Int:
+		b, ok := b.(Int)
+		return ok && a.V == b.V
+	case Float:
+		b, ok := b.(Float)
+		return ok && a.V == b.V
+	case *Array:
+		b, ok := b.(*Array)
+		if !ok || len(a.Elems) != len(b.Elems) {
+			return false
+		}
+		for i := range a.Elems {
+			if !Equal(a.Elems[i], b.Elems[i]) {
+				return false
+			}
+		}
+		return true
+	case *Struct:
+		b, ok := b.(*Struct)
+		if !ok || len(a.Fields) != len(b.Fields) {
+			return false
+		}
+		for name, field := range a.Fields {
+			if !Equal(field, b.Fields[name]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
@@ -0,0 +1,210 @@
+// Package cache stores fetched modules between compilations.
+//
+// Every file is kept twice: as a content-addressed object, objects/<sha256>, and at its key, e.g.
+// github.com/user/repo/lib/util.fer, where the parser reads it. The index records the hash of every
+// key, so a file edited or damaged in the cache is detected when it is loaded.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// INDEX_FILE lists the entries of the cache, at the cache root
+const INDEX_FILE = "index.json"
+
+const objectsDir = "objects"
+
+// Entry is a file stored in the cache
+type Entry struct {
+	Key    string    `json:"key"`    // slash separated path of the file inside the cache
+	Hash   string    `json:"sha256"` // hex SHA-256 of the content
+	Size   int64     `json:"size"`
+	Stored time.Time `json:"stored"`
+}
+
+// Cache is a module cache directory
+type Cache struct {
+	Dir     string
+	entries map[string]Entry
+}
+
+type index struct {
+	Entries []Entry `json:"entries"`
+}
+
+// Open loads the cache in dir, a missing directory is an empty cache
+func Open(dir string) (*Cache, error) {
+	c := &Cache{Dir: filepath.ToSlash(dir), entries: make(map[string]Entry)}
+	data, err := os.ReadFile(filepath.FromSlash(c.indexPath()))
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var idx index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("corrupted cache index %s: %w, run 'ferret cache clean'", c.indexPath(), err)
+	}
+	for _, entry := range idx.Entries {
+		c.entries[entry.Key] = entry
+	}
+	return c, nil
+}
+
+// Hash returns the hex SHA-256 of data, the address of its object
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Path is where the file of a key lives, whether it is cached or not
+func (c *Cache) Path(key string) string {
+	return c.Dir + "/" + key
+}
+
+// Has reports whether the index has an entry for key
+func (c *Cache) Has(key string) bool {
+	_, ok := c.entries[key]
+	return ok
+}
+
+// Lookup returns the index entry of key, without reading the file
+func (c *Cache) Lookup(key string) (Entry, bool) {
+	entry, ok := c.entries[key]
+	return entry, ok
+}
+
+// Get returns the path of a cached file after checking its content against the index.
+// A damaged file is restored from its object, when the object is damaged too Get fails.
+func (c *Cache) Get(key string) (string, bool, error) {
+	entry, ok := c.entries[key]
+	if !ok {
+		return "", false, nil
+	}
+	path := c.Path(key)
+	if data, err := os.ReadFile(filepath.FromSlash(path)); err == nil && Hash(data) == entry.Hash {
+		return path, true, nil
+	}
+
+	object, err := os.ReadFile(filepath.FromSlash(c.objectPath(entry.Hash)))
+	if err != nil || Hash(object) != entry.Hash {
+		return "", false, fmt.Errorf("cache entry %s does not match its sha256 %s, run 'ferret cache clean'", key, entry.Hash)
+	}
+	if err := writeFile(path, object); err != nil {
+		return "", false, err
+	}
+	return path, true, nil
+}
+
+// Put stores data under key and returns the path of its file
+func (c *Cache) Put(key string, data []byte) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	hash := Hash(data)
+	if err := writeFile(c.objectPath(hash), data); err != nil {
+		return "", err
+	}
+	path := c.Path(key)
+	if err := writeFile(path, data); err != nil {
+		return "", err
+	}
+	c.entries[key] = Entry{Key: key, Hash: hash, Size: int64(len(data)), Stored: time.Now().UTC()}
+	return path, c.save()
+}
+
+// Entries returns every entry, sorted by key
+func (c *Cache) Entries() []Entry {
+	entries := make([]Entry, 0, len(c.entries))
+	for _, entry := range c.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries
+}
+
+// Verify checks every entry's file and object against the index and returns a message per problem
+func (c *Cache) Verify() []string {
+	var problems []string
+	for _, entry := range c.Entries() {
+		for _, path := range []string{c.Path(entry.Key), c.objectPath(entry.Hash)} {
+			data, err := os.ReadFile(filepath.FromSlash(path))
+			switch {
+			case err != nil:
+				problems = append(problems, fmt.Sprintf("%s: %s is missing", entry.Key, path))
+			case Hash(data) != entry.Hash:
+				problems = append(problems, fmt.Sprintf("%s: %s has sha256 %s, expected %s", entry.Key, path, Hash(data), entry.Hash))
+			}
+		}
+	}
+	return problems
+}
+
+// Clean removes the cache directory with everything in it
+func (c *Cache) Clean() error {
+	c.entries = make(map[string]Entry)
+	return os.RemoveAll(filepath.FromSlash(c.Dir))
+}
+
+func (c *Cache) indexPath() string {
+	return c.Dir + "/" + INDEX_FILE
+}
+
+func (c *Cache) objectPath(hash string) string {
+	return c.Dir + "/" + objectsDir + "/" + hash
+}
+
+func (c *Cache) save() error {
+	data, err := json.MarshalIndent(index{Entries: c.Entries()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(c.indexPath(), data)
+}
+
+// checkKey rejects keys that would place a file outside the cache or over its own files
+func checkKey(key string) error {
+	if key == "" || key == INDEX_FILE || strings.HasPrefix(key, objectsDir+"/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid cache key: %s", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("invalid cache key: %s", key)
+		}
+	}
+	return nil
+}
+
+// writeFile writes through a temporary file, so an interrupted compilation never leaves half a file behind
+func writeFile(path string, data []byte) error {
+	dir := filepath.Dir(filepath.FromSlash(path))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.FromSlash(path))
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const key = "github.com/user/repo/lib/util.fer"
+
+func TestPutAndGet(t *testing.T) {
+	dir := filepath.ToSlash(t.TempDir())
+	c, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found, err := c.Get(key); found || err != nil {
+		t.Fatalf("expected an empty cache, found = %v, err = %v", found, err)
+	}
+
+	path, err := c.Put(key, []byte("fn util() {}"))
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if path != dir+"/"+key {
+		t.Errorf("Put() = %q, want %q", path, dir+"/"+key)
+	}
+
+	// The entry survives into the next compilation
+	reopened, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, found, err := reopened.Get(key)
+	if !found || err != nil || got != path {
+		t.Fatalf("Get() = %q, %v, %v after reopening", got, found, err)
+	}
+	entries := reopened.Entries()
+	if len(entries) != 1 || entries[0].Hash != Hash([]byte("fn util() {}")) || entries[0].Size != 12 {
+		t.Errorf("unexpected entries %+v", entries)
+	}
+}
+
+func TestIntegrity(t *testing.T) {
+	dir := filepath.ToSlash(t.TempDir())
+	c, _ := Open(dir)
+	path, err := c.Put(key, []byte("fn util() {}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An edited file is detected and restored from its object
+	os.WriteFile(path, []byte("fn evil() {}"), 0644)
+	if problems := c.Verify(); len(problems) != 1 || !strings.Contains(problems[0], "expected") {
+		t.Errorf("Verify() = %q, want one hash mismatch", problems)
+	}
+	if _, found, err := c.Get(key); !found || err != nil {
+		t.Fatalf("Get() found = %v, err = %v, expected the file to be restored", found, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "fn util() {}" {
+		t.Errorf("restored file = %q", data)
+	}
+
+	// Without an intact object there is nothing to restore from
+	os.WriteFile(path, []byte("fn evil() {}"), 0644)
+	os.WriteFile(c.objectPath(Hash([]byte("fn util() {}"))), []byte("fn evil() {}"), 0644)
+	if _, _, err := c.Get(key); err == nil || !strings.Contains(err.Error(), "does not match its sha256") {
+		t.Errorf("expected an integrity error, got %v", err)
+	}
+	if problems := c.Verify(); len(problems) != 2 {
+		t.Errorf("Verify() = %q, want two problems", problems)
+	}
+}
+
+func TestCorruptedIndex(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, INDEX_FILE), []byte("{not json"), 0644)
+	if _, err := Open(dir); err == nil || !strings.Contains(err.Error(), "corrupted cache index") {
+		t.Errorf("expected a corrupted index error, got %v", err)
+	}
+}
+
+func TestInvalidKeys(t *testing.T) {
+	c, _ := Open(t.TempDir())
+	for _, key := range []string{"", INDEX_FILE, "objects/abc", "../outside.fer", "a//b.fer", `a\b.fer`} {
+		if _, err := c.Put(key, nil); err == nil {
+			t.Errorf("Put(%q) should fail", key)
+		}
+	}
+}
+
+func TestClean(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "modules")
+	c, _ := Open(dir)
+	if _, err := c.Put(key, []byte("fn util() {}")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Clean(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected the cache directory to be removed, stat error = %v", err)
+	}
+	if len(c.Entries()) != 0 {
+		t.Error("expected no entries after Clean")
+	}
+}
@@ -0,0 +1,328 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// UpdateProjectConfig sets the value at a path of keys in the .ferret.json of projectRoot.
+// Only the text of that value changes, the rest of the file keeps its formatting.
+func UpdateProjectConfig(projectRoot string, path []string, value any) error {
+	configPath := filepath.Join(projectRoot, CONFIG_FILE)
+	data, err := os.ReadFile(filepath.FromSlash(configPath))
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	updated, err := SetJSONValue(data, path, value)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", CONFIG_FILE, err)
+	}
+	return os.WriteFile(filepath.FromSlash(configPath), updated, 0644)
+}
+
+// SetJSONValue returns data with the value at path replaced, or added to the innermost object
+// of path that exists. The new value is indented like its surroundings.
+func SetJSONValue(data []byte, path []string, value any) ([]byte, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+	s := &jsonScanner{data: data}
+	s.unit = s.indentUnit()
+
+	obj := s.ws(0)
+	if obj >= len(data) || data[obj] != '{' {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+	for i, key := range path {
+		m, err := s.member(obj, key)
+		if err != nil {
+			return nil, err
+		}
+		if !m.found {
+			// Build the missing part of the path from the inside out
+			for j := len(path) - 1; j > i; j-- {
+				value = map[string]any{path[j]: value}
+			}
+			return s.insert(obj, m, key, value)
+		}
+		if i == len(path)-1 {
+			text, err := s.render(value, s.lineIndent(m.keyStart))
+			if err != nil {
+				return nil, err
+			}
+			return splice(data, m.valueStart, m.valueEnd, text), nil
+		}
+		if data[m.valueStart] != '{' {
+			return nil, fmt.Errorf("'%s' is not an object", key)
+		}
+		obj = m.valueStart
+	}
+	return data, nil
+}
+
+// RemoveJSONValue returns data without the member at path, and unchanged when there is none.
+// The line of the member goes with it, the rest of the file keeps its formatting.
+func RemoveJSONValue(data []byte, path []string) ([]byte, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+	s := &jsonScanner{data: data}
+	obj := s.ws(0)
+	if obj >= len(data) || data[obj] != '{' {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+	for i, key := range path {
+		m, err := s.member(obj, key)
+		if err != nil {
+			return nil, err
+		}
+		if !m.found {
+			return data, nil
+		}
+		if i < len(path)-1 {
+			if data[m.valueStart] != '{' {
+				return data, nil
+			}
+			obj = m.valueStart
+			continue
+		}
+		switch {
+		case m.prevEnd >= 0:
+			return splice(data, m.prevEnd, m.valueEnd, nil), nil
+		case m.nextKey >= 0:
+			return splice(data, m.keyStart, m.nextKey, nil), nil
+		default:
+			return splice(data, obj+1, m.closing, nil), nil
+		}
+	}
+	return data, nil
+}
+
+type jsonScanner struct {
+	data []byte
+	unit string // one level of indentation
+}
+
+// jsonMember is where a key of an object is, or where it would go when it is missing
+type jsonMember struct {
+	found                          bool
+	keyStart, valueStart, valueEnd int
+	lastEnd                        int // end of the last member's value, -1 in an empty object
+	firstKey                       int // start of the first key, -1 in an empty object
+	prevEnd                        int // end of the value before the key, -1 when it is the first
+	nextKey                        int // start of the key after it, -1 when it is the last
+	closing                        int // the object's '}'
+}
+
+func (s *jsonScanner) member(obj int, key string) (jsonMember, error) {
+	m := jsonMember{lastEnd: -1, firstKey: -1, prevEnd: -1, nextKey: -1}
+	i := s.ws(obj + 1)
+	for i < len(s.data) && s.data[i] != '}' {
+		keyStart := i
+		name, next, err := s.str(i)
+		if err != nil {
+			return m, err
+		}
+		if m.firstKey < 0 {
+			m.firstKey = keyStart
+		}
+		if m.found && m.nextKey < 0 {
+			m.nextKey = keyStart
+		}
+		i = s.ws(next)
+		if i >= len(s.data) || s.data[i] != ':' {
+			return m, fmt.Errorf("expected ':' at offset %d", i)
+		}
+		valueStart := s.ws(i + 1)
+		valueEnd, err := s.value(valueStart)
+		if err != nil {
+			return m, err
+		}
+		if name == key && !m.found {
+			m.found, m.keyStart, m.valueStart, m.valueEnd = true, keyStart, valueStart, valueEnd
+			m.prevEnd = m.lastEnd
+		}
+		m.lastEnd = valueEnd
+		i = s.ws(valueEnd)
+		if i < len(s.data) && s.data[i] == ',' {
+			i = s.ws(i + 1)
+		}
+	}
+	if i >= len(s.data) {
+		return m, fmt.Errorf("unterminated object")
+	}
+	m.closing = i
+	return m, nil
+}
+
+// members calls fn with every key of the object at obj, where it starts and where its value starts
+func (s *jsonScanner) members(obj int, fn func(key string, keyStart, valueStart int)) error {
+	i := s.ws(obj + 1)
+	for i < len(s.data) && s.data[i] != '}' {
+		key, next, err := s.str(i)
+		if err != nil {
+			return err
+		}
+		valueStart := s.ws(s.ws(next) + 1)
+		valueEnd, err := s.value(valueStart)
+		if err != nil {
+			return err
+		}
+		fn(key, i, valueStart)
+		i = s.ws(valueEnd)
+		if i < len(s.data) && s.data[i] == ',' {
+			i = s.ws(i + 1)
+		}
+	}
+	return nil
+}
+
+// elements calls fn with the index and start of every element of the array at arr
+func (s *jsonScanner) elements(arr int, fn func(index, start int)) error {
+	i := s.ws(arr + 1)
+	for index := 0; i < len(s.data) && s.data[i] != ']'; index++ {
+		end, err := s.value(i)
+		if err != nil {
+			return err
+		}
+		fn(index, i)
+		i = s.ws(end)
+		if i < len(s.data) && s.data[i] == ',' {
+			i = s.ws(i + 1)
+		}
+	}
+	return nil
+}
+
+// insert adds key to the object at obj, after its last member
+func (s *jsonScanner) insert(obj int, m jsonMember, key string, value any) ([]byte, error) {
+	objIndent := s.lineIndent(obj)
+	memberIndent := objIndent + s.unit
+	if m.firstKey >= 0 && s.ownLine(m.firstKey) {
+		memberIndent = s.lineIndent(m.firstKey)
+	}
+	text, err := s.render(value, memberIndent)
+	if err != nil {
+		return nil, err
+	}
+	name, _ := json.Marshal(key)
+	entry := fmt.Sprintf("%s: %s", name, text)
+
+	if m.lastEnd < 0 {
+		return splice(s.data, obj+1, m.closing, []byte("\n"+memberIndent+entry+"\n"+objIndent)), nil
+	}
+	return splice(s.data, m.lastEnd, m.lastEnd, []byte(",\n"+memberIndent+entry)), nil
+}
+
+// render formats a value to follow a key on a line indented by indent
+func (s *jsonScanner) render(value any, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent(indent, s.unit)
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+func (s *jsonScanner) ws(i int) int {
+	for i < len(s.data) && (s.data[i] == ' ' || s.data[i] == '\t' || s.data[i] == '\n' || s.data[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// str decodes the string starting at i and returns the offset after it
+func (s *jsonScanner) str(i int) (string, int, error) {
+	if i >= len(s.data) || s.data[i] != '"' {
+		return "", i, fmt.Errorf("expected a string at offset %d", i)
+	}
+	for j := i + 1; j < len(s.data); j++ {
+		switch s.data[j] {
+		case '\\':
+			j++
+		case '"':
+			var str string
+			if err := json.Unmarshal(s.data[i:j+1], &str); err != nil {
+				return "", i, err
+			}
+			return str, j + 1, nil
+		}
+	}
+	return "", i, fmt.Errorf("unterminated string at offset %d", i)
+}
+
+// value returns the offset after the value starting at i
+func (s *jsonScanner) value(i int) (int, error) {
+	if i >= len(s.data) {
+		return i, fmt.Errorf("unexpected end of JSON")
+	}
+	switch s.data[i] {
+	case '"':
+		_, end, err := s.str(i)
+		return end, err
+	case '{', '[':
+		depth := 0
+		for j := i; j < len(s.data); j++ {
+			switch s.data[j] {
+			case '"':
+				_, end, err := s.str(j)
+				if err != nil {
+					return j, err
+				}
+				j = end - 1
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return j + 1, nil
+				}
+			}
+		}
+		return i, fmt.Errorf("unterminated value at offset %d", i)
+	default:
+		j := i
+		for j < len(s.data) && !bytes.ContainsRune([]byte(",}] \t\r\n"), rune(s.data[j])) {
+			j++
+		}
+		return j, nil
+	}
+}
+
+// lineIndent returns the white space the line holding offset i starts with
+func (s *jsonScanner) lineIndent(i int) string {
+	start := bytes.LastIndexByte(s.data[:i], '\n') + 1
+	end := start
+	for end < len(s.data) && (s.data[end] == ' ' || s.data[end] == '\t') {
+		end++
+	}
+	return string(s.data[start:end])
+}
+
+// ownLine reports whether only white space comes before offset i on its line
+func (s *jsonScanner) ownLine(i int) bool {
+	return len(s.lineIndent(i)) == i-(bytes.LastIndexByte(s.data[:i], '\n')+1)
+}
+
+// indentUnit is the indentation of the first indented line, two spaces in a file without one
+func (s *jsonScanner) indentUnit() string {
+	for _, line := range bytes.Split(s.data, []byte("\n")) {
+		trimmed := bytes.TrimLeft(line, " \t")
+		if len(trimmed) > 0 && len(trimmed) < len(line) {
+			return string(line[:len(line)-len(trimmed)])
+		}
+	}
+	return "  "
+}
+
+func splice(data []byte, start, end int, text []byte) []byte {
+	out := make([]byte, 0, len(data)-(end-start)+len(text))
+	out = append(out, data[:start]...)
+	out = append(out, text...)
+	return append(out, data[end:]...)
+}
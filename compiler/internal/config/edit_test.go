@@ -0,0 +1,135 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSetJSONValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		path  []string
+		value any
+		want  string
+	}{
+		{
+			"replace keeps the rest",
+			`{
+    "compiler": { "version": "0.1.0" },
+    "dependencies": {
+        "modules": ["a"]
+    }
+}`,
+			[]string{"dependencies", "modules"}, []string{"a", "b"},
+			`{
+    "compiler": { "version": "0.1.0" },
+    "dependencies": {
+        "modules": [
+            "a",
+            "b"
+        ]
+    }
+}`,
+		},
+		{
+			"add to an empty object",
+			`{
+  "dependencies": {}
+}`,
+			[]string{"dependencies", "modules"}, []string{"a"},
+			`{
+  "dependencies": {
+    "modules": [
+      "a"
+    ]
+  }
+}`,
+		},
+		{
+			"add a missing object",
+			`{
+  "cache": {
+    "path": ".ferret/modules"
+  }
+}`,
+			[]string{"dependencies", "modules"}, []string{},
+			`{
+  "cache": {
+    "path": ".ferret/modules"
+  },
+  "dependencies": {
+    "modules": []
+  }
+}`,
+		},
+		{
+			"strings with braces and escapes",
+			`{"note": "a } \" [", "n": 1}`,
+			[]string{"n"}, 2,
+			`{"note": "a } \" [", "n": 2}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SetJSONValue([]byte(tt.input), tt.path, tt.value)
+			if err != nil {
+				t.Fatalf("SetJSONValue() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("SetJSONValue() =\n%s\nwant\n%s", got, tt.want)
+			}
+			if !json.Valid(got) {
+				t.Error("result is not valid JSON")
+			}
+		})
+	}
+}
+
+func TestSetJSONValueErrors(t *testing.T) {
+	for _, input := range []string{`[]`, `{"dependencies": 1}`, `{"a": "unterminated}`} {
+		if _, err := SetJSONValue([]byte(input), []string{"dependencies", "modules"}, nil); err == nil {
+			t.Errorf("SetJSONValue(%s) should fail", input)
+		}
+	}
+}
+
+func TestParseDependency(t *testing.T) {
+	deps := DependencyConfig{Modules: []string{"github.com/user/lib@v1.2.0", "github.com/user/tools"}}
+	if dep, ok := deps.Find("github.com/user/lib"); !ok || dep.Version != "v1.2.0" || dep.String() != "github.com/user/lib@v1.2.0" {
+		t.Errorf("Find() = %+v, %v", dep, ok)
+	}
+	if dep, ok := deps.Find("github.com/user/tools"); !ok || dep.Version != "" || dep.String() != "github.com/user/tools" {
+		t.Errorf("Find() = %+v, %v", dep, ok)
+	}
+	if _, ok := deps.Find("github.com/user/other"); ok {
+		t.Error("Find() found a missing dependency")
+	}
+}
+
+func TestRemoveJSONValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		path  []string
+		want  string
+	}{
+		{"last member", "{\n  \"a\": 1,\n  \"b\": 2\n}", []string{"b"}, "{\n  \"a\": 1\n}"},
+		{"first member", "{\n  \"a\": 1,\n  \"b\": 2\n}", []string{"a"}, "{\n  \"b\": 2\n}"},
+		{"only member", `{"a": {"b": [1]}}`, []string{"a", "b"}, `{"a": {}}`},
+		{"missing", `{"a": 1}`, []string{"c", "d"}, `{"a": 1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RemoveJSONValue([]byte(tt.input), tt.path)
+			if err != nil {
+				t.Fatalf("RemoveJSONValue() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("RemoveJSONValue() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"compiler/internal/semver"
+)
+
+// migration upgrades one thing earlier versions of the compiler wrote differently, and describes
+// what it changed, nothing when the config is already current
+type migration func(cfg map[string]any, data []byte, name string) ([]byte, string, error)
+
+// migrations run in order, each on the output of the previous one
+var migrations = []migration{
+	dropProjectRoot,
+	addName,
+	updateCompilerVersion,
+	dropLocalDependencies,
+}
+
+// MigrateProjectConfig upgrades the .ferret.json of projectRoot to the current schema and returns what
+// it changed. The file keeps its formatting, and is not written when nothing changed. Problems a migration
+// cannot fix, an unknown key for one, are returned as ConfigErrors after the migrated file is written.
+func MigrateProjectConfig(projectRoot string) ([]string, error) {
+	configPath := filepath.Join(projectRoot, CONFIG_FILE)
+	data, err := os.ReadFile(filepath.FromSlash(configPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	absRoot, err := filepath.Abs(projectRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	migrated, changes, err := MigrateConfig(filepath.ToSlash(configPath), data, filepath.Base(absRoot))
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) > 0 {
+		if err := os.WriteFile(filepath.FromSlash(configPath), migrated, 0644); err != nil {
+			return nil, err
+		}
+	}
+	return changes, ValidateProjectConfig(filepath.ToSlash(configPath), migrated)
+}
+
+// MigrateConfig applies the migrations to the content of the .ferret.json at file, name is the directory
+// of the project. A file that is not JSON cannot be migrated.
+func MigrateConfig(file string, data []byte, name string) ([]byte, []string, error) {
+	var changes []string
+	for _, migrate := range migrations {
+		var cfg map[string]any
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, nil, ValidateProjectConfig(file, data)
+		}
+		updated, change, err := migrate(cfg, data, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if change != "" {
+			data = updated
+			changes = append(changes, change)
+		}
+	}
+	return data, changes, nil
+}
+
+// dropProjectRoot removes the "ProjectRoot" 'ferret init' wrote, the project root is where .ferret.json is
+func dropProjectRoot(cfg map[string]any, data []byte, _ string) ([]byte, string, error) {
+	if _, found := cfg["ProjectRoot"]; !found {
+		return data, "", nil
+	}
+	updated, err := RemoveJSONValue(data, []string{"ProjectRoot"})
+	return updated, "removed 'ProjectRoot', the project root is the directory of " + CONFIG_FILE, err
+}
+
+// addName writes down the directory name projects without a name are imported by,
+// so renaming the directory does not change their import paths
+func addName(cfg map[string]any, data []byte, name string) ([]byte, string, error) {
+	if _, found := cfg["name"]; found || checkName(name) != nil {
+		return data, "", nil
+	}
+	updated, err := SetJSONValue(data, []string{"name"}, name)
+	return updated, fmt.Sprintf("set 'name' to %q, the import root it had from its directory", name), err
+}
+
+// updateCompilerVersion sets compiler.version to this compiler when it is missing or older than it.
+// A project written for a newer compiler cannot be migrated down.
+func updateCompilerVersion(cfg map[string]any, data []byte, _ string) ([]byte, string, error) {
+	compiler, isObject := cfg["compiler"].(map[string]any)
+	version, isString := compiler["version"].(string)
+	if (cfg["compiler"] != nil && !isObject) || (compiler["version"] != nil && !isString) {
+		return data, "", nil // reported by validation
+	}
+	change := fmt.Sprintf("set 'compiler.version' to %s", COMPILER_VERSION)
+	if version != "" {
+		constraint, err := compilerRange(version)
+		if err != nil {
+			return data, "", nil
+		}
+		current, _ := semver.Parse(COMPILER_VERSION)
+		if constraint.Allows(current) {
+			return data, "", nil
+		}
+		if current.Compare(constraint.Min) < 0 {
+			return nil, "", fmt.Errorf("the project needs compiler %s, this is %s, upgrade the compiler instead", constraint, COMPILER_VERSION)
+		}
+		change = fmt.Sprintf("updated 'compiler.version' from %s to %s", version, COMPILER_VERSION)
+	}
+	updated, err := SetJSONValue(data, []string{"compiler", "version"}, COMPILER_VERSION)
+	return updated, change, err
+}
+
+// dropLocalDependencies removes the paths of modules of the project earlier compilers accepted in
+// dependencies.modules, only repositories are dependencies now and the modules are imported without them
+func dropLocalDependencies(cfg map[string]any, data []byte, _ string) ([]byte, string, error) {
+	deps, _ := cfg["dependencies"].(map[string]any)
+	entries, isArray := deps["modules"].([]any)
+	if !isArray {
+		return data, "", nil // reported by validation
+	}
+	kept := []string{}
+	var dropped []string
+	for _, entry := range entries {
+		path, isString := entry.(string)
+		if !isString {
+			return data, "", nil
+		}
+		if isLocalDependency(ParseDependency(path).Path) {
+			dropped = append(dropped, strconv.Quote(path))
+			continue
+		}
+		kept = append(kept, path)
+	}
+	if len(dropped) == 0 {
+		return data, "", nil
+	}
+	change := fmt.Sprintf("removed %s from 'dependencies.modules', modules of the project are imported without listing them", strings.Join(dropped, ", "))
+	if len(kept) == 0 && len(deps) == 1 {
+		updated, err := RemoveJSONValue(data, []string{"dependencies"})
+		return updated, change, err
+	}
+	updated, err := SetJSONValue(data, []string{"dependencies", "modules"}, kept)
+	return updated, change, err
+}
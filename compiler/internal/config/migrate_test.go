@@ -0,0 +1,143 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrateProjectConfig(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "app")
+	os.Mkdir(root, 0755)
+	// What 'ferret init' wrote before the config had a name
+	old := `{
+  "compiler": {
+    "version": "0.0.3"
+  },
+  "cache": {
+    "path": ".ferret/modules"
+  },
+  "ProjectRoot": ""
+}`
+	os.WriteFile(filepath.Join(root, CONFIG_FILE), []byte(old), 0644)
+	if _, err := LoadProjectConfig(root); err == nil {
+		t.Fatal("expected the old config to be rejected")
+	}
+
+	changes, err := MigrateProjectConfig(root)
+	if err != nil {
+		t.Fatalf("MigrateProjectConfig() error = %v", err)
+	}
+	if len(changes) != 3 {
+		t.Errorf("expected 3 changes, got %q", changes)
+	}
+	data, _ := os.ReadFile(filepath.Join(root, CONFIG_FILE))
+	want := `{
+  "compiler": {
+    "version": "0.1.0"
+  },
+  "cache": {
+    "path": ".ferret/modules"
+  },
+  "name": "app"
+}`
+	if string(data) != want {
+		t.Errorf("migrated config =\n%s\nwant\n%s", data, want)
+	}
+	if cfg, err := LoadProjectConfig(root); err != nil || cfg.Name != "app" {
+		t.Errorf("LoadProjectConfig() after migrating = %v, %v", cfg, err)
+	}
+
+	if changes, err := MigrateProjectConfig(root); err != nil || len(changes) != 0 {
+		t.Errorf("expected a current config to stay as it is, got %q, %v", changes, err)
+	}
+}
+
+func TestMigrateConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{"newer compiler", `{"name": "app", "compiler": {"version": "1.0.0"}}`, "upgrade the compiler instead"},
+		{"not JSON", `{"name": }`, ".ferret.json:1:10: invalid character '}'"},
+		{"left for validation", `{"name": "app", "compiler": {"version": "0.1.0"}, "colour": true}`, "unknown key 'colour'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := filepath.Join(t.TempDir(), "app")
+			os.Mkdir(root, 0755)
+			os.WriteFile(filepath.Join(root, CONFIG_FILE), []byte(tt.input), 0644)
+			_, err := MigrateProjectConfig(root)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("MigrateProjectConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreateDefaultProjectConfigIsValid(t *testing.T) {
+	root := t.TempDir()
+	if err := CreateDefaultProjectConfig(root); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadProjectConfig(root); err != nil {
+		t.Errorf("LoadProjectConfig() error = %v", err)
+	}
+	if changes, err := MigrateProjectConfig(root); err != nil || len(changes) != 0 {
+		t.Errorf("expected a new config to be current, got %q, %v", changes, err)
+	}
+}
+
+func TestMigrateLocalDependencies(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"only local modules", `{
+  "name": "app",
+  "compiler": { "version": "0.1.0" },
+  "dependencies": {
+      "modules": [
+          "code/remote/graphics",
+          "code/remote/audio"
+        ]
+  }
+}`, `{
+  "name": "app",
+  "compiler": { "version": "0.1.0" }
+}`},
+		{"local and remote", `{
+  "name": "app",
+  "compiler": { "version": "0.1.0" },
+  "dependencies": { "modules": ["code/remote/audio", "github.com/user/lib@^1.2"] }
+}`, `{
+  "name": "app",
+  "compiler": { "version": "0.1.0" },
+  "dependencies": { "modules": [
+    "github.com/user/lib@^1.2"
+  ] }
+}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := filepath.Join(t.TempDir(), "app")
+			os.Mkdir(root, 0755)
+			os.WriteFile(filepath.Join(root, CONFIG_FILE), []byte(tt.input), 0644)
+			changes, err := MigrateProjectConfig(root)
+			if err != nil {
+				t.Fatalf("MigrateProjectConfig() error = %v", err)
+			}
+			if len(changes) != 1 || !strings.Contains(changes[0], `"code/remote/audio"`) {
+				t.Errorf("changes = %q, want the local modules removed", changes)
+			}
+			if data, _ := os.ReadFile(filepath.Join(root, CONFIG_FILE)); string(data) != tt.want {
+				t.Errorf("migrated config =\n%s\nwant\n%s", data, tt.want)
+			}
+		})
+	}
+}
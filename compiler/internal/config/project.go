@@ -5,17 +5,68 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 const CONFIG_FILE = ".ferret.json"
 
 // ProjectConfig represents the structure
 type ProjectConfig struct {
-	Compiler     CompilerConfig   `json:"compiler"`
-	Cache        CacheConfig      `json:"cache"`
-	Remote       RemoteConfig     `json:"remote"`
-	Dependencies DependencyConfig `json:"dependencies"`
-	ProjectRoot  string
+	// Name is the first part of the import paths of the project's modules, the directory name when empty
+	Name string `json:"name,omitempty"`
+	// Imports maps import path aliases to what they stand for, see ImportAlias
+	Imports      map[string]string `json:"imports,omitempty"`
+	Compiler     CompilerConfig    `json:"compiler"`
+	Cache        CacheConfig       `json:"cache"`
+	Remote       RemoteConfig      `json:"remote"`
+	Dependencies DependencyConfig  `json:"dependencies"`
+	ProjectRoot  string            `json:"-"` // the directory of the config file
+	// Workspace is the workspace the project is a member of, nil when it is not in one
+	Workspace *WorkspaceConfig `json:"-"`
+}
+
+// ImportRoot is the first part of the import paths of the project's own modules: "app" in "app/data"
+func (c *ProjectConfig) ImportRoot() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return filepath.Base(c.ProjectRoot)
+}
+
+// ImportAlias replaces the alias an import path starts with by its target, the longest alias first.
+// An alias ending in '/' replaces a prefix, "@lib/" makes "@lib/util" stand for "<target>util",
+// any other alias only a whole path. ok is false when no alias matches.
+func (c *ProjectConfig) ImportAlias(importPath string) (target string, ok bool) {
+	alias := ""
+	for key := range c.Imports {
+		matches := key == importPath || (strings.HasSuffix(key, "/") && strings.HasPrefix(importPath, key))
+		if matches && len(key) > len(alias) {
+			alias = key
+		}
+	}
+	if alias == "" {
+		return "", false
+	}
+	return c.Imports[alias] + strings.TrimPrefix(importPath, alias), true
+}
+
+// DEFAULT_CACHE_PATH is used when the config does not set cache.path
+const DEFAULT_CACHE_PATH = ".ferret/modules"
+
+// CachePath returns the absolute, slash separated module cache directory of the project,
+// the workspace's for a member of a workspace
+func (c *ProjectConfig) CachePath() string {
+	if c.Workspace != nil {
+		return c.Workspace.CachePath()
+	}
+	path := c.Cache.Path
+	if path == "" {
+		path = DEFAULT_CACHE_PATH
+	}
+	if filepath.IsAbs(path) {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(filepath.Join(c.ProjectRoot, path))
 }
 
 // CompilerConfig contains compiler-specific settings
@@ -31,21 +82,82 @@ type CacheConfig struct {
 // RemoteConfig defines remote module import/export settings
 type RemoteConfig struct {
 	Enabled bool `json:"enabled"`
-	Share   bool `json:"share"`
+	// Share makes every module of the project importable by other projects, unless Exports lists them
+	Share bool `json:"share"`
+	// Exports are the module paths other projects can import, "lib/util", or "lib/" for every module below lib
+	Exports []string `json:"exports,omitempty"`
+}
+
+// Shares reports whether other projects can import the module at modulePath, relative to the project root.
+// The modules listed in remote.exports are shared, without a list remote.share decides for all of them.
+func (c *ProjectConfig) Shares(modulePath string) bool {
+	if len(c.Remote.Exports) == 0 {
+		return c.Remote.Share
+	}
+	for _, export := range c.Remote.Exports {
+		if export == modulePath || (strings.HasSuffix(export, "/") && strings.HasPrefix(modulePath, export)) {
+			return true
+		}
+	}
+	return false
 }
 
 type DependencyConfig struct {
 	Modules []string `json:"modules,omitempty"`
 }
 
+// Dependency is an entry of dependencies.modules, a repository and the version its modules are fetched at,
+// written "github.com/user/repo@v1.2.0". An entry without a version follows the default branch.
+type Dependency struct {
+	Path    string
+	Version string
+}
+
+// ParseDependency splits an entry of dependencies.modules at its '@'
+func ParseDependency(entry string) Dependency {
+	path, version, _ := strings.Cut(entry, "@")
+	return Dependency{Path: path, Version: version}
+}
+
+func (d Dependency) String() string {
+	if d.Version == "" {
+		return d.Path
+	}
+	return d.Path + "@" + d.Version
+}
+
+// List returns the dependencies in the order they are written
+func (d DependencyConfig) List() []Dependency {
+	deps := make([]Dependency, 0, len(d.Modules))
+	for _, entry := range d.Modules {
+		deps = append(deps, ParseDependency(entry))
+	}
+	return deps
+}
+
+// Find returns the dependency on the repository at path
+func (d DependencyConfig) Find(path string) (Dependency, bool) {
+	for _, dep := range d.List() {
+		if dep.Path == path {
+			return dep, true
+		}
+	}
+	return Dependency{}, false
+}
+
 // CreateDefaultProjectConfig creates a default ferret.project.json configuration
 func CreateDefaultProjectConfig(projectRoot string) error {
+	absRoot, err := filepath.Abs(projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
 	config := &ProjectConfig{
+		Name: filepath.Base(absRoot),
 		Compiler: CompilerConfig{
-			Version: "0.1.0",
+			Version: COMPILER_VERSION,
 		},
 		Cache: CacheConfig{
-			Path: ".ferret/modules",
+			Path: DEFAULT_CACHE_PATH,
 		},
 		Remote: RemoteConfig{
 			Enabled: true,
@@ -76,18 +188,32 @@ func IsProjectRoot(dir string) bool {
 	return err == nil
 }
 
+// LoadProjectConfig loads the config of a project, and the workspace it is a member of
 func LoadProjectConfig(projectRoot string) (*ProjectConfig, error) {
+	config, err := loadProjectConfig(projectRoot)
+	if err != nil {
+		return nil, err
+	}
+	if config.Workspace, err = FindWorkspace(projectRoot); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func loadProjectConfig(projectRoot string) (*ProjectConfig, error) {
 	configPath := filepath.Join(projectRoot, CONFIG_FILE)
 	data, err := os.ReadFile(filepath.FromSlash(configPath))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	if err := ValidateProjectConfig(filepath.ToSlash(configPath), data); err != nil {
+		return nil, err
+	}
 	var config ProjectConfig
 	if err := json.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
-
 	config.ProjectRoot = projectRoot
 	return &config, nil
 }
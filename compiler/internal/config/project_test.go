@@ -0,0 +1,99 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportAlias(t *testing.T) {
+	cfg := &ProjectConfig{Imports: map[string]string{
+		"@lib/":      "vendor/lib/",
+		"@lib/core/": "src/core/",
+		"@utils":     "src/utils",
+		"@gfx/":      "github.com/user/gfx/",
+	}}
+	tests := []struct {
+		importPath string
+		want       string
+		wantOk     bool
+	}{
+		{"@lib/list", "vendor/lib/list", true},
+		{"@lib/core/vec", "src/core/vec", true},
+		{"@utils", "src/utils", true},
+		{"@utils/more", "", false},
+		{"@gfx/draw", "github.com/user/gfx/draw", true},
+		{"app/data", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.importPath, func(t *testing.T) {
+			got, ok := cfg.ImportAlias(tt.importPath)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("ImportAlias(%q) = %q, %v, want %q, %v", tt.importPath, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestProjectName(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+		want    string
+	}{
+		{"directory name", `{}`, "", "checkout"},
+		{"configured name", `{"name": "app"}`, "", "app"},
+		{"name with a slash", `{"name": "my/app"}`, "invalid name", ""},
+		{"empty alias target", `{"imports": {"@lib/": ""}}`, "neither can be empty", ""},
+		{"prefix alias to a path", `{"imports": {"@lib/": "vendor/lib"}}`, "a prefix alias ends in '/'", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := filepath.Join(t.TempDir(), "checkout")
+			os.MkdirAll(root, 0755)
+			os.WriteFile(filepath.Join(root, CONFIG_FILE), []byte(tt.config), 0644)
+			cfg, err := LoadProjectConfig(root)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadProjectConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cfg.ImportRoot(); got != tt.want {
+				t.Errorf("ImportRoot() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShares(t *testing.T) {
+	tests := []struct {
+		name    string
+		remote  RemoteConfig
+		module  string
+		private bool
+	}{
+		{"share all", RemoteConfig{Share: true}, "internal/core", false},
+		{"share nothing", RemoteConfig{}, "lib/util", true},
+		{"exported module", RemoteConfig{Exports: []string{"lib/util"}}, "lib/util", false},
+		{"exported prefix", RemoteConfig{Exports: []string{"lib/"}}, "lib/math/vec", false},
+		{"not exported", RemoteConfig{Share: true, Exports: []string{"lib/"}}, "internal/core", true},
+		{"whole path only", RemoteConfig{Exports: []string{"lib/util"}}, "lib/util/more", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &ProjectConfig{Remote: tt.remote}
+			if got := cfg.Shares(tt.module); got == tt.private {
+				t.Errorf("Shares(%q) = %v", tt.module, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"compiler/internal/remote"
+	"compiler/internal/semver"
+	"compiler/internal/source"
+)
+
+// ConfigError is a problem with a config file, at the line and column of the key or value it is about
+type ConfigError struct {
+	File         string
+	Line, Column int
+	Message      string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+}
+
+// ConfigErrors are all the problems found in a config file, in the order they appear
+type ConfigErrors []*ConfigError
+
+func (e ConfigErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+type valueKind int
+
+const (
+	kindObject valueKind = iota // the keys of fields
+	kindMap                     // any keys, every value is elem
+	kindArray                   // every element is elem
+	kindString
+	kindBool
+)
+
+// schema describes the values a config file accepts
+type schema struct {
+	kind   valueKind
+	fields map[string]*schema
+	elem   *schema
+	check  func(value string) error      // of a string
+	entry  func(key, value string) error // of an entry of a map of strings
+}
+
+func object(fields map[string]*schema) *schema { return &schema{kind: kindObject, fields: fields} }
+func mapOf(elem *schema) *schema               { return &schema{kind: kindMap, elem: elem} }
+func arrayOf(elem *schema) *schema             { return &schema{kind: kindArray, elem: elem} }
+func stringValue() *schema                     { return &schema{kind: kindString} }
+func boolValue() *schema                       { return &schema{kind: kindBool} }
+
+func (s *schema) withCheck(check func(string) error) *schema {
+	s.check = check
+	return s
+}
+
+func (s *schema) withEntry(entry func(key, value string) error) *schema {
+	s.entry = entry
+	return s
+}
+
+// projectSchema is the current schema of .ferret.json
+var projectSchema = object(map[string]*schema{
+	"name":    stringValue().withCheck(checkName),
+	"imports": mapOf(stringValue()).withEntry(checkImportAlias),
+	"compiler": object(map[string]*schema{
+		"version": stringValue().withCheck(CheckCompilerVersion),
+	}),
+	"cache": object(map[string]*schema{
+		"path": stringValue(),
+	}),
+	"remote": object(map[string]*schema{
+		"enabled": boolValue(),
+		"share":   boolValue(),
+		"exports": arrayOf(stringValue().withCheck(checkExport)),
+	}),
+	"dependencies": object(map[string]*schema{
+		"modules": arrayOf(stringValue().withCheck(checkDependency)),
+	}),
+})
+
+var workspaceSchema = object(map[string]*schema{
+	"members": arrayOf(stringValue()),
+	"cache": object(map[string]*schema{
+		"path": stringValue(),
+	}),
+})
+
+// legacyKeys were written by earlier versions of 'ferret init' and are removed by 'ferret config migrate'
+var legacyKeys = map[string]bool{
+	"ProjectRoot": true,
+}
+
+// ValidateProjectConfig checks the content of a .ferret.json against the schema, and its compiler.version
+// against this compiler. file is only used in the errors, which are ConfigErrors.
+func ValidateProjectConfig(file string, data []byte) error {
+	return validate(file, data, projectSchema)
+}
+
+func validate(file string, data []byte, root *schema) error {
+	v := &validator{s: &jsonScanner{data: data}, file: file}
+	if err := json.Unmarshal(data, new(any)); err != nil {
+		offset := len(data)
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) && syntaxErr.Offset > 0 {
+			offset = int(syntaxErr.Offset) - 1
+		}
+		v.report(offset, "%s", strings.TrimPrefix(err.Error(), "json: "))
+		return v.errs
+	}
+	v.value(v.s.ws(0), root, "")
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+// validator walks a syntactically valid JSON document alongside its schema
+type validator struct {
+	s    *jsonScanner
+	file string
+	errs ConfigErrors
+}
+
+func (v *validator) report(offset int, format string, args ...any) {
+	pos := position(v.s.data, offset)
+	v.errs = append(v.errs, &ConfigError{File: v.file, Line: pos.Line, Column: pos.Column, Message: fmt.Sprintf(format, args...)})
+}
+
+// position returns the line and column of a byte offset, columns count characters
+func position(data []byte, offset int) *source.Position {
+	offset = min(offset, len(data))
+	before := data[:offset]
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	return &source.Position{
+		Line:   bytes.Count(before, []byte("\n")) + 1,
+		Column: utf8.RuneCount(before[lineStart:]) + 1,
+		Index:  offset,
+	}
+}
+
+// Locate returns where the member at path is in a config file, from its key to the end of its value.
+// When it is missing that is the deepest part of path that exists, and the start of the file for none.
+func Locate(data []byte, path ...string) *source.Location {
+	s := &jsonScanner{data: data}
+	start, end := 0, 0
+	obj := s.ws(0)
+	for _, key := range path {
+		if obj >= len(data) || data[obj] != '{' {
+			break
+		}
+		m, err := s.member(obj, key)
+		if err != nil || !m.found {
+			break
+		}
+		start, end, obj = m.keyStart, m.valueEnd, m.valueStart
+	}
+	return source.NewLocation(position(data, start), position(data, end))
+}
+
+func (v *validator) value(i int, s *schema, path string) {
+	want := map[valueKind]string{kindObject: "an object", kindMap: "an object", kindArray: "an array", kindString: "a string", kindBool: "a boolean"}[s.kind]
+	if got := kindOf(v.s.data[i]); got != want {
+		if path == "" {
+			v.report(i, "expected %s, found %s", want, got)
+		} else {
+			v.report(i, "'%s' is %s, not %s", path, want, got)
+		}
+		return
+	}
+
+	switch s.kind {
+	case kindObject, kindMap:
+		seen := make(map[string]bool)
+		v.s.members(i, func(key string, keyStart, valueStart int) {
+			if seen[key] {
+				v.report(keyStart, "duplicate key '%s'", key)
+				return
+			}
+			seen[key] = true
+			field := s.elem
+			if s.kind == kindObject {
+				if field = s.fields[key]; field == nil {
+					v.report(keyStart, "%s", unknownKey(key, path, s))
+					return
+				}
+			}
+			v.value(valueStart, field, join(path, key))
+			if s.entry != nil && kindOf(v.s.data[valueStart]) == "a string" {
+				value, _, _ := v.s.str(valueStart)
+				if err := s.entry(key, value); err != nil {
+					v.report(keyStart, "%s", err)
+				}
+			}
+		})
+	case kindArray:
+		v.s.elements(i, func(index, start int) {
+			v.value(start, s.elem, fmt.Sprintf("%s[%d]", path, index))
+		})
+	case kindString:
+		if s.check != nil {
+			value, _, _ := v.s.str(i)
+			if err := s.check(value); err != nil {
+				v.report(i, "%s", err)
+			}
+		}
+	}
+}
+
+func unknownKey(key, path string, s *schema) string {
+	msg := fmt.Sprintf("unknown key '%s'", key)
+	if path != "" {
+		msg += fmt.Sprintf(" in '%s'", path)
+	}
+	if path == "" && legacyKeys[key] {
+		return msg + ", it is no longer used, run 'ferret config migrate'"
+	}
+	for field := range s.fields {
+		if strings.EqualFold(field, key) {
+			return msg + fmt.Sprintf(", did you mean '%s'?", field)
+		}
+	}
+	return msg
+}
+
+// kindOf names the type of the JSON value starting with c
+func kindOf(c byte) string {
+	switch c {
+	case '{':
+		return "an object"
+	case '[':
+		return "an array"
+	case '"':
+		return "a string"
+	case 't', 'f':
+		return "a boolean"
+	case 'n':
+		return "null"
+	}
+	return "a number"
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// checkName reports a name that cannot be the first part of import paths
+func checkName(name string) error {
+	if strings.ContainsAny(name, "/\\") || name == "." || name == ".." {
+		return fmt.Errorf("invalid name %q, it is the first part of import paths and cannot contain '/'", name)
+	}
+	return nil
+}
+
+// checkImportAlias reports an alias that cannot replace a part of an import path
+func checkImportAlias(alias, target string) error {
+	if alias == "" || target == "" {
+		return fmt.Errorf("invalid import alias %q: %q, neither can be empty", alias, target)
+	}
+	if strings.HasSuffix(alias, "/") != strings.HasSuffix(target, "/") {
+		return fmt.Errorf("invalid import alias %q: %q, a prefix alias ends in '/' and so does its target", alias, target)
+	}
+	return nil
+}
+
+// checkExport reports an entry of remote.exports that is not a module path inside the project
+func checkExport(export string) error {
+	inner := strings.TrimSuffix(export, "/")
+	if inner == "" || strings.HasPrefix(export, "/") || strings.Contains(export, "\\") || path.Clean(inner) != inner || strings.HasPrefix(inner, "..") {
+		return fmt.Errorf("invalid export %q, write the path of a module from the project root, like \"lib/util\", or \"lib/\" for every module below lib", export)
+	}
+	return nil
+}
+
+// checkDependency reports an entry of dependencies.modules that does not name a repository, or whose
+// version is neither a semver constraint nor a branch or tag
+func checkDependency(entry string) error {
+	dep := ParseDependency(entry)
+	if isLocalDependency(dep.Path) {
+		return fmt.Errorf("invalid dependency %q, it names a module of the project, which is imported without listing it, run 'ferret config migrate'", entry)
+	}
+	if _, err := remote.ParseRepo(dep.Path); err != nil || (strings.Contains(entry, "@") && dep.Version == "") {
+		return fmt.Errorf("invalid dependency %q, write it as github.com/user/repo or github.com/user/repo@version", entry)
+	}
+	if dep.Version == "" || semver.IsConstraint(dep.Version) {
+		return nil
+	}
+	if strings.ContainsAny(dep.Version[:1], "^~0123456789") || (dep.Version[0] == 'v' && len(dep.Version) > 1 && strings.ContainsAny(dep.Version[1:2], "0123456789")) {
+		return fmt.Errorf("invalid version %q in dependency %q, expected a constraint like ^1.2, ~1.2.3 or v1.2.0", dep.Version, entry)
+	}
+	if !isRefName(dep.Version) {
+		return fmt.Errorf("invalid version %q in dependency %q, expected a semver constraint or a branch or tag name", dep.Version, entry)
+	}
+	return nil
+}
+
+// isLocalDependency reports whether an entry of dependencies.modules is a path like "code/remote/graphics",
+// which earlier compilers accepted for modules of the project, instead of a repository on a host
+func isLocalDependency(path string) bool {
+	host, _, _ := strings.Cut(path, "/")
+	return host != "" && !strings.Contains(host, ".")
+}
+
+// isRefName reports whether a version can be a git branch or tag name
+func isRefName(ref string) bool {
+	if strings.HasPrefix(ref, "-") || strings.HasPrefix(ref, "/") || strings.HasSuffix(ref, "/") || strings.HasSuffix(ref, ".") ||
+		strings.HasSuffix(ref, ".lock") || strings.Contains(ref, "..") || strings.Contains(ref, "//") {
+		return false
+	}
+	for _, r := range ref {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("._-/", r)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateProjectConfig(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string // line:column: message of every error
+	}{
+		{"valid", `{
+  "name": "app",
+  "imports": { "@lib/": "src/lib/" },
+  "compiler": { "version": "0.1.0" },
+  "remote": { "enabled": true, "share": false },
+  "dependencies": { "modules": ["github.com/user/lib@^1.2"] }
+}`, nil},
+		{"empty object", `{}`, nil},
+		{"unknown key", `{
+  "compiler": {
+    "version": "0.1.0",
+    "target": "c"
+  }
+}`, []string{"4:5: unknown key 'target' in 'compiler'"}},
+		{"misspelled key", `{"Remote": {}}`, []string{"1:2: unknown key 'Remote', did you mean 'remote'?"}},
+		{"legacy key", `{"ProjectRoot": ""}`, []string{"1:2: unknown key 'ProjectRoot', it is no longer used, run 'ferret config migrate'"}},
+		{"wrong types", `{
+  "remote": { "enabled": "yes" },
+  "dependencies": { "modules": ["github.com/user/lib", 2] }
+}`, []string{
+			"2:26: 'remote.enabled' is a boolean, not a string",
+			"3:56: 'dependencies.modules[1]' is a string, not a number",
+		}},
+		{"not an object", `[]`, []string{"1:1: expected an object, found an array"}},
+		{"duplicate key", `{"name": "a", "name": "b"}`, []string{"1:15: duplicate key 'name'"}},
+		{"syntax error", "{\n  \"name\": \"app\",\n}", []string{"3:1: invalid character '}' looking for beginning of object key string"}},
+		{"invalid name", `{"name": "a/b"}`, []string{`1:10: invalid name "a/b", it is the first part of import paths and cannot contain '/'`}},
+		{"invalid alias", `{"imports": {"@lib/": "src"}}`, []string{`1:14: invalid import alias "@lib/": "src", a prefix alias ends in '/' and so does its target`}},
+		{"invalid dependency", `{"dependencies": {"modules": ["github.com/user/lib@"]}}`, []string{`1:31: invalid dependency "github.com/user/lib@", write it as github.com/user/repo or github.com/user/repo@version`}},
+		{"dependency without a repository", `{"dependencies": {"modules": ["github.com/user"]}}`, []string{
+			`1:31: invalid dependency "github.com/user", write it as github.com/user/repo or github.com/user/repo@version`,
+		}},
+		{"local module dependency", `{"dependencies": {"modules": ["code/remote/graphics"]}}`, []string{
+			`1:31: invalid dependency "code/remote/graphics", it names a module of the project, which is imported without listing it, run 'ferret config migrate'`,
+		}},
+		{"invalid dependency version", `{"dependencies": {"modules": ["github.com/user/lib@^1.x", "github.com/user/lib@my branch"]}}`, []string{
+			`1:31: invalid version "^1.x" in dependency "github.com/user/lib@^1.x", expected a constraint like ^1.2, ~1.2.3 or v1.2.0`,
+			`1:59: invalid version "my branch" in dependency "github.com/user/lib@my branch", expected a semver constraint or a branch or tag name`,
+		}},
+		{"dependency versions", `{"dependencies": {"modules": ["github.com/user/a@^1.2", "github.com/user/b@~0.3.1", "github.com/user/c@v1.2.0", "github.com/user/d@feature/x", "github.com/user/e"]}}`, nil},
+		{"invalid export", `{"remote": {"exports": ["lib/", "../other"]}}`, []string{`1:33: invalid export "../other", write the path of a module from the project root, like "lib/util", or "lib/" for every module below lib`}},
+		{"newer compiler", `{"compiler": {"version": "0.3.0"}}`, []string{"1:26: the project needs compiler ^0.3.0, this is 0.1.0, upgrade the compiler"}},
+		{"multi-byte column", `{"name": "é", "x": 1}`, []string{"1:15: unknown key 'x'"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateProjectConfig(CONFIG_FILE, []byte(tt.input))
+			var got []string
+			var errs ConfigErrors
+			if errors.As(err, &errs) {
+				for _, e := range errs {
+					got = append(got, strings.TrimPrefix(e.Error(), CONFIG_FILE+":"))
+				}
+			} else if err != nil {
+				t.Fatalf("expected ConfigErrors, got %v", err)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("ValidateProjectConfig() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestCheckCompilerVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		wantErr string
+	}{
+		{"", ""},
+		{"0.1.0", ""},
+		{"v0.1", ""},
+		{"~0.1.0", ""},
+		{"0.0.4", "is written for compiler 0.0.4, which is not compatible with 0.1.0"},
+		{"0.2.0", "needs compiler ^0.2.0"},
+		{"latest", "invalid compiler version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			err := CheckCompilerVersion(tt.version)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("CheckCompilerVersion(%q) error = %v", tt.version, err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("CheckCompilerVersion(%q) error = %v, want %q", tt.version, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadProjectConfigLocatesErrors(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, CONFIG_FILE), []byte("{\n  \"cache\": { \"path\": 3 }\n}"), 0644)
+
+	_, err := LoadProjectConfig(root)
+	want := filepath.ToSlash(filepath.Join(root, CONFIG_FILE)) + ":2:22: 'cache.path' is a string, not a number"
+	if err == nil || err.Error() != want {
+		t.Errorf("LoadProjectConfig() error = %v, want %s", err, want)
+	}
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+
+	"compiler/internal/semver"
+)
+
+// COMPILER_VERSION is the version of this compiler, the one 'ferret init' writes to compiler.version
+const COMPILER_VERSION = "0.1.0"
+
+// compilerRange is the range of compilers a project's compiler.version accepts. A version accepts the
+// releases compatible with it, as a ^ constraint does: "0.1.0" accepts 0.1.x, "1.2.0" every 1.x from 1.2.0 up.
+func compilerRange(version string) (semver.Constraint, error) {
+	text := version
+	if _, err := semver.Parse(version); err == nil {
+		text = "^" + version
+	}
+	constraint, err := semver.ParseConstraint(text)
+	if err != nil {
+		return semver.Constraint{}, fmt.Errorf("invalid compiler version %q, write the version the project is written for, like \"%s\"", version, COMPILER_VERSION)
+	}
+	return constraint, nil
+}
+
+// CheckCompilerVersion reports whether a project whose compiler.version is version compiles with
+// this compiler. An empty version is not checked, 'ferret config migrate' sets it.
+func CheckCompilerVersion(version string) error {
+	if version == "" {
+		return nil
+	}
+	constraint, err := compilerRange(version)
+	if err != nil {
+		return err
+	}
+	current, _ := semver.Parse(COMPILER_VERSION)
+	switch {
+	case constraint.Allows(current):
+		return nil
+	case current.Compare(constraint.Min) < 0:
+		return fmt.Errorf("the project needs compiler %s, this is %s, upgrade the compiler", constraint, COMPILER_VERSION)
+	default:
+		return fmt.Errorf("the project is written for compiler %s, which is not compatible with %s, run 'ferret config migrate'", version, COMPILER_VERSION)
+	}
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// WORKSPACE_FILE lists the projects of a repository that are built together
+const WORKSPACE_FILE = "ferret.workspace.json"
+
+// WorkspaceConfig is a ferret.workspace.json. Its members import each other's modules by project name,
+// without remote paths, and share one module cache.
+type WorkspaceConfig struct {
+	Members []string    `json:"members"` // project roots, relative to the workspace root
+	Cache   CacheConfig `json:"cache"`
+	Root    string      `json:"-"`
+}
+
+// LoadWorkspace reads the workspace file in root, every member has to be a project
+func LoadWorkspace(root string) (*WorkspaceConfig, error) {
+	path := filepath.Join(filepath.FromSlash(root), WORKSPACE_FILE)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workspace file: %w", err)
+	}
+	if err := validate(filepath.ToSlash(path), data, workspaceSchema); err != nil {
+		return nil, err
+	}
+	var workspace WorkspaceConfig
+	if err := json.Unmarshal(data, &workspace); err != nil {
+		return nil, fmt.Errorf("invalid %s in %s: %w", WORKSPACE_FILE, root, err)
+	}
+	workspace.Root = filepath.ToSlash(root)
+
+	for _, member := range workspace.Members {
+		if member == "" || filepath.IsAbs(member) || strings.HasPrefix(filepath.ToSlash(filepath.Clean(member)), "../") {
+			return nil, fmt.Errorf("invalid member %q in %s, members are directories inside the workspace", member, WORKSPACE_FILE)
+		}
+	}
+	for _, member := range workspace.MemberRoots() {
+		if !IsProjectRoot(member) {
+			return nil, fmt.Errorf("member %s of %s has no %s", member, WORKSPACE_FILE, CONFIG_FILE)
+		}
+	}
+	return &workspace, nil
+}
+
+// FindWorkspace returns the workspace a project is a member of, looking in the project root and the
+// directories above it. It returns nil when no workspace file up the tree lists the project.
+func FindWorkspace(projectRoot string) (*WorkspaceConfig, error) {
+	root, err := filepath.Abs(filepath.FromSlash(projectRoot))
+	if err != nil {
+		return nil, err
+	}
+	for dir := root; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, WORKSPACE_FILE)); err == nil {
+			workspace, err := LoadWorkspace(filepath.ToSlash(dir))
+			if err != nil {
+				return nil, err
+			}
+			if slices.Contains(workspace.MemberRoots(), filepath.ToSlash(root)) {
+				return workspace, nil
+			}
+		}
+		if filepath.Dir(dir) == dir {
+			return nil, nil
+		}
+	}
+}
+
+// FindWorkspaceRoot returns the nearest directory from dir up that has a workspace file
+func FindWorkspaceRoot(dir string) (string, error) {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, WORKSPACE_FILE)); err == nil {
+			return filepath.ToSlash(dir), nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("%s not found", WORKSPACE_FILE)
+		}
+		dir = parent
+	}
+}
+
+// MemberRoots returns the absolute, slash separated roots of the members in the order they are listed
+func (w *WorkspaceConfig) MemberRoots() []string {
+	roots := make([]string, 0, len(w.Members))
+	for _, member := range w.Members {
+		root, err := filepath.Abs(filepath.Join(filepath.FromSlash(w.Root), filepath.FromSlash(member)))
+		if err != nil {
+			root = filepath.Join(w.Root, member)
+		}
+		roots = append(roots, filepath.ToSlash(root))
+	}
+	return roots
+}
+
+// LoadMembers loads the config of every member. Members are imported by their import root,
+// so two members cannot share one.
+func (w *WorkspaceConfig) LoadMembers() ([]*ProjectConfig, error) {
+	members := make([]*ProjectConfig, 0, len(w.Members))
+	byName := make(map[string]*ProjectConfig)
+	for _, root := range w.MemberRoots() {
+		member, err := loadProjectConfig(root)
+		if err != nil {
+			return nil, err
+		}
+		member.Workspace = w
+		if other, found := byName[member.ImportRoot()]; found {
+			return nil, fmt.Errorf("members %s and %s of %s are both imported as '%s', give one of them another name in %s",
+				other.ProjectRoot, member.ProjectRoot, WORKSPACE_FILE, member.ImportRoot(), CONFIG_FILE)
+		}
+		byName[member.ImportRoot()] = member
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// CachePath returns the absolute, slash separated module cache directory every member uses
+func (w *WorkspaceConfig) CachePath() string {
+	path := w.Cache.Path
+	if path == "" {
+		path = DEFAULT_CACHE_PATH
+	}
+	if filepath.IsAbs(path) {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(filepath.Join(w.Root, path))
+}
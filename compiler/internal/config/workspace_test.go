@@ -0,0 +1,79 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// createWorkspace writes a workspace file, and a .ferret.json with the given content in each directory of configs
+func createWorkspace(t *testing.T, workspace string, configs map[string]string) string {
+	t.Helper()
+	root := filepath.ToSlash(t.TempDir())
+	os.WriteFile(filepath.Join(root, WORKSPACE_FILE), []byte(workspace), 0644)
+	for dir, content := range configs {
+		os.MkdirAll(filepath.Join(root, dir), 0755)
+		os.WriteFile(filepath.Join(root, dir, CONFIG_FILE), []byte(content), 0644)
+	}
+	return root
+}
+
+func TestWorkspace(t *testing.T) {
+	root := createWorkspace(t, `{"members": ["app", "libs/geo"]}`, map[string]string{
+		"app":      `{"cache": {"path": "own-cache"}}`,
+		"libs/geo": `{"name": "geometry"}`,
+		"other":    `{}`,
+	})
+
+	app, err := LoadProjectConfig(root + "/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.Workspace == nil || app.Workspace.Root != root {
+		t.Fatalf("expected app to be a member of the workspace, got %+v", app.Workspace)
+	}
+	if got := app.CachePath(); got != root+"/"+DEFAULT_CACHE_PATH {
+		t.Errorf("CachePath() = %q, want the workspace cache", got)
+	}
+
+	other, err := LoadProjectConfig(root + "/other")
+	if err != nil || other.Workspace != nil {
+		t.Errorf("expected a project the workspace does not list to be on its own, got %+v, %v", other.Workspace, err)
+	}
+
+	members, err := app.Workspace.LoadMembers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 || members[0].ImportRoot() != "app" || members[1].ImportRoot() != "geometry" {
+		t.Errorf("LoadMembers() = %v", members)
+	}
+}
+
+func TestWorkspaceErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		workspace string
+		configs   map[string]string
+		wantErr   string
+	}{
+		{"member outside", `{"members": ["../app"]}`, nil, "members are directories inside the workspace"},
+		{"member without config", `{"members": ["app"]}`, map[string]string{"lib": `{}`}, "has no .ferret.json"},
+		{"same import root", `{"members": ["a/lib", "b/lib"]}`, map[string]string{"a/lib": `{}`, "b/lib": `{}`}, "are both imported as 'lib'"},
+		{"invalid file", `{"members": "app"}`, nil, "ferret.workspace.json:1:13: 'members' is an array, not a string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := createWorkspace(t, tt.workspace, tt.configs)
+			workspace, err := LoadWorkspace(root)
+			if err == nil {
+				_, err = workspace.LoadMembers()
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
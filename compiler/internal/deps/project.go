@@ -0,0 +1,289 @@
+package deps
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"compiler/internal/cache"
+	"compiler/internal/config"
+	"compiler/internal/lockfile"
+	"compiler/internal/remote"
+)
+
+// Project is a project whose dependencies the ferret add, remove, update and deps commands change
+type Project struct {
+	Config *config.ProjectConfig
+	Store  *Store
+}
+
+// Update is what 'ferret update' did to a repository of the dependency graph
+type Update struct {
+	Dependency config.Dependency
+	Version    string // the version selected for it
+	Files      int    // files fetched again
+	Changed    int    // files whose content or version is not the one pinned before
+}
+
+// OpenProject opens the cache and the lockfile of a project
+func OpenProject(project *config.ProjectConfig, fetcher remote.Fetcher) (*Project, error) {
+	moduleCache, err := cache.Open(project.CachePath())
+	if err != nil {
+		return nil, err
+	}
+	lock, err := lockfile.Load(project.ProjectRoot)
+	if err != nil {
+		return nil, err
+	}
+	return &Project{Config: project, Store: &Store{Cache: moduleCache, Fetcher: fetcher, Lock: lock}}, nil
+}
+
+// Add makes the project depend on a repository, "github.com/user/repo[@version]". A module path inside
+// the repository works too. Without a version the dependency asks for the latest release and the ones
+// compatible with it, or follows the default branch when the repository has no releases.
+// Versions are selected again, so a version no release matches, or one conflicting with another
+// dependency, fails here and leaves .ferret.json unchanged.
+func (p *Project) Add(spec string) (config.Dependency, error) {
+	dep := config.ParseDependency(spec)
+	repo, err := remote.ParseRepo(dep.Path)
+	if err != nil {
+		return dep, err
+	}
+	dep.Path = repo.RepoPath()
+
+	if dep.Version == "" {
+		latest, ok, err := p.Store.Latest(repo)
+		if err != nil && !errors.Is(err, remote.ErrNotFound) {
+			return dep, err
+		}
+		if ok {
+			dep.Version = "^" + strings.TrimPrefix(latest.String(), "v")
+		}
+	}
+
+	modules := slices.Clone(p.Config.Dependencies.Modules)
+	if i := p.index(dep.Path); i >= 0 {
+		modules[i] = dep.String()
+	} else {
+		modules = append(modules, dep.String())
+	}
+	selection, err := p.Store.Select(p.name(), config.DependencyConfig{Modules: modules}.List(), true)
+	if err != nil {
+		return dep, err
+	}
+	// Changing the version of a dependency pins its files again at the new version, it is not drift
+	if _, err := p.apply(selection, []string{dep.Path}); err != nil {
+		return dep, err
+	}
+	return dep, p.save(modules)
+}
+
+// Remove drops the dependency on a repository, and from the lockfile the files of every repository
+// that is no longer part of the dependency graph
+func (p *Project) Remove(path string) error {
+	repo, err := remote.ParseRepo(path)
+	if err != nil {
+		return err
+	}
+	i := p.index(repo.RepoPath())
+	if i < 0 {
+		return fmt.Errorf("%s is not a dependency", repo.RepoPath())
+	}
+	modules := slices.Delete(slices.Clone(p.Config.Dependencies.Modules), i, i+1)
+
+	for _, entry := range p.pinned(repo) {
+		p.Store.Lock.Remove(entry.Path)
+	}
+	// Indirect dependencies are only dropped when the rest of the graph can be selected, e.g. offline
+	// with configs missing from the cache they stay pinned until the next update
+	if selection, err := p.Store.Select(p.name(), config.DependencyConfig{Modules: modules}.List(), true); err == nil {
+		for _, entry := range p.Store.Lock.Entries() {
+			if _, used := selection.Versions[entry.Source]; !used {
+				p.Store.Lock.Remove(entry.Path)
+			}
+		}
+	}
+	return p.save(modules)
+}
+
+// Update selects the versions of the dependency graph again, ignoring the versions ferret.lock pins,
+// and fetches the pinned files of the named repositories again, or of all of them, to pin the new content.
+// A repository whose selected version moved is fetched again either way.
+func (p *Project) Update(paths ...string) ([]Update, error) {
+	var named []string
+	for _, path := range paths {
+		repo, err := remote.ParseRepo(path)
+		if err != nil {
+			return nil, err
+		}
+		if p.index(repo.RepoPath()) < 0 {
+			return nil, fmt.Errorf("%s is not a dependency", repo.RepoPath())
+		}
+		named = append(named, repo.RepoPath())
+	}
+
+	selection, err := p.Store.Select(p.name(), p.Config.Dependencies.List(), false)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		named = slices.Collect(maps.Keys(selection.Versions))
+	}
+	updates, err := p.apply(selection, named)
+	if err != nil {
+		return nil, err
+	}
+	return updates, p.Store.Lock.Save()
+}
+
+// apply refreshes the repositories of a selection whose pinned version is not the selected one,
+// and the forced ones, in the order of their paths
+func (p *Project) apply(selection *Selection, force []string) ([]Update, error) {
+	repos := slices.Sorted(maps.Keys(selection.Versions))
+	var updates []Update
+	for _, path := range repos {
+		repo, err := remote.ParseRepo(path)
+		if err != nil {
+			return nil, err
+		}
+		version := selection.Versions[path]
+		pinned, ok := p.Store.Lock.Get(LockPath(repo, config.CONFIG_FILE))
+		if ok && pinned.Version == version && !slices.Contains(force, path) {
+			continue
+		}
+
+		dep, direct := p.Config.Dependencies.Find(path)
+		if !direct {
+			dep = config.Dependency{Path: path}
+		}
+		update, err := p.refresh(dep, repo, version)
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, update)
+	}
+	return updates, nil
+}
+
+// refresh fetches the config and the pinned files of a repository at a version, and pins them
+func (p *Project) refresh(dep config.Dependency, repo remote.Module, version string) (Update, error) {
+	files := []string{config.CONFIG_FILE}
+	for _, entry := range p.pinned(repo) {
+		if file := entry.Path[len(repo.RepoPath())+1:]; file != config.CONFIG_FILE {
+			files = append(files, file)
+		}
+	}
+
+	update := Update{Dependency: dep, Version: version}
+	for _, file := range files {
+		var check func([]byte) error
+		if file == config.CONFIG_FILE {
+			check = CheckConfig(repo)
+		}
+		changed, err := p.Store.Refresh(repo, version, file, check)
+		if err != nil {
+			return update, p.fetchError(repo, version, err)
+		}
+		update.Files++
+		if changed {
+			update.Changed++
+		}
+	}
+	return update, nil
+}
+
+// Tree writes the dependencies as a tree, with the dependencies of every dependency below it.
+// A constraint is followed by the version selected for it.
+func (p *Project) Tree(w io.Writer) error {
+	selection, err := p.Store.Select(p.name(), p.Config.Dependencies.List(), true)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, p.name())
+	if err := p.tree(w, selection, p.Config.Dependencies.List(), "", []string{}); err != nil {
+		return err
+	}
+	// The configs of indirect dependencies are pinned on the way
+	return p.Store.Lock.Save()
+}
+
+func (p *Project) tree(w io.Writer, selection *Selection, list []config.Dependency, indent string, path []string) error {
+	for i, dep := range list {
+		branch, next := "├── ", "│   "
+		if i == len(list)-1 {
+			branch, next = "└── ", "    "
+		}
+		repo, err := remote.ParseRepo(dep.Path)
+		if err != nil {
+			return err
+		}
+		version := selection.Version(repo)
+		line := dep.String()
+		if dep.Version != "" && dep.Version != version {
+			line += " (" + version + ")"
+		}
+		if slices.Contains(path, repo.RepoPath()) {
+			fmt.Fprintf(w, "%s%s%s (cycle)\n", indent, branch, line)
+			continue
+		}
+		fmt.Fprintf(w, "%s%s%s\n", indent, branch, line)
+
+		configPath, err := p.Store.Config(repo, version)
+		if err != nil {
+			return err
+		}
+		depConfig, err := config.LoadProjectConfig(filepath.Dir(configPath))
+		if err != nil {
+			return err
+		}
+		if err := p.tree(w, selection, depConfig.Dependencies.List(), indent+next, append(path, repo.RepoPath())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// name is how the dependency graph calls the project
+func (p *Project) name() string {
+	return p.Config.ImportRoot()
+}
+
+// pinned returns the lockfile entries of a repository
+func (p *Project) pinned(repo remote.Module) []lockfile.Entry {
+	var entries []lockfile.Entry
+	for _, entry := range p.Store.Lock.Entries() {
+		if entry.Source == repo.RepoPath() {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func (p *Project) index(path string) int {
+	for i, dep := range p.Config.Dependencies.List() {
+		if dep.Path == path {
+			return i
+		}
+	}
+	return -1
+}
+
+// save writes the dependencies into .ferret.json and the lockfile
+func (p *Project) save(modules []string) error {
+	if err := config.UpdateProjectConfig(p.Config.ProjectRoot, []string{"dependencies", "modules"}, modules); err != nil {
+		return err
+	}
+	p.Config.Dependencies.Modules = modules
+	return p.Store.Lock.Save()
+}
+
+func (p *Project) fetchError(repo remote.Module, version string, err error) error {
+	if errors.Is(err, remote.ErrNotFound) {
+		return fmt.Errorf("%s has no %s at %s", repo.RepoPath(), config.CONFIG_FILE, version)
+	}
+	return err
+}
@@ -0,0 +1,160 @@
+package deps
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"compiler/internal/config"
+	"compiler/internal/remote"
+)
+
+// mapFetcher serves files keyed by "user/repo/ref/file"
+type mapFetcher map[string]string
+
+func (f mapFetcher) Fetch(repo, ref, file string) ([]byte, error) {
+	content, ok := f[repo+"/"+ref+"/"+file]
+	if !ok {
+		return nil, remote.ErrNotFound
+	}
+	return []byte(content), nil
+}
+
+const projectConfig = `{
+    "compiler": { "version": "0.1.0" },
+    "remote": { "enabled": true },
+    "dependencies": {}
+}
+`
+
+func openTestProject(t *testing.T, fetcher mapFetcher) *Project {
+	t.Helper()
+	root := filepath.ToSlash(filepath.Join(t.TempDir(), "app"))
+	os.MkdirAll(root, 0755)
+	if err := os.WriteFile(filepath.Join(root, config.CONFIG_FILE), []byte(projectConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadProjectConfig(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	project, err := OpenProject(cfg, fetcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return project
+}
+
+func TestAddAndRemove(t *testing.T) {
+	fetcher := mapFetcher{
+		"user/lib/v1/.ferret.json": `{}`,
+		"user/lib/v1/util.fer":     "fn util() {}",
+		"user/lib/v2/.ferret.json": `{}`,
+		"user/lib/v2/util.fer":     "fn util() { two(); }",
+	}
+	project := openTestProject(t, fetcher)
+
+	if _, err := project.Add("github.com/user/lib/util@v1"); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(project.Config.ProjectRoot, config.CONFIG_FILE))
+	if !strings.Contains(string(data), `"compiler": { "version": "0.1.0" },`) || !strings.Contains(string(data), `"github.com/user/lib@v1"`) {
+		t.Errorf("unexpected config after add:\n%s", data)
+	}
+
+	// A compilation pins the module it imports
+	repo, _ := remote.ParseRepo("github.com/user/lib")
+	if _, err := project.Store.File(repo, "v1", "util.fer", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := project.Add("github.com/user/lib@v2"); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if got := project.Config.Dependencies.Modules; len(got) != 1 || got[0] != "github.com/user/lib@v2" {
+		t.Errorf("expected the dependency to move to v2, got %v", got)
+	}
+	if entry, _ := project.Store.Lock.Get("github.com/user/lib/util.fer"); entry.Version != "v2" {
+		t.Errorf("expected the pinned module to move to v2, got %+v", entry)
+	}
+
+	if _, err := project.Add("github.com/user/missing"); err == nil || !strings.Contains(err.Error(), "has no .ferret.json at main") {
+		t.Errorf("expected a missing repository to fail, got %v", err)
+	}
+
+	if err := project.Remove("github.com/user/lib"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if len(project.Config.Dependencies.Modules) != 0 || len(project.Store.Lock.Entries()) != 0 {
+		t.Errorf("expected no dependencies and pins, got %v, %v", project.Config.Dependencies.Modules, project.Store.Lock.Entries())
+	}
+	if err := project.Remove("github.com/user/lib"); err == nil {
+		t.Error("expected removing a missing dependency to fail")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	fetcher := mapFetcher{
+		"user/lib/main/.ferret.json": `{}`,
+		"user/lib/main/util.fer":     "fn util() {}",
+	}
+	project := openTestProject(t, fetcher)
+	if _, err := project.Add("github.com/user/lib"); err != nil {
+		t.Fatal(err)
+	}
+	repo, _ := remote.ParseRepo("github.com/user/lib")
+	if _, err := project.Store.File(repo, "main", "util.fer", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	fetcher["user/lib/main/util.fer"] = "fn util() { changed(); }"
+	updates, err := project.Update()
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if len(updates) != 1 || updates[0].Files != 2 || updates[0].Changed != 1 {
+		t.Errorf("Update() = %+v, want 2 files with 1 changed", updates)
+	}
+
+	// The next compilation accepts the new content
+	project.Store.Locked = true
+	if _, err := project.Store.File(repo, "main", "util.fer", nil); err != nil {
+		t.Errorf("expected the updated module to match the lockfile, got %v", err)
+	}
+
+	if _, err := project.Update("github.com/user/other"); err == nil {
+		t.Error("expected updating a missing dependency to fail")
+	}
+}
+
+func TestTree(t *testing.T) {
+	fetcher := mapFetcher{
+		"user/lib/main/.ferret.json":   `{"dependencies": {"modules": ["github.com/user/base@v1", "github.com/user/lib"]}}`,
+		"user/base/v1/.ferret.json":    `{}`,
+		"user/tools/main/.ferret.json": `{}`,
+	}
+	project := openTestProject(t, fetcher)
+	for _, spec := range []string{"github.com/user/lib", "github.com/user/tools"} {
+		if _, err := project.Add(spec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out strings.Builder
+	if err := project.Tree(&out); err != nil {
+		t.Fatalf("Tree() error = %v", err)
+	}
+	want := `app
+├── github.com/user/lib
+│   ├── github.com/user/base@v1
+│   └── github.com/user/lib (cycle)
+└── github.com/user/tools
+`
+	if out.String() != want {
+		t.Errorf("Tree() =\n%s\nwant\n%s", out.String(), want)
+	}
+	if _, ok := project.Store.Lock.Get("github.com/user/base/.ferret.json"); !ok {
+		t.Error("expected the config of an indirect dependency to be pinned")
+	}
+}
@@ -0,0 +1,281 @@
+package deps
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"compiler/internal/config"
+	"compiler/internal/remote"
+	"compiler/internal/semver"
+)
+
+// Requirement is the version a project, or one of its dependencies, asks a repository to be at
+type Requirement struct {
+	Repo    string   // github.com/user/repo
+	Version string   // as written in dependencies.modules: a constraint, a branch, or empty for the default branch
+	Chain   []string // who requires it, from the project down: app, github.com/user/lib@v1.1.0
+}
+
+func (r Requirement) String() string {
+	version := r.Version
+	if version == "" {
+		version = remote.DefaultBranch
+	}
+	return fmt.Sprintf("%s required by %s", version, strings.Join(r.Chain, " -> "))
+}
+
+// Selection is the version every repository of a dependency graph is fetched at
+type Selection struct {
+	Versions map[string]string // repository path -> tag or branch
+}
+
+// Version returns the version a repository is fetched at, its default branch when nothing depends on it
+func (s *Selection) Version(repo remote.Module) string {
+	if s != nil {
+		if version, ok := s.Versions[repo.RepoPath()]; ok {
+			return version
+		}
+	}
+	return remote.DefaultBranch
+}
+
+// Root is a project a selection starts from, with the dependencies its .ferret.json lists
+type Root struct {
+	Name         string
+	Dependencies []config.Dependency
+}
+
+// requirer is a repository at a version whose dependencies are requirements, a root project has no repository
+type requirer struct {
+	repo, version string
+	chain         []string
+	root          []config.Dependency
+}
+
+type required struct {
+	Requirement
+	by requirer
+}
+
+// Select picks a version for every repository the project depends on, directly or through other
+// dependencies, by minimal version selection: every requirement asks for the lowest version it allows,
+// and a repository is fetched at the highest of those. The selection only changes when a requirement
+// does, not when a repository tags a new release. With useLock, a version pinned in ferret.lock is
+// used when the requirement allows it, so a locked project selects without listing tags.
+// Requirements that the selected versions do not satisfy are a conflict, reported with who made them.
+func (s *Store) Select(name string, dependencies []config.Dependency, useLock bool) (*Selection, error) {
+	return s.SelectAll([]Root{{Name: name, Dependencies: dependencies}}, useLock)
+}
+
+// SelectAll is Select for several projects built together, the members of a workspace,
+// so a repository is at the same version for all of them
+func (s *Store) SelectAll(roots []Root, useLock bool) (*Selection, error) {
+	selection := &Selection{Versions: make(map[string]string)}
+	var all []required
+	visited := make(map[string]bool) // repository@version
+
+	var queue []requirer
+	for _, root := range roots {
+		queue = append(queue, requirer{chain: []string{root.Name}, root: root.Dependencies})
+	}
+	for len(queue) > 0 {
+		by := queue[0]
+		queue = queue[1:]
+		list := by.root
+		if by.repo != "" {
+			var err error
+			if list, err = s.dependencies(by.repo, by.version); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, dep := range list {
+			repo, err := remote.ParseRepo(dep.Path)
+			if err != nil {
+				return nil, fmt.Errorf("invalid dependency %s of %s: %w", dep.Path, by.chain[len(by.chain)-1], err)
+			}
+			req := Requirement{Repo: repo.RepoPath(), Version: dep.Version, Chain: by.chain}
+			version, err := s.minimal(repo, req, useLock)
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, required{req, by})
+
+			if current, ok := selection.Versions[req.Repo]; !ok || higher(version, current) {
+				selection.Versions[req.Repo] = version
+			}
+			if node := req.Repo + "@" + version; !visited[node] {
+				visited[node] = true
+				queue = append(queue, requirer{repo: req.Repo, version: version, chain: append(slices.Clone(by.chain), node)})
+			}
+		}
+	}
+	return selection, selection.check(all)
+}
+
+// check reports the first repository whose selected version does not satisfy a requirement on it.
+// Only requirements of the selected versions count, a version that was raised no longer requires anything.
+func (s *Selection) check(all []required) error {
+	var active []Requirement
+	for _, r := range all {
+		if r.by.repo == "" || s.Versions[r.by.repo] == r.by.version {
+			active = append(active, r.Requirement)
+		}
+	}
+	for _, req := range active {
+		selected := s.Versions[req.Repo]
+		if satisfies(req, selected) {
+			continue
+		}
+		var lines []string
+		for _, other := range active {
+			if other.Repo == req.Repo {
+				lines = append(lines, "    "+other.String())
+			}
+		}
+		return fmt.Errorf("conflicting versions of %s, %s was selected:\n%s", req.Repo, selected, strings.Join(lines, "\n"))
+	}
+	return nil
+}
+
+// minimal returns the lowest version a requirement allows. A branch or other ref that is not a
+// semantic version is only allowed as written.
+func (s *Store) minimal(repo remote.Module, req Requirement, useLock bool) (string, error) {
+	if req.Version == "" {
+		return remote.DefaultBranch, nil
+	}
+	constraint, err := semver.ParseConstraint(req.Version)
+	if err != nil {
+		return req.Version, nil
+	}
+	if useLock {
+		if entry, ok := s.Lock.Get(LockPath(repo, config.CONFIG_FILE)); ok {
+			if pinned, err := semver.Parse(entry.Version); err == nil && constraint.Allows(pinned) {
+				return entry.Version, nil
+			}
+		}
+	}
+
+	versions, err := s.versions(repo)
+	if errors.Is(err, errNoTags) && constraint.Max == nil {
+		// An exact version can be fetched as written without knowing the other tags
+		return req.Version, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot list the versions of %s for %s: %w", req.Repo, req, err)
+	}
+	for _, version := range versions {
+		if constraint.Allows(version) {
+			return version.Tag, nil
+		}
+	}
+
+	available := "it has no version tags"
+	if len(versions) > 0 {
+		tags := make([]string, len(versions))
+		for i, version := range versions {
+			tags[i] = version.Tag
+		}
+		available = "available: " + strings.Join(tags, ", ")
+	}
+	return "", fmt.Errorf("no version of %s matches %s, %s", req.Repo, req, available)
+}
+
+var errNoTags = errors.New("the fetcher cannot list tags")
+
+// versions lists the semantic version tags of a repository once per store
+func (s *Store) versions(repo remote.Module) ([]semver.Version, error) {
+	if versions, ok := s.tags[repo.RepoPath()]; ok {
+		return versions, nil
+	}
+	lister, ok := s.Fetcher.(remote.VersionLister)
+	if !ok {
+		return nil, errNoTags
+	}
+	tags, err := lister.Tags(repo.RepoName())
+	if err != nil {
+		return nil, err
+	}
+	if s.tags == nil {
+		s.tags = make(map[string][]semver.Version)
+	}
+	s.tags[repo.RepoPath()] = semver.ParseTags(tags)
+	return s.tags[repo.RepoPath()], nil
+}
+
+// Latest returns the highest release of a repository, false when it has no semantic version tags
+func (s *Store) Latest(repo remote.Module) (semver.Version, bool, error) {
+	versions, err := s.versions(repo)
+	if errors.Is(err, errNoTags) {
+		return semver.Version{}, false, nil
+	}
+	if err != nil {
+		return semver.Version{}, false, err
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].Pre == "" {
+			return versions[i], true, nil
+		}
+	}
+	return semver.Version{}, false, nil
+}
+
+// dependencies reads the dependencies of a repository at a version from its cached .ferret.json.
+// Selection does not pin the configs it reads, only the versions it selects are pinned when they are used.
+func (s *Store) dependencies(repoPath, version string) ([]config.Dependency, error) {
+	repo, err := remote.ParseRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	key := CacheKey(repo, version, config.CONFIG_FILE)
+	path, found, err := s.Cache.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	if found {
+		data, err = os.ReadFile(filepath.FromSlash(path))
+	} else {
+		data, err = s.fetch(repo, version, config.CONFIG_FILE, CheckConfig(repo))
+		if err == nil {
+			_, err = s.Cache.Put(key, data)
+		}
+	}
+	if errors.Is(err, remote.ErrNotFound) {
+		return nil, fmt.Errorf("%s is not a Ferret project, it has no %s at %s", repo.RepoPath(), config.CONFIG_FILE, version)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg config.ProjectConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid %s in %s: %w", config.CONFIG_FILE, repo.RepoPath(), err)
+	}
+	return cfg.Dependencies.List(), nil
+}
+
+// higher reports whether version a sorts after b, branches are not ordered
+func higher(a, b string) bool {
+	va, errA := semver.Parse(a)
+	vb, errB := semver.Parse(b)
+	return errA == nil && errB == nil && va.Compare(vb) > 0
+}
+
+// satisfies reports whether the selected version is one the requirement allows
+func satisfies(req Requirement, selected string) bool {
+	switch {
+	case req.Version == "":
+		return selected == remote.DefaultBranch
+	case !semver.IsConstraint(req.Version):
+		return selected == req.Version
+	}
+	constraint, _ := semver.ParseConstraint(req.Version)
+	version, err := semver.Parse(selected)
+	return err == nil && constraint.Allows(version)
+}
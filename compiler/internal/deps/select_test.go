@@ -0,0 +1,198 @@
+package deps
+
+import (
+	"strings"
+	"testing"
+
+	"compiler/internal/config"
+	"compiler/internal/lockfile"
+	"compiler/internal/remote"
+)
+
+// taggedFetcher also lists the tags of its repositories, keyed by "user/repo"
+type taggedFetcher struct {
+	mapFetcher
+	tags map[string][]string
+}
+
+func (f taggedFetcher) Tags(repo string) ([]string, error) {
+	tags, ok := f.tags[repo]
+	if !ok {
+		return nil, remote.ErrNotFound
+	}
+	return tags, nil
+}
+
+func graphFetcher() taggedFetcher {
+	return taggedFetcher{
+		mapFetcher: mapFetcher{
+			"user/lib/v1.0.0/.ferret.json":   `{"dependencies": {"modules": ["github.com/user/base@^1.0"]}}`,
+			"user/lib/v1.1.0/.ferret.json":   `{"dependencies": {"modules": ["github.com/user/base@^1.2"]}}`,
+			"user/tools/v1.0.0/.ferret.json": `{"dependencies": {"modules": ["github.com/user/base@~1.1"]}}`,
+			"user/tools/v2.0.0/.ferret.json": `{"dependencies": {"modules": ["github.com/user/base@^2.0"]}}`,
+			"user/base/main/.ferret.json":    `{}`,
+			"user/base/v1.0.0/.ferret.json":  `{}`,
+			"user/base/v1.1.0/.ferret.json":  `{}`,
+			"user/base/v1.2.0/.ferret.json":  `{}`,
+			"user/base/v2.0.0/.ferret.json":  `{}`,
+		},
+		tags: map[string][]string{
+			"user/lib":   {"v1.1.0", "v1.0.0"},
+			"user/tools": {"v2.0.0", "v1.0.0"},
+			"user/base":  {"v2.0.0", "v1.2.0", "v1.1.0", "v1.0.0", "v1.3.0-rc.1"},
+		},
+	}
+}
+
+func testStore(t *testing.T, fetcher remote.Fetcher) *Store {
+	t.Helper()
+	project := openTestProject(t, mapFetcher{})
+	project.Store.Fetcher = fetcher
+	return project.Store
+}
+
+func dependencies(modules ...string) []config.Dependency {
+	return config.DependencyConfig{Modules: modules}.List()
+}
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		name    string
+		modules []string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			"Minimal versions",
+			[]string{"github.com/user/lib@^1.0"},
+			map[string]string{"github.com/user/lib": "v1.0.0", "github.com/user/base": "v1.0.0"},
+			"",
+		},
+		{
+			"Highest of the minimal versions",
+			[]string{"github.com/user/lib@^1.0", "github.com/user/tools@^1.0"},
+			map[string]string{"github.com/user/lib": "v1.0.0", "github.com/user/tools": "v1.0.0", "github.com/user/base": "v1.1.0"},
+			"",
+		},
+		{
+			"Raised version requires more",
+			[]string{"github.com/user/lib@^1.1", "github.com/user/base@1.0.0"},
+			nil,
+			"conflicting versions of github.com/user/base, v1.2.0 was selected:\n" +
+				"    1.0.0 required by app\n" +
+				"    ^1.2 required by app -> github.com/user/lib@v1.1.0",
+		},
+		{
+			"Conflict through two dependencies",
+			[]string{"github.com/user/lib@^1.1", "github.com/user/tools@~1.0"},
+			nil,
+			"conflicting versions of github.com/user/base, v1.2.0 was selected:\n" +
+				"    ^1.2 required by app -> github.com/user/lib@v1.1.0\n" +
+				"    ~1.1 required by app -> github.com/user/tools@v1.0.0",
+		},
+		{
+			"Branch and release",
+			[]string{"github.com/user/lib@^1.0", "github.com/user/base@main"},
+			nil,
+			"conflicting versions of github.com/user/base, main was selected",
+		},
+		{
+			"No matching version",
+			[]string{"github.com/user/lib@^3.0"},
+			nil,
+			"no version of github.com/user/lib matches ^3.0 required by app, available: v1.0.0, v1.1.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selection, err := testStore(t, graphFetcher()).Select("app", dependencies(tt.modules...), true)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Select() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Select() error = %v", err)
+			}
+			if len(selection.Versions) != len(tt.want) {
+				t.Errorf("Select() = %v, want %v", selection.Versions, tt.want)
+			}
+			for repo, version := range tt.want {
+				if selection.Versions[repo] != version {
+					t.Errorf("Select() = %v, want %v", selection.Versions, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSelectPrefersLock(t *testing.T) {
+	store := testStore(t, graphFetcher())
+	store.Lock.Set(lockfile.Entry{Path: "github.com/user/lib/.ferret.json", Source: "github.com/user/lib", Version: "v1.1.0"})
+	modules := dependencies("github.com/user/lib@^1.0")
+
+	locked, err := store.Select("app", modules, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if locked.Versions["github.com/user/lib"] != "v1.1.0" || locked.Versions["github.com/user/base"] != "v1.2.0" {
+		t.Errorf("expected the pinned version to be kept, got %v", locked.Versions)
+	}
+
+	unlocked, err := store.Select("app", modules, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unlocked.Versions["github.com/user/lib"] != "v1.0.0" {
+		t.Errorf("expected the minimal version without the lockfile, got %v", unlocked.Versions)
+	}
+
+	// A pinned version the constraint does not allow is not used
+	if selection, err := store.Select("app", dependencies("github.com/user/lib@~1.0"), true); err != nil || selection.Versions["github.com/user/lib"] != "v1.0.0" {
+		t.Errorf("expected the pin outside the constraint to be ignored, got %v, %v", selection, err)
+	}
+}
+
+func TestAddLatestRelease(t *testing.T) {
+	project := openTestProject(t, mapFetcher{})
+	project.Store.Fetcher = graphFetcher()
+
+	dep, err := project.Add("github.com/user/lib")
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if dep.Version != "^1.1.0" {
+		t.Errorf("expected the latest release as the constraint, got %s", dep)
+	}
+	if entry, _ := project.Store.Lock.Get("github.com/user/base/.ferret.json"); entry.Version != "v1.2.0" {
+		t.Errorf("expected the indirect dependency to be pinned at its selected version, got %+v", entry)
+	}
+
+	if _, err := project.Add("github.com/user/tools@~1.0"); err == nil || !strings.Contains(err.Error(), "conflicting versions of github.com/user/base") {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+	if len(project.Config.Dependencies.Modules) != 1 {
+		t.Errorf("expected a conflicting dependency not to be added, got %v", project.Config.Dependencies.Modules)
+	}
+
+	var out strings.Builder
+	if err := project.Tree(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := `app
+└── github.com/user/lib@^1.1.0 (v1.1.0)
+    └── github.com/user/base@^1.2 (v1.2.0)
+`
+	if out.String() != want {
+		t.Errorf("Tree() =\n%s\nwant\n%s", out.String(), want)
+	}
+
+	if err := project.Remove("github.com/user/lib"); err != nil {
+		t.Fatal(err)
+	}
+	if entries := project.Store.Lock.Entries(); len(entries) != 0 {
+		t.Errorf("expected the indirect dependency to be unpinned with the direct one, got %v", entries)
+	}
+}
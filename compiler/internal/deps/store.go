@@ -0,0 +1,108 @@
+// Package deps fetches the remote dependencies of a project and keeps .ferret.json, the module cache
+// and ferret.lock in agreement about them.
+package deps
+
+import (
+	"errors"
+	"fmt"
+
+	"compiler/internal/cache"
+	"compiler/internal/config"
+	"compiler/internal/lockfile"
+	"compiler/internal/remote"
+	"compiler/internal/semver"
+)
+
+// Store fetches files of remote repositories into the module cache and pins them in the lockfile
+type Store struct {
+	Cache   *cache.Cache
+	Fetcher remote.Fetcher
+	Lock    *lockfile.Lockfile
+	Locked  bool // refuse files the lockfile does not pin yet, instead of pinning them
+
+	tags map[string][]semver.Version // repository path -> its version tags, listed once
+}
+
+// CacheKey is where a file of a repository at a version is cached, e.g. github.com/user/repo@main/lib/util.fer
+func CacheKey(repo remote.Module, version, file string) string {
+	return repo.RepoPath() + "@" + version + "/" + file
+}
+
+// LockPath is how the lockfile names a file of a repository, without the version it is pinned to
+func LockPath(repo remote.Module, file string) string {
+	return repo.RepoPath() + "/" + file
+}
+
+// File returns the cached path of a file of a repository at a version, fetching it on first use.
+// check can reject fetched content before it is cached. The content has to match the lockfile.
+func (s *Store) File(repo remote.Module, version, file string, check func([]byte) error) (string, error) {
+	key := CacheKey(repo, version, file)
+	if path, found, err := s.Cache.Get(key); found || err != nil {
+		if err != nil {
+			return "", err
+		}
+		entry, _ := s.Cache.Lookup(key)
+		if err := s.Lock.Verify(s.pin(repo, version, file, entry.Hash), s.Locked); err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+
+	data, err := s.fetch(repo, version, file, check)
+	if err != nil {
+		return "", err
+	}
+	if err := s.Lock.Verify(s.pin(repo, version, file, cache.Hash(data)), s.Locked); err != nil {
+		return "", err
+	}
+	return s.Cache.Put(key, data)
+}
+
+// Refresh fetches a file again, replacing its cached copy and its entry in the lockfile.
+// It reports whether the content differs from what the lockfile pinned.
+func (s *Store) Refresh(repo remote.Module, version, file string, check func([]byte) error) (bool, error) {
+	data, err := s.fetch(repo, version, file, check)
+	if err != nil {
+		return false, err
+	}
+	if _, err := s.Cache.Put(CacheKey(repo, version, file), data); err != nil {
+		return false, err
+	}
+	entry := s.pin(repo, version, file, cache.Hash(data))
+	previous, pinned := s.Lock.Get(entry.Path)
+	s.Lock.Set(entry)
+	return !pinned || previous != entry, nil
+}
+
+// Config returns the cached .ferret.json of a repository, a repository without one is not a Ferret project
+func (s *Store) Config(repo remote.Module, version string) (string, error) {
+	path, err := s.File(repo, version, config.CONFIG_FILE, CheckConfig(repo))
+	if errors.Is(err, remote.ErrNotFound) {
+		return "", fmt.Errorf("%s is not a Ferret project, it has no %s at %s", repo.RepoPath(), config.CONFIG_FILE, version)
+	}
+	return path, err
+}
+
+// CheckConfig rejects a fetched .ferret.json that is not a valid config
+func CheckConfig(repo remote.Module) func([]byte) error {
+	return func(data []byte) error {
+		return config.ValidateProjectConfig(repo.RepoPath()+"/"+config.CONFIG_FILE, data)
+	}
+}
+
+func (s *Store) fetch(repo remote.Module, version, file string, check func([]byte) error) ([]byte, error) {
+	data, err := s.Fetcher.Fetch(repo.RepoName(), version, file)
+	if err != nil {
+		return nil, err
+	}
+	if check != nil {
+		if err := check(data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func (s *Store) pin(repo remote.Module, version, file, hash string) lockfile.Entry {
+	return lockfile.Entry{Path: LockPath(repo, file), Source: repo.RepoPath(), Version: version, Hash: hash}
+}
@@ -0,0 +1,122 @@
+package deps
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"compiler/internal/cache"
+	"compiler/internal/lockfile"
+	"compiler/internal/remote"
+)
+
+// VENDOR_DIR holds copies of the remote files a project uses, so it compiles without the network
+const VENDOR_DIR = "vendor"
+
+// VendorPath is where a file pinned in the lockfile is vendored, vendor/github.com/user/repo/lib/util.fer.
+// Only one version of a repository is pinned, so the version is not part of the path.
+func VendorPath(projectRoot, lockPath string) string {
+	return filepath.ToSlash(filepath.Join(projectRoot, VENDOR_DIR, filepath.FromSlash(lockPath)))
+}
+
+// HasVendor reports whether the project has a vendor directory
+func HasVendor(projectRoot string) bool {
+	info, err := os.Stat(filepath.Join(projectRoot, VENDOR_DIR))
+	return err == nil && info.IsDir()
+}
+
+// ReadVendored returns the vendored copy of a pinned file, found is false when it is not vendored.
+// A copy that does not match the lockfile is an error, it is not silently replaced by a fetched one.
+func ReadVendored(projectRoot string, entry lockfile.Entry) (path string, found bool, err error) {
+	path = VendorPath(projectRoot, entry.Path)
+	data, err := os.ReadFile(filepath.FromSlash(path))
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if hash := cache.Hash(data); hash != entry.Hash {
+		return "", true, fmt.Errorf("%s/%s is stale: sha256 is %s, %s pins %s, run 'ferret vendor'", VENDOR_DIR, entry.Path, hash, lockfile.FILE, entry.Hash)
+	}
+	return path, true, nil
+}
+
+// Vendor copies every file the lockfile pins into vendor/, from the module cache or fetched when it is
+// not cached, and verified against the lockfile either way. What was vendored before is replaced.
+func (p *Project) Vendor() (int, error) {
+	root := p.Config.ProjectRoot
+	staging := filepath.Join(root, VENDOR_DIR+".tmp")
+	if err := os.RemoveAll(staging); err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(staging)
+
+	entries := p.Store.Lock.Entries()
+	for _, entry := range entries {
+		repo, err := remote.ParseRepo(entry.Source)
+		if err != nil {
+			return 0, fmt.Errorf("invalid source of %s in %s: %w", entry.Path, lockfile.FILE, err)
+		}
+		cached, err := p.Store.File(repo, entry.Version, strings.TrimPrefix(entry.Path, entry.Source+"/"), nil)
+		if err != nil {
+			return 0, p.fetchError(repo, entry.Version, err)
+		}
+		data, err := os.ReadFile(filepath.FromSlash(cached))
+		if err != nil {
+			return 0, err
+		}
+		target := filepath.Join(staging, filepath.FromSlash(entry.Path))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return 0, err
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return 0, err
+		}
+	}
+
+	vendorDir := filepath.Join(root, VENDOR_DIR)
+	if err := os.RemoveAll(vendorDir); err != nil {
+		return 0, err
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+	return len(entries), os.Rename(staging, vendorDir)
+}
+
+// VerifyVendor compares vendor/ with the lockfile, and describes the stale files whose content is not
+// the pinned one, the pinned files missing from it and the extra files the lockfile does not pin
+func VerifyVendor(projectRoot string, lock *lockfile.Lockfile) []string {
+	var problems []string
+	pinned := make(map[string]bool)
+	for _, entry := range lock.Entries() {
+		pinned[entry.Path] = true
+		if _, found, err := ReadVendored(projectRoot, entry); err != nil {
+			problems = append(problems, err.Error())
+		} else if !found {
+			problems = append(problems, fmt.Sprintf("%s is pinned in %s but not vendored", entry.Path, lockfile.FILE))
+		}
+	}
+
+	vendorDir := filepath.Join(projectRoot, VENDOR_DIR)
+	var extra []string
+	filepath.WalkDir(vendorDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(vendorDir, path)
+		if err == nil && !pinned[filepath.ToSlash(rel)] {
+			extra = append(extra, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	slices.Sort(extra)
+	for _, path := range extra {
+		problems = append(problems, fmt.Sprintf("%s/%s is not pinned in %s", VENDOR_DIR, path, lockfile.FILE))
+	}
+	return problems
+}
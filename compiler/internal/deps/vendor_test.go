@@ -0,0 +1,63 @@
+package deps
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"compiler/internal/remote"
+)
+
+func TestVendor(t *testing.T) {
+	project := openTestProject(t, mapFetcher{
+		"user/lib/v1/.ferret.json": `{}`,
+		"user/lib/v1/util.fer":     "fn util() {}",
+	})
+	if _, err := project.Add("github.com/user/lib@v1"); err != nil {
+		t.Fatal(err)
+	}
+	repo, _ := remote.ParseRepo("github.com/user/lib")
+	if _, err := project.Store.File(repo, "v1", "util.fer", nil); err != nil {
+		t.Fatal(err)
+	}
+	root := project.Config.ProjectRoot
+
+	// Vendoring works from the cache alone
+	project.Store.Fetcher = mapFetcher{}
+	count, err := project.Vendor()
+	if err != nil || count != 2 {
+		t.Fatalf("Vendor() = %d, %v, want 2 files", count, err)
+	}
+	if data, err := os.ReadFile(VendorPath(root, "github.com/user/lib/util.fer")); err != nil || string(data) != "fn util() {}" {
+		t.Errorf("expected the module to be vendored, got %q, %v", data, err)
+	}
+	if problems := VerifyVendor(root, project.Store.Lock); len(problems) != 0 {
+		t.Errorf("expected a fresh vendor directory, got %v", problems)
+	}
+
+	os.WriteFile(VendorPath(root, "github.com/user/lib/util.fer"), []byte("fn util() { edited(); }"), 0644)
+	os.WriteFile(VendorPath(root, "github.com/user/lib/extra.fer"), nil, 0644)
+	os.Remove(VendorPath(root, "github.com/user/lib/.ferret.json"))
+	problems := strings.Join(VerifyVendor(root, project.Store.Lock), "\n")
+	for _, want := range []string{
+		"github.com/user/lib/.ferret.json is pinned in ferret.lock but not vendored",
+		"vendor/github.com/user/lib/util.fer is stale",
+		"vendor/github.com/user/lib/extra.fer is not pinned in ferret.lock",
+	} {
+		if !strings.Contains(problems, want) {
+			t.Errorf("expected %q in the problems, got\n%s", want, problems)
+		}
+	}
+
+	// Vendoring again replaces the directory
+	if _, err := project.Vendor(); err != nil {
+		t.Fatal(err)
+	}
+	if problems := VerifyVendor(root, project.Store.Lock); len(problems) != 0 {
+		t.Errorf("expected vendoring again to fix the problems, got %v", problems)
+	}
+	if _, err := os.Stat(filepath.Join(root, VENDOR_DIR+".tmp")); !os.IsNotExist(err) {
+		t.Errorf("expected the staging directory to be removed, got %v", err)
+	}
+}
@@ -16,6 +16,12 @@ type Expression interface {
 	Expr()
 }
 
+// LValue represents an expression that names a storage location and can be assigned to
+type LValue interface {
+	Expression
+	LValue()
+}
+
 type BlockConstruct interface {
 	Node
 	Block()
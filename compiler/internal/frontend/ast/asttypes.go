@@ -123,6 +123,7 @@ type StructField struct {
 	FieldIdentifier *IdentifierExpr
 	FieldType       DataType    // nil for literal
 	FieldValue      *Expression // nil for type
+	IsPrivate       bool        // Declared with 'priv', only accessible from the struct's module
 	source.Location
 }
 
@@ -16,6 +16,7 @@ func (b *Block) Loc() *source.Location { return &b.Location }
 type FunctionDecl struct {
 	Identifier *IdentifierExpr
 	Function   *FunctionLiteral // Function literal
+	IsPrivate  bool             // Declared with 'priv', hidden from other modules
 	source.Location
 }
 
@@ -37,13 +38,39 @@ func (i *IfStmt) Loc() *source.Location { return &i.Location }
 
 // MethodDecl represents a method declaration
 type MethodDecl struct {
-	Method   *IdentifierExpr
-	Receiver *Parameter // Receiver parameter: e.g. in `fn (t *T) M(n int)`, `t` is the receiver
-	IsRRef   bool       // Whether the receiver is a reference
-	Function *FunctionLiteral
+	Method    *IdentifierExpr
+	Receiver  *Parameter // Receiver parameter: e.g. in `fn (t *T) M(n int)`, `t` is the receiver
+	IsRRef    bool       // Whether the receiver is a reference
+	Function  *FunctionLiteral
+	IsPrivate bool // Declared with 'priv', only callable from the receiver's module
 	source.Location
 }
 
 func (m *MethodDecl) INode() Node           { return m }
 func (m *MethodDecl) Block()                {} // Block is a marker interface for all statements
 func (m *MethodDecl) Loc() *source.Location { return &m.Location }
+
+// WhileStmt represents a loop that runs its body while the condition holds
+type WhileStmt struct {
+	Condition *Expression
+	Body      *Block
+	source.Location
+}
+
+func (w *WhileStmt) INode() Node           { return w }
+func (w *WhileStmt) Block()                {} // Block is a marker interface for all statements
+func (w *WhileStmt) Loc() *source.Location { return &w.Location }
+
+// ForStmt represents a three clause loop: for init; condition; post { body }
+// Every clause is optional, a missing condition loops forever.
+type ForStmt struct {
+	Init      Node        // VarDeclStmt, AssignmentStmt or ExpressionStmt
+	Condition *Expression // nil when omitted
+	Post      Node        // AssignmentStmt or ExpressionStmt
+	Body      *Block
+	source.Location
+}
+
+func (f *ForStmt) INode() Node           { return f }
+func (f *ForStmt) Block()                {} // Block is a marker interface for all statements
+func (f *ForStmt) Loc() *source.Location { return &f.Location }
@@ -58,6 +58,7 @@ type IndexableExpr struct {
 
 func (i *IndexableExpr) INode() Node           { return i }
 func (i *IndexableExpr) Expr()                 {} // Expr is a marker interface for all expressions
+func (i *IndexableExpr) LValue()               {} // LValue is a marker interface for all lvalues
 func (i *IndexableExpr) Loc() *source.Location { return &i.Location }
 
 type ArrayLiteralExpr struct {
@@ -41,6 +41,7 @@ type VarDeclStmt struct {
 	Variables    []*VariableToDeclare
 	Initializers []Expression
 	IsConst      bool
+	IsPrivate    bool // Declared with 'priv', hidden from other modules
 	source.Location
 }
 
@@ -65,8 +66,9 @@ func (a *AssignmentStmt) Loc() *source.Location { return &a.Location }
 
 // TypeDeclStmt represents a type declaration statement
 type TypeDeclStmt struct {
-	Alias    *IdentifierExpr // The name of the type
-	BaseType DataType        // The underlying type
+	Alias     *IdentifierExpr // The name of the type
+	BaseType  DataType        // The underlying type
+	IsPrivate bool            // Declared with 'priv', hidden from other modules
 	source.Location
 }
 
@@ -87,12 +89,30 @@ func (r *ReturnStmt) Loc() *source.Location { return &r.Location }
 // ImportStmt represents an import statement
 type ImportStmt struct {
 	ImportPath *StringLiteral // The import path as written in source (e.g., "code/data")
-	ModuleName string         // The alias or last part of the import path (e.g., "data")
+	ModulePath string         // The import path the module is known by, ImportPath with an alias of .ferret.json expanded
+	ModuleName string         // The alias or last part of the import path (e.g., "data"), empty when Names or Wildcard is set
 	FullPath   string         // The fully resolved, normalized file path (always with .fer)
+	Names      []*ImportName  // import "path" { a, b as c } brings these names into scope
+	Wildcard   bool           // import "path" { * } brings every exported name into scope
+	IsPublic   bool           // pub import re-exports the imported names
 
 	source.Location
 }
 
+// ImportName is one entry of an import list, `Vector as Vec` is imported as Vec
+type ImportName struct {
+	Name  *IdentifierExpr
+	Alias *IdentifierExpr // nil when the name is not renamed
+}
+
+// LocalName is the name the imported symbol is known by in the importing module
+func (n *ImportName) LocalName() *IdentifierExpr {
+	if n.Alias != nil {
+		return n.Alias
+	}
+	return n.Name
+}
+
 func (i *ImportStmt) INode() Node           { return i }
 func (i *ImportStmt) Stmt()                 {} // Stmt is a marker interface for all statements
 func (i *ImportStmt) Loc() *source.Location { return &i.Location }
@@ -105,3 +125,21 @@ type ModuleDeclStmt struct {
 func (m *ModuleDeclStmt) INode() Node           { return m }
 func (m *ModuleDeclStmt) Stmt()                 {} // Stmt is a marker interface for all statements
 func (m *ModuleDeclStmt) Loc() *source.Location { return &m.Location }
+
+// BreakStmt leaves the innermost loop
+type BreakStmt struct {
+	source.Location
+}
+
+func (b *BreakStmt) INode() Node           { return b }
+func (b *BreakStmt) Stmt()                 {} // Stmt is a marker interface for all statements
+func (b *BreakStmt) Loc() *source.Location { return &b.Location }
+
+// ContinueStmt starts the next iteration of the innermost loop
+type ContinueStmt struct {
+	source.Location
+}
+
+func (c *ContinueStmt) INode() Node           { return c }
+func (c *ContinueStmt) Stmt()                 {} // Stmt is a marker interface for all statements
+func (c *ContinueStmt) Loc() *source.Location { return &c.Location }
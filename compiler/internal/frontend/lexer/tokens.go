@@ -21,8 +21,11 @@ const (
 	FOREACH_TOKEN    TOKEN = "foreach"
 	WHILE_TOKEN      TOKEN = "while"
 	DO_TOKEN         TOKEN = "do"
+	BREAK_TOKEN      TOKEN = "break"
+	CONTINUE_TOKEN   TOKEN = "continue"
 	IDENTIFIER_TOKEN TOKEN = "identifier"
 	PRIVATE_TOKEN    TOKEN = "priv"
+	PUBLIC_TOKEN     TOKEN = "pub"
 	RETURN_TOKEN     TOKEN = "return"
 	IMPORT_TOKEN     TOKEN = "import"
 	AS_TOKEN         TOKEN = "as"
@@ -100,9 +103,12 @@ var keyWordsMap map[TOKEN]bool = map[TOKEN]bool{
 	FOREACH_TOKEN:   true,
 	WHILE_TOKEN:     true,
 	DO_TOKEN:        true,
+	BREAK_TOKEN:     true,
+	CONTINUE_TOKEN:  true,
 	TYPE_TOKEN:      true,
 	STRUCT_TOKEN:    true,
 	PRIVATE_TOKEN:   true,
+	PUBLIC_TOKEN:    true,
 	INTERFACE_TOKEN: true,
 	FUNCTION_TOKEN:  true,
 	RETURN_TOKEN:    true,
@@ -159,7 +159,21 @@ func parseFunctionLiteral(p *Parser, start *source.Position, isAnonymous, parseN
 
 	params, returnTypes := parseSignature(p, parseNewParams, params...)
 
+	// A named function can end with ';' instead of a body, the standard library declares native functions this way
+	if !isAnonymous && p.match(lexer.SEMICOLON_TOKEN) {
+		end := p.advance()
+		return &ast.FunctionLiteral{
+			Params:     params,
+			ReturnType: returnTypes,
+			Location:   *source.NewLocation(start, &end.End),
+		}
+	}
+
+	// loops around a function literal do not extend into its body
+	outerLoops := p.loopDepth
+	p.loopDepth = 0
 	block := parseBlock(p)
+	p.loopDepth = outerLoops
 
 	location := *source.NewLocation(start, block.Loc().End)
 
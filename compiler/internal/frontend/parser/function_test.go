@@ -71,9 +71,21 @@ func TestFunctionParsing(t *testing.T) {
 		},
 		{
 			name:    "Missing function body",
+			input:   "fn add(a: i32, b: i32) -> i32",
+			isValid: false,
+			desc:    "Function without body or ';' should fail",
+		},
+		{
+			name:    "Native function declaration",
 			input:   "fn add(a: i32, b: i32) -> i32;",
+			isValid: true,
+			desc:    "Function ending with ';' should parse, the resolver only allows it in the standard library",
+		},
+		{
+			name:    "Anonymous function without body",
+			input:   "let f = fn(a: i32) -> i32;",
 			isValid: false,
-			desc:    "Function without body should fail",
+			desc:    "Anonymous function without body should fail",
 		},
 		{
 			name: "Function with complex return types",
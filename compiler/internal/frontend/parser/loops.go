@@ -0,0 +1,126 @@
+package parser
+
+import (
+	"compiler/internal/frontend/ast"
+	"compiler/internal/frontend/lexer"
+	"compiler/internal/report"
+	"compiler/internal/source"
+)
+
+// parseLoopCondition parses a loop condition, parentheses are optional like in if statements
+func parseLoopCondition(p *Parser) ast.Expression {
+	var condition ast.Expression
+	if p.match(lexer.OPEN_PAREN) {
+		p.advance() // consume '('
+		condition = parseExpression(p)
+		p.consume(lexer.CLOSE_PAREN, report.EXPECTED_CLOSE_PAREN)
+	} else {
+		condition = parseExpression(p)
+	}
+	return condition
+}
+
+// parseLoopBody parses the body of a loop, where break and continue are allowed
+func parseLoopBody(p *Parser) *ast.Block {
+	p.loopDepth++
+	defer func() { p.loopDepth-- }()
+	return parseBlock(p)
+}
+
+// parseWhileStatement parses a while loop
+//
+// while CONDITION {BODY}
+func parseWhileStatement(p *Parser) ast.BlockConstruct {
+	start := p.consume(lexer.WHILE_TOKEN, report.EXPECTED_WHILE)
+
+	condition := parseLoopCondition(p)
+	if condition == nil {
+		token := p.peek()
+		p.ctx.Reports.Add(p.fullPath, source.NewLocation(&token.Start, &token.End), report.EXPECTED_LOOP_CONDITION+" after 'while'", report.PARSING_PHASE).SetLevel(report.SYNTAX_ERROR)
+		return nil
+	}
+
+	body := parseLoopBody(p)
+
+	return &ast.WhileStmt{
+		Condition: &condition,
+		Body:      body,
+		Location:  *source.NewLocation(&start.Start, body.Loc().End),
+	}
+}
+
+// parseForStatement parses a three clause for loop, every clause may be empty
+//
+// for INIT; CONDITION; POST {BODY}
+func parseForStatement(p *Parser) ast.BlockConstruct {
+	start := p.consume(lexer.FOR_TOKEN, report.EXPECTED_FOR)
+
+	stmt := &ast.ForStmt{}
+
+	if !p.match(lexer.SEMICOLON_TOKEN) {
+		stmt.Init = parseForClause(p, true)
+	}
+	p.consume(lexer.SEMICOLON_TOKEN, report.EXPECTED_FOR_INIT_SEMI)
+
+	if !p.match(lexer.SEMICOLON_TOKEN) {
+		condition := parseExpression(p)
+		if condition == nil {
+			token := p.peek()
+			p.ctx.Reports.Add(p.fullPath, source.NewLocation(&token.Start, &token.End), report.EXPECTED_LOOP_CONDITION, report.PARSING_PHASE).SetLevel(report.SYNTAX_ERROR)
+			return nil
+		}
+		stmt.Condition = &condition
+	}
+	p.consume(lexer.SEMICOLON_TOKEN, report.EXPECTED_FOR_COND_SEMI)
+
+	if !p.match(lexer.OPEN_CURLY) {
+		stmt.Post = parseForClause(p, false)
+	}
+
+	stmt.Body = parseLoopBody(p)
+	stmt.Location = *source.NewLocation(&start.Start, stmt.Body.Loc().End)
+
+	return stmt
+}
+
+// parseForClause parses the initializer or post statement of a for loop.
+// Only the initializer may declare variables.
+func parseForClause(p *Parser, isInit bool) ast.Node {
+	if isInit && p.match(lexer.LET_TOKEN) {
+		return parseVarDecl(p)
+	}
+
+	token := p.peek()
+	if p.match(lexer.IDENTIFIER_TOKEN) {
+		if expr := parseExpression(p); expr != nil {
+			return parseExpressionStatement(p, expr)
+		}
+	}
+
+	msg := report.INVALID_FOR_POST_CLAUSE
+	if isInit {
+		msg = report.INVALID_FOR_INIT_CLAUSE
+	}
+	p.ctx.Reports.Add(p.fullPath, source.NewLocation(&token.Start, &token.End), msg, report.PARSING_PHASE).SetLevel(report.SYNTAX_ERROR)
+	return nil
+}
+
+// parseBreakStmt parses a break statement
+func parseBreakStmt(p *Parser) ast.Statement {
+	token := p.consume(lexer.BREAK_TOKEN, report.BREAK_OUTSIDE_LOOP)
+	if p.loopDepth == 0 {
+		p.ctx.Reports.Add(p.fullPath, source.NewLocation(&token.Start, &token.End), report.BREAK_OUTSIDE_LOOP, report.PARSING_PHASE).SetLevel(report.SYNTAX_ERROR)
+		return nil
+	}
+	return &ast.BreakStmt{Location: *source.NewLocation(&token.Start, &token.End)}
+}
+
+// parseContinueStmt parses a continue statement
+func parseContinueStmt(p *Parser) ast.Statement {
+	token := p.consume(lexer.CONTINUE_TOKEN, report.CONTINUE_OUTSIDE_LOOP)
+	if p.loopDepth == 0 {
+		p.ctx.Reports.Add(p.fullPath, source.NewLocation(&token.Start, &token.End), report.CONTINUE_OUTSIDE_LOOP, report.PARSING_PHASE).SetLevel(report.SYNTAX_ERROR)
+		return nil
+	}
+	return &ast.ContinueStmt{Location: *source.NewLocation(&token.Start, &token.End)}
+}
@@ -0,0 +1,110 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestLoopParsing(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		isValid bool
+		desc    string
+	}{
+		{
+			name: "While loop",
+			input: `while x < 10 {
+				x = x + 1;
+			}`,
+			isValid: true,
+			desc:    "Basic while loop",
+		},
+		{
+			name: "While loop with parentheses",
+			input: `while (x < 10) {
+				x = x + 1;
+			}`,
+			isValid: true,
+			desc:    "While loop with parenthesized condition",
+		},
+		{
+			name:    "While without condition",
+			input:   "while { x = 1; }",
+			isValid: false,
+			desc:    "While loop without a condition should fail",
+		},
+		{
+			name: "For loop",
+			input: `for let i = 0; i < 10; i++ {
+				x = x + i;
+			}`,
+			isValid: true,
+			desc:    "Three clause for loop",
+		},
+		{
+			name: "For loop with assignment clauses",
+			input: `for i = 0; i < 10; i = i + 1 {
+				x = x + i;
+			}`,
+			isValid: true,
+			desc:    "For loop with assignments as initializer and post statement",
+		},
+		{
+			name: "For loop without clauses",
+			input: `for ;; {
+				break;
+			}`,
+			isValid: true,
+			desc:    "For loop with every clause omitted",
+		},
+		{
+			name:    "For loop missing semicolon",
+			input:   "for let i = 0 i < 10; i++ { }",
+			isValid: false,
+			desc:    "For loop clauses must be separated by semicolons",
+		},
+		{
+			name:    "Declaration as post statement",
+			input:   "for ; x < 10; let y = 1 { }",
+			isValid: false,
+			desc:    "Only the for loop initializer may declare variables",
+		},
+		{
+			name: "Break and continue",
+			input: `while true {
+				if x > 5 {
+					break;
+				}
+				continue;
+			}`,
+			isValid: true,
+			desc:    "Break and continue inside a loop",
+		},
+		{
+			name:    "Break outside loop",
+			input:   "break;",
+			isValid: false,
+			desc:    "Break outside of a loop should fail",
+		},
+		{
+			name:    "Continue outside loop",
+			input:   "if x { continue; }",
+			isValid: false,
+			desc:    "Continue outside of a loop should fail",
+		},
+		{
+			name: "Break inside function inside loop",
+			input: `while true {
+				let f = fn() { break; };
+			}`,
+			isValid: false,
+			desc:    "A function body does not inherit the enclosing loop",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testParseWithPanic(t, tt.input, tt.desc, tt.isValid)
+		})
+	}
+}
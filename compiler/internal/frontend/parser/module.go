@@ -1,10 +1,13 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	"compiler/colors"
+	"compiler/ctx"
 	"compiler/internal/frontend/ast"
 	"compiler/internal/frontend/lexer"
 	"compiler/internal/report"
@@ -19,16 +22,31 @@ func parseImport(p *Parser) ast.Node {
 	importToken := p.consume(lexer.STRING_TOKEN, report.EXPECTED_IMPORT_PATH)
 
 	importpath := importToken.Value
+	// The module is known by its path with an alias of .ferret.json expanded, however the import spells it
+	modulePath := p.ctx.ExpandImportPath(importpath, p.fullPath)
 
 	// Support: import "path" as Alias;
 	var moduleName string
-	if p.match(lexer.AS_TOKEN) {
+	hasAlias := p.match(lexer.AS_TOKEN)
+	if hasAlias {
 		p.advance() // consume 'as'
 		aliasToken := p.consume(lexer.IDENTIFIER_TOKEN, "Expected identifier after 'as' in import")
 		moduleName = aliasToken.Value
-	} else {
+	}
+
+	// Support: import "path" { a, b as c }; and import "path" { * };
+	var names []*ast.ImportName
+	wildcard := false
+	end := importToken.End
+	if p.match(lexer.OPEN_CURLY) {
+		if hasAlias {
+			p.ctx.Reports.Add(p.fullPath, source.NewLocation(&start.Start, &importToken.End), report.ALIAS_WITH_IMPORT_LIST, report.PARSING_PHASE).AddHint("Remove the alias, only the listed names are imported").SetLevel(report.SYNTAX_ERROR)
+		}
+		names, wildcard, end = parseImportList(p)
+		moduleName = ""
+	} else if !hasAlias {
 		// Default: use last part of path (without extension)
-		parts := strings.Split(importpath, "/")
+		parts := strings.Split(modulePath, "/")
 		if len(parts) == 0 {
 			p.ctx.Reports.Add(p.fullPath, source.NewLocation(&start.Start, &importToken.End), report.INVALID_IMPORT_PATH, report.PARSING_PHASE).SetLevel(report.SYNTAX_ERROR)
 			return nil
@@ -38,9 +56,16 @@ func parseImport(p *Parser) ast.Node {
 		moduleName = strings.TrimSuffix(parts[len(parts)-1], suf)
 	}
 
-	loc := *source.NewLocation(&start.Start, &importToken.End)
+	loc := *source.NewLocation(&start.Start, &end)
 
 	moduleFullPath, err := fs.ResolveModule(importpath, p.fullPath, p.ctx)
+	var shareErr *fs.ShareError
+	if errors.As(err, &shareErr) {
+		// The repository keeps the module private, so the report points at its config
+		p.ctx.Reports.Add(shareErr.ConfigPath, shareErr.Location, err.Error(), report.PARSING_PHASE).AddRelated(p.fullPath, &loc, "imported here").SetLevel(report.CRITICAL_ERROR)
+		colors.RED.Println("Error resolving module:", err)
+		return nil
+	}
 	if err != nil {
 		p.ctx.Reports.Add(p.fullPath, &loc, err.Error(), report.PARSING_PHASE).SetLevel(report.CRITICAL_ERROR)
 		colors.RED.Println("Error resolving module:", err)
@@ -52,8 +77,11 @@ func parseImport(p *Parser) ast.Node {
 			Value:    importpath,
 			Location: loc,
 		},
+		ModulePath: modulePath,
 		ModuleName: moduleName,
 		FullPath:   moduleFullPath,
+		Names:      names,
+		Wildcard:   wildcard,
 		Location:   loc,
 	}
 
@@ -79,19 +107,166 @@ func parseImport(p *Parser) ast.Node {
 	p.ctx.DepGraph[p.fullPath] = append(p.ctx.DepGraph[p.fullPath], moduleFullPath)
 
 	// Check if the module is already cached
-	if !p.ctx.HasModule(importpath) {
-		module := NewParser(moduleFullPath, p.ctx, p.debug).Parse()
+	if !p.ctx.HasModule(modulePath) {
+		module := parseImportedModule(p, moduleFullPath)
 		if module == nil {
 			p.ctx.Reports.Add(p.fullPath, &loc, "Failed to parse imported module", report.PARSING_PHASE).SetLevel(report.SEMANTIC_ERROR)
 			return &ast.ImportStmt{Location: loc}
 		}
+		if module.ImportPath != "" && module.ImportPath != modulePath {
+			p.ctx.Reports.Add(p.fullPath, &loc, fmt.Sprintf("'%s' is a file of module '%s'", importpath, module.ImportPath), report.PARSING_PHASE).AddHint(fmt.Sprintf("Import \"%s\" instead", module.ImportPath)).SetLevel(report.SEMANTIC_ERROR)
+			return &ast.ImportStmt{Location: loc}
+		}
 	}
 
-	p.modulenameToImportpath[moduleName] = importpath
+	if moduleName != "" {
+		p.modulenameToImportpath[moduleName] = modulePath
+	}
 
 	return stmt
 }
 
+// parseImportedModule parses the module an import resolved to, a directory module or a single file
+func parseImportedModule(p *Parser, moduleFullPath string) *ast.Program {
+	if fs.IsValidFile(moduleFullPath) {
+		return NewParser(moduleFullPath, p.ctx, p.debug).Parse()
+	}
+	programs := parseModuleDir(p.ctx, moduleFullPath, p.debug)
+	if len(programs) == 0 {
+		return nil
+	}
+	return programs[0]
+}
+
+// parseModuleDecl parses `mod name;`, which makes the file one of the files of its directory's module
+func parseModuleDecl(p *Parser) ast.Node {
+	start := p.consume(lexer.MODULE_TOKEN, "Expected 'mod' keyword")
+	name := p.consume(lexer.IDENTIFIER_TOKEN, report.EXPECTED_MODULE_NAME)
+	return &ast.ModuleDeclStmt{
+		ModuleName: &ast.IdentifierExpr{Name: name.Value, Location: *source.NewLocation(&name.Start, &name.End)},
+		Location:   *source.NewLocation(&start.Start, &name.End),
+	}
+}
+
+// parseModuleOf parses the directory module of a file declaring 'mod', and returns the file's program
+func parseModuleOf(p *Parser) *ast.Program {
+	dir := filepath.ToSlash(filepath.Dir(p.fullPath))
+	var programs []*ast.Program
+	if module, err := p.ctx.GetModule(p.ctx.FullPathToImportPath(dir)); err == nil {
+		programs = module.Files
+	} else {
+		programs = parseModuleDir(p.ctx, dir, p.debug)
+	}
+	for _, program := range programs {
+		if program.FullPath == p.fullPath {
+			return program
+		}
+	}
+	return &ast.Program{}
+}
+
+// parseModuleDir parses every .fer file of a directory into one module. The files share the module's
+// namespace, imports included, and each of them has to declare the same 'mod name;'.
+func parseModuleDir(ctxx *ctx.CompilerContext, dir string, debug bool) []*ast.Program {
+	dir = filepath.ToSlash(dir)
+	files, err := fs.ModuleFiles(dir)
+	if err != nil {
+		return nil
+	}
+	importPath := ctxx.FullPathToImportPath(dir)
+
+	ctxx.StartParsing(dir)
+	defer ctxx.FinishParsing(dir)
+
+	aliases := make(map[string]string)
+	programs := make([]*ast.Program, 0, len(files))
+	for _, file := range files {
+		fp := NewParser(file, ctxx, debug)
+		fp.importPath = importPath
+		fp.modulenameToImportpath = aliases
+		fp.inModuleDir = true
+		if program := fp.Parse(); program.FullPath != "" {
+			programs = append(programs, program)
+		}
+	}
+
+	if module, err := ctxx.GetModule(importPath); err == nil {
+		module.Dir = dir
+	}
+	checkModuleNames(ctxx, importPath, programs)
+	return programs
+}
+
+// checkModuleNames reports the files of a directory module that do not declare it, or declare another name
+func checkModuleNames(ctxx *ctx.CompilerContext, importPath string, programs []*ast.Program) {
+	var first *ast.ModuleDeclStmt
+	var firstFile string
+	for _, program := range programs {
+		decl, ok := program.Nodes[0].(*ast.ModuleDeclStmt)
+		if !ok {
+			continue
+		}
+		if first == nil {
+			first, firstFile = decl, program.FullPath
+			continue
+		}
+		if decl.ModuleName.Name != first.ModuleName.Name {
+			ctxx.Reports.Add(program.FullPath, decl.ModuleName.Loc(), fmt.Sprintf("module name '%s' does not match '%s' declared by the other files of '%s'", decl.ModuleName.Name, first.ModuleName.Name, importPath), report.PARSING_PHASE).
+				AddRelated(firstFile, first.ModuleName.Loc(), fmt.Sprintf("'%s' is declared here", first.ModuleName.Name)).
+				AddHint(fmt.Sprintf("Every file in the directory must declare 'mod %s;'", first.ModuleName.Name)).SetLevel(report.SEMANTIC_ERROR)
+		}
+	}
+
+	name := fs.LastPart(importPath)
+	if first != nil {
+		name = first.ModuleName.Name
+	}
+	for _, program := range programs {
+		if _, ok := program.Nodes[0].(*ast.ModuleDeclStmt); !ok {
+			ctxx.Reports.Add(program.FullPath, program.Nodes[0].Loc(), fmt.Sprintf("file is part of module '%s' but does not declare it", importPath), report.PARSING_PHASE).
+				AddHint(fmt.Sprintf("Add 'mod %s;' as the first statement of the file", name)).SetLevel(report.SEMANTIC_ERROR)
+		}
+	}
+}
+
+// parseImportList parses the { a, b as c } or { * } after an import path
+func parseImportList(p *Parser) ([]*ast.ImportName, bool, source.Position) {
+	open := p.advance() // consume '{'
+
+	if p.match(lexer.MUL_TOKEN) {
+		p.advance() // consume '*'
+		end := p.consume(lexer.CLOSE_CURLY, report.EXPECTED_CLOSE_BRACE).End
+		return nil, true, end
+	}
+
+	var names []*ast.ImportName
+	for !p.match(lexer.CLOSE_CURLY) {
+		nameToken := p.consume(lexer.IDENTIFIER_TOKEN, report.EXPECTED_IMPORT_NAME)
+		entry := &ast.ImportName{
+			Name: &ast.IdentifierExpr{Name: nameToken.Value, Location: *source.NewLocation(&nameToken.Start, &nameToken.End)},
+		}
+		if p.match(lexer.AS_TOKEN) {
+			p.advance() // consume 'as'
+			aliasToken := p.consume(lexer.IDENTIFIER_TOKEN, "Expected identifier after 'as' in import")
+			entry.Alias = &ast.IdentifierExpr{Name: aliasToken.Value, Location: *source.NewLocation(&aliasToken.Start, &aliasToken.End)}
+		}
+		names = append(names, entry)
+
+		if !p.match(lexer.CLOSE_CURLY) {
+			comma := p.consume(lexer.COMMA_TOKEN, report.EXPECTED_COMMA_OR_CLOSE_CURLY)
+			if p.match(lexer.CLOSE_CURLY) {
+				p.ctx.Reports.Add(p.fullPath, source.NewLocation(&comma.Start, &comma.End), report.TRAILING_COMMA_NOT_ALLOWED, report.PARSING_PHASE).AddHint("Remove the trailing comma").SetLevel(report.WARNING)
+			}
+		}
+	}
+
+	end := p.consume(lexer.CLOSE_CURLY, report.EXPECTED_CLOSE_BRACE).End
+	if len(names) == 0 {
+		p.ctx.Reports.Add(p.fullPath, source.NewLocation(&open.Start, &end), report.EMPTY_IMPORT_LIST, report.PARSING_PHASE).AddHint("Name the symbols to import, or use { * } for all of them").SetLevel(report.SYNTAX_ERROR)
+	}
+	return names, false, end
+}
+
 func parseScopeResolution(p *Parser, expr ast.Expression) (ast.Expression, bool) {
 	// Handle scope resolution operator
 	if module, ok := expr.(*ast.IdentifierExpr); ok {
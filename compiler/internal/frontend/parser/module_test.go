@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"compiler/ctx"
+	"compiler/internal/config"
+	"compiler/internal/frontend/ast"
+	"compiler/internal/report"
+	"compiler/internal/testutil"
+)
+
+// parseImports parses main.fer in a project that also has app/maths.fer, recovering from syntax errors
+func parseImports(t *testing.T, input string) (program *ast.Program, reports report.Reports) {
+	t.Helper()
+
+	projectRoot := filepath.ToSlash(filepath.Join(testutil.CreateTempProject(t), "app"))
+	testutil.CreateTestFileInDir(t, projectRoot, "maths.fer", `fn add() {} fn sub() {}`)
+	mainPath := testutil.CreateTestFileInDir(t, projectRoot, "main.fer", input)
+
+	context := &ctx.CompilerContext{
+		Modules:       make(map[string]*ctx.Module),
+		Reports:       report.Reports{},
+		ProjectConfig: &config.ProjectConfig{ProjectRoot: projectRoot},
+		ProjectRoot:   projectRoot,
+	}
+
+	defer func() {
+		recover()
+		reports = context.Reports
+	}()
+	program = NewParser(filepath.ToSlash(mainPath), context, false).Parse()
+	return program, context.Reports
+}
+
+func TestModuleStatementParsing(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantMsg string // empty when the import is valid
+	}{
+		{"Plain import", `import "app/maths";`, ""},
+		{"Aliased import", `import "app/maths" as m;`, ""},
+		{"Selective import", `import "app/maths" { add, sub };`, ""},
+		{"Renamed import", `import "app/maths" { add as plus };`, ""},
+		{"Wildcard import", `import "app/maths" { * };`, ""},
+		{"Public import", `pub import "app/maths" { add };`, ""},
+		{"Public wildcard import", `pub import "app/maths" { * };`, ""},
+
+		// invalid
+		{"Empty list", `import "app/maths" {};`, report.EMPTY_IMPORT_LIST},
+		{"Alias with list", `import "app/maths" as m { add };`, report.ALIAS_WITH_IMPORT_LIST},
+		{"Missing name", `import "app/maths" { add, , sub };`, report.EXPECTED_IMPORT_NAME},
+		{"Missing comma", `import "app/maths" { add sub };`, report.EXPECTED_COMMA_OR_CLOSE_CURLY},
+		{"Public import without list", `pub import "app/maths";`, report.PUB_IMPORT_WITHOUT_LIST},
+		{"Public declaration", `pub let x = 1;`, report.INVALID_PUBLIC_STATEMENT},
+		{"Module declaration not first", `let x = 1; mod app;`, report.MODULE_DECL_NOT_FIRST},
+		{"Module declaration without name", `mod;`, report.EXPECTED_MODULE_NAME},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, reports := parseImports(t, tt.input)
+			var messages []string
+			for _, r := range reports {
+				if r.Level == report.SYNTAX_ERROR || r.Level == report.CRITICAL_ERROR {
+					messages = append(messages, r.Message)
+				}
+			}
+			if tt.wantMsg == "" && len(messages) > 0 {
+				t.Errorf("expected no errors, got %q", messages)
+			}
+			if tt.wantMsg != "" && !strings.Contains(strings.Join(messages, "\n"), tt.wantMsg) {
+				t.Errorf("expected error %q, got %q", tt.wantMsg, messages)
+			}
+		})
+	}
+}
+
+func TestImportListNodes(t *testing.T) {
+	program, reports := parseImports(t, `import "app/maths" { add, sub as minus };
+pub import "app/maths" { * };`)
+	if program == nil || len(program.Nodes) != 2 {
+		t.Fatalf("expected 2 import statements, reports: %v", reports)
+	}
+
+	list := program.Nodes[0].(*ast.ImportStmt)
+	if list.ModuleName != "" || list.IsPublic || len(list.Names) != 2 {
+		t.Fatalf("expected a private list of 2 names without alias, got %+v", list)
+	}
+	if list.Names[0].LocalName().Name != "add" || list.Names[1].Name.Name != "sub" || list.Names[1].LocalName().Name != "minus" {
+		t.Errorf("unexpected names %+v %+v", list.Names[0], list.Names[1])
+	}
+	if _, bound := program.ModulenameToImportpath["maths"]; bound {
+		t.Error("an import list should not bind the module name")
+	}
+
+	wildcard := program.Nodes[1].(*ast.ImportStmt)
+	if !wildcard.Wildcard || !wildcard.IsPublic || wildcard.Loc().Start.Column != 1 {
+		t.Errorf("expected a public wildcard import starting at 'pub', got %+v", wildcard)
+	}
+}
@@ -23,6 +23,8 @@ type Parser struct {
 	modulenameToImportpath map[string]string // import alias -> full path
 	ctx                    *ctx.CompilerContext
 	debug                  bool // debug mode for additional logging
+	loopDepth              int  // number of loops enclosing the current statement within the current function
+	inModuleDir            bool // parsed as one file of a directory module, see parseModuleDir
 }
 
 func NewParser(filePath string, ctxx *ctx.CompilerContext, debug bool) *Parser {
@@ -170,6 +172,12 @@ func parseBlock(p *Parser) *ast.Block {
 
 	for !p.isAtEnd() && p.peek().Kind != lexer.CLOSE_CURLY {
 		node := parseNode(p)
+		if decl, ok := node.(*ast.ModuleDeclStmt); ok {
+			if len(nodes) > 0 {
+				p.ctx.Reports.Add(p.fullPath, decl.Loc(), report.MODULE_DECL_NOT_FIRST, report.PARSING_PHASE).AddHint("Move the declaration to the top of the file").SetLevel(report.SYNTAX_ERROR)
+			}
+			p.modulename = decl.ModuleName.Name
+		}
 		if node != nil {
 			nodes = append(nodes, node)
 		}
@@ -211,6 +219,8 @@ func parseNode(p *Parser) ast.Node {
 	switch p.peek().Kind {
 	case lexer.IMPORT_TOKEN:
 		node = parseImport(p)
+	case lexer.MODULE_TOKEN:
+		node = parseModuleDecl(p)
 	case lexer.LET_TOKEN, lexer.CONST_TOKEN:
 		node = parseVarDecl(p)
 	case lexer.TYPE_TOKEN:
@@ -221,6 +231,18 @@ func parseNode(p *Parser) ast.Node {
 		node = parseFunctionLike(p)
 	case lexer.IF_TOKEN:
 		node = parseIfStatement(p)
+	case lexer.WHILE_TOKEN:
+		node = parseWhileStatement(p)
+	case lexer.FOR_TOKEN:
+		node = parseForStatement(p)
+	case lexer.BREAK_TOKEN:
+		node = parseBreakStmt(p)
+	case lexer.CONTINUE_TOKEN:
+		node = parseContinueStmt(p)
+	case lexer.PRIVATE_TOKEN:
+		node = parsePrivateDecl(p)
+	case lexer.PUBLIC_TOKEN:
+		node = parsePublicImport(p)
 	case lexer.AT_TOKEN:
 		node = parseStructLiteral(p)
 	case lexer.IDENTIFIER_TOKEN:
@@ -259,6 +281,11 @@ func parseNode(p *Parser) ast.Node {
 
 // Parse is the entry point for parsing
 func (p *Parser) Parse() *ast.Program {
+	// A file declaring 'mod' is one file of its directory's module, which is parsed as a whole
+	if p.match(lexer.MODULE_TOKEN) && !p.inModuleDir {
+		return parseModuleOf(p)
+	}
+
 	var nodes []ast.Node
 
 	// Start tracking the entry point parsing
@@ -267,6 +294,12 @@ func (p *Parser) Parse() *ast.Program {
 	for !p.isAtEnd() {
 		// Parse the statement
 		node := parseNode(p)
+		if decl, ok := node.(*ast.ModuleDeclStmt); ok {
+			if len(nodes) > 0 {
+				p.ctx.Reports.Add(p.fullPath, decl.Loc(), report.MODULE_DECL_NOT_FIRST, report.PARSING_PHASE).AddHint("Move the declaration to the top of the file").SetLevel(report.SYNTAX_ERROR)
+			}
+			p.modulename = decl.ModuleName.Name
+		}
 		if node != nil {
 			nodes = append(nodes, node)
 		} else {
@@ -117,6 +117,12 @@ func parseArrayType(p *Parser) (ast.DataType, bool) {
 
 // parseStructField parses a single struct field
 func parseStructField(p *Parser) *ast.StructField {
+	start := p.peek().Start
+	isPrivate := p.match(lexer.PRIVATE_TOKEN)
+	if isPrivate {
+		p.advance()
+	}
+
 	// Parse field name
 	nameToken := p.consume(lexer.IDENTIFIER_TOKEN, report.EXPECTED_FIELD_NAME+" got "+p.peek().Value)
 	fieldName := nameToken.Value
@@ -134,7 +140,8 @@ func parseStructField(p *Parser) *ast.StructField {
 				Location: *source.NewLocation(&nameToken.Start, &nameToken.End),
 			},
 			FieldType: fieldType,
-			Location:  *source.NewLocation(&nameToken.Start, fieldType.Loc().End),
+			IsPrivate: isPrivate,
+			Location:  *source.NewLocation(&start, fieldType.Loc().End),
 		}
 	}
 }
@@ -141,6 +141,13 @@ func parseVarDecl(p *Parser) ast.Statement {
 		}
 	}
 
+	// constants can never be assigned later, so every one needs a value now
+	if isConst && len(values) < varCount {
+		uninitialized := variables[len(values)].Identifier
+		p.ctx.Reports.Add(p.fullPath, uninitialized.Loc(), report.CONSTANT_WITHOUT_INITIALIZER+": "+uninitialized.Name, report.PARSING_PHASE).AddHint("Add a value for every constant").SetLevel(report.NORMAL_ERROR)
+		return nil
+	}
+
 	if len(values) > varCount {
 		token := p.peek()
 		p.ctx.Reports.Add(p.fullPath, source.NewLocation(&token.Start, &token.End), "values cannot be more than the number of variables", report.PARSING_PHASE).SetLevel(report.SYNTAX_ERROR)
@@ -20,6 +20,10 @@ func TestParseVarDecl(t *testing.T) {
 		{"let p, q: i32, str = 10, \"hello\";", true, "Multiple typed variables initialized"},
 		{"let x, y = p;", true, "Mismatched variable and value count"},
 		{"let x, y: i32;", true, "Shared type annotation"},
+		{"const x = 42;", true, "Constant initialized with a value"},
+		{"const x: i32 = 42;", true, "Typed constant initialized"},
+		{"const x: i32;", false, "Constant without initializer"},
+		{"const x, y: i32 = 10;", false, "Constant list with a missing initializer"},
 	}
 
 	for _, tt := range tests {
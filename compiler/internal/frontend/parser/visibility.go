@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"compiler/internal/frontend/ast"
+	"compiler/internal/frontend/lexer"
+	"compiler/internal/report"
+	"compiler/internal/source"
+)
+
+// parsePrivateDecl parses a declaration marked with 'priv', which keeps it out of the module's exports
+func parsePrivateDecl(p *Parser) ast.Node {
+	priv := p.advance() // consume 'priv'
+
+	var node ast.Node
+	switch p.peek().Kind {
+	case lexer.LET_TOKEN, lexer.CONST_TOKEN:
+		stmt := parseVarDecl(p)
+		if decl, ok := stmt.(*ast.VarDeclStmt); ok {
+			decl.IsPrivate = true
+		}
+		node = stmt
+	case lexer.TYPE_TOKEN:
+		stmt := parseTypeDecl(p)
+		if decl, ok := stmt.(*ast.TypeDeclStmt); ok {
+			decl.IsPrivate = true
+		}
+		node = stmt
+	case lexer.FUNCTION_TOKEN:
+		node = parseFunctionLike(p)
+		switch decl := node.(type) {
+		case *ast.FunctionDecl:
+			decl.IsPrivate = true
+		case *ast.MethodDecl:
+			decl.IsPrivate = true
+		default:
+			reportInvalidPrivate(p, priv)
+		}
+	default:
+		reportInvalidPrivate(p, priv)
+		return parseNode(p)
+	}
+
+	if node != nil && node.Loc().Start != nil {
+		node.Loc().Start = &priv.Start
+	}
+	return node
+}
+
+func reportInvalidPrivate(p *Parser, priv lexer.Token) {
+	p.ctx.Reports.Add(p.fullPath, source.NewLocation(&priv.Start, &priv.End), report.INVALID_PRIVATE_DECLARATION, report.PARSING_PHASE).AddHint("Remove 'priv'").SetLevel(report.SYNTAX_ERROR)
+}
+
+// parsePublicImport parses 'pub import', which re-exports the imported names from the current module
+func parsePublicImport(p *Parser) ast.Node {
+	pub := p.advance() // consume 'pub'
+	if !p.match(lexer.IMPORT_TOKEN) {
+		p.ctx.Reports.Add(p.fullPath, source.NewLocation(&pub.Start, &pub.End), report.INVALID_PUBLIC_STATEMENT, report.PARSING_PHASE).AddHint("Remove 'pub'").SetLevel(report.SYNTAX_ERROR)
+		return parseNode(p)
+	}
+
+	node := parseImport(p)
+	stmt, ok := node.(*ast.ImportStmt)
+	if !ok {
+		return node
+	}
+	if stmt.Names == nil && !stmt.Wildcard {
+		p.ctx.Reports.Add(p.fullPath, stmt.Loc(), report.PUB_IMPORT_WITHOUT_LIST, report.PARSING_PHASE).AddHint("List the names, or use { * } to re-export all of them").SetLevel(report.SYNTAX_ERROR)
+	}
+	stmt.IsPublic = true
+	stmt.Location.Start = &pub.Start
+	return stmt
+}
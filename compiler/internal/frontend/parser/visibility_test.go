@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"testing"
+
+	"compiler/internal/frontend/ast"
+	"compiler/internal/testutil"
+)
+
+func TestPrivateParsing(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		isValid bool
+		desc    string
+	}{
+		{"Private variable", `priv let secret = 42;`, true, "Private let declaration"},
+		{"Private constant", `priv const limit: i32 = 10;`, true, "Private const declaration"},
+		{"Private function", `priv fn helper() {}`, true, "Private function declaration"},
+		{"Private type", `priv type Id i32;`, true, "Private type declaration"},
+		{"Private method", `priv fn (p: Point) norm() -> i32 { return 0; }`, true, "Private method declaration"},
+		{"Private struct field", `type User struct { name: str, priv password: str };`, true, "Struct with a private field"},
+
+		// invalid
+		{"Private expression", `priv x = 1;`, false, "'priv' on an assignment"},
+		{"Private anonymous function", `priv fn () {};`, false, "'priv' on a function literal"},
+		{"Private import", `priv import "app/data";`, false, "'priv' on an import"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testParseWithPanic(t, tt.input, tt.desc, tt.isValid)
+		})
+	}
+}
+
+func TestPrivateFlags(t *testing.T) {
+	input := `priv let secret = 42;
+let open = 1;
+priv fn helper() {}
+priv type User struct { name: str, priv password: str };`
+
+	filePath := testutil.CreateTestFile(t, input)
+	ctx := createTestCompilerContext(t, filePath)
+	defer ctx.Destroy()
+	nodes := NewParser(filePath, ctx, false).Parse().Nodes
+
+	if len(nodes) != 4 {
+		t.Fatalf("expected 4 nodes, got %d", len(nodes))
+	}
+	if decl := nodes[0].(*ast.VarDeclStmt); !decl.IsPrivate || decl.Loc().Start.Column != 1 {
+		t.Errorf("expected a private let starting at the 'priv' keyword, got %+v", decl)
+	}
+	if nodes[1].(*ast.VarDeclStmt).IsPrivate {
+		t.Error("let without 'priv' should be public")
+	}
+	if !nodes[2].(*ast.FunctionDecl).IsPrivate {
+		t.Error("expected a private function")
+	}
+	typeDecl := nodes[3].(*ast.TypeDeclStmt)
+	fields := typeDecl.BaseType.(*ast.StructType).Fields
+	if !typeDecl.IsPrivate || fields[0].IsPrivate || !fields[1].IsPrivate {
+		t.Errorf("expected a private type with only 'password' private, got %+v", fields)
+	}
+}
@@ -0,0 +1,132 @@
+// Package lockfile reads and writes ferret.lock, which pins every remote file a project
+// depends on to the version it was fetched at and the SHA-256 of its content.
+package lockfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// FILE is the name of the lockfile, next to .ferret.json
+const FILE = "ferret.lock"
+
+// FORMAT_VERSION is written into every lockfile, a lockfile from a newer compiler is refused
+const FORMAT_VERSION = 1
+
+// Entry pins one remote file
+type Entry struct {
+	Path    string `json:"path"`    // the file, e.g. github.com/user/repo/lib/util.fer
+	Source  string `json:"source"`  // the repository it comes from, e.g. github.com/user/repo
+	Version string `json:"version"` // the branch, tag or commit it was fetched at
+	Hash    string `json:"sha256"`
+}
+
+// Lockfile is the content of ferret.lock
+type Lockfile struct {
+	Path    string // where the lockfile is read from and written to
+	entries map[string]Entry
+	changed bool
+}
+
+type file struct {
+	Version int     `json:"version"`
+	Entries []Entry `json:"entries"`
+}
+
+// Load reads the lockfile in the project root, a missing lockfile is empty
+func Load(projectRoot string) (*Lockfile, error) {
+	l := &Lockfile{Path: filepath.ToSlash(filepath.Join(projectRoot, FILE)), entries: make(map[string]Entry)}
+	data, err := os.ReadFile(filepath.FromSlash(l.Path))
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", FILE, err)
+	}
+	if f.Version > FORMAT_VERSION {
+		return nil, fmt.Errorf("%s has format version %d, this compiler reads up to %d", FILE, f.Version, FORMAT_VERSION)
+	}
+	for _, entry := range f.Entries {
+		l.entries[entry.Path] = entry
+	}
+	return l, nil
+}
+
+// Get returns the entry pinning a file
+func (l *Lockfile) Get(path string) (Entry, bool) {
+	entry, ok := l.entries[path]
+	return entry, ok
+}
+
+// Set pins a file, replacing its entry
+func (l *Lockfile) Set(entry Entry) {
+	if existing, ok := l.entries[entry.Path]; ok && existing == entry {
+		return
+	}
+	l.entries[entry.Path] = entry
+	l.changed = true
+}
+
+// Remove drops the entry of a file
+func (l *Lockfile) Remove(path string) {
+	if _, ok := l.entries[path]; ok {
+		delete(l.entries, path)
+		l.changed = true
+	}
+}
+
+// Entries returns every entry, sorted by path
+func (l *Lockfile) Entries() []Entry {
+	entries := make([]Entry, 0, len(l.entries))
+	for _, entry := range l.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries
+}
+
+// Changed reports whether entries were set or removed since the lockfile was loaded or saved
+func (l *Lockfile) Changed() bool {
+	return l.changed
+}
+
+// Save writes the lockfile when it changed
+func (l *Lockfile) Save() error {
+	if !l.changed {
+		return nil
+	}
+	data, err := json.MarshalIndent(file{Version: FORMAT_VERSION, Entries: l.Entries()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.FromSlash(l.Path), append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	l.changed = false
+	return nil
+}
+
+// Verify checks the content of a file against its entry. A file that is not locked is pinned, unless
+// locked is set, then it is an error as well, since the lockfile would have to change.
+func (l *Lockfile) Verify(entry Entry, locked bool) error {
+	pinned, ok := l.entries[entry.Path]
+	switch {
+	case !ok && locked:
+		return fmt.Errorf("%s is not in %s, and --locked does not allow adding it", entry.Path, FILE)
+	case !ok:
+		l.Set(entry)
+	case pinned.Hash != entry.Hash:
+		return fmt.Errorf("%s does not match %s: sha256 is %s, locked %s", entry.Path, FILE, entry.Hash, pinned.Hash)
+	case pinned.Version != entry.Version || pinned.Source != entry.Source:
+		return fmt.Errorf("%s does not match %s: resolved %s@%s, locked %s@%s", entry.Path, FILE, entry.Source, entry.Version, pinned.Source, pinned.Version)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package lockfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var util = Entry{Path: "github.com/user/repo/lib/util.fer", Source: "github.com/user/repo", Version: "main", Hash: "aaaa"}
+
+func TestSaveAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Load(dir)
+	if err != nil || len(l.Entries()) != 0 {
+		t.Fatalf("expected an empty lockfile, got %v, %v", l, err)
+	}
+
+	l.Set(util)
+	if !l.Changed() {
+		t.Error("expected the lockfile to change")
+	}
+	if err := l.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := reloaded.Get(util.Path); !ok || got != util {
+		t.Errorf("Get() = %+v, %v after reloading", got, ok)
+	}
+	reloaded.Set(util)
+	if reloaded.Changed() {
+		t.Error("setting an identical entry should not change the lockfile")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantMsg string
+	}{
+		{"invalid json", "{", "invalid ferret.lock"},
+		{"newer format", `{"version": 99, "entries": []}`, "format version 99"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			os.WriteFile(filepath.Join(dir, FILE), []byte(tt.content), 0644)
+			if _, err := Load(dir); err == nil || !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tampered := util
+	tampered.Hash = "bbbb"
+	moved := util
+	moved.Version = "v2"
+	other := util
+	other.Path = "github.com/user/repo/lib/other.fer"
+
+	tests := []struct {
+		name    string
+		entry   Entry
+		locked  bool
+		wantMsg string // empty when the entry verifies
+	}{
+		{"pinned", util, true, ""},
+		{"tampered", tampered, false, "sha256 is bbbb, locked aaaa"},
+		{"drifted", moved, false, "resolved github.com/user/repo@v2, locked github.com/user/repo@main"},
+		{"new file", other, false, ""},
+		{"new file when locked", other, true, "--locked does not allow adding it"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &Lockfile{entries: map[string]Entry{util.Path: util}}
+			err := l.Verify(tt.entry, tt.locked)
+			if tt.wantMsg == "" {
+				if err != nil {
+					t.Fatalf("Verify() error = %v", err)
+				}
+				if got, _ := l.Get(tt.entry.Path); got != tt.entry {
+					t.Errorf("expected %s to be pinned", tt.entry.Path)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("Verify() error = %v, want %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package remote
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Host is the only code host remote imports can name, e.g. "github.com/user/repo/path"
+const Host = "github.com"
+
+// DefaultBranch is the ref remote modules are fetched from
+const DefaultBranch = "main"
+
+// DefaultBaseURL serves the raw files of GitHub repositories
+const DefaultBaseURL = "https://raw.githubusercontent.com"
+
+// DefaultAPIURL lists the tags of GitHub repositories
+const DefaultAPIURL = "https://api.github.com"
+
+// ErrNotFound is returned by a Fetcher when the repository or the file does not exist
+var ErrNotFound = errors.New("not found")
+
+// Fetcher downloads one file of a remote repository at a ref.
+// The compiler uses HTTPFetcher, tests plug in one serving a local server.
+type Fetcher interface {
+	Fetch(repo, ref, file string) ([]byte, error)
+}
+
+// VersionLister is implemented by fetchers that can list the tags of a repository, version constraints need it
+type VersionLister interface {
+	Tags(repo string) ([]string, error)
+}
+
+// HTTPFetcher fetches files from BaseURL/<repo>/<ref>/<file>, the layout of raw.githubusercontent.com,
+// and lists tags from APIURL/repos/<repo>/tags, the GitHub REST API
+type HTTPFetcher struct {
+	BaseURL string
+	APIURL  string
+	Client  *http.Client
+}
+
+// NewHTTPFetcher returns a fetcher for GitHub
+func NewHTTPFetcher() *HTTPFetcher {
+	return &HTTPFetcher{
+		BaseURL: DefaultBaseURL,
+		APIURL:  DefaultAPIURL,
+		Client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (f *HTTPFetcher) Fetch(repo, ref, file string) ([]byte, error) {
+	return f.get(fmt.Sprintf("%s/%s/%s/%s", strings.TrimSuffix(f.BaseURL, "/"), repo, ref, file))
+}
+
+// Tags lists the tags of a repository, following the pages the API splits them into
+func (f *HTTPFetcher) Tags(repo string) ([]string, error) {
+	apiURL := f.APIURL
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+	var names []string
+	visited := make(map[string]bool)
+	for url := fmt.Sprintf("%s/repos/%s/tags?per_page=100", strings.TrimSuffix(apiURL, "/"), repo); url != "" && !visited[url]; {
+		visited[url] = true
+		data, header, err := f.getPage(url)
+		if err != nil {
+			return nil, err
+		}
+		var tags []struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(data, &tags); err != nil {
+			return nil, fmt.Errorf("invalid tag list for %s: %w", repo, err)
+		}
+		for _, tag := range tags {
+			names = append(names, tag.Name)
+		}
+		url = nextPage(header)
+	}
+	return names, nil
+}
+
+// nextPage returns the URL of the next page from a Link header, `<url>; rel="next", <url>; rel="last"`,
+// or "" on the last page
+func nextPage(header http.Header) string {
+	for _, link := range strings.Split(header.Get("Link"), ",") {
+		target, params, found := strings.Cut(link, ";")
+		if !found {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			if strings.ReplaceAll(strings.TrimSpace(param), " ", "") == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(target), "<>")
+			}
+		}
+	}
+	return ""
+}
+
+func (f *HTTPFetcher) get(url string) ([]byte, error) {
+	data, _, err := f.getPage(url)
+	return data, err
+}
+
+// getPage fetches a URL and returns its body with the response headers
+func (f *HTTPFetcher) getPage(url string) ([]byte, http.Header, error) {
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, nil, fmt.Errorf("%s: %w", url, ErrNotFound)
+	case resp.StatusCode != http.StatusOK:
+		return nil, nil, fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	return data, resp.Header, err
+}
@@ -0,0 +1,89 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestHTTPFetcher(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/user/repo/main/lib/util.fer":
+			w.Write([]byte("fn util() {}"))
+		case "/user/repo/main/broken.fer":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/repos/user/repo/tags":
+			w.Write([]byte(`[{"name": "v1.1.0"}, {"name": "v1.0.0"}]`))
+		case "/repos/user/many/tags":
+			// The API splits long lists into pages linked from the Link header
+			page := r.URL.Query().Get("page")
+			if page == "" {
+				page = "1"
+			}
+			next := map[string]string{"1": "2", "2": "3"}[page]
+			if next != "" {
+				w.Header().Set("Link", fmt.Sprintf(`<http://%s/repos/user/many/tags?per_page=100&page=%s>; rel="next", <http://%s/repos/user/many/tags?per_page=100&page=3>; rel="last"`, r.Host, next, r.Host))
+			}
+			fmt.Fprintf(w, `[{"name": "v1.%s.0"}]`, page)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := &HTTPFetcher{BaseURL: server.URL + "/", APIURL: server.URL}
+
+	data, err := fetcher.Fetch("user/repo", "main", "lib/util.fer")
+	if err != nil || string(data) != "fn util() {}" {
+		t.Errorf("Fetch() = %q, %v", data, err)
+	}
+	if _, err := fetcher.Fetch("user/repo", "main", "missing.fer"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing file, got %v", err)
+	}
+	if _, err := fetcher.Fetch("user/repo", "main", "broken.fer"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a server error, got %v", err)
+	}
+	if tags, err := fetcher.Tags("user/repo"); err != nil || !slices.Equal(tags, []string{"v1.1.0", "v1.0.0"}) {
+		t.Errorf("Tags() = %v, %v", tags, err)
+	}
+	if tags, err := fetcher.Tags("user/many"); err != nil || !slices.Equal(tags, []string{"v1.1.0", "v1.2.0", "v1.3.0"}) {
+		t.Errorf("Tags() = %v, %v, want every page", tags, err)
+	}
+	if _, err := fetcher.Tags("user/missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing repository, got %v", err)
+	}
+}
+
+func TestParseModule(t *testing.T) {
+	tests := []struct {
+		importPath string
+		want       Module
+		wantErr    bool
+	}{
+		{"github.com/user/repo/lib", Module{"user", "repo", "lib"}, false},
+		{"github.com/user/repo/lib/util", Module{"user", "repo", "lib/util"}, false},
+		{"github.com/user/repo", Module{}, true},
+		{"gitlab.com/user/repo/lib", Module{}, true},
+		{"github.com/user/repo/../../escape", Module{}, true},
+		{"github.com/user//lib", Module{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.importPath, func(t *testing.T) {
+			got, err := ParseModule(tt.importPath)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseModule(%q) error = %v, wantErr %v", tt.importPath, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseModule(%q) = %+v, want %+v", tt.importPath, got, tt.want)
+			}
+			if !tt.wantErr && got.ImportPath() != tt.importPath {
+				t.Errorf("ImportPath() = %q, want %q", got.ImportPath(), tt.importPath)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package remote
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Module is a remote import path split into its repository and the module inside it
+type Module struct {
+	User string
+	Repo string
+	Path string // the module path inside the repository, without the .fer extension
+}
+
+// IsRemote reports whether an import path names a module on the remote host
+func IsRemote(importPath string) bool {
+	return strings.HasPrefix(importPath, Host+"/")
+}
+
+// ParseModule splits "github.com/user/repo/path/to/module" into its parts
+func ParseModule(importPath string) (Module, error) {
+	parts := strings.Split(importPath, "/")
+	if len(parts) < 4 || parts[0] != Host {
+		return Module{}, fmt.Errorf("invalid remote import path: %s, expected %s/user/repo/module", importPath, Host)
+	}
+	// The path names a file in the cache, so it must not be able to climb out of it
+	for _, part := range parts[1:] {
+		if part == "" || part == "." || part == ".." || strings.Contains(part, "\\") {
+			return Module{}, fmt.Errorf("invalid remote import path: %s", importPath)
+		}
+	}
+	return Module{User: parts[1], Repo: parts[2], Path: strings.Join(parts[3:], "/")}, nil
+}
+
+// RepoName is the repository in "user/repo" form, as fetchers expect it
+func (m Module) RepoName() string {
+	return m.User + "/" + m.Repo
+}
+
+// RepoPath is the import path of the repository root, "github.com/user/repo"
+func (m Module) RepoPath() string {
+	return Host + "/" + m.RepoName()
+}
+
+// ImportPath is the import path the module was parsed from
+func (m Module) ImportPath() string {
+	return m.RepoPath() + "/" + m.Path
+}
+
+// ParseRepo parses the repository an import path or a repository path belongs to, the Path of the result is empty
+func ParseRepo(path string) (Module, error) {
+	module, err := ParseModule(strings.TrimSuffix(path, "/") + "/_")
+	if err != nil {
+		return Module{}, fmt.Errorf("invalid repository path: %s, expected %s/user/repo", path, Host)
+	}
+	module.Path = ""
+	return module, nil
+}
+
+// StripVersion removes the version from a path inside a repository, "github.com/user/repo@v1/lib" becomes "github.com/user/repo/lib"
+func StripVersion(path string) string {
+	parts := strings.SplitN(path, "/", 4)
+	if len(parts) < 3 || parts[0] != Host {
+		return path
+	}
+	parts[2], _, _ = strings.Cut(parts[2], "@")
+	return strings.Join(parts, "/")
+}
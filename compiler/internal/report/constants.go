@@ -7,6 +7,13 @@ const (
 	EXPECTED_PACKAGE_NAME    = "Expected package name"
 	EXPECTED_IMPORT_PATH     = "Expected import path"
 	INVALID_IMPORT_PATH      = "Invalid import path"
+	EXPECTED_IMPORT_NAME     = "Expected a name to import"
+	EMPTY_IMPORT_LIST        = "Import list must name at least one symbol"
+	ALIAS_WITH_IMPORT_LIST   = "An import with a list of names cannot also have a module alias"
+	PUB_IMPORT_WITHOUT_LIST  = "'pub import' needs a list of names to re-export"
+	INVALID_PUBLIC_STATEMENT = "'pub' can only mark import statements, other declarations are public unless marked 'priv'"
+	EXPECTED_MODULE_NAME     = "Expected module name after 'mod'"
+	MODULE_DECL_NOT_FIRST    = "'mod' must be the first statement of the file"
 )
 
 // general error messages
@@ -47,6 +54,7 @@ const (
 const (
 	MISMATCHED_VARIABLE_AND_TYPE_COUNT = "Mismatched variable and type count"
 	SINGLE_VALUE_MULTIPLE_VARIABLES    = "Single value cannot be assigned to multiple variables"
+	CONSTANT_WITHOUT_INITIALIZER       = "Constant must be initialized"
 )
 
 const (
@@ -163,3 +171,21 @@ const (
 	EXPECTED_IF   = "Expected 'if' keyword"
 	EXPECTED_ELSE = "Expected 'else' keyword"
 )
+
+// Error messages for loops
+const (
+	EXPECTED_WHILE          = "Expected 'while' keyword"
+	EXPECTED_FOR            = "Expected 'for' keyword"
+	EXPECTED_LOOP_CONDITION = "Expected loop condition"
+	BREAK_OUTSIDE_LOOP      = "'break' is not inside a loop"
+	CONTINUE_OUTSIDE_LOOP   = "'continue' is not inside a loop"
+	EXPECTED_FOR_INIT_SEMI  = "Expected ';' after the for loop initializer"
+	EXPECTED_FOR_COND_SEMI  = "Expected ';' after the for loop condition"
+	INVALID_FOR_POST_CLAUSE = "Invalid for loop post statement"
+	INVALID_FOR_INIT_CLAUSE = "Invalid for loop initializer"
+)
+
+// Error messages for visibility
+const (
+	INVALID_PRIVATE_DECLARATION = "'priv' can only mark let, const, type and fn declarations"
+)
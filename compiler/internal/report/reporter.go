@@ -80,12 +80,20 @@ type HintContainer struct {
 	col  int
 }
 
+// RelatedInfo points to another location that helps explain a report, such as a declaration
+type RelatedInfo struct {
+	FilePath string
+	Location *source.Location
+	Message  string
+}
+
 // Report represents a diagnostic report used both internally and by LSP.
 type Report struct {
 	FilePath string
 	Location *source.Location
 	Message  string
 	Hints    HintContainer
+	Related  []RelatedInfo
 	Level    REPORT_TYPE
 	Phase    COMPILATION_PHASE
 }
@@ -139,6 +147,10 @@ func printReport(r *Report) {
 	} else {
 		reportColor.Println(underline)
 	}
+
+	for _, related := range r.Related {
+		colors.GREY.Printf("%s= note: %s [%s:%d:%d]\n", strings.Repeat(" ", numlen+1), related.Message, related.FilePath, related.Location.Start.Line, related.Location.Start.Column)
+	}
 }
 
 // makeParts reads the source file and generates a code snippet and underline
@@ -208,6 +220,16 @@ func (r *Report) AddHintAt(msg string, col int) *Report {
 	return r
 }
 
+// AddRelated attaches a secondary location, such as the declaration involved in the report
+func (r *Report) AddRelated(filePath string, location *source.Location, msg string) *Report {
+	r.Related = append(r.Related, RelatedInfo{
+		FilePath: filePath,
+		Location: location,
+		Message:  msg,
+	})
+	return r
+}
+
 // Add creates and registers a new diagnostic report with basic position validation.
 // It returns a pointer to the newly created Diagnostic.
 func (r *Reports) Add(filePath string, location *source.Location, msg string, phase COMPILATION_PHASE) *Report {
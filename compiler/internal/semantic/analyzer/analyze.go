@@ -3,12 +3,16 @@ package analyzer
 import (
 	"compiler/ctx"
 	"compiler/internal/frontend/ast"
+	"compiler/internal/semantic"
 )
 
 type AnalyzerNode struct {
 	Ctx     *ctx.CompilerContext
 	Program *ast.Program
 	Debug   bool
+	scopes  []*semantic.SymbolTable // stack of local scopes, empty at module level
+	// Reads collects the module-level symbols read while it is non-nil, the resolver uses it to order global initializers
+	Reads map[*semantic.Symbol]bool
 }
 
 func NewAnalyzerNode(program *ast.Program, ctx *ctx.CompilerContext, debug bool) *AnalyzerNode {
@@ -18,3 +22,49 @@ func NewAnalyzerNode(program *ast.Program, ctx *ctx.CompilerContext, debug bool)
 		Debug:   debug,
 	}
 }
+
+// CurrentScope returns the innermost scope, which is the module symbol table at the top level
+func (r *AnalyzerNode) CurrentScope() *semantic.SymbolTable {
+	if len(r.scopes) > 0 {
+		return r.scopes[len(r.scopes)-1]
+	}
+	module, err := r.Ctx.GetModule(r.Program.ImportPath)
+	if err != nil {
+		return nil
+	}
+	return module.SymbolTable
+}
+
+// EnterScope makes the scope owned by node the current scope.
+// The scope is created on first use, so later passes see the symbols declared by earlier ones.
+func (r *AnalyzerNode) EnterScope(node ast.Node) *semantic.SymbolTable {
+	parent := r.CurrentScope()
+	module, err := r.Ctx.GetModule(r.Program.ImportPath)
+	if err != nil {
+		scope := semantic.NewSymbolTable(parent)
+		r.scopes = append(r.scopes, scope)
+		return scope
+	}
+	if module.Scopes == nil {
+		module.Scopes = make(map[ast.Node]*semantic.SymbolTable)
+	}
+	scope, exists := module.Scopes[node]
+	if !exists {
+		scope = semantic.NewSymbolTable(parent)
+		module.Scopes[node] = scope
+	}
+	r.scopes = append(r.scopes, scope)
+	return scope
+}
+
+// ExitScope returns to the enclosing scope
+func (r *AnalyzerNode) ExitScope() {
+	if len(r.scopes) > 0 {
+		r.scopes = r.scopes[:len(r.scopes)-1]
+	}
+}
+
+// IsModuleScope reports whether the analyzer is at the top level of the module
+func (r *AnalyzerNode) IsModuleScope() bool {
+	return len(r.scopes) == 0
+}
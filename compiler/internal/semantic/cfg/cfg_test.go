@@ -0,0 +1,217 @@
+package cfg
+
+import (
+	"maps"
+	"strings"
+	"testing"
+
+	"compiler/internal/frontend/ast"
+	"compiler/internal/frontend/parser"
+	"compiler/internal/testutil/fixture"
+)
+
+// parseSource parses a single file program
+func parseSource(t *testing.T, src string) *ast.Program {
+	t.Helper()
+	context := fixture.Project(t, map[string]string{"main.fer": src})
+	return parser.NewParser(context.ProjectRoot+"/main.fer", context, false).Parse()
+}
+
+// functionGraph builds the graph of the named function in src
+func functionGraph(t *testing.T, src, name string) *Graph {
+	t.Helper()
+	for _, fn := range BuildProgram(parseSource(t, src)) {
+		if fn.Name == name {
+			return fn.Graph
+		}
+	}
+	t.Fatalf("no function named %q", name)
+	return nil
+}
+
+// findBlock returns the first block holding a node that matches
+func findBlock(t *testing.T, g *Graph, match func(ast.Node) bool) *Block {
+	t.Helper()
+	for _, block := range g.Blocks {
+		for _, node := range block.Nodes {
+			if match(node) {
+				return block
+			}
+		}
+	}
+	t.Fatal("no block holds a matching node")
+	return nil
+}
+
+// blockWith returns the block holding a node that starts on the given line
+func blockWith(t *testing.T, g *Graph, line int) *Block {
+	t.Helper()
+	return findBlock(t, g, func(node ast.Node) bool { return node.Loc().Start.Line == line })
+}
+
+func TestIfElseJoins(t *testing.T) {
+	g := functionGraph(t, `fn f(c: bool) -> i32 {
+    let y = 0;
+    if c {
+        y = 1;
+    } else {
+        y = 2;
+    }
+    return y;
+}`, "f")
+
+	cond := blockWith(t, g, 3)
+	if len(cond.Succs) != 2 {
+		t.Fatalf("condition block has %d successors, want 2", len(cond.Succs))
+	}
+	join := blockWith(t, g, 8)
+	if len(join.Preds) != 2 {
+		t.Errorf("block after if/else has %d predecessors, want 2", len(join.Preds))
+	}
+	for _, branch := range []int{4, 6} {
+		if blockWith(t, g, branch).Succs[0] != join {
+			t.Errorf("branch on line %d does not flow into the join block", branch)
+		}
+	}
+}
+
+func TestReturnMakesCodeUnreachable(t *testing.T) {
+	g := functionGraph(t, `fn f() -> i32 {
+    return 1;
+    let x = 2;
+}`, "f")
+
+	reachable := g.Reachable()
+	if !reachable[blockWith(t, g, 2)] {
+		t.Error("return statement should be reachable")
+	}
+	if reachable[blockWith(t, g, 3)] {
+		t.Error("code after return should be unreachable")
+	}
+	if !reachable[g.Exit] {
+		t.Error("exit should be reachable through the return")
+	}
+}
+
+func TestLoopEdges(t *testing.T) {
+	g := functionGraph(t, `fn f(n: i32) {
+    for let i = 0; i < n; i++ {
+        if i == 3 {
+            continue;
+        }
+        if i == 5 {
+            break;
+        }
+    }
+    let done = 1;
+}`, "f")
+
+	header := findBlock(t, g, func(node ast.Node) bool {
+		_, isCondition := node.(*ast.BinaryExpr)
+		return isCondition && node.Loc().Start.Line == 2
+	})
+	post := findBlock(t, g, func(node ast.Node) bool {
+		_, isPost := node.(*ast.ExpressionStmt)
+		return isPost
+	})
+	if len(post.Succs) != 1 || post.Succs[0] != header {
+		t.Fatalf("post statement should loop back to the condition")
+	}
+	// the loop body's end and continue both run the post statement
+	if len(post.Preds) != 2 {
+		t.Errorf("post statement has %d predecessors, want 2", len(post.Preds))
+	}
+
+	after := blockWith(t, g, 10)
+	// the condition failing and break both leave the loop
+	if len(after.Preds) != 2 {
+		t.Errorf("block after the loop has %d predecessors, want 2", len(after.Preds))
+	}
+}
+
+// blockSet is a fact for the tests: the IDs of the blocks that may have run
+type blockSet map[int]bool
+
+func mayHaveRun(direction Direction) Analysis[blockSet] {
+	return Analysis[blockSet]{
+		Direction: direction,
+		Boundary:  func() blockSet { return blockSet{} },
+		Initial:   func() blockSet { return blockSet{} },
+		Meet: func(a, b blockSet) blockSet {
+			union := maps.Clone(a)
+			maps.Copy(union, b)
+			return union
+		},
+		Transfer: func(block *Block, fact blockSet) blockSet {
+			out := maps.Clone(fact)
+			out[block.ID] = true
+			return out
+		},
+		Equal: func(a, b blockSet) bool { return maps.Equal(a, b) },
+	}
+}
+
+func TestSolveForward(t *testing.T) {
+	g := functionGraph(t, `fn f(n: i32) {
+    let i = 0;
+    while i < n {
+        i++;
+    }
+    let done = i;
+}`, "f")
+
+	result := Solve(g, mayHaveRun(Forward))
+
+	body := blockWith(t, g, 4)
+	header := blockWith(t, g, 3)
+	if !result.In[header.ID][body.ID] {
+		t.Error("loop body should flow back into the loop condition")
+	}
+	if result.In[body.ID][blockWith(t, g, 6).ID] {
+		t.Error("code after the loop cannot run before the loop body")
+	}
+	if !result.Out[g.Exit.ID][body.ID] {
+		t.Error("loop body should be able to run before the exit")
+	}
+}
+
+func TestSolveBackward(t *testing.T) {
+	g := functionGraph(t, `fn f(c: bool) -> i32 {
+    if c {
+        return 1;
+    }
+    let x = 2;
+    return x;
+}`, "f")
+
+	result := Solve(g, mayHaveRun(Backward))
+
+	early := blockWith(t, g, 3)
+	late := blockWith(t, g, 5)
+	if result.Out[early.ID][late.ID] {
+		t.Error("code after an early return cannot run after it")
+	}
+	if !result.Out[g.Entry.ID][late.ID] || !result.Out[g.Entry.ID][early.ID] {
+		t.Error("both returns should be able to run after the entry")
+	}
+}
+
+func TestDOT(t *testing.T) {
+	dot := DOT(BuildProgram(parseSource(t, `fn add(a: i32, b: i32) -> i32 {
+    return a + b;
+}
+let x = add(1, 2);`)))
+
+	for _, want := range []string{
+		"digraph cfg {",
+		`label="main";`,
+		`label="add";`,
+		`2:5 return`,
+		`4:1 let x`,
+		"f1_b0 -> f1_b2;",
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT output does not contain %q:\n%s", want, dot)
+		}
+	}
+}
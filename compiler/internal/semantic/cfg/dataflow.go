@@ -0,0 +1,95 @@
+package cfg
+
+// Direction says which way facts flow through the graph
+type Direction int
+
+const (
+	Forward  Direction = iota // facts flow from the entry along the edges
+	Backward                  // facts flow from the exit against the edges
+)
+
+// Analysis describes a dataflow problem over facts of type F.
+// Meet and Transfer must not modify their arguments, the solver shares facts between blocks.
+type Analysis[F any] struct {
+	Direction Direction
+	// Boundary is the fact at the entry of a forward analysis or at the exit of a backward one
+	Boundary func() F
+	// Initial is the fact every other block starts with, it must be the identity of Meet
+	Initial func() F
+	// Meet combines the facts arriving at a block over different edges
+	Meet func(a, b F) F
+	// Transfer computes the fact on the far side of a block, in the direction of the analysis
+	Transfer func(block *Block, fact F) F
+	// Equal reports whether two facts are the same, so the solver knows when it is done
+	Equal func(a, b F) bool
+}
+
+// Result holds the solved facts indexed by Block.ID. In is the fact before the
+// block's first node runs and Out the fact after its last node, in either direction.
+type Result[F any] struct {
+	In  []F
+	Out []F
+}
+
+// Solve runs the worklist algorithm until no fact changes
+func Solve[F any](g *Graph, a Analysis[F]) *Result[F] {
+	result := &Result[F]{
+		In:  make([]F, len(g.Blocks)),
+		Out: make([]F, len(g.Blocks)),
+	}
+	for i := range g.Blocks {
+		result.In[i] = a.Initial()
+		result.Out[i] = a.Initial()
+	}
+
+	// incoming is where facts come from and outgoing where changes propagate to
+	start, incoming, outgoing := g.Entry, predecessors, successors
+	before, after := result.In, result.Out
+	if a.Direction == Backward {
+		start, incoming, outgoing = g.Exit, successors, predecessors
+		before, after = result.Out, result.In
+	}
+	before[start.ID] = a.Boundary()
+
+	worklist := make([]*Block, 0, len(g.Blocks))
+	queued := make([]bool, len(g.Blocks))
+	for i := range g.Blocks {
+		block := g.Blocks[i]
+		if a.Direction == Backward {
+			block = g.Blocks[len(g.Blocks)-1-i]
+		}
+		worklist = append(worklist, block)
+		queued[block.ID] = true
+	}
+
+	for len(worklist) > 0 {
+		block := worklist[0]
+		worklist = worklist[1:]
+		queued[block.ID] = false
+
+		if block != start && len(incoming(block)) > 0 {
+			fact := a.Initial()
+			for _, other := range incoming(block) {
+				fact = a.Meet(fact, after[other.ID])
+			}
+			before[block.ID] = fact
+		}
+
+		fact := a.Transfer(block, before[block.ID])
+		if a.Equal(fact, after[block.ID]) {
+			continue
+		}
+		after[block.ID] = fact
+		for _, next := range outgoing(block) {
+			if !queued[next.ID] {
+				queued[next.ID] = true
+				worklist = append(worklist, next)
+			}
+		}
+	}
+
+	return result
+}
+
+func predecessors(block *Block) []*Block { return block.Preds }
+func successors(block *Block) []*Block   { return block.Succs }
@@ -0,0 +1,185 @@
+package cfg
+
+import (
+	"fmt"
+	"strings"
+
+	"compiler/internal/frontend/ast"
+)
+
+// Function is a named graph, one per function body plus one for the module top level
+type Function struct {
+	Name  string
+	Graph *Graph
+}
+
+// BuildProgram builds a graph for the top level of the program and for every function in it
+func BuildProgram(program *ast.Program) []Function {
+	functions := []Function{{Name: program.Modulename, Graph: Build(program.Nodes)}}
+	collectFunctions(program.Nodes, &functions)
+	return functions
+}
+
+// collectFunctions appends a graph for every named function, method and function literal found in nodes
+func collectFunctions(nodes []ast.Node, functions *[]Function) {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *ast.FunctionDecl:
+			addFunction(n.Identifier.Name, n.Function, functions)
+		case *ast.MethodDecl:
+			name := n.Method.Name
+			if userType, ok := n.Receiver.Type.(*ast.UserDefinedType); ok {
+				name = string(userType.TypeName) + "." + name
+			}
+			addFunction(name, n.Function, functions)
+		case *ast.FunctionLiteral:
+			addFunction(literalName(n), n, functions)
+		case *ast.VarDeclStmt:
+			for i, init := range n.Initializers {
+				if fn, ok := init.(*ast.FunctionLiteral); ok && i < len(n.Variables) {
+					addFunction(n.Variables[i].Identifier.Name, fn, functions)
+				}
+			}
+		case *ast.Block:
+			collectFunctions(n.Nodes, functions)
+		case *ast.IfStmt:
+			collectFunctions([]ast.Node{n.Body}, functions)
+			if n.Alternative != nil {
+				collectFunctions([]ast.Node{n.Alternative}, functions)
+			}
+		case *ast.WhileStmt:
+			collectFunctions(n.Body.Nodes, functions)
+		case *ast.ForStmt:
+			collectFunctions(n.Body.Nodes, functions)
+		}
+	}
+}
+
+func addFunction(name string, fn *ast.FunctionLiteral, functions *[]Function) {
+	*functions = append(*functions, Function{Name: name, Graph: BuildFunction(fn)})
+	if fn.Body != nil {
+		collectFunctions(fn.Body.Nodes, functions)
+	}
+}
+
+func literalName(fn *ast.FunctionLiteral) string {
+	return fmt.Sprintf("fn@%d:%d", fn.Start.Line, fn.Start.Column)
+}
+
+// DOT renders the graphs as a Graphviz digraph with one cluster per function
+func DOT(functions []Function) string {
+	var sb strings.Builder
+	sb.WriteString("digraph cfg {\n")
+	sb.WriteString("  node [shape=box, fontname=\"monospace\"];\n")
+
+	for i, fn := range functions {
+		fmt.Fprintf(&sb, "  subgraph cluster_%d {\n", i)
+		fmt.Fprintf(&sb, "    label=%q;\n", fn.Name)
+		for _, block := range fn.Graph.Blocks {
+			fmt.Fprintf(&sb, "    f%d_b%d [label=\"%s\"];\n", i, block.ID, blockLabel(fn.Graph, block))
+		}
+		for _, block := range fn.Graph.Blocks {
+			for _, succ := range block.Succs {
+				fmt.Fprintf(&sb, "    f%d_b%d -> f%d_b%d;\n", i, block.ID, i, succ.ID)
+			}
+		}
+		sb.WriteString("  }\n")
+	}
+
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// blockLabel lists the block's nodes one per line, left aligned
+func blockLabel(g *Graph, block *Block) string {
+	title := fmt.Sprintf("B%d", block.ID)
+	switch block {
+	case g.Entry:
+		title = "entry"
+	case g.Exit:
+		title = "exit"
+	}
+
+	lines := []string{title}
+	for _, node := range block.Nodes {
+		loc := node.Loc()
+		lines = append(lines, fmt.Sprintf("%d:%d %s", loc.Start.Line, loc.Start.Column, describeNode(node)))
+	}
+
+	label := strings.Join(lines, "\n")
+	label = strings.ReplaceAll(label, `\`, `\\`)
+	label = strings.ReplaceAll(label, `"`, `\"`)
+	return strings.ReplaceAll(label, "\n", `\l`) + `\l`
+}
+
+// describeNode gives a short human readable summary of a node
+func describeNode(node ast.Node) string {
+	switch n := node.(type) {
+	case *ast.VarDeclStmt:
+		keyword := "let"
+		if n.IsConst {
+			keyword = "const"
+		}
+		names := make([]string, len(n.Variables))
+		for i, v := range n.Variables {
+			names[i] = v.Identifier.Name
+		}
+		return keyword + " " + strings.Join(names, ", ")
+	case *ast.AssignmentStmt:
+		return describeExprs(*n.Left) + " = ..."
+	case *ast.ExpressionStmt:
+		return describeExprs(*n.Expressions)
+	case *ast.ReturnStmt:
+		return "return"
+	case *ast.TypeDeclStmt:
+		return "type " + n.Alias.Name
+	case *ast.ImportStmt:
+		return "import " + n.ImportPath.Value
+	case *ast.ModuleDeclStmt:
+		return "mod " + n.ModuleName.Name
+	case *ast.FunctionDecl:
+		return "fn " + n.Identifier.Name
+	case *ast.MethodDecl:
+		return "fn " + n.Method.Name
+	case ast.Expression:
+		// expressions only appear on their own as branch conditions
+		return "branch " + describeExpr(n)
+	}
+	return fmt.Sprintf("%T", node)
+}
+
+func describeExprs(exprs []ast.Expression) string {
+	parts := make([]string, len(exprs))
+	for i, expr := range exprs {
+		parts[i] = describeExpr(expr)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func describeExpr(expr ast.Expression) string {
+	switch e := expr.(type) {
+	case *ast.IdentifierExpr:
+		return e.Name
+	case *ast.BinaryExpr:
+		return describeExpr(*e.Left) + " " + e.Operator.Value + " " + describeExpr(*e.Right)
+	case *ast.UnaryExpr:
+		return e.Operator.Value + describeExpr(*e.Operand)
+	case *ast.PrefixExpr:
+		return e.Operator.Value + describeExpr(*e.Operand)
+	case *ast.PostfixExpr:
+		return describeExpr(*e.Operand) + e.Operator.Value
+	case *ast.FieldAccessExpr:
+		return describeExpr(*e.Object) + "." + e.Field.Name
+	case *ast.IndexableExpr:
+		return describeExpr(*e.Indexable) + "[" + describeExpr(*e.Index) + "]"
+	case *ast.FunctionCallExpr:
+		return describeExpr(*e.Caller) + "(...)"
+	case *ast.VarScopeResolution:
+		return e.Module.Name + "::" + e.Var.Name
+	case *ast.IntLiteral:
+		return e.Raw
+	case *ast.StringLiteral:
+		return fmt.Sprintf("%q", e.Value)
+	}
+	return "..."
+}
@@ -0,0 +1,175 @@
+// Package cfg builds control-flow graphs of function bodies and solves dataflow problems over them.
+package cfg
+
+import "compiler/internal/frontend/ast"
+
+// Block is a basic block: nodes that always run one after another
+type Block struct {
+	ID    int
+	Nodes []ast.Node // simple statements and branch conditions in execution order
+	Succs []*Block
+	Preds []*Block
+}
+
+// Graph is the control-flow graph of a single function body.
+// Entry and Exit hold no nodes, every return jumps to Exit.
+type Graph struct {
+	Entry  *Block
+	Exit   *Block
+	Blocks []*Block // indexed by Block.ID
+}
+
+// loopTargets are the blocks break and continue jump to inside a loop
+type loopTargets struct {
+	breakTo    *Block
+	continueTo *Block
+}
+
+type builder struct {
+	graph   *Graph
+	current *Block
+	loops   []loopTargets
+}
+
+// Build builds the control-flow graph of a function body or a module's top level.
+// Nested functions are kept as opaque nodes, they get their own graph.
+func Build(nodes []ast.Node) *Graph {
+	b := &builder{graph: &Graph{}}
+	b.graph.Entry = b.newBlock()
+	b.graph.Exit = b.newBlock()
+	b.current = b.newBlock()
+	link(b.graph.Entry, b.current)
+
+	b.addNodes(nodes)
+	link(b.current, b.graph.Exit)
+
+	return b.graph
+}
+
+// BuildFunction builds the control-flow graph of a function literal's body
+func BuildFunction(fn *ast.FunctionLiteral) *Graph {
+	if fn.Body == nil {
+		return Build(nil)
+	}
+	return Build(fn.Body.Nodes)
+}
+
+func (b *builder) newBlock() *Block {
+	block := &Block{ID: len(b.graph.Blocks)}
+	b.graph.Blocks = append(b.graph.Blocks, block)
+	return block
+}
+
+func link(from, to *Block) {
+	from.Succs = append(from.Succs, to)
+	to.Preds = append(to.Preds, from)
+}
+
+// jump ends the current block with an edge to target. Code after a jump starts
+// in a fresh block without predecessors, so it is unreachable.
+func (b *builder) jump(target *Block) {
+	link(b.current, target)
+	b.current = b.newBlock()
+}
+
+func (b *builder) addNodes(nodes []ast.Node) {
+	for _, node := range nodes {
+		b.addNode(node)
+	}
+}
+
+func (b *builder) addNode(node ast.Node) {
+	switch n := node.(type) {
+	case *ast.Block:
+		b.addNodes(n.Nodes)
+	case *ast.IfStmt:
+		b.addIf(n)
+	case *ast.WhileStmt:
+		b.addLoop(nil, n.Condition, nil, n.Body)
+	case *ast.ForStmt:
+		b.addLoop(n.Init, n.Condition, n.Post, n.Body)
+	case *ast.ReturnStmt:
+		b.current.Nodes = append(b.current.Nodes, n)
+		b.jump(b.graph.Exit)
+	case *ast.BreakStmt:
+		if len(b.loops) > 0 {
+			b.jump(b.loops[len(b.loops)-1].breakTo)
+		}
+	case *ast.ContinueStmt:
+		if len(b.loops) > 0 {
+			b.jump(b.loops[len(b.loops)-1].continueTo)
+		}
+	default:
+		b.current.Nodes = append(b.current.Nodes, n)
+	}
+}
+
+func (b *builder) addIf(stmt *ast.IfStmt) {
+	cond := b.current
+	cond.Nodes = append(cond.Nodes, *stmt.Condition)
+	join := b.newBlock()
+
+	b.current = b.newBlock()
+	link(cond, b.current)
+	b.addNodes(stmt.Body.Nodes)
+	link(b.current, join)
+
+	if stmt.Alternative != nil {
+		b.current = b.newBlock()
+		link(cond, b.current)
+		b.addNode(stmt.Alternative)
+		link(b.current, join)
+	} else {
+		link(cond, join)
+	}
+
+	b.current = join
+}
+
+// addLoop adds a while loop or a for loop, a nil condition loops until a break
+func (b *builder) addLoop(init ast.Node, condition *ast.Expression, post ast.Node, body *ast.Block) {
+	if init != nil {
+		b.addNode(init)
+	}
+
+	header := b.newBlock()
+	link(b.current, header)
+	after := b.newBlock()
+	if condition != nil {
+		header.Nodes = append(header.Nodes, *condition)
+		link(header, after)
+	}
+
+	continueTo := header
+	if post != nil {
+		continueTo = b.newBlock()
+		continueTo.Nodes = append(continueTo.Nodes, post)
+		link(continueTo, header)
+	}
+
+	b.loops = append(b.loops, loopTargets{breakTo: after, continueTo: continueTo})
+	b.current = b.newBlock()
+	link(header, b.current)
+	b.addNodes(body.Nodes)
+	link(b.current, continueTo)
+	b.loops = b.loops[:len(b.loops)-1]
+
+	b.current = after
+}
+
+// Reachable returns the blocks that can run, starting from the entry
+func (g *Graph) Reachable() map[*Block]bool {
+	seen := map[*Block]bool{g.Entry: true}
+	stack := []*Block{g.Entry}
+	for len(stack) > 0 {
+		block := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, succ := range block.Succs {
+			if !seen[succ] {
+				seen[succ] = true
+				stack = append(stack, succ)
+			}
+		}
+	}
+	return seen
+}
@@ -0,0 +1,133 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+
+	"compiler/ctx"
+	"compiler/internal/frontend/ast"
+	"compiler/internal/report"
+	"compiler/internal/semantic"
+	"compiler/internal/semantic/analyzer"
+	"compiler/internal/source"
+)
+
+// resolveImport makes the imported module reachable through its alias, or binds the names its list imports.
+// The module itself is resolved by ResolveModules before any module importing it.
+func resolveImport(r *analyzer.AnalyzerNode, currentModule *ctx.Module, importStmt *ast.ImportStmt) {
+	if importStmt.FullPath == "" {
+		return
+	}
+	importModule, err := r.Ctx.GetModule(importStmt.ModulePath)
+	if err != nil {
+		r.Ctx.Reports.Add(r.Program.FullPath, importStmt.Loc(), err.Error(), report.RESOLVER_PHASE).SetLevel(report.SEMANTIC_ERROR)
+		return
+	}
+
+	if importStmt.ModuleName != "" {
+		currentModule.SymbolTable.Imports[importStmt.ModuleName] = importModule.SymbolTable
+		return
+	}
+
+	if importStmt.Wildcard {
+		for _, name := range exportedNames(importModule) {
+			bindImportedName(r, currentModule, importStmt, name, importModule.Exports.Symbols[name], importStmt.Loc())
+		}
+		return
+	}
+
+	for _, entry := range importStmt.Names {
+		sym := lookupImportedName(r, importStmt, importModule, entry.Name)
+		if sym == nil {
+			continue
+		}
+		local := entry.LocalName()
+		bindImportedName(r, currentModule, importStmt, local.Name, sym, local.Loc())
+	}
+}
+
+// exportedNames returns the names a module exports, sorted so wildcard imports bind them deterministically
+func exportedNames(module *ctx.Module) []string {
+	if module.Exports == nil {
+		return nil
+	}
+	names := make([]string, 0, len(module.Exports.Symbols))
+	for name := range module.Exports.Symbols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupImportedName finds a name listed in an import, reporting it when the module does not export it
+func lookupImportedName(r *analyzer.AnalyzerNode, importStmt *ast.ImportStmt, importModule *ctx.Module, name *ast.IdentifierExpr) *semantic.Symbol {
+	sym, found := lookupMember(importModule, name.Name)
+	if !found {
+		r.Ctx.Reports.Add(r.Program.FullPath, name.Loc(), fmt.Sprintf("'%s' not found in module '%s'", name.Name, importStmt.ImportPath.Value), report.RESOLVER_PHASE).SetLevel(report.SEMANTIC_ERROR)
+		return nil
+	}
+	if !checkExported(r, importModule, name.Name, sym, name.Loc()) {
+		return nil
+	}
+	return sym
+}
+
+// bindImportedName declares an imported symbol in the module's import scope, and exports it again from a pub import
+func bindImportedName(r *analyzer.AnalyzerNode, module *ctx.Module, importStmt *ast.ImportStmt, name string, sym *semantic.Symbol, loc *source.Location) {
+	if existing, found := module.ImportScope.Symbols[name]; found {
+		// Importing the same symbol twice, e.g. through a wildcard and a list, is harmless
+		if existing != sym {
+			previous := module.ImportedFrom[name]
+			r.Ctx.Reports.Add(r.Program.FullPath, loc, fmt.Sprintf("'%s' is already imported from '%s'", name, previous.ImportPath.Value), report.RESOLVER_PHASE).
+				AddRelated(importedIn(r, module, previous), previous.Loc(), fmt.Sprintf("'%s' is imported here", name)).
+				AddHint("Rename one of them with 'as'").SetLevel(report.SEMANTIC_ERROR)
+		}
+		return
+	}
+	module.ImportScope.Symbols[name] = sym
+	module.ImportedFrom[name] = importStmt
+	if importStmt.IsPublic {
+		exportSymbol(module, name, sym, false)
+	}
+}
+
+// checkImportCollisions reports top-level declarations that reuse a name an import list brought in.
+// It runs after the declarations are collected, so both sides are known.
+func checkImportCollisions(r *analyzer.AnalyzerNode, module *ctx.Module) {
+	names := make([]string, 0, len(module.ImportedFrom))
+	for name := range module.ImportedFrom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		declared, found := module.SymbolTable.Symbols[name]
+		if !found {
+			continue
+		}
+		importStmt := module.ImportedFrom[name]
+		r.Ctx.Reports.Add(declaredIn(declared, r.Program.FullPath), declared.Location, fmt.Sprintf("'%s' is already imported from '%s'", name, importStmt.ImportPath.Value), report.RESOLVER_PHASE).
+			AddRelated(importedIn(r, module, importStmt), importStmt.Loc(), fmt.Sprintf("'%s' is imported here", name)).
+			AddHint("Rename the declaration, or import the name with 'as'").SetLevel(report.SEMANTIC_ERROR)
+	}
+}
+
+// importedIn returns the file of the module an import statement is written in
+func importedIn(r *analyzer.AnalyzerNode, module *ctx.Module, importStmt *ast.ImportStmt) string {
+	if file := module.FileOf(importStmt); file != nil {
+		return file.FullPath
+	}
+	return r.Program.FullPath
+}
+
+// markNameUsed counts a reference to a name an import list brought in, for the unused import warnings
+func markNameUsed(r *analyzer.AnalyzerNode, name string, sym *semantic.Symbol) {
+	module, err := r.Ctx.GetModule(r.Program.ImportPath)
+	if err != nil || module.ImportScope == nil || module.ImportScope.Symbols[name] != sym {
+		return
+	}
+	if module.NameUses == nil {
+		module.NameUses = make(map[string]int)
+	}
+	module.NameUses[name]++
+}
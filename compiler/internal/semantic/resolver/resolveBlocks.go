@@ -0,0 +1,52 @@
+package resolver
+
+import (
+	"compiler/internal/frontend/ast"
+	"compiler/internal/semantic/analyzer"
+)
+
+// resolveBlock resolves the statements of a block in its own scope
+func resolveBlock(r *analyzer.AnalyzerNode, block *ast.Block) {
+	scope := r.EnterScope(block)
+	defer r.ExitScope()
+
+	for _, node := range block.Nodes {
+		resolveNode(r, node)
+	}
+	reportUnusedLocals(r, scope, nil)
+}
+
+// resolveIfStmt resolves the condition and every branch of an if statement
+func resolveIfStmt(r *analyzer.AnalyzerNode, stmt *ast.IfStmt) {
+	if stmt.Condition != nil {
+		resolveExpr(r, *stmt.Condition)
+	}
+	resolveBlock(r, stmt.Body)
+	if stmt.Alternative != nil {
+		resolveNode(r, stmt.Alternative)
+	}
+}
+
+// resolveWhileStmt resolves the condition and body of a while loop
+func resolveWhileStmt(r *analyzer.AnalyzerNode, stmt *ast.WhileStmt) {
+	resolveExpr(r, *stmt.Condition)
+	resolveBlock(r, stmt.Body)
+}
+
+// resolveForStmt resolves a for loop, the initializer declares its variables in a scope owned by the loop
+func resolveForStmt(r *analyzer.AnalyzerNode, stmt *ast.ForStmt) {
+	scope := r.EnterScope(stmt)
+	defer r.ExitScope()
+
+	if stmt.Init != nil {
+		resolveNode(r, stmt.Init)
+	}
+	if stmt.Condition != nil {
+		resolveExpr(r, *stmt.Condition)
+	}
+	if stmt.Post != nil {
+		resolveNode(r, stmt.Post)
+	}
+	resolveBlock(r, stmt.Body)
+	reportUnusedLocals(r, scope, nil)
+}
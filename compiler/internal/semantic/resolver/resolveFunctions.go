@@ -0,0 +1,140 @@
+package resolver
+
+import (
+	"fmt"
+
+	"compiler/internal/frontend/ast"
+	"compiler/internal/report"
+	"compiler/internal/semantic"
+	"compiler/internal/semantic/analyzer"
+	"compiler/internal/utils/fs"
+)
+
+// resolveFunctionDecl declares a named function in the current scope and resolves its body
+func resolveFunctionDecl(r *analyzer.AnalyzerNode, decl *ast.FunctionDecl) {
+	declareFunction(r, decl)
+	resolveFunctionLiteral(r, decl.Function)
+}
+
+// declareFunction declares a named function in the current scope, it returns nil for a function without a name
+func declareFunction(r *analyzer.AnalyzerNode, decl *ast.FunctionDecl) *semantic.Symbol {
+	if decl.Identifier == nil {
+		return nil
+	}
+	name := decl.Identifier.Name
+	// Native functions are implemented by the compiler, so only the standard library can declare them
+	if decl.Function.Body == nil && !fs.IsStd(r.Program.ImportPath) {
+		r.Ctx.Reports.Add(r.Program.FullPath, decl.Identifier.Loc(), fmt.Sprintf("function '%s' has no body", name), report.RESOLVER_PHASE).AddHint("Only the standard library declares functions without a body, add one in braces").SetLevel(report.SEMANTIC_ERROR)
+	}
+	fnType := semantic.FunctionLiteralToSemanticType(decl.Function)
+	sym := semantic.NewSymbolWithLocation(name, semantic.SymbolFunc, fnType, decl.Identifier.Loc())
+	if err := r.CurrentScope().Declare(name, sym); err != nil {
+		r.Ctx.Reports.Add(r.Program.FullPath, decl.Identifier.Loc(), err.Error(), report.RESOLVER_PHASE).SetLevel(report.SEMANTIC_ERROR)
+	}
+	return sym
+}
+
+// resolveFunctionLiteral resolves a function signature and its body
+func resolveFunctionLiteral(r *analyzer.AnalyzerNode, fn *ast.FunctionLiteral) {
+	resolveFunctionScope(r, fn, nil, fn.Params...)
+}
+
+// resolveFunctionScope resolves the function body in a new scope holding the receiver, if any, and the given parameters
+func resolveFunctionScope(r *analyzer.AnalyzerNode, fn *ast.FunctionLiteral, receiver *ast.Parameter, params ...ast.Parameter) {
+	if receiver != nil {
+		params = append([]ast.Parameter{*receiver}, params...)
+	}
+
+	for _, returnType := range fn.ReturnType {
+		resolveType(r, returnType)
+	}
+
+	if fn.Body == nil {
+		for _, param := range params {
+			resolveType(r, param.Type)
+		}
+		return
+	}
+
+	scope := r.EnterScope(fn.Body)
+	defer r.ExitScope()
+
+	paramNames := make(map[string]bool, len(params))
+	for i, param := range params {
+		paramNames[param.Identifier.Name] = receiver == nil || i > 0
+		resolveType(r, param.Type)
+		name := param.Identifier.Name
+		sym := semantic.NewSymbolWithLocation(name, semantic.SymbolVar, semantic.ASTToSemanticType(param.Type), param.Identifier.Loc())
+		if err := scope.Declare(name, sym); err != nil {
+			r.Ctx.Reports.Add(r.Program.FullPath, param.Identifier.Loc(), err.Error(), report.RESOLVER_PHASE).SetLevel(report.SEMANTIC_ERROR)
+		}
+	}
+
+	for _, node := range fn.Body.Nodes {
+		resolveNode(r, node)
+	}
+	reportUnusedLocals(r, scope, paramNames)
+}
+
+// resolveMethodDecl attaches the method to its receiver's struct type and resolves the method body
+func resolveMethodDecl(r *analyzer.AnalyzerNode, decl *ast.MethodDecl) {
+	declareMethod(r, decl)
+	resolveMethodBody(r, decl)
+}
+
+// declareMethod attaches the method to its receiver's struct type
+func declareMethod(r *analyzer.AnalyzerNode, decl *ast.MethodDecl) {
+	receiver := decl.Receiver
+	resolveType(r, receiver.Type)
+	if decl.Function.Body == nil {
+		r.Ctx.Reports.Add(r.Program.FullPath, decl.Method.Loc(), fmt.Sprintf("method '%s' has no body", decl.Method.Name), report.RESOLVER_PHASE).AddHint("Add a body in braces").SetLevel(report.SEMANTIC_ERROR)
+	}
+
+	if structType := receiverStructType(r, receiver); structType != nil {
+		method := semantic.FunctionLiteralToSemanticType(decl.Function)
+		if decl.IsPrivate && structType.GetMethod(decl.Method.Name) == nil && !structType.HasField(decl.Method.Name) {
+			structType.SetPrivate(decl.Method.Name)
+		}
+		if !structType.AddMethod(decl.Method.Name, method) {
+			r.Ctx.Reports.Add(r.Program.FullPath, decl.Method.Loc(), fmt.Sprintf("'%s' is already declared as a field or method of '%s'", decl.Method.Name, structType.Name), report.RESOLVER_PHASE).SetLevel(report.SEMANTIC_ERROR)
+		}
+	}
+}
+
+// resolveMethodBody resolves the method body with the receiver in scope next to the parameters
+func resolveMethodBody(r *analyzer.AnalyzerNode, decl *ast.MethodDecl) {
+	resolveFunctionScope(r, decl.Function, decl.Receiver, decl.Function.Params...)
+}
+
+// receiverStructType finds the struct type a method receiver refers to, reporting an error if there is none
+func receiverStructType(r *analyzer.AnalyzerNode, receiver *ast.Parameter) *semantic.StructType {
+	userType, ok := receiver.Type.(*ast.UserDefinedType)
+	if !ok {
+		r.Ctx.Reports.Add(r.Program.FullPath, receiver.Type.Loc(), "methods can only be declared on struct types defined in this module", report.RESOLVER_PHASE).SetLevel(report.SEMANTIC_ERROR)
+		return nil
+	}
+
+	sym, found := r.CurrentScope().Lookup(string(userType.TypeName))
+	if !found || sym.Kind != semantic.SymbolType {
+		return nil // Reported by resolveType
+	}
+
+	structType, ok := sym.Type.(*semantic.StructType)
+	if !ok {
+		r.Ctx.Reports.Add(r.Program.FullPath, receiver.Type.Loc(), fmt.Sprintf("methods can only be declared on struct types, '%s' is %s", userType.TypeName, sym.Type.String()), report.RESOLVER_PHASE).SetLevel(report.SEMANTIC_ERROR)
+		return nil
+	}
+	return structType
+}
+
+// resolveReturnStmt resolves the returned expressions
+func resolveReturnStmt(r *analyzer.AnalyzerNode, stmt *ast.ReturnStmt) {
+	if stmt.Values == nil {
+		return
+	}
+	for _, value := range *stmt.Values {
+		if value != nil {
+			resolveExpr(r, value)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package resolver
+
+import (
+	"compiler/internal/frontend/ast"
+	"compiler/internal/report"
+	"compiler/internal/semantic"
+	"compiler/internal/semantic/analyzer"
+)
+
+// resolveType checks that every named type referenced by a type expression is declared
+func resolveType(r *analyzer.AnalyzerNode, dataType ast.DataType) {
+	switch t := dataType.(type) {
+	case *ast.UserDefinedType:
+		resolveUserDefinedType(r, t)
+	case *ast.TypeScopeResolution:
+		resolveTypeScopeResolution(r, t)
+	case *ast.ArrayType:
+		resolveType(r, t.ElementType)
+	case *ast.StructType:
+		for _, field := range t.Fields {
+			if field.FieldType != nil {
+				resolveType(r, field.FieldType)
+			}
+		}
+	case *ast.InterfaceType:
+		for _, method := range t.Methods {
+			for _, param := range method.Params {
+				resolveType(r, param.Type)
+			}
+			for _, returnType := range method.ReturnType {
+				resolveType(r, returnType)
+			}
+		}
+	case *ast.FunctionType:
+		for _, param := range t.Parameters {
+			resolveType(r, param)
+		}
+		for _, returnType := range t.ReturnTypes {
+			resolveType(r, returnType)
+		}
+	default:
+		// Primitive types need no resolution
+	}
+}
+
+// resolveUserDefinedType checks that a named type is declared and is actually a type
+func resolveUserDefinedType(r *analyzer.AnalyzerNode, t *ast.UserDefinedType) {
+	typeName := string(t.TypeName)
+	sym, found := r.CurrentScope().Lookup(typeName)
+	if !found {
+		r.Ctx.Reports.Add(r.Program.FullPath, t.Loc(), "undefined type: "+typeName, report.RESOLVER_PHASE).SetLevel(report.SEMANTIC_ERROR)
+		return
+	}
+	if sym.Kind != semantic.SymbolType {
+		r.Ctx.Reports.Add(r.Program.FullPath, t.Loc(), "'"+typeName+"' is not a type", report.RESOLVER_PHASE).SetLevel(report.SEMANTIC_ERROR)
+		return
+	}
+	sym.Uses++
+	markNameUsed(r, typeName, sym)
+}
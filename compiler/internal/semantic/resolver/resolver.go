@@ -14,10 +14,28 @@ import (
 	"compiler/internal/types"
 )
 
+// ResolveModules resolves every module in the context once, each after the modules it imports
+func ResolveModules(context *ctx.CompilerContext, debug bool) {
+	for _, importPath := range context.ModuleOrder() {
+		ResolveProgram(analyzer.NewAnalyzerNode(context.Modules[importPath].AST, context, debug))
+	}
+}
+
+// ResolveProgram resolves a single module, its imports must be resolved already
 func ResolveProgram(r *analyzer.AnalyzerNode) {
-	for _, node := range r.Program.Nodes {
-		resolveNode(r, node)
+	module, err := r.Ctx.GetModule(r.Program.ImportPath)
+	if err != nil {
+		r.Ctx.Reports.Add(r.Program.FullPath, r.Program.Loc(), err.Error(), report.RESOLVER_PHASE).SetLevel(report.CRITICAL_ERROR)
+		return
 	}
+	if module.Phase >= ctx.PHASE_RESOLVED {
+		return
+	}
+
+	resolveTopLevel(r, module)
+	inFiles(r, module, func() { reportUnusedImports(r) })
+	reportUnusedFunctions(r, module)
+	module.Phase = ctx.PHASE_RESOLVED
 	if r.Debug {
 		colors.GREEN.Printf("Resolved '%s'\n", r.Program.FullPath)
 	}
@@ -29,9 +47,13 @@ func resolveNode(r *analyzer.AnalyzerNode, node ast.Node) {
 		r.Ctx.Reports.Add(r.Program.FullPath, r.Program.Loc(), err.Error(), report.RESOLVER_PHASE).SetLevel(report.CRITICAL_ERROR)
 		return
 	}
+	// Top-level declarations are resolved by resolveTopLevel, these are all local
+	reportLocalPrivate(r, node)
 	switch n := node.(type) {
 	case *ast.ImportStmt:
 		resolveImport(r, currentModule, n)
+	case *ast.ModuleDeclStmt:
+		r.Ctx.Reports.Add(r.Program.FullPath, n.Loc(), report.MODULE_DECL_NOT_FIRST, report.RESOLVER_PHASE).AddHint("Move the declaration to the top of the file").SetLevel(report.SEMANTIC_ERROR)
 	case *ast.VarDeclStmt:
 		resolveVarDecl(r, n)
 	case *ast.AssignmentStmt:
@@ -40,19 +62,26 @@ func resolveNode(r *analyzer.AnalyzerNode, node ast.Node) {
 		resolveExpressionStmt(r, n)
 	case *ast.TypeDeclStmt:
 		resolveTypeDecl(r, n)
-	case *ast.TypeScopeResolution:
-		resolveTypeScopeResolution(r, n)
-	// Basic data types - these are primitive types that don't need special resolution
-	case *ast.StringType:
-		// String type is a primitive, no additional resolution needed
-	case *ast.IntType:
-		// Integer type is a primitive, no additional resolution needed
-	case *ast.FloatType:
-		// Float type is a primitive, no additional resolution needed
-	case *ast.BoolType:
-		// Boolean type is a primitive, no additional resolution needed
-	case *ast.ByteType:
-		// Byte type is a primitive, no additional resolution needed
+	case *ast.FunctionDecl:
+		resolveFunctionDecl(r, n)
+	case *ast.MethodDecl:
+		resolveMethodDecl(r, n)
+	case *ast.FunctionLiteral:
+		resolveFunctionLiteral(r, n)
+	case *ast.ReturnStmt:
+		resolveReturnStmt(r, n)
+	case *ast.IfStmt:
+		resolveIfStmt(r, n)
+	case *ast.Block:
+		resolveBlock(r, n)
+	case *ast.WhileStmt:
+		resolveWhileStmt(r, n)
+	case *ast.ForStmt:
+		resolveForStmt(r, n)
+	case *ast.BreakStmt, *ast.ContinueStmt:
+		// Nothing to resolve, the parser checks that they are inside a loop
+	case ast.DataType:
+		resolveType(r, n)
 	default:
 		fmt.Printf("[Resolver] Node <%T> is not implemented yet\n", n)
 		os.Exit(-1)
@@ -60,93 +89,106 @@ func resolveNode(r *analyzer.AnalyzerNode, node ast.Node) {
 }
 
 func resolveTypeDecl(r *analyzer.AnalyzerNode, stmt *ast.TypeDeclStmt) {
+	if declareType(r, stmt) != nil {
+		// Resolved after declaring so that a type can refer to itself
+		resolveType(r, stmt.BaseType)
+	}
+}
+
+// declareType declares the type in the current module, it returns nil if the type name is not usable
+func declareType(r *analyzer.AnalyzerNode, stmt *ast.TypeDeclStmt) *semantic.Symbol {
 	// check if type is already declared or built-in or keyword
 	typeName := stmt.Alias.Name
-	if lexer.IsKeyword(typeName) || types.IsPrimitiveType(typeName) {
+	if isReservedTypeName(typeName) {
 		r.Ctx.Reports.Add(r.Program.FullPath, stmt.Alias.Loc(), "cannot declare type with reserved keyword: "+typeName, report.RESOLVER_PHASE).SetLevel(report.SEMANTIC_ERROR)
-		return
+		return nil
 	}
 	//declare the type in the current module
 	currentModule, err := r.Ctx.GetModule(r.Program.ImportPath)
 	if err != nil {
 		r.Ctx.Reports.Add(r.Program.FullPath, stmt.Alias.Loc(), err.Error(), report.RESOLVER_PHASE).SetLevel(report.CRITICAL_ERROR)
-		return
+		return nil
 	}
 
 	// Convert AST type to semantic type
 	semanticType := semantic.ASTToSemanticType(stmt.BaseType)
+	// Named structs and interfaces take the declared name
+	switch t := semanticType.(type) {
+	case *semantic.StructType:
+		t.Name = types.TYPE_NAME(typeName)
+		t.Module = r.Program.ImportPath
+	case *semantic.InterfaceType:
+		t.Name = types.TYPE_NAME(typeName)
+		t.Module = r.Program.ImportPath
+	}
 	sym := semantic.NewSymbolWithLocation(typeName, semantic.SymbolType, semanticType, stmt.Alias.Loc())
-	currentModule.SymbolTable.Declare(typeName, sym)
+	if err := currentModule.SymbolTable.Declare(typeName, sym); err != nil {
+		r.Ctx.Reports.Add(r.Program.FullPath, stmt.Alias.Loc(), err.Error(), report.RESOLVER_PHASE).SetLevel(report.SEMANTIC_ERROR)
+	}
+	return sym
 }
 
-func resolveImport(r *analyzer.AnalyzerNode, currentModule *ctx.Module, importStmt *ast.ImportStmt) {
-	if importStmt.ModuleName != "" && importStmt.FullPath != "" {
-		importModule, err := r.Ctx.GetModule(importStmt.ImportPath.Value)
-		if err == nil {
-			importModuleAST := importModule.AST
-			//semantic.AddPreludeSymbols(importModule.SymbolTable)
-			anz := analyzer.NewAnalyzerNode(importModuleAST, r.Ctx, r.Debug)
-			ResolveProgram(anz)
-			currentModule.SymbolTable.Imports[importStmt.ModuleName] = importModule.SymbolTable
-		} else {
-			r.Ctx.Reports.Add(r.Program.FullPath, importStmt.Loc(), err.Error(), report.RESOLVER_PHASE).SetLevel(report.SEMANTIC_ERROR)
-		}
-	}
+func isReservedTypeName(name string) bool {
+	return lexer.IsKeyword(name) || types.IsPrimitiveType(name)
 }
 
 func resolveVarDecl(r *analyzer.AnalyzerNode, stmt *ast.VarDeclStmt) {
-	currentModuleImportpath := r.Program.ImportPath
 	for i, v := range stmt.Variables {
-		name := v.Identifier.Name
-		kind := semantic.SymbolVar
-		if stmt.IsConst {
-			kind = semantic.SymbolConst
-		}
 		// Type checking: ensure explicit type exists if provided
-		currentModule, err := r.Ctx.GetModule(currentModuleImportpath)
-		if err != nil {
+		if _, err := r.Ctx.GetModule(r.Program.ImportPath); err != nil {
 			r.Ctx.Reports.Add(r.Program.FullPath, v.Identifier.Loc(), err.Error(), report.RESOLVER_PHASE).SetLevel(report.CRITICAL_ERROR)
 			return
 		}
 
 		if v.ExplicitType != nil {
-			resolveNode(r, v.ExplicitType)
+			resolveType(r, v.ExplicitType)
 		}
+		declareVariable(r, stmt, v)
+		resolveVarInitializer(r, stmt, i)
+	}
+}
 
-		// Convert AST type to semantic type
-		var semanticType semantic.Type
-		if v.ExplicitType != nil {
-			semanticType = semantic.ASTToSemanticType(v.ExplicitType)
-		}
+// declareVariable declares one variable of the statement in the current scope
+func declareVariable(r *analyzer.AnalyzerNode, stmt *ast.VarDeclStmt, v *ast.VariableToDeclare) *semantic.Symbol {
+	name := v.Identifier.Name
+	kind := semantic.SymbolVar
+	if stmt.IsConst {
+		kind = semantic.SymbolConst
+	}
 
-		sym := semantic.NewSymbolWithLocation(name, kind, semanticType, v.Identifier.Loc())
+	// Convert AST type to semantic type
+	var semanticType semantic.Type
+	if v.ExplicitType != nil {
+		semanticType = semantic.ASTToSemanticType(v.ExplicitType)
+	}
 
-		err = currentModule.SymbolTable.Declare(name, sym)
-		if err != nil {
-			// Redeclaration error
-			r.Ctx.Reports.Add(r.Program.FullPath, v.Identifier.Loc(), err.Error(), report.RESOLVER_PHASE).SetLevel(report.SEMANTIC_ERROR)
-		}
-		// Check initializer expression if present
-		if i < len(stmt.Initializers) && stmt.Initializers[i] != nil {
-			resolveExpr(r, stmt.Initializers[i])
-		}
+	sym := semantic.NewSymbolWithLocation(name, kind, semanticType, v.Identifier.Loc())
+
+	err := r.CurrentScope().Declare(name, sym)
+	if err != nil {
+		// Redeclaration error
+		r.Ctx.Reports.Add(r.Program.FullPath, v.Identifier.Loc(), err.Error(), report.RESOLVER_PHASE).SetLevel(report.SEMANTIC_ERROR)
+	}
+	return sym
+}
+
+// resolveVarInitializer resolves the initializer of the i-th variable, if it has one
+func resolveVarInitializer(r *analyzer.AnalyzerNode, stmt *ast.VarDeclStmt, i int) {
+	if i < len(stmt.Initializers) && stmt.Initializers[i] != nil {
+		resolveExpr(r, stmt.Initializers[i])
 	}
 }
 
 func resolveAssignment(r *analyzer.AnalyzerNode, stmt *ast.AssignmentStmt) { // Check that all left-hand side variables are declared
 	for _, lhs := range *stmt.Left {
 		if id, ok := lhs.(*ast.IdentifierExpr); ok {
-			varSym, found := r.Ctx.Modules[r.Program.FullPath].SymbolTable.Lookup(id.Name)
+			sym, found := r.CurrentScope().Lookup(id.Name)
 			if !found {
 				r.Ctx.Reports.Add(r.Program.FullPath, id.Loc(), "assignment to undeclared variable: "+id.Name, report.RESOLVER_PHASE).SetLevel(report.SEMANTIC_ERROR)
-			} else if varSym.Type != nil {
-				// Type checking: ensure type exists for variable
-				typeName := string(varSym.Type.TypeName())
-				typeSym, found := r.Ctx.Modules[r.Program.FullPath].SymbolTable.Lookup(typeName)
-				if !found || typeSym.Kind != semantic.SymbolType {
-					r.Ctx.Reports.Add(r.Program.FullPath, id.Loc(), "unknown type for variable: "+typeName, report.RESOLVER_PHASE).SetLevel(report.SEMANTIC_ERROR)
-				}
+				continue
 			}
+			// Writing to an imported name is rejected by the type checker, the import is still referenced
+			markNameUsed(r, id.Name, sym)
 		} else {
 			resolveExpr(r, lhs)
 		}
@@ -225,8 +267,15 @@ func resolveIdentifierExpr(r *analyzer.AnalyzerNode, iden *ast.IdentifierExpr) {
 		return
 	}
 
-	if _, found := module.SymbolTable.Lookup(iden.Name); !found {
+	sym, found := r.CurrentScope().Lookup(iden.Name)
+	if !found {
 		r.Ctx.Reports.Add(r.Program.FullPath, iden.Loc(), "undeclared variable: "+iden.Name, report.RESOLVER_PHASE).SetLevel(report.SEMANTIC_ERROR)
+		return
+	}
+	sym.Uses++
+	markNameUsed(r, iden.Name, sym)
+	if r.Reads != nil && module.SymbolTable.Symbols[iden.Name] == sym {
+		r.Reads[sym] = true
 	}
 }
 
@@ -245,6 +294,14 @@ func resolveArrayLiterals(r *analyzer.AnalyzerNode, expr *ast.ArrayLiteralExpr)
 }
 
 func resolveStructLiteralExpr(r *analyzer.AnalyzerNode, expr *ast.StructLiteralExpr) {
+	// The type checker reports a name that is not a struct type, the use of one is counted here so an
+	// imported type used only by literals is not unused
+	if expr.StructName != nil {
+		if sym, found := r.CurrentScope().Lookup(expr.StructName.Name); found {
+			sym.Uses++
+			markNameUsed(r, expr.StructName.Name, sym)
+		}
+	}
 	// Resolve struct field values
 	for _, field := range expr.Fields {
 		if field.FieldValue != nil {
@@ -253,15 +310,6 @@ func resolveStructLiteralExpr(r *analyzer.AnalyzerNode, expr *ast.StructLiteralE
 	}
 }
 
-func resolveFunctionLiteral(r *analyzer.AnalyzerNode, fn *ast.FunctionLiteral) {
-	// Resolve function body
-	if fn.Body != nil {
-		for _, node := range fn.Body.Nodes {
-			resolveNode(r, node)
-		}
-	}
-}
-
 func resolveTypeScopeResolution(r *analyzer.AnalyzerNode, expr *ast.TypeScopeResolution) {
 
 	modulename := expr.Module.Name
@@ -288,8 +336,10 @@ func resolveTypeScopeResolution(r *analyzer.AnalyzerNode, expr *ast.TypeScopeRes
 		return
 	}
 
+	markImportUsed(r, modulename)
+
 	// Look up the type symbol in the imported module's symbol table
-	symbol, found := importModule.SymbolTable.Lookup(typeName)
+	symbol, found := lookupMember(importModule, typeName)
 	if !found {
 		r.Ctx.Reports.Add(r.Program.FullPath, expr.TypeNode.Loc(), fmt.Sprintf("type '%s' not found in module '%s'", typeName, modulename), report.RESOLVER_PHASE).SetLevel(report.SEMANTIC_ERROR)
 		return
@@ -300,6 +350,10 @@ func resolveTypeScopeResolution(r *analyzer.AnalyzerNode, expr *ast.TypeScopeRes
 		r.Ctx.Reports.Add(r.Program.FullPath, expr.TypeNode.Loc(), fmt.Sprintf("expected type but found variable '%s' in module '%s'", typeName, modulename), report.RESOLVER_PHASE).SetLevel(report.SEMANTIC_ERROR)
 		return
 	}
+	if !checkExported(r, importModule, typeName, symbol, expr.TypeNode.Loc()) {
+		return
+	}
+	symbol.Uses++
 }
 
 func resolveVarScopeResolution(r *analyzer.AnalyzerNode, expr ast.VarScopeResolution) {
@@ -318,8 +372,10 @@ func resolveVarScopeResolution(r *analyzer.AnalyzerNode, expr ast.VarScopeResolu
 		return
 	}
 
+	markImportUsed(r, modulename)
+
 	// Look up the variable symbol in the imported module's symbol table
-	symbol, found := importModule.SymbolTable.Lookup(expr.Var.Name)
+	symbol, found := lookupMember(importModule, expr.Var.Name)
 	if !found {
 		r.Ctx.Reports.Add(r.Program.FullPath, expr.Var.Loc(), fmt.Sprintf("variable '%s' not found in module '%s'", expr.Var.Name, modulename), report.RESOLVER_PHASE).SetLevel(report.SEMANTIC_ERROR)
 		return
@@ -330,4 +386,8 @@ func resolveVarScopeResolution(r *analyzer.AnalyzerNode, expr ast.VarScopeResolu
 		r.Ctx.Reports.Add(r.Program.FullPath, expr.Var.Loc(), fmt.Sprintf("expected variable but found type '%s' in module '%s'", expr.Var.Name, modulename), report.RESOLVER_PHASE).SetLevel(report.SEMANTIC_ERROR)
 		return
 	}
+	if !checkExported(r, importModule, expr.Var.Name, symbol, expr.Var.Loc()) {
+		return
+	}
+	symbol.Uses++
 }
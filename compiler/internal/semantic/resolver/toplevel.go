@@ -0,0 +1,333 @@
+package resolver
+
+import (
+	"fmt"
+	"strings"
+
+	"compiler/ctx"
+	"compiler/internal/frontend/ast"
+	"compiler/internal/report"
+	"compiler/internal/semantic"
+	"compiler/internal/semantic/analyzer"
+)
+
+// initGraph records which module symbols the top-level declarations and statements read,
+// so global initializers can run in dependency order
+type initGraph struct {
+	vars       []*semantic.Symbol                             // top-level variables in declaration order
+	declaredBy map[*semantic.Symbol]ast.Node                  // top-level variable or function -> declaring node
+	reads      map[*semantic.Symbol]map[*semantic.Symbol]bool // variable initializer or function body -> module symbols it reads
+	nodeReads  map[ast.Node]map[*semantic.Symbol]bool         // top-level statement -> module symbols it reads
+	functions  map[*ast.FunctionDecl]*semantic.Symbol
+	variables  map[*ast.VariableToDeclare]*semantic.Symbol
+}
+
+// resolveTopLevel resolves the module in two passes. Every top-level type, function, method and
+// variable is declared first, so bodies and initializers can refer to declarations further down,
+// or to declarations in the other files of a directory module.
+func resolveTopLevel(r *analyzer.AnalyzerNode, module *ctx.Module) {
+	// pub imports add to the exports, so they exist before the imports are resolved
+	module.Exports = semantic.NewSymbolTable(nil)
+	inFiles(r, module, func() {
+		for _, node := range r.Program.Nodes {
+			if importStmt, ok := node.(*ast.ImportStmt); ok {
+				resolveImport(r, module, importStmt)
+			}
+		}
+	})
+
+	g := &initGraph{
+		declaredBy: make(map[*semantic.Symbol]ast.Node),
+		reads:      make(map[*semantic.Symbol]map[*semantic.Symbol]bool),
+		nodeReads:  make(map[ast.Node]map[*semantic.Symbol]bool),
+		functions:  make(map[*ast.FunctionDecl]*semantic.Symbol),
+		variables:  make(map[*ast.VariableToDeclare]*semantic.Symbol),
+	}
+	collectDeclarations(r, module, g)
+	checkImportCollisions(r, module)
+	inFiles(r, module, func() {
+		for _, node := range r.Program.Nodes {
+			resolveTopLevelNode(r, g, node)
+		}
+	})
+	module.Order = orderTopLevel(r, module, g)
+}
+
+// inFiles runs fn once for every file of the module, with r.Program set to the file so reports point into it
+func inFiles(r *analyzer.AnalyzerNode, module *ctx.Module, fn func()) {
+	program := r.Program
+	defer func() { r.Program = program }()
+	if len(module.Files) == 0 {
+		fn()
+		return
+	}
+	for _, file := range module.Files {
+		r.Program = file
+		fn()
+	}
+}
+
+// declaredIn returns the file a top-level symbol is declared in, or file when the symbol does not record one
+func declaredIn(sym *semantic.Symbol, file string) string {
+	if sym.File != "" {
+		return sym.File
+	}
+	return file
+}
+
+// collectDeclarations is the first pass, it declares the top-level symbols without looking into bodies
+func collectDeclarations(r *analyzer.AnalyzerNode, module *ctx.Module, g *initGraph) {
+	inFiles(r, module, func() {
+		for _, node := range r.Program.Nodes {
+			switch n := node.(type) {
+			case *ast.TypeDeclStmt:
+				if sym := declareType(r, n); sym != nil {
+					sym.File = r.Program.FullPath
+					exportSymbol(module, sym.Name, sym, n.IsPrivate)
+				}
+			case *ast.FunctionDecl:
+				if sym := declareFunction(r, n); sym != nil {
+					sym.File = r.Program.FullPath
+					g.functions[n] = sym
+					g.declaredBy[sym] = n
+					exportSymbol(module, sym.Name, sym, n.IsPrivate)
+				}
+			case *ast.VarDeclStmt:
+				for _, v := range n.Variables {
+					sym := declareVariable(r, n, v)
+					sym.File = r.Program.FullPath
+					g.variables[v] = sym
+					g.declaredBy[sym] = n
+					g.vars = append(g.vars, sym)
+					exportSymbol(module, sym.Name, sym, n.IsPrivate)
+				}
+			}
+		}
+	})
+
+	// Methods need every receiver type declared first
+	inFiles(r, module, func() {
+		for _, node := range r.Program.Nodes {
+			if method, ok := node.(*ast.MethodDecl); ok {
+				declareMethod(r, method)
+			}
+		}
+	})
+}
+
+// resolveTopLevelNode is the second pass, it resolves a top-level node whose symbols are already declared
+func resolveTopLevelNode(r *analyzer.AnalyzerNode, g *initGraph, node ast.Node) {
+	switch n := node.(type) {
+	case *ast.ImportStmt, *ast.ModuleDeclStmt:
+		// Resolved before the declarations
+	case *ast.TypeDeclStmt:
+		if !isReservedTypeName(n.Alias.Name) {
+			resolveType(r, n.BaseType)
+		}
+	case *ast.FunctionDecl:
+		reads := collectReads(r, func() { resolveFunctionLiteral(r, n.Function) })
+		if sym := g.functions[n]; sym != nil {
+			g.reads[sym] = reads
+		}
+	case *ast.MethodDecl:
+		resolveMethodBody(r, n)
+	case *ast.VarDeclStmt:
+		g.nodeReads[n] = collectReads(r, func() {
+			for i, v := range n.Variables {
+				if v.ExplicitType != nil {
+					resolveType(r, v.ExplicitType)
+				}
+				g.reads[g.variables[v]] = collectReads(r, func() { resolveVarInitializer(r, n, i) })
+			}
+		})
+	default:
+		g.nodeReads[n] = collectReads(r, func() { resolveNode(r, n) })
+	}
+}
+
+// collectReads runs resolve and returns the module symbols it read, nested collections also count for the outer one
+func collectReads(r *analyzer.AnalyzerNode, resolve func()) map[*semantic.Symbol]bool {
+	outer := r.Reads
+	r.Reads = make(map[*semantic.Symbol]bool)
+	resolve()
+	reads := r.Reads
+	r.Reads = outer
+	for sym := range reads {
+		if outer != nil {
+			outer[sym] = true
+		}
+	}
+	return reads
+}
+
+// orderTopLevel reports initialization cycles and returns the top-level nodes in evaluation order.
+// Statements keep their source order, the files of a directory module in file name order, unless
+// they read a variable declared further down, then that declaration runs first.
+func orderTopLevel(r *analyzer.AnalyzerNode, module *ctx.Module, g *initGraph) []ast.Node {
+	reportInitCycles(r, g)
+
+	var declarations, statements, functions []ast.Node
+	inFiles(r, module, func() {
+		for _, node := range r.Program.Nodes {
+			switch node.(type) {
+			case *ast.ImportStmt, *ast.ModuleDeclStmt, *ast.TypeDeclStmt:
+				declarations = append(declarations, node)
+			case *ast.FunctionDecl, *ast.MethodDecl:
+				functions = append(functions, node)
+			default:
+				statements = append(statements, node)
+			}
+		}
+	})
+
+	deps := make(map[ast.Node]map[ast.Node]bool, len(statements))
+	for _, stmt := range statements {
+		deps[stmt] = g.dependencies(stmt)
+	}
+
+	order := declarations
+	done := make(map[ast.Node]bool, len(statements))
+	for len(done) < len(statements) {
+		var next ast.Node
+		for _, stmt := range statements {
+			if !done[stmt] && ready(deps[stmt], done) {
+				next = stmt
+				break
+			}
+		}
+		if next == nil {
+			// Only happens in a cycle, which is reported above: fall back to source order
+			for _, stmt := range statements {
+				if !done[stmt] {
+					next = stmt
+					break
+				}
+			}
+		}
+		done[next] = true
+		order = append(order, next)
+	}
+
+	return append(order, functions...)
+}
+
+func ready(deps, done map[ast.Node]bool) bool {
+	for dep := range deps {
+		if !done[dep] {
+			return false
+		}
+	}
+	return true
+}
+
+// dependencies returns the variable declarations a top-level statement needs to run before it,
+// following calls into the bodies of top-level functions
+func (g *initGraph) dependencies(stmt ast.Node) map[ast.Node]bool {
+	deps := make(map[ast.Node]bool)
+	seen := make(map[*semantic.Symbol]bool)
+	pending := make([]*semantic.Symbol, 0, len(g.nodeReads[stmt]))
+	for sym := range g.nodeReads[stmt] {
+		pending = append(pending, sym)
+	}
+
+	for len(pending) > 0 {
+		sym := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if seen[sym] {
+			continue
+		}
+		seen[sym] = true
+
+		switch decl := g.declaredBy[sym].(type) {
+		case *ast.VarDeclStmt:
+			if decl != stmt {
+				deps[decl] = true
+			}
+		case *ast.FunctionDecl:
+			for read := range g.reads[sym] {
+				pending = append(pending, read)
+			}
+		}
+	}
+	return deps
+}
+
+// reportInitCycles reports every variable whose initializer needs its own value, directly or through other declarations
+func reportInitCycles(r *analyzer.AnalyzerNode, g *initGraph) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[*semantic.Symbol]int)
+	var stack []*semantic.Symbol
+
+	var visit func(sym *semantic.Symbol)
+	visit = func(sym *semantic.Symbol) {
+		state[sym] = visiting
+		stack = append(stack, sym)
+
+		reads := make([]*semantic.Symbol, 0, len(g.reads[sym]))
+		for read := range g.reads[sym] {
+			if _, tracked := g.declaredBy[read]; tracked {
+				reads = append(reads, read)
+			}
+		}
+		sortBySource(reads)
+
+		for _, read := range reads {
+			switch state[read] {
+			case unvisited:
+				visit(read)
+			case visiting:
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == read {
+						reportInitCycle(r, g, stack[i:])
+						break
+					}
+				}
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[sym] = visited
+	}
+
+	for _, sym := range g.vars {
+		if state[sym] == unvisited {
+			visit(sym)
+		}
+	}
+}
+
+// reportInitCycle reports a cycle of declarations, each reading the next and the last reading the first.
+// Cycles made only of functions are plain recursion and are fine.
+func reportInitCycle(r *analyzer.AnalyzerNode, g *initGraph, cycle []*semantic.Symbol) {
+	start := -1
+	for i, sym := range cycle {
+		if _, isVar := g.declaredBy[sym].(*ast.VarDeclStmt); isVar {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return
+	}
+	cycle = append(append([]*semantic.Symbol{}, cycle[start:]...), cycle[:start]...)
+	first := cycle[0]
+
+	msg := fmt.Sprintf("initialization cycle: '%s' refers to itself", first.Name)
+	if len(cycle) > 1 {
+		names := make([]string, 0, len(cycle)+1)
+		for _, sym := range cycle {
+			names = append(names, sym.Name)
+		}
+		msg = "initialization cycle: " + strings.Join(append(names, first.Name), " -> ")
+	}
+
+	rep := r.Ctx.Reports.Add(declaredIn(first, r.Program.FullPath), first.Location, msg, report.RESOLVER_PHASE)
+	for i, sym := range cycle[1:] {
+		rep.AddRelated(declaredIn(sym, r.Program.FullPath), sym.Location, fmt.Sprintf("'%s' refers to '%s'", sym.Name, cycle[(i+2)%len(cycle)].Name))
+	}
+	rep.AddHint("Assign one of the values in a statement instead of the initializer").SetLevel(report.SEMANTIC_ERROR)
+}
@@ -0,0 +1,157 @@
+package resolver
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"compiler/ctx"
+	"compiler/internal/frontend/ast"
+	"compiler/internal/report"
+	"compiler/internal/semantic"
+	"compiler/internal/semantic/analyzer"
+	"compiler/internal/source"
+)
+
+// isIgnoredName reports whether a name opts out of unused warnings with a leading underscore
+func isIgnoredName(name string) bool {
+	return strings.HasPrefix(name, "_")
+}
+
+// sortedSymbols returns the symbols of a scope in source order so warnings come out deterministically
+func sortedSymbols(scope *semantic.SymbolTable) []*semantic.Symbol {
+	symbols := make([]*semantic.Symbol, 0, len(scope.Symbols))
+	for _, sym := range scope.Symbols {
+		if sym.Location != nil {
+			symbols = append(symbols, sym)
+		}
+	}
+	sortBySource(symbols)
+	return symbols
+}
+
+// sortBySource sorts symbols by where they are declared
+func sortBySource(symbols []*semantic.Symbol) {
+	slices.SortFunc(symbols, func(a, b *semantic.Symbol) int {
+		if c := cmp.Compare(a.Location.Start.Line, b.Location.Start.Line); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Location.Start.Column, b.Location.Start.Column)
+	})
+}
+
+// reportUnusedLocals warns about the variables, constants and functions of a local scope that are never read.
+// params maps the names of the scope's parameters to whether they are reported when unused: a method
+// receiver is not, the method may exist only to satisfy an interface.
+func reportUnusedLocals(r *analyzer.AnalyzerNode, scope *semantic.SymbolTable, params map[string]bool) {
+	for _, sym := range sortedSymbols(scope) {
+		if sym.Uses > 0 || isIgnoredName(sym.Name) {
+			continue
+		}
+
+		var what string
+		warn, isParam := params[sym.Name]
+		switch {
+		case isParam && !warn:
+			continue
+		case isParam:
+			what = "parameter"
+		case sym.Kind == semantic.SymbolVar:
+			what = "variable"
+		case sym.Kind == semantic.SymbolConst:
+			what = "constant"
+		case sym.Kind == semantic.SymbolFunc:
+			what = "function"
+		default:
+			continue
+		}
+		warnUnused(r, sym.Location, what, sym.Name)
+	}
+}
+
+// reportUnusedImports warns about imports that are never referenced: a module alias never used
+// through alias::name, each name of an import list on its own, and wildcards that bring in nothing used
+func reportUnusedImports(r *analyzer.AnalyzerNode) {
+	module, err := r.Ctx.GetModule(r.Program.ImportPath)
+	if err != nil {
+		return
+	}
+
+	for _, node := range r.Program.Nodes {
+		stmt, ok := node.(*ast.ImportStmt)
+		if !ok || stmt.IsPublic {
+			continue // A pub import is used by the modules importing this one
+		}
+		switch {
+		case stmt.Wildcard:
+			if stmt.FullPath != "" && !usesAnyName(module, stmt) {
+				r.Ctx.Reports.Add(r.Program.FullPath, stmt.Loc(), fmt.Sprintf("unused import '%s'", stmt.ImportPath.Value), report.RESOLVER_PHASE).AddHint("Remove the import").SetLevel(report.WARNING)
+			}
+		case stmt.Names != nil:
+			for _, entry := range stmt.Names {
+				local := entry.LocalName()
+				if isIgnoredName(local.Name) || module.ImportedFrom[local.Name] != stmt {
+					continue // Not bound, the failed import was reported already
+				}
+				if module.NameUses[local.Name] == 0 {
+					r.Ctx.Reports.Add(r.Program.FullPath, local.Loc(), fmt.Sprintf("unused import '%s'", local.Name), report.RESOLVER_PHASE).AddHint("Remove the name from the import list").SetLevel(report.WARNING)
+				}
+			}
+		default:
+			if isIgnoredName(stmt.ModuleName) {
+				continue
+			}
+			if _, imported := r.Program.ModulenameToImportpath[stmt.ModuleName]; !imported {
+				continue // The import failed and was reported already
+			}
+			if module.ImportUses[stmt.ModuleName] == 0 {
+				r.Ctx.Reports.Add(r.Program.FullPath, stmt.Loc(), fmt.Sprintf("unused import '%s'", stmt.ImportPath.Value), report.RESOLVER_PHASE).AddHint("Remove the import").SetLevel(report.WARNING)
+			}
+		}
+	}
+}
+
+// usesAnyName reports whether any name a wildcard import brought in is referenced
+func usesAnyName(module *ctx.Module, stmt *ast.ImportStmt) bool {
+	for name, from := range module.ImportedFrom {
+		if from == stmt && module.NameUses[name] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// reportUnusedFunctions warns about private top-level functions no code calls. An exported function
+// may be called by a module outside the build, such as another project of the workspace.
+func reportUnusedFunctions(r *analyzer.AnalyzerNode, module *ctx.Module) {
+	inFiles(r, module, func() {
+		for _, sym := range sortedSymbols(module.SymbolTable) {
+			if sym.Kind != semantic.SymbolFunc || sym.Uses > 0 || isIgnoredName(sym.Name) {
+				continue
+			}
+			if declaredIn(sym, r.Program.FullPath) != r.Program.FullPath {
+				continue // Reported with the file declaring it
+			}
+			if !isExported(module, sym.Name, sym) {
+				warnUnused(r, sym.Location, "function", sym.Name)
+			}
+		}
+	})
+}
+
+// markImportUsed counts a reference made through an import alias
+func markImportUsed(r *analyzer.AnalyzerNode, alias string) {
+	module, err := r.Ctx.GetModule(r.Program.ImportPath)
+	if err != nil {
+		return
+	}
+	if module.ImportUses == nil {
+		module.ImportUses = make(map[string]int)
+	}
+	module.ImportUses[alias]++
+}
+
+func warnUnused(r *analyzer.AnalyzerNode, loc *source.Location, what, name string) {
+	r.Ctx.Reports.Add(r.Program.FullPath, loc, fmt.Sprintf("unused %s '%s'", what, name), report.RESOLVER_PHASE).AddHint("Prefix the name with '_' if this is intended").SetLevel(report.WARNING)
+}
@@ -0,0 +1,192 @@
+package resolver
+
+import (
+	"slices"
+	"testing"
+
+	"compiler/internal/report"
+	"compiler/internal/testutil/fixture"
+)
+
+// resolveProject writes the files into a project named "app", then parses and resolves the entry file
+func resolveProject(t *testing.T, files map[string]string, entry string) report.Reports {
+	t.Helper()
+	return fixture.Compile(fixture.Project(t, files), entry, ResolveModules)
+}
+
+// warnings returns the messages of the warnings in reports
+func warnings(reports report.Reports) []string {
+	var msgs []string
+	for _, r := range reports {
+		if r.Level == report.WARNING {
+			msgs = append(msgs, r.Message)
+		}
+	}
+	return msgs
+}
+
+func TestUnusedWarnings(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{
+			name: "unused local variable",
+			src: `fn f() {
+    let x = 1;
+}
+f();`,
+			want: []string{"unused variable 'x'"},
+		},
+		{
+			name: "assignment is not a use",
+			src: `fn f() {
+    let x = 1;
+    x = 2;
+}
+f();`,
+			want: []string{"unused variable 'x'"},
+		},
+		{
+			name: "used local variable",
+			src: `fn f() -> i32 {
+    let x = 1;
+    return x;
+}
+f();`,
+		},
+		{
+			name: "unused parameter",
+			src: `fn f(a: i32, b: i32) -> i32 {
+    return a;
+}
+f(1, 2);`,
+			want: []string{"unused parameter 'b'"},
+		},
+		{
+			name: "unused method receiver",
+			src: `type Square struct { side: f64 };
+fn (s: Square) name(prefix: str) -> str {
+    return "square";
+}
+let n = @Square{side: 1.0}.name("a");
+let _n = n;`,
+			want: []string{"unused parameter 'prefix'"},
+		},
+		{
+			name: "underscore prefix",
+			src: `fn _helper(_unused: i32) {
+    let _x = 1;
+}`,
+		},
+		{
+			name: "unused private function",
+			src:  `priv fn helper() {}`,
+			want: []string{"unused function 'helper'"},
+		},
+		{
+			name: "exported functions may be called outside the build",
+			src:  `fn helper() {}`,
+		},
+		{
+			name: "unused local in nested block",
+			src: `fn f(c: bool) {
+    if c {
+        const limit = 10;
+    }
+}
+f(true);`,
+			want: []string{"unused constant 'limit'"},
+		},
+		{
+			name: "top-level variables may be used by importers",
+			src:  `let x = 1;`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := warnings(resolveProject(t, map[string]string{"main.fer": tt.src}, "main.fer"))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("warnings = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnusedImportWarnings(t *testing.T) {
+	files := map[string]string{
+		"data.fer":  `let name = "Fuad"; fn helper() {}`,
+		"maths.fer": `let pi = 3.14;`,
+		"main.fer": `import "app/data";
+import "app/maths";
+let n = maths::pi;`,
+	}
+
+	got := warnings(resolveProject(t, files, "main.fer"))
+	want := []string{"unused import 'app/data'"}
+	if !slices.Equal(got, want) {
+		t.Errorf("warnings = %q, want %q", got, want)
+	}
+}
+
+func TestUnusedPrivateFunctionWarnings(t *testing.T) {
+	files := map[string]string{
+		"data.fer": `fn exported() {}
+priv fn used() {}
+priv fn unused() {}
+let x = used;`,
+		"main.fer": `import "app/data";
+let f = data::exported;`,
+	}
+
+	got := warnings(resolveProject(t, files, "main.fer"))
+	want := []string{"unused function 'unused'"}
+	if !slices.Equal(got, want) {
+		t.Errorf("warnings = %q, want %q", got, want)
+	}
+}
+
+func TestUnusedImportNameWarnings(t *testing.T) {
+	files := map[string]string{
+		"maths.fer": `fn add() {} fn sub() {} fn mul() {}`,
+		"data.fer":  `let name = "Fuad";`,
+		"shapes.fer": `type Point struct {
+    x: i32
+};`,
+		"facade.fer": `pub import "app/shapes" { Point };`,
+		"main.fer": `import "app/maths" { add, sub as minus, mul as _mul };
+import "app/data" { * };
+import "app/facade";
+let f = add;
+fn origin(p: facade::Point) -> i32 {
+    return p.x;
+}
+let o = origin;`,
+	}
+
+	got := warnings(resolveProject(t, files, "main.fer"))
+	want := []string{"unused import 'minus'", "unused import 'app/data'"}
+	if !slices.Equal(got, want) {
+		t.Errorf("warnings = %q, want %q", got, want)
+	}
+}
+
+func TestStructLiteralUsesImportName(t *testing.T) {
+	files := map[string]string{
+		"shapes.fer": `type Point struct {
+    x: i32
+};
+type Size struct {
+    w: i32
+};`,
+		"main.fer": `import "app/shapes" { Point as Pt, Size };
+let p = @Pt{ x: 1 };
+let s = @Size{ w: 2 };`,
+	}
+
+	if got := warnings(resolveProject(t, files, "main.fer")); len(got) != 0 {
+		t.Errorf("warnings = %q, want none", got)
+	}
+}
@@ -0,0 +1,78 @@
+package resolver
+
+import (
+	"fmt"
+
+	"compiler/ctx"
+	"compiler/internal/frontend/ast"
+	"compiler/internal/report"
+	"compiler/internal/semantic"
+	"compiler/internal/semantic/analyzer"
+	"compiler/internal/source"
+)
+
+// exportSymbol adds a public symbol to the module's exports under name. A module exports its own
+// top-level declarations under their names, and the names a pub import re-exports.
+func exportSymbol(module *ctx.Module, name string, sym *semantic.Symbol, isPrivate bool) {
+	if sym == nil || isPrivate {
+		return
+	}
+	if module.Exports == nil {
+		module.Exports = semantic.NewSymbolTable(nil)
+	}
+	// A redeclared name keeps the first symbol, the redeclaration is reported already
+	_ = module.Exports.Declare(name, sym)
+}
+
+// isExported reports whether sym is what the module exports under name
+func isExported(module *ctx.Module, name string, sym *semantic.Symbol) bool {
+	if module.Exports == nil {
+		return false
+	}
+	exported, found := module.Exports.Symbols[name]
+	return found && exported == sym
+}
+
+// lookupMember finds what module::name refers to: an exported name first, then a private top-level declaration
+// so the caller can report it. Names the module only imports for itself are not members.
+func lookupMember(module *ctx.Module, name string) (*semantic.Symbol, bool) {
+	if module.Exports != nil {
+		if sym, found := module.Exports.Symbols[name]; found {
+			return sym, true
+		}
+	}
+	sym, found := module.SymbolTable.Symbols[name]
+	return sym, found
+}
+
+// checkExported reports an error when a reference from another module reaches a private symbol.
+// The module is named by its import path, however the reference reaches it.
+func checkExported(r *analyzer.AnalyzerNode, importModule *ctx.Module, name string, sym *semantic.Symbol, loc *source.Location) bool {
+	if isExported(importModule, name, sym) {
+		return true
+	}
+	rep := r.Ctx.Reports.Add(r.Program.FullPath, loc, fmt.Sprintf("'%s' is private to module '%s'", name, importModule.AST.ImportPath), report.RESOLVER_PHASE)
+	if sym.Location != nil {
+		rep.AddRelated(declaredIn(sym, importModule.AST.FullPath), sym.Location, fmt.Sprintf("'%s' is declared private here", sym.Name))
+	}
+	rep.AddHint("Remove 'priv' from the declaration to export it").SetLevel(report.SEMANTIC_ERROR)
+	return false
+}
+
+// reportLocalPrivate reports 'priv' on a declaration inside a function or block, where it has no meaning
+func reportLocalPrivate(r *analyzer.AnalyzerNode, node ast.Node) {
+	isPrivate := false
+	switch n := node.(type) {
+	case *ast.VarDeclStmt:
+		isPrivate = n.IsPrivate
+	case *ast.TypeDeclStmt:
+		isPrivate = n.IsPrivate
+	case *ast.FunctionDecl:
+		isPrivate = n.IsPrivate
+	case *ast.MethodDecl:
+		isPrivate = n.IsPrivate
+	}
+	if isPrivate {
+		r.Ctx.Reports.Add(r.Program.FullPath, node.Loc(), "'priv' is only allowed on top-level declarations", report.RESOLVER_PHASE).AddHint("Remove 'priv', local declarations are never visible outside their block").SetLevel(report.SEMANTIC_ERROR)
+	}
+}
@@ -22,6 +22,8 @@ type Symbol struct {
 	Kind     SymbolKind
 	Type     Type             // Now uses semantic.Type instead of ast.DataType
 	Location *source.Location // Optional, only when needed for error reporting
+	Uses     int              // Number of times the symbol is read, counted by the resolver
+	File     string           // The file declaring a top-level symbol, modules can span several files
 }
 
 // NewSymbol creates a new symbol with the given properties
@@ -0,0 +1,124 @@
+package typecheck
+
+import (
+	"compiler/internal/frontend/ast"
+	"compiler/internal/report"
+	"compiler/internal/semantic"
+	"compiler/internal/semantic/analyzer"
+)
+
+// checkAssignmentTarget reports an error if the expression cannot be written to.
+// Only identifiers, field accesses and index expressions rooted at a local variable are assignable.
+func checkAssignmentTarget(r *analyzer.AnalyzerNode, target ast.Expression) bool {
+	if _, ok := target.(ast.LValue); !ok {
+		reportInvalidTarget(r, target, nonLValueMessage(target))
+		return false
+	}
+
+	switch root := lvalueRoot(target).(type) {
+	case *ast.IdentifierExpr:
+		return checkRootSymbol(r, target, root)
+	case *ast.VarScopeResolution:
+		reportInvalidTarget(r, target, "cannot modify imported module member '"+root.Module.Name+"::"+root.Var.Name+"'")
+		return false
+	default:
+		reportInvalidTarget(r, target, "cannot assign to a field or element of a temporary value")
+		return false
+	}
+}
+
+// lvalueRoot walks field accesses and index expressions down to the expression they start from
+func lvalueRoot(expr ast.Expression) ast.Expression {
+	for {
+		switch e := expr.(type) {
+		case *ast.FieldAccessExpr:
+			expr = *e.Object
+		case *ast.IndexableExpr:
+			expr = *e.Indexable
+		default:
+			return expr
+		}
+	}
+}
+
+// checkRootSymbol rejects writes through constants, functions, types and names bound by import lists
+func checkRootSymbol(r *analyzer.AnalyzerNode, target ast.Expression, root *ast.IdentifierExpr) bool {
+	sym, found := r.CurrentScope().Lookup(root.Name)
+	if !found {
+		return true // Reported by the resolver
+	}
+
+	if module, err := r.Ctx.GetModule(r.Program.ImportPath); err == nil && module.ImportScope != nil && module.ImportScope.Symbols[root.Name] == sym {
+		if target == ast.Expression(root) {
+			reportInvalidTarget(r, target, "cannot assign to imported module member '"+root.Name+"'")
+		} else {
+			reportInvalidTarget(r, target, "cannot modify imported module member '"+root.Name+"'")
+		}
+		return false
+	}
+
+	switch sym.Kind {
+	case semantic.SymbolConst:
+		if target == ast.Expression(root) {
+			reportInvalidTarget(r, target, "cannot assign to constant '"+root.Name+"'")
+		} else {
+			reportInvalidTarget(r, target, "cannot modify constant '"+root.Name+"'")
+		}
+		return false
+	case semantic.SymbolFunc:
+		reportInvalidTarget(r, target, "cannot assign to function '"+root.Name+"'")
+		return false
+	case semantic.SymbolType:
+		reportInvalidTarget(r, target, "cannot assign to type '"+root.Name+"'")
+		return false
+	}
+	return true
+}
+
+// nonLValueMessage describes why an expression is not assignable
+func nonLValueMessage(expr ast.Expression) string {
+	switch e := expr.(type) {
+	case *ast.VarScopeResolution:
+		return "cannot assign to imported module member '" + e.Module.Name + "::" + e.Var.Name + "'"
+	case *ast.FunctionCallExpr:
+		return "cannot assign to the result of a function call"
+	case *ast.IntLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.BoolLiteral, *ast.ByteLiteral,
+		*ast.ArrayLiteralExpr, *ast.StructLiteralExpr, *ast.FunctionLiteral:
+		return "cannot assign to a literal"
+	default:
+		return "cannot assign to this expression"
+	}
+}
+
+// reportInvalidTarget reports an invalid assignment target
+func reportInvalidTarget(r *analyzer.AnalyzerNode, target ast.Expression, message string) {
+	r.Ctx.Reports.Add(
+		r.Program.FullPath,
+		target.Loc(),
+		message,
+		report.TYPECHECK_PHASE,
+	).SetLevel(report.SEMANTIC_ERROR)
+}
+
+// inferIncDecType checks the operand of ++ or -- and returns its type
+func inferIncDecType(r *analyzer.AnalyzerNode, operand ast.Expression, operator string) semantic.Type {
+	if !checkAssignmentTarget(r, operand) {
+		return nil
+	}
+
+	operandType := inferExpressionType(r, operand)
+	if operandType == nil {
+		return nil
+	}
+
+	if !isNumericType(resolveTypeAlias(r, operandType)) {
+		r.Ctx.Reports.Add(
+			r.Program.FullPath,
+			operand.Loc(),
+			"invalid operation: "+operator+" on "+operandType.String(),
+			report.TYPECHECK_PHASE,
+		).SetLevel(report.SEMANTIC_ERROR)
+		return nil
+	}
+	return operandType
+}
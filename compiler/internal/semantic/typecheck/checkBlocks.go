@@ -0,0 +1,50 @@
+package typecheck
+
+import (
+	"compiler/internal/frontend/ast"
+	"compiler/internal/semantic/analyzer"
+)
+
+// checkBlock type checks the statements of a block in its own scope
+func checkBlock(r *analyzer.AnalyzerNode, block *ast.Block) {
+	r.EnterScope(block)
+	defer r.ExitScope()
+
+	for _, node := range block.Nodes {
+		checkNode(r, node)
+	}
+}
+
+// checkIfStmt type checks the condition and every branch of an if statement
+func checkIfStmt(r *analyzer.AnalyzerNode, stmt *ast.IfStmt) {
+	if stmt.Condition != nil {
+		inferExpressionType(r, *stmt.Condition)
+	}
+	checkBlock(r, stmt.Body)
+	if stmt.Alternative != nil {
+		checkNode(r, stmt.Alternative)
+	}
+}
+
+// checkWhileStmt type checks the condition and body of a while loop
+func checkWhileStmt(r *analyzer.AnalyzerNode, stmt *ast.WhileStmt) {
+	inferExpressionType(r, *stmt.Condition)
+	checkBlock(r, stmt.Body)
+}
+
+// checkForStmt type checks every clause of a for loop in the scope created by the resolver
+func checkForStmt(r *analyzer.AnalyzerNode, stmt *ast.ForStmt) {
+	r.EnterScope(stmt)
+	defer r.ExitScope()
+
+	if stmt.Init != nil {
+		checkNode(r, stmt.Init)
+	}
+	if stmt.Condition != nil {
+		inferExpressionType(r, *stmt.Condition)
+	}
+	if stmt.Post != nil {
+		checkNode(r, stmt.Post)
+	}
+	checkBlock(r, stmt.Body)
+}
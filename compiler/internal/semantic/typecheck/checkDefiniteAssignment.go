@@ -0,0 +1,362 @@
+package typecheck
+
+import (
+	"fmt"
+	"slices"
+
+	"compiler/internal/frontend/ast"
+	"compiler/internal/report"
+	"compiler/internal/semantic/analyzer"
+	"compiler/internal/semantic/cfg"
+)
+
+// localVar is a variable declared inside a flow unit
+type localVar struct {
+	decl     *ast.VariableToDeclare
+	unit     *flowUnit
+	index    int  // position in unit.vars
+	captured bool // used by a nested function, so its flow cannot be followed
+}
+
+// flowUnit is a function body (or the module top level) analyzed on its own control-flow graph
+type flowUnit struct {
+	nodes []ast.Node
+	vars  []*localVar
+	decls map[*ast.VariableToDeclare]*localVar
+	uses  map[*ast.IdentifierExpr]*localVar // identifiers naming a variable of this unit
+}
+
+// assignmentBinder links identifiers to the local variables they name, mirroring the resolver's scopes
+type assignmentBinder struct {
+	scopes []map[string]*localVar // a nil entry is a name that is not tracked, like a parameter
+	unit   *flowUnit
+	units  []*flowUnit
+}
+
+// checkDefiniteAssignment reports reads of variables that are not assigned on every path leading to them
+func checkDefiniteAssignment(r *analyzer.AnalyzerNode) {
+	b := &assignmentBinder{}
+	b.bindUnit(fileOrder(r))
+
+	for _, unit := range b.units {
+		checkUnitAssignments(r, unit)
+	}
+}
+
+func (b *assignmentBinder) bindUnit(nodes []ast.Node, params ...ast.Parameter) {
+	outer := b.unit
+	b.unit = &flowUnit{
+		nodes: nodes,
+		decls: make(map[*ast.VariableToDeclare]*localVar),
+		uses:  make(map[*ast.IdentifierExpr]*localVar),
+	}
+	b.units = append(b.units, b.unit)
+
+	b.pushScope()
+	for _, param := range params {
+		b.declare(param.Identifier.Name, nil)
+	}
+	b.bindNodes(nodes)
+	b.popScope()
+
+	b.unit = outer
+}
+
+func (b *assignmentBinder) pushScope() {
+	b.scopes = append(b.scopes, make(map[string]*localVar))
+}
+
+func (b *assignmentBinder) popScope() {
+	b.scopes = b.scopes[:len(b.scopes)-1]
+}
+
+func (b *assignmentBinder) declare(name string, v *localVar) {
+	b.scopes[len(b.scopes)-1][name] = v
+}
+
+func (b *assignmentBinder) lookup(name string) *localVar {
+	for i := len(b.scopes) - 1; i >= 0; i-- {
+		if v, ok := b.scopes[i][name]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+func (b *assignmentBinder) bindNodes(nodes []ast.Node) {
+	for _, node := range nodes {
+		b.bindNode(node)
+	}
+}
+
+func (b *assignmentBinder) bindNode(node ast.Node) {
+	switch n := node.(type) {
+	case *ast.VarDeclStmt:
+		b.bindExprs(n.Initializers)
+		for _, variable := range n.Variables {
+			v := &localVar{decl: variable, unit: b.unit, index: len(b.unit.vars)}
+			b.unit.vars = append(b.unit.vars, v)
+			b.unit.decls[variable] = v
+			b.declare(variable.Identifier.Name, v)
+		}
+	case *ast.AssignmentStmt:
+		b.bindExprs(*n.Left)
+		b.bindExprs(*n.Right)
+	case *ast.ExpressionStmt:
+		b.bindExprs(*n.Expressions)
+	case *ast.ReturnStmt:
+		if n.Values != nil {
+			b.bindExprs(*n.Values)
+		}
+	case *ast.Block:
+		b.pushScope()
+		b.bindNodes(n.Nodes)
+		b.popScope()
+	case *ast.IfStmt:
+		b.bindExpr(*n.Condition)
+		b.bindNode(n.Body)
+		if n.Alternative != nil {
+			b.bindNode(n.Alternative)
+		}
+	case *ast.WhileStmt:
+		b.bindExpr(*n.Condition)
+		b.bindNode(n.Body)
+	case *ast.ForStmt:
+		b.pushScope()
+		if n.Init != nil {
+			b.bindNode(n.Init)
+		}
+		if n.Condition != nil {
+			b.bindExpr(*n.Condition)
+		}
+		if n.Post != nil {
+			b.bindNode(n.Post)
+		}
+		b.bindNode(n.Body)
+		b.popScope()
+	case *ast.FunctionDecl:
+		b.declare(n.Identifier.Name, nil)
+		if n.Function.Body != nil {
+			b.bindUnit(n.Function.Body.Nodes, n.Function.Params...)
+		}
+	case *ast.MethodDecl:
+		if n.Function.Body != nil {
+			b.bindUnit(n.Function.Body.Nodes, append([]ast.Parameter{*n.Receiver}, n.Function.Params...)...)
+		}
+	case *ast.FunctionLiteral:
+		b.bindExpr(n)
+	}
+}
+
+func (b *assignmentBinder) bindExprs(exprs []ast.Expression) {
+	for _, expr := range exprs {
+		b.bindExpr(expr)
+	}
+}
+
+func (b *assignmentBinder) bindExpr(expr ast.Expression) {
+	switch e := expr.(type) {
+	case *ast.IdentifierExpr:
+		v := b.lookup(e.Name)
+		if v == nil {
+			return
+		}
+		if v.unit != b.unit {
+			v.captured = true
+			return
+		}
+		b.unit.uses[e] = v
+	case *ast.BinaryExpr:
+		b.bindExpr(*e.Left)
+		b.bindExpr(*e.Right)
+	case *ast.UnaryExpr:
+		b.bindExpr(*e.Operand)
+	case *ast.PrefixExpr:
+		b.bindExpr(*e.Operand)
+	case *ast.PostfixExpr:
+		b.bindExpr(*e.Operand)
+	case *ast.FunctionCallExpr:
+		b.bindExpr(*e.Caller)
+		b.bindExprs(e.Arguments)
+	case *ast.FieldAccessExpr:
+		b.bindExpr(*e.Object)
+	case *ast.IndexableExpr:
+		b.bindExpr(*e.Indexable)
+		b.bindExpr(*e.Index)
+	case *ast.ArrayLiteralExpr:
+		b.bindExprs(e.Elements)
+	case *ast.StructLiteralExpr:
+		for _, field := range e.Fields {
+			if field.FieldValue != nil {
+				b.bindExpr(*field.FieldValue)
+			}
+		}
+	case *ast.FunctionLiteral:
+		if e.Body != nil {
+			b.bindUnit(e.Body.Nodes, e.Params...)
+		}
+	}
+}
+
+// assignedSet records which variables of a unit are definitely assigned, indexed like unit.vars
+type assignedSet []bool
+
+// fullAssignedSet is the fact for blocks not reached yet, meeting it changes nothing
+func fullAssignedSet(size int) assignedSet {
+	set := make(assignedSet, size)
+	for i := range set {
+		set[i] = true
+	}
+	return set
+}
+
+// definiteAssignment is a forward must analysis: a variable is assigned at a point
+// only when it is assigned on every path from the entry
+func definiteAssignment(unit *flowUnit) cfg.Analysis[assignedSet] {
+	size := len(unit.vars)
+	return cfg.Analysis[assignedSet]{
+		Direction: cfg.Forward,
+		Boundary:  func() assignedSet { return make(assignedSet, size) },
+		Initial:   func() assignedSet { return fullAssignedSet(size) },
+		Meet: func(a, b assignedSet) assignedSet {
+			met := make(assignedSet, size)
+			for i := range met {
+				met[i] = a[i] && b[i]
+			}
+			return met
+		},
+		Transfer: func(block *cfg.Block, in assignedSet) assignedSet {
+			state := slices.Clone(in)
+			for _, node := range block.Nodes {
+				transferAssignments(unit, node, state, nil)
+			}
+			return state
+		},
+		Equal: slices.Equal[assignedSet],
+	}
+}
+
+// checkUnitAssignments solves the analysis over the unit's graph, then replays
+// every reachable block once to report the reads that happen before an assignment
+func checkUnitAssignments(r *analyzer.AnalyzerNode, unit *flowUnit) {
+	if len(unit.vars) == 0 {
+		return
+	}
+
+	graph := cfg.Build(unit.nodes)
+	result := cfg.Solve(graph, definiteAssignment(unit))
+
+	reachable := graph.Reachable()
+	for _, block := range graph.Blocks {
+		// nothing runs in unreachable code, so there is nothing to report
+		if !reachable[block] {
+			continue
+		}
+		state := slices.Clone(result.In[block.ID])
+		for _, node := range block.Nodes {
+			transferAssignments(unit, node, state, func(ident *ast.IdentifierExpr, v *localVar) {
+				reportUnassignedRead(r, ident, v)
+			})
+		}
+	}
+}
+
+// transferAssignments applies a node to the assigned set, calling onUnassigned for each read of a variable that may be unassigned
+func transferAssignments(unit *flowUnit, node ast.Node, state assignedSet, onUnassigned func(*ast.IdentifierExpr, *localVar)) {
+	reads := func(expr ast.Expression) {
+		forEachRead(unit, expr, func(ident *ast.IdentifierExpr, v *localVar) {
+			if !state[v.index] && !v.captured && onUnassigned != nil {
+				onUnassigned(ident, v)
+			}
+		})
+	}
+
+	switch n := node.(type) {
+	case *ast.VarDeclStmt:
+		for _, init := range n.Initializers {
+			reads(init)
+		}
+		for i, variable := range n.Variables {
+			// a loop runs the declaration again, so a variable without initializer starts unassigned each time
+			state[unit.decls[variable].index] = i < len(n.Initializers)
+		}
+	case *ast.AssignmentStmt:
+		for _, value := range *n.Right {
+			reads(value)
+		}
+		for _, target := range *n.Left {
+			if ident, ok := target.(*ast.IdentifierExpr); ok {
+				if v, tracked := unit.uses[ident]; tracked {
+					state[v.index] = true
+				}
+				continue
+			}
+			// writing to a field or element needs the variable itself to hold a value
+			reads(target)
+		}
+	case *ast.ExpressionStmt:
+		for _, expr := range *n.Expressions {
+			reads(expr)
+		}
+	case *ast.ReturnStmt:
+		if n.Values != nil {
+			for _, value := range *n.Values {
+				reads(value)
+			}
+		}
+	case ast.Expression:
+		reads(n)
+	}
+}
+
+// forEachRead calls fn for every identifier in expr that reads a variable of the unit, in evaluation order
+func forEachRead(unit *flowUnit, expr ast.Expression, fn func(*ast.IdentifierExpr, *localVar)) {
+	switch e := expr.(type) {
+	case *ast.IdentifierExpr:
+		if v, ok := unit.uses[e]; ok {
+			fn(e, v)
+		}
+	case *ast.BinaryExpr:
+		forEachRead(unit, *e.Left, fn)
+		forEachRead(unit, *e.Right, fn)
+	case *ast.UnaryExpr:
+		forEachRead(unit, *e.Operand, fn)
+	case *ast.PrefixExpr:
+		forEachRead(unit, *e.Operand, fn)
+	case *ast.PostfixExpr:
+		forEachRead(unit, *e.Operand, fn)
+	case *ast.FunctionCallExpr:
+		forEachRead(unit, *e.Caller, fn)
+		for _, arg := range e.Arguments {
+			forEachRead(unit, arg, fn)
+		}
+	case *ast.FieldAccessExpr:
+		forEachRead(unit, *e.Object, fn)
+	case *ast.IndexableExpr:
+		forEachRead(unit, *e.Indexable, fn)
+		forEachRead(unit, *e.Index, fn)
+	case *ast.ArrayLiteralExpr:
+		for _, element := range e.Elements {
+			forEachRead(unit, element, fn)
+		}
+	case *ast.StructLiteralExpr:
+		for _, field := range e.Fields {
+			if field.FieldValue != nil {
+				forEachRead(unit, *field.FieldValue, fn)
+			}
+		}
+	}
+}
+
+func reportUnassignedRead(r *analyzer.AnalyzerNode, ident *ast.IdentifierExpr, v *localVar) {
+	r.Ctx.Reports.Add(
+		r.Program.FullPath,
+		ident.Loc(),
+		fmt.Sprintf("variable used before being assigned: '%s'", ident.Name),
+		report.TYPECHECK_PHASE,
+	).AddRelated(
+		r.Program.FullPath,
+		v.decl.Identifier.Loc(),
+		fmt.Sprintf("'%s' is declared here", ident.Name),
+	).AddHint("Assign a value on every path before this use").SetLevel(report.SEMANTIC_ERROR)
+}
@@ -0,0 +1,93 @@
+package typecheck
+
+import (
+	"fmt"
+
+	"compiler/internal/frontend/ast"
+	"compiler/internal/report"
+	"compiler/internal/semantic"
+	"compiler/internal/semantic/analyzer"
+)
+
+// checkFunctionLiteral type checks a function body inside the scope created by the resolver
+func checkFunctionLiteral(r *analyzer.AnalyzerNode, fn *ast.FunctionLiteral) {
+	if fn.Body == nil {
+		return
+	}
+
+	r.EnterScope(fn.Body)
+	defer r.ExitScope()
+
+	for _, node := range fn.Body.Nodes {
+		checkNode(r, node)
+	}
+}
+
+// checkReturnStmt type checks the returned expressions
+func checkReturnStmt(r *analyzer.AnalyzerNode, stmt *ast.ReturnStmt) {
+	if stmt.Values == nil {
+		return
+	}
+	for _, value := range *stmt.Values {
+		inferExpressionType(r, value)
+	}
+}
+
+// inferFunctionCallType checks the arguments of a call and returns the type of its result
+func inferFunctionCallType(r *analyzer.AnalyzerNode, e *ast.FunctionCallExpr) semantic.Type {
+	calleeType := resolveTypeAlias(r, inferExpressionType(r, *e.Caller))
+	if calleeType == nil {
+		for _, arg := range e.Arguments {
+			inferExpressionType(r, arg)
+		}
+		return nil
+	}
+
+	fnType, ok := calleeType.(*semantic.FunctionType)
+	if !ok {
+		r.Ctx.Reports.Add(
+			r.Program.FullPath,
+			(*e.Caller).Loc(),
+			"cannot call non-function type "+calleeType.String(),
+			report.TYPECHECK_PHASE,
+		).SetLevel(report.SEMANTIC_ERROR)
+		return nil
+	}
+
+	checkCallArguments(r, e, fnType)
+
+	if len(fnType.ReturnTypes) == 1 {
+		return fnType.ReturnTypes[0]
+	}
+	return nil
+}
+
+// checkCallArguments checks the argument count and that each argument is assignable to its parameter
+func checkCallArguments(r *analyzer.AnalyzerNode, e *ast.FunctionCallExpr, fnType *semantic.FunctionType) {
+	if len(e.Arguments) != len(fnType.Parameters) {
+		r.Ctx.Reports.Add(
+			r.Program.FullPath,
+			e.Loc(),
+			fmt.Sprintf("wrong number of arguments: expected %d, got %d", len(fnType.Parameters), len(e.Arguments)),
+			report.TYPECHECK_PHASE,
+		).SetLevel(report.SEMANTIC_ERROR)
+	}
+
+	for i, arg := range e.Arguments {
+		argType := inferExpressionType(r, arg)
+		if i >= len(fnType.Parameters) || argType == nil || fnType.Parameters[i] == nil {
+			continue
+		}
+
+		paramType := resolveTypeAlias(r, fnType.Parameters[i])
+		sourceType := resolveTypeAlias(r, argType)
+		if !semantic.IsAssignableFrom(paramType, sourceType) {
+			r.Ctx.Reports.Add(
+				r.Program.FullPath,
+				arg.Loc(),
+				typeMismatchMessage(fmt.Sprintf("type mismatch: cannot use %s as argument %d of type %s", argType.String(), i+1, fnType.Parameters[i].String()), paramType, sourceType),
+				report.TYPECHECK_PHASE,
+			).SetLevel(report.SEMANTIC_ERROR)
+		}
+	}
+}
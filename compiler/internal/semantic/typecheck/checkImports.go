@@ -6,10 +6,10 @@ import (
 	"compiler/internal/semantic/analyzer"
 )
 
-// checkImportStmt performs type checking on import statements
+// checkImportStmt checks that the imported module exists, CheckModules checks the module itself
 func checkImportStmt(r *analyzer.AnalyzerNode, stmt *ast.ImportStmt) {
 	//check the imported module
-	importModule, err := r.Ctx.GetModule(stmt.ImportPath.Value)
+	importModule, err := r.Ctx.GetModule(stmt.ModulePath)
 	if err != nil {
 		r.Ctx.Reports.Add(
 			r.Program.FullPath,
@@ -27,10 +27,5 @@ func checkImportStmt(r *analyzer.AnalyzerNode, stmt *ast.ImportStmt) {
 			"imported module has no AST: "+stmt.ImportPath.Value,
 			report.TYPECHECK_PHASE,
 		).SetLevel(report.SEMANTIC_ERROR)
-		return
 	}
-
-	//typecheck it
-	anz := analyzer.NewAnalyzerNode(importModule.AST, r.Ctx, r.Debug)
-	CheckProgram(anz)
 }
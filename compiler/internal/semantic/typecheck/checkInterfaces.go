@@ -0,0 +1,31 @@
+package typecheck
+
+import (
+	"strings"
+
+	"compiler/internal/semantic"
+	"compiler/internal/types"
+)
+
+// typeMismatchMessage extends a type mismatch message with the methods the source type lacks
+// when the target is an interface
+func typeMismatchMessage(message string, target, source semantic.Type) string {
+	iface, ok := target.(*semantic.InterfaceType)
+	if !ok {
+		return message
+	}
+
+	problems := semantic.InterfaceMismatches(iface, source)
+	if len(problems) == 0 {
+		return message
+	}
+	return message + ": " + shortTypeName(source) + " does not implement " + iface.String() + " (" + strings.Join(problems, "; ") + ")"
+}
+
+// shortTypeName names a type without spelling out the fields of named structs
+func shortTypeName(t semantic.Type) string {
+	if structType, ok := t.(*semantic.StructType); ok && structType.Name != types.STRUCT {
+		return string(structType.Name)
+	}
+	return t.String()
+}
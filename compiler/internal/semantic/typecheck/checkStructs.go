@@ -1,10 +1,13 @@
 package typecheck
 
 import (
+	"fmt"
+
 	"compiler/internal/frontend/ast"
 	"compiler/internal/report"
 	"compiler/internal/semantic"
 	"compiler/internal/semantic/analyzer"
+	"compiler/internal/source"
 )
 
 // validateStructLiteralFields validates that all fields in a struct literal match the struct definition
@@ -60,6 +63,10 @@ func validateSingleStructField(r *analyzer.AnalyzerNode, field ast.StructField,
 		).SetLevel(report.SEMANTIC_ERROR)
 		return false
 	}
+	if semanticStructType.IsHiddenFrom(fieldName, r.Program.ImportPath) {
+		reportPrivateMember(r, field.FieldIdentifier.Loc(), "field", fieldName, semanticStructType)
+		return false
+	}
 
 	providedFields[fieldName] = true
 
@@ -84,7 +91,7 @@ func validateFieldValueType(r *analyzer.AnalyzerNode, field ast.StructField, sem
 			r.Ctx.Reports.Add(
 				r.Program.FullPath,
 				(*field.FieldValue).Loc(),
-				"type mismatch for field '"+fieldName+"': cannot assign "+actualType.String()+" to "+expectedType.String(),
+				typeMismatchMessage("type mismatch for field '"+fieldName+"': cannot assign "+actualType.String()+" to "+expectedType.String(), expectedType, actualType),
 				report.TYPECHECK_PHASE,
 			).SetLevel(report.SEMANTIC_ERROR)
 			return false
@@ -96,6 +103,11 @@ func validateFieldValueType(r *analyzer.AnalyzerNode, field ast.StructField, sem
 // validateRequiredFields checks if all required fields are provided in the struct literal
 func validateRequiredFields(r *analyzer.AnalyzerNode, e *ast.StructLiteralExpr, semanticStructType *semantic.StructType, providedFields map[string]bool) bool {
 	for fieldName := range semanticStructType.Fields {
+		if !providedFields[fieldName] && semanticStructType.IsHiddenFrom(fieldName, r.Program.ImportPath) {
+			// The literal could never set it, so the struct can only be built inside its module
+			reportPrivateMember(r, e.Loc(), "field", fieldName, semanticStructType)
+			return false
+		}
 		if !providedFields[fieldName] {
 			r.Ctx.Reports.Add(
 				r.Program.FullPath,
@@ -108,3 +120,13 @@ func validateRequiredFields(r *analyzer.AnalyzerNode, e *ast.StructLiteralExpr,
 	}
 	return true
 }
+
+// reportPrivateMember reports a use of a 'priv' field or method outside the module declaring the struct
+func reportPrivateMember(r *analyzer.AnalyzerNode, loc *source.Location, kind, name string, structType *semantic.StructType) {
+	r.Ctx.Reports.Add(
+		r.Program.FullPath,
+		loc,
+		fmt.Sprintf("%s '%s' of '%s' is private to module '%s'", kind, name, structType.Name, structType.Module),
+		report.TYPECHECK_PHASE,
+	).SetLevel(report.SEMANTIC_ERROR)
+}
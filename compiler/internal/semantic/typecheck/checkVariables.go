@@ -8,13 +8,12 @@ import (
 
 // checkVarDecl performs type checking on variable declarations
 func checkVarDecl(r *analyzer.AnalyzerNode, stmt *ast.VarDeclStmt) {
-	currentModule, err := r.Ctx.GetModule(r.Program.ImportPath)
-	if err != nil {
+	if _, err := r.Ctx.GetModule(r.Program.ImportPath); err != nil {
 		return
 	}
 
 	for i, v := range stmt.Variables {
-		sym, found := currentModule.SymbolTable.Lookup(v.Identifier.Name)
+		sym, found := r.CurrentScope().Lookup(v.Identifier.Name)
 		if !found {
 			continue // Error should have been reported by resolver
 		}
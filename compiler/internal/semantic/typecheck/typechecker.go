@@ -1,6 +1,9 @@
 package typecheck
 
 import (
+	"fmt"
+	"strings"
+
 	"compiler/colors"
 	"compiler/ctx"
 	"compiler/internal/frontend/ast"
@@ -10,16 +13,68 @@ import (
 	"compiler/internal/types"
 )
 
-// CheckProgram performs type checking on the entire program
+// CheckModules type checks every module in the context once, each after the modules it imports
+func CheckModules(context *ctx.CompilerContext, debug bool) {
+	for _, importPath := range context.ModuleOrder() {
+		CheckProgram(analyzer.NewAnalyzerNode(context.Modules[importPath].AST, context, debug))
+	}
+}
+
+// CheckProgram performs type checking on a single module, its imports must be checked already
 func CheckProgram(r *analyzer.AnalyzerNode) {
-	for _, node := range r.Program.Nodes {
+	module, err := r.Ctx.GetModule(r.Program.ImportPath)
+	if err != nil {
+		r.Ctx.Reports.Add(r.Program.FullPath, r.Program.Loc(), err.Error(), report.TYPECHECK_PHASE).SetLevel(report.CRITICAL_ERROR)
+		return
+	}
+	if module.Phase >= ctx.PHASE_TYPECHECKED {
+		return
+	}
+
+	program := r.Program
+	for _, node := range topLevelOrder(r) {
+		// The order spans every file of a directory module, reports go to the file of the node
+		if file := module.FileOf(node); file != nil {
+			r.Program = file
+		}
 		checkNode(r, node)
 	}
+	for _, file := range module.Files {
+		r.Program = file
+		checkDefiniteAssignment(r)
+	}
+	r.Program = program
+
+	module.Phase = ctx.PHASE_TYPECHECKED
 	if r.Debug {
 		colors.GREEN.Printf("Type checked '%s'\n", r.Program.FullPath)
 	}
 }
 
+// topLevelOrder returns the top-level nodes in the order the resolver decided they are evaluated in,
+// so global initializers are checked before the declarations that read them
+func topLevelOrder(r *analyzer.AnalyzerNode) []ast.Node {
+	if module, err := r.Ctx.GetModule(r.Program.ImportPath); err == nil && module.Order != nil {
+		return module.Order
+	}
+	return r.Program.Nodes
+}
+
+// fileOrder is topLevelOrder restricted to the nodes of the current file
+func fileOrder(r *analyzer.AnalyzerNode) []ast.Node {
+	module, err := r.Ctx.GetModule(r.Program.ImportPath)
+	if err != nil || len(module.Files) < 2 {
+		return topLevelOrder(r)
+	}
+	var nodes []ast.Node
+	for _, node := range topLevelOrder(r) {
+		if module.FileOf(node) == r.Program {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
 // checkNode performs type checking on a single AST node
 func checkNode(r *analyzer.AnalyzerNode, node ast.Node) {
 	switch n := node.(type) {
@@ -33,6 +88,22 @@ func checkNode(r *analyzer.AnalyzerNode, node ast.Node) {
 		checkExpressionStmt(r, n)
 	case *ast.TypeDeclStmt:
 		checkTypeDecl(r, n)
+	case *ast.FunctionDecl:
+		checkFunctionLiteral(r, n.Function)
+	case *ast.MethodDecl:
+		checkFunctionLiteral(r, n.Function)
+	case *ast.FunctionLiteral:
+		checkFunctionLiteral(r, n)
+	case *ast.IfStmt:
+		checkIfStmt(r, n)
+	case *ast.Block:
+		checkBlock(r, n)
+	case *ast.WhileStmt:
+		checkWhileStmt(r, n)
+	case *ast.ForStmt:
+		checkForStmt(r, n)
+	case *ast.ReturnStmt:
+		checkReturnStmt(r, n)
 	// Add more cases as needed
 	default:
 		// Skip nodes that don't need type checking
@@ -50,7 +121,7 @@ func checkTypeCompatibility(r *analyzer.AnalyzerNode, v *ast.VariableToDeclare,
 			r.Ctx.Reports.Add(
 				r.Program.FullPath,
 				v.Identifier.Loc(),
-				"type mismatch: cannot assign "+initType.String()+" to "+sym.Type.String(),
+				typeMismatchMessage("type mismatch: cannot assign "+initType.String()+" to "+sym.Type.String(), targetType, sourceType),
 				report.TYPECHECK_PHASE,
 			).SetLevel(report.SEMANTIC_ERROR)
 		}
@@ -79,6 +150,10 @@ func checkAssignment(r *analyzer.AnalyzerNode, stmt *ast.AssignmentStmt) {
 	rightExprs := *stmt.Right
 
 	for i, leftExpr := range leftExprs {
+		if !checkAssignmentTarget(r, leftExpr) {
+			continue
+		}
+
 		if i >= len(rightExprs) {
 			break // Mismatched assignment count - should be caught elsewhere
 		}
@@ -87,11 +162,13 @@ func checkAssignment(r *analyzer.AnalyzerNode, stmt *ast.AssignmentStmt) {
 		rightType := inferExpressionType(r, rightExprs[i])
 
 		if leftType != nil && rightType != nil {
-			if !semantic.IsAssignableFrom(leftType, rightType) {
+			targetType := resolveTypeAlias(r, leftType)
+			sourceType := resolveTypeAlias(r, rightType)
+			if !semantic.IsAssignableFrom(targetType, sourceType) {
 				r.Ctx.Reports.Add(
 					r.Program.FullPath,
 					leftExpr.Loc(),
-					"type mismatch: cannot assign "+rightType.String()+" to "+leftType.String(),
+					typeMismatchMessage("type mismatch: cannot assign "+rightType.String()+" to "+leftType.String(), targetType, sourceType),
 					report.TYPECHECK_PHASE,
 				).SetLevel(report.SEMANTIC_ERROR)
 			}
@@ -113,20 +190,85 @@ func checkTypeDecl(r *analyzer.AnalyzerNode, stmt *ast.TypeDeclStmt) {
 	// Basic validation - more sophisticated checks can be added here
 	if stmt.BaseType != nil {
 		checkTypeValidity(r, stmt.BaseType)
+		checkRecursiveType(r, stmt)
 	}
 }
 
-// checkTypeValidity checks if a type is valid (exists and is well-formed)
-func checkTypeValidity(r *analyzer.AnalyzerNode, dataType ast.DataType) bool {
-	currentModule, err := r.Ctx.GetModule(r.Program.ImportPath)
-	if err != nil {
-		return false
+// checkRecursiveType reports a type that contains itself by value, directly or through other types
+// of the module: its values would be infinitely large. Arrays and functions hold their elements by
+// reference and break the cycle. A cycle is reported once, at its first declared type.
+func checkRecursiveType(r *analyzer.AnalyzerNode, stmt *ast.TypeDeclStmt) {
+	decls := make(map[string]*ast.TypeDeclStmt)
+	order := make(map[*ast.TypeDeclStmt]int)
+	for i, node := range topLevelOrder(r) {
+		if decl, ok := node.(*ast.TypeDeclStmt); ok && decl.Alias != nil && decl.BaseType != nil {
+			decls[decl.Alias.Name] = decl
+			order[decl] = i
+		}
 	}
 
+	cycle := typeCycle(stmt, stmt.BaseType, decls, []string{stmt.Alias.Name}, make(map[*ast.TypeDeclStmt]bool))
+	if cycle == nil {
+		return
+	}
+	for _, name := range cycle {
+		if order[decls[name]] < order[stmt] {
+			return
+		}
+	}
+
+	msg := fmt.Sprintf("invalid recursive type: '%s' contains itself", stmt.Alias.Name)
+	if len(cycle) > 1 {
+		msg = "invalid recursive type: " + strings.Join(append(cycle, stmt.Alias.Name), " -> ")
+	}
+	rep := r.Ctx.Reports.Add(r.Program.FullPath, stmt.Alias.Loc(), msg, report.TYPECHECK_PHASE)
+	module, _ := r.Ctx.GetModule(r.Program.ImportPath)
+	for i, name := range cycle[1:] {
+		file := r.Program.FullPath
+		if declaredIn := module.FileOf(decls[name]); declaredIn != nil {
+			file = declaredIn.FullPath
+		}
+		rep.AddRelated(file, decls[name].Alias.Loc(), fmt.Sprintf("'%s' contains '%s'", name, cycle[(i+2)%len(cycle)]))
+	}
+	rep.AddHint("Hold one of the values in an array, which stores its elements by reference").SetLevel(report.SEMANTIC_ERROR)
+}
+
+// typeCycle returns the names of the types leading from a type back to the declaration start, or nil
+// when its values do not contain a start value
+func typeCycle(start *ast.TypeDeclStmt, dataType ast.DataType, decls map[string]*ast.TypeDeclStmt, path []string, visited map[*ast.TypeDeclStmt]bool) []string {
+	switch t := dataType.(type) {
+	case *ast.UserDefinedType:
+		decl, found := decls[string(t.TypeName)]
+		if !found {
+			return nil // A primitive or imported type, imported modules cannot refer back to this one
+		}
+		if decl == start {
+			return path
+		}
+		if visited[decl] {
+			return nil
+		}
+		visited[decl] = true
+		return typeCycle(start, decl.BaseType, decls, append(path, decl.Alias.Name), visited)
+	case *ast.StructType:
+		for _, field := range t.Fields {
+			if field.FieldType == nil {
+				continue
+			}
+			if cycle := typeCycle(start, field.FieldType, decls, path, visited); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// checkTypeValidity checks if a type is valid (exists and is well-formed)
+func checkTypeValidity(r *analyzer.AnalyzerNode, dataType ast.DataType) bool {
 	switch t := dataType.(type) {
 	case *ast.UserDefinedType:
 		// Check if the user-defined type exists
-		_, found := currentModule.SymbolTable.Lookup(string(t.TypeName))
+		_, found := r.CurrentScope().Lookup(string(t.TypeName))
 		if !found {
 			r.Ctx.Reports.Add(
 				r.Program.FullPath,
@@ -159,8 +301,7 @@ func inferExpressionType(r *analyzer.AnalyzerNode, expr ast.Expression) semantic
 		return nil
 	}
 
-	currentModule, err := r.Ctx.GetModule(r.Program.ImportPath)
-	if err != nil {
+	if _, err := r.Ctx.GetModule(r.Program.ImportPath); err != nil {
 		return nil
 	}
 
@@ -168,7 +309,7 @@ func inferExpressionType(r *analyzer.AnalyzerNode, expr ast.Expression) semantic
 
 	switch e := expr.(type) {
 	case *ast.IdentifierExpr:
-		resultType = inferIdentifierType(currentModule, e)
+		resultType = inferIdentifierType(r, e)
 	case *ast.StringLiteral:
 		resultType = semantic.CreatePrimitiveType(types.STRING)
 	case *ast.IntLiteral:
@@ -186,13 +327,22 @@ func inferExpressionType(r *analyzer.AnalyzerNode, expr ast.Expression) semantic
 	case *ast.VarScopeResolution:
 		resultType = inferVarScopeResolutionType(r, e)
 	case *ast.StructLiteralExpr:
-		resultType = inferStructLiteralType(r, currentModule, e)
+		resultType = inferStructLiteralType(r, e)
 	case *ast.ArrayLiteralExpr:
 		resultType = inferArrayLiteralType(r, e)
 	case *ast.IndexableExpr:
 		resultType = inferIndexableType(r, e)
 	case *ast.TypeScopeResolution:
 		resultType = inferTypeScopeResolutionType(r, e)
+	case *ast.FunctionCallExpr:
+		resultType = inferFunctionCallType(r, e)
+	case *ast.PrefixExpr:
+		resultType = inferIncDecType(r, *e.Operand, e.Operator.Value)
+	case *ast.PostfixExpr:
+		resultType = inferIncDecType(r, *e.Operand, e.Operator.Value)
+	case *ast.FunctionLiteral:
+		checkFunctionLiteral(r, e)
+		resultType = semantic.FunctionLiteralToSemanticType(e)
 	default:
 		resultType = nil
 	}
@@ -288,6 +438,15 @@ func isIntegerType(typeName types.TYPE_NAME) bool {
 	}
 }
 
+// isNumericType checks if a type is an integer or floating point type
+func isNumericType(t semantic.Type) bool {
+	primType, ok := t.(*semantic.PrimitiveType)
+	if !ok {
+		return false
+	}
+	return isIntegerType(primType.Name) || primType.Name == types.FLOAT32 || primType.Name == types.FLOAT64
+}
+
 // resolveTypeAlias resolves a type alias to its underlying type
 func resolveTypeAlias(r *analyzer.AnalyzerNode, t semantic.Type) semantic.Type {
 	userType, ok := t.(*semantic.UserType)
@@ -311,12 +470,12 @@ func resolveTypeAlias(r *analyzer.AnalyzerNode, t semantic.Type) semantic.Type {
 
 // resolveTypeInCurrentModule tries to resolve a type in the current module
 func resolveTypeInCurrentModule(r *analyzer.AnalyzerNode, userType *semantic.UserType) semantic.Type {
-	currentModule, err := r.Ctx.GetModule(r.Program.ImportPath)
-	if err != nil {
+	scope := r.CurrentScope()
+	if scope == nil {
 		return nil
 	}
 
-	sym, found := currentModule.SymbolTable.Lookup(string(userType.Name))
+	sym, found := scope.Lookup(string(userType.Name))
 	if found && sym.Kind == semantic.SymbolType && sym.Type != nil {
 		return sym.Type
 	}
@@ -344,28 +503,47 @@ func resolveTypeInImportedModules(r *analyzer.AnalyzerNode, userType *semantic.U
 // Helper functions for inferExpressionType to reduce cognitive complexity
 
 // inferIdentifierType infers the type of an identifier expression
-func inferIdentifierType(currentModule *ctx.Module, e *ast.IdentifierExpr) semantic.Type {
-	sym, found := currentModule.SymbolTable.Lookup(e.Name)
+func inferIdentifierType(r *analyzer.AnalyzerNode, e *ast.IdentifierExpr) semantic.Type {
+	sym, found := r.CurrentScope().Lookup(e.Name)
 	if found {
 		return sym.Type
 	}
 	return nil
 }
 
-// inferFieldAccessType infers the type of a field access expression
+// inferFieldAccessType infers the type of a field access or method selection expression
 func inferFieldAccessType(r *analyzer.AnalyzerNode, e *ast.FieldAccessExpr) semantic.Type {
-	objectType := inferExpressionType(r, *e.Object)
-	if structType, ok := objectType.(*semantic.StructType); ok {
-		fieldType := structType.GetFieldType(e.Field.Name)
-		if fieldType == nil {
-			r.Ctx.Reports.Add(
-				r.Program.FullPath,
-				e.Field.Loc(),
-				"field '"+e.Field.Name+"' not found in "+structType.String(),
-				report.TYPECHECK_PHASE,
-			).SetLevel(report.SEMANTIC_ERROR)
+	objectType := resolveTypeAlias(r, inferExpressionType(r, *e.Object))
+	switch t := objectType.(type) {
+	case *semantic.StructType:
+		if fieldType := t.GetFieldType(e.Field.Name); fieldType != nil {
+			if t.IsHiddenFrom(e.Field.Name, r.Program.ImportPath) {
+				reportPrivateMember(r, e.Field.Loc(), "field", e.Field.Name, t)
+			}
+			return fieldType
+		}
+		if method := t.GetMethod(e.Field.Name); method != nil {
+			if t.IsHiddenFrom(e.Field.Name, r.Program.ImportPath) {
+				reportPrivateMember(r, e.Field.Loc(), "method", e.Field.Name, t)
+			}
+			return method
 		}
-		return fieldType
+		r.Ctx.Reports.Add(
+			r.Program.FullPath,
+			e.Field.Loc(),
+			"field '"+e.Field.Name+"' not found in "+t.String(),
+			report.TYPECHECK_PHASE,
+		).SetLevel(report.SEMANTIC_ERROR)
+	case *semantic.InterfaceType:
+		if method, ok := t.Methods[e.Field.Name]; ok {
+			return method
+		}
+		r.Ctx.Reports.Add(
+			r.Program.FullPath,
+			e.Field.Loc(),
+			"method '"+e.Field.Name+"' not found in "+t.String(),
+			report.TYPECHECK_PHASE,
+		).SetLevel(report.SEMANTIC_ERROR)
 	}
 	return nil
 }
@@ -432,7 +610,7 @@ func inferVarScopeResolutionType(r *analyzer.AnalyzerNode, e *ast.VarScopeResolu
 }
 
 // inferStructLiteralType infers the type of a struct literal expression
-func inferStructLiteralType(r *analyzer.AnalyzerNode, currentModule *ctx.Module, e *ast.StructLiteralExpr) semantic.Type {
+func inferStructLiteralType(r *analyzer.AnalyzerNode, e *ast.StructLiteralExpr) semantic.Type {
 	if e.IsAnonymous {
 		panic("Anonymous struct literals are not yet supported")
 	}
@@ -442,7 +620,7 @@ func inferStructLiteralType(r *analyzer.AnalyzerNode, currentModule *ctx.Module,
 	}
 
 	structTypeName := e.StructName.Name
-	sym, found := currentModule.SymbolTable.Lookup(structTypeName)
+	sym, found := r.CurrentScope().Lookup(structTypeName)
 	if !found {
 		r.Ctx.Reports.Add(
 			r.Program.FullPath,
@@ -0,0 +1,900 @@
+package typecheck
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"compiler/ctx"
+	"compiler/internal/remote"
+	"compiler/internal/report"
+	"compiler/internal/semantic/resolver"
+	"compiler/internal/testutil/fixture"
+)
+
+// checkSource parses, resolves and type checks a single file program and returns the reports
+func checkSource(t *testing.T, src string) report.Reports {
+	t.Helper()
+	return checkProject(t, map[string]string{"main.fer": src}, "main.fer")
+}
+
+// checkProject writes the files into a project named "app", then parses, resolves and
+// type checks the entry file and returns the reports
+func checkProject(t *testing.T, files map[string]string, entry string) report.Reports {
+	t.Helper()
+	return checkProjectWith(t, files, entry, nil)
+}
+
+// checkProjectWith is checkProject with a hook to adjust the compiler context before parsing
+func checkProjectWith(t *testing.T, files map[string]string, entry string, configure func(*ctx.CompilerContext)) report.Reports {
+	t.Helper()
+
+	context := fixture.Project(t, files)
+	if configure != nil {
+		configure(context)
+	}
+	return fixture.Compile(context, entry, resolver.ResolveModules, CheckModules)
+}
+
+// expectReports checks whether the program produced an error containing wantMsg
+func expectReports(t *testing.T, src string, wantErr bool, wantMsg string) {
+	t.Helper()
+	expectProjectReports(t, checkSource(t, src), wantErr, wantMsg)
+}
+
+// expectProjectReports checks whether the reports contain an error containing wantMsg
+func expectProjectReports(t *testing.T, reports report.Reports, wantErr bool, wantMsg string) {
+	t.Helper()
+
+	if reports.HasErrors() != wantErr {
+		var msgs []string
+		for _, r := range reports {
+			msgs = append(msgs, r.Message)
+		}
+		t.Fatalf("HasErrors() = %v, want %v; reports: %v", reports.HasErrors(), wantErr, msgs)
+	}
+
+	if wantMsg == "" {
+		return
+	}
+	for _, r := range reports {
+		if strings.Contains(r.Message, wantMsg) {
+			return
+		}
+	}
+	t.Errorf("expected a report containing %q", wantMsg)
+}
+
+const shapeDecls = `
+type Shape interface {
+    fn area() -> f64,
+    fn name() -> str
+};
+
+type Rect struct {
+    w: f64,
+    h: f64
+};
+
+fn (r: Rect) area() -> f64 {
+    return r.w * r.h;
+}
+`
+
+func TestInterfaceImplementation(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr bool
+		wantMsg string
+	}{
+		{
+			name: "struct implementing every method",
+			src: shapeDecls + `
+fn (r: Rect) name() -> str { return "rect"; }
+let s: Shape = @Rect{ w: 1.0, h: 2.0 };`,
+		},
+		{
+			name: "struct missing a method",
+			src: shapeDecls + `
+let s: Shape = @Rect{ w: 1.0, h: 2.0 };`,
+			wantErr: true,
+			wantMsg: "Rect does not implement Shape (missing method 'name')",
+		},
+		{
+			name: "method with mismatched signature",
+			src: shapeDecls + `
+fn (r: Rect) name() -> i32 { return 1; }
+let s: Shape = @Rect{ w: 1.0, h: 2.0 };`,
+			wantErr: true,
+			wantMsg: "method 'name' has signature fn() -> i32, want fn() -> str",
+		},
+		{
+			name: "interface typed parameter",
+			src: shapeDecls + `
+fn describe(s: Shape) -> str { return s.name(); }
+let d = describe(@Rect{ w: 1.0, h: 2.0 });`,
+			wantErr: true,
+			wantMsg: "as argument 1 of type Shape: Rect does not implement Shape",
+		},
+		{
+			name: "interface typed assignment",
+			src: shapeDecls + `
+fn (r: Rect) name() -> str { return "rect"; }
+type Named interface { fn name() -> str };
+let n: Named = @Rect{ w: 1.0, h: 2.0 };
+n = @Rect{ w: 3.0, h: 4.0 };`,
+		},
+		{
+			name: "method conflicting with field",
+			src: shapeDecls + `
+fn (r: Rect) w() -> f64 { return r.h; }`,
+			wantErr: true,
+			wantMsg: "'w' is already declared as a field or method of 'Rect'",
+		},
+		{
+			name: "method on non-struct type",
+			src: `type Integer i32;
+fn (i: Integer) double() -> i32 { return 2; }`,
+			wantErr: true,
+			wantMsg: "methods can only be declared on struct types",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectReports(t, tt.src, tt.wantErr, tt.wantMsg)
+		})
+	}
+}
+
+func TestAssignmentTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr bool
+		wantMsg string
+	}{
+		{
+			name: "assignment to variable",
+			src: `let x = 1;
+x = 2;`,
+		},
+		{
+			name: "assignment to constant",
+			src: `const graphics = "1080p";
+graphics = "x";`,
+			wantErr: true,
+			wantMsg: "cannot assign to constant 'graphics'",
+		},
+		{
+			name: "field of constant struct",
+			src: `type Point struct { x: i32, y: i32 };
+const origin = @Point{ x: 0, y: 0 };
+origin.x = 1;`,
+			wantErr: true,
+			wantMsg: "cannot modify constant 'origin'",
+		},
+		{
+			name: "element of constant array",
+			src: `const numbers = [1, 2, 3];
+numbers[0] = 4;`,
+			wantErr: true,
+			wantMsg: "cannot modify constant 'numbers'",
+		},
+		{
+			name: "field and element of variables",
+			src: `type Point struct { x: i32, y: i32 };
+let p = @Point{ x: 0, y: 0 };
+let numbers = [1, 2, 3];
+p.x = 1;
+numbers[0] = 4;`,
+		},
+		{
+			name: "increment of constant",
+			src: `const count = 1;
+count++;`,
+			wantErr: true,
+			wantMsg: "cannot assign to constant 'count'",
+		},
+		{
+			name: "increment of variable",
+			src: `let count = 1;
+count++;`,
+		},
+		{
+			name: "assignment to function",
+			src: `fn f() {}
+f = fn() {};`,
+			wantErr: true,
+			wantMsg: "cannot assign to function 'f'",
+		},
+		{
+			name: "assignment to call result",
+			src: `fn f() -> i32 { return 1; }
+f() = 2;`,
+			wantErr: true,
+			wantMsg: "cannot assign to the result of a function call",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectReports(t, tt.src, tt.wantErr, tt.wantMsg)
+		})
+	}
+}
+
+func TestAssignmentToImportedMember(t *testing.T) {
+	files := map[string]string{
+		"data.fer": `let myData = "Fuad";`,
+		"main.fer": `import "app/data";
+data::myData = "x";`,
+	}
+	expectProjectReports(t, checkProject(t, files, "main.fer"), true, "cannot assign to imported module member 'data::myData'")
+}
+
+func TestAssignmentToSelectiveImport(t *testing.T) {
+	files := map[string]string{
+		"data.fer": `type Point struct { x: i32 };
+let dv = 1;
+let origin = @Point{x: 0};`,
+		"main.fer": `import "app/data" { dv, origin };
+dv = 4;
+origin.x = 1;`,
+	}
+	reports := checkProject(t, files, "main.fer")
+	expectProjectReports(t, reports, true, "cannot assign to imported module member 'dv'")
+	expectProjectReports(t, reports, true, "cannot modify imported module member 'origin'")
+	for _, r := range reports {
+		if strings.Contains(r.Message, "unused import") {
+			t.Errorf("unexpected report %q", r.Message)
+		}
+	}
+}
+
+func TestDefiniteAssignment(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr bool
+		wantMsg string
+	}{
+		{
+			name: "read before assignment",
+			src: `let y: f32;
+let z = y;`,
+			wantErr: true,
+			wantMsg: "variable used before being assigned: 'y'",
+		},
+		{
+			name: "read after assignment",
+			src: `let y: i32;
+y = 1;
+let z = y;`,
+		},
+		{
+			name: "assigned in both branches",
+			src: `fn f(c: bool) -> i32 {
+    let y: i32;
+    if c {
+        y = 1;
+    } else {
+        y = 2;
+    }
+    return y;
+}`,
+		},
+		{
+			name: "assigned in one branch",
+			src: `fn f(c: bool) -> i32 {
+    let y: i32;
+    if c {
+        y = 1;
+    }
+    return y;
+}`,
+			wantErr: true,
+			wantMsg: "variable used before being assigned: 'y'",
+		},
+		{
+			name: "other branch returns early",
+			src: `fn f(c: bool) -> i32 {
+    let y: i32;
+    if c {
+        y = 1;
+    } else {
+        return 0;
+    }
+    return y;
+}`,
+		},
+		{
+			name: "assigned only inside loop",
+			src: `fn f(n: i32) -> i32 {
+    let y: i32;
+    let i = 0;
+    while i < n {
+        y = i;
+        i++;
+    }
+    return y;
+}`,
+			wantErr: true,
+			wantMsg: "variable used before being assigned: 'y'",
+		},
+		{
+			name: "read after loop that only exits by break",
+			src: `fn f() -> i32 {
+    let y: i32;
+    for ;; {
+        y = 1;
+        break;
+    }
+    return y;
+}`,
+		},
+		{
+			name: "continue skips the assignment",
+			src: `fn f(c: bool) -> i32 {
+    for let i = 0; i < 10; i++ {
+        let y: i32;
+        if c {
+            continue;
+        }
+        y = i;
+        let z = y;
+    }
+    return 0;
+}`,
+		},
+		{
+			name: "field write needs a value",
+			src: `type Point struct { x: i32, y: i32 };
+let p: Point;
+p.x = 1;`,
+			wantErr: true,
+			wantMsg: "variable used before being assigned: 'p'",
+		},
+		{
+			name: "captured by a closure",
+			src: `let y: i32;
+let set = fn() { y = 1; };
+let z = y;`,
+		},
+		{
+			name: "parameter shadows outer variable",
+			src: `let y: i32;
+fn f(y: i32) -> i32 { return y; }`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectReports(t, tt.src, tt.wantErr, tt.wantMsg)
+		})
+	}
+}
+
+func TestDefiniteAssignmentRelatedSpan(t *testing.T) {
+	reports := checkSource(t, `let y: f32;
+let z = y;`)
+
+	for _, r := range reports {
+		if !strings.Contains(r.Message, "used before being assigned") {
+			continue
+		}
+		if len(r.Related) != 1 {
+			t.Fatalf("expected one related location, got %d", len(r.Related))
+		}
+		if loc := r.Related[0].Location; loc.Start.Line != 1 || loc.Start.Column != 5 {
+			t.Errorf("related location = %d:%d, want the declaration at 1:5", loc.Start.Line, loc.Start.Column)
+		}
+		return
+	}
+	t.Fatal("expected a 'used before being assigned' report")
+}
+
+func TestTopLevelDeclarationOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr bool
+		wantMsg string
+	}{
+		{
+			name: "call function declared below",
+			src: `let n = double(2);
+fn double(x: i32) -> i32 {
+    return x * 2;
+}`,
+		},
+		{
+			name: "mutually recursive functions",
+			src: `fn isEven(n: i32) -> i32 {
+    if n == 0 {
+        return 1;
+    }
+    return isOdd(n - 1);
+}
+fn isOdd(n: i32) -> i32 {
+    if n == 0 {
+        return 0;
+    }
+    return isEven(n - 1);
+}
+let e = isEven(4);`,
+		},
+		{
+			name: "variable declared below",
+			src: `let someValue = laterDeclaredValue;
+let laterDeclaredValue = "declared later";`,
+		},
+		{
+			name: "initializer type comes from the later declaration",
+			src: `let s: str = later;
+let later = 5;`,
+			wantErr: true,
+			wantMsg: "cannot assign i32 to str",
+		},
+		{
+			name: "method declared before its struct",
+			src: `fn (p: Point) sum() -> i32 {
+    return p.x + p.y;
+}
+type Point struct {
+    x: i32,
+    y: i32
+};`,
+		},
+		{
+			name: "type refers to a type declared below",
+			src: `type Line struct {
+    from: Point,
+    to: Point
+};
+type Point struct {
+    x: i32,
+    y: i32
+};`,
+		},
+		{
+			name:    "struct contains itself",
+			src:     `type Node struct { value: i32, next: Node };`,
+			wantErr: true,
+			wantMsg: "invalid recursive type: 'Node' contains itself",
+		},
+		{
+			name: "structs contain each other",
+			src: `type A struct { b: B };
+type B struct { inner: struct { a: A } };`,
+			wantErr: true,
+			wantMsg: "invalid recursive type: A -> B -> A",
+		},
+		{
+			name: "recursion through an array",
+			src: `type Tree struct { value: i32, children: []Tree };
+type Graph struct { nodes: []Tree, root: Tree };`,
+		},
+		{
+			name:    "variable refers to itself",
+			src:     `let x: i32 = x + 1;`,
+			wantErr: true,
+			wantMsg: "initialization cycle: 'x' refers to itself",
+		},
+		{
+			name: "variables refer to each other",
+			src: `let a: i32 = b;
+let b: i32 = a;`,
+			wantErr: true,
+			wantMsg: "initialization cycle: a -> b -> a",
+		},
+		{
+			name: "cycle through a function",
+			src: `let total: i32 = compute();
+fn compute() -> i32 {
+    return total + 1;
+}`,
+			wantErr: true,
+			wantMsg: "initialization cycle: total -> compute -> total",
+		},
+		{
+			name: "function reading a variable declared below",
+			src: `let total: i32 = compute();
+fn compute() -> i32 {
+    return base + 1;
+}
+let base: i32 = 41;`,
+		},
+		{
+			name: "local variables still need declaring first",
+			src: `fn f() -> i32 {
+    let a = b;
+    let b = 1;
+    return a;
+}`,
+			wantErr: true,
+			wantMsg: "undeclared variable: b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectReports(t, tt.src, tt.wantErr, tt.wantMsg)
+		})
+	}
+}
+
+func TestDiamondImport(t *testing.T) {
+	files := map[string]string{
+		"d.fer": `let shared: i32 = 1;
+type Point struct {
+    x: i32
+};
+let broken: str = 5;`,
+		"b.fer": `import "app/d";
+let fromB = d::shared;`,
+		"c.fer": `import "app/d";
+let fromC = d::shared + 1;
+fn originX(p: d::Point) -> i32 {
+    return p.x;
+}`,
+		"main.fer": `import "app/b";
+import "app/c";
+let total = b::fromB;
+let point = c::fromC;`,
+	}
+
+	reports := checkProject(t, files, "main.fer")
+	var errs report.Reports
+	var msgs []string
+	for _, r := range reports {
+		if r.Level != report.WARNING {
+			errs = append(errs, r)
+			msgs = append(msgs, r.Message)
+		}
+	}
+	if len(errs) != 1 {
+		t.Fatalf("expected the one error in d.fer, got: %v", msgs)
+	}
+	if !strings.Contains(errs[0].Message, "cannot assign i32 to str") || !strings.HasSuffix(errs[0].FilePath, "/d.fer") {
+		t.Errorf("expected the error to be reported in d.fer, got %q in %s", errs[0].Message, errs[0].FilePath)
+	}
+}
+
+func TestPrivateMembers(t *testing.T) {
+	data := `let name = "Fuad";
+priv let secret = 42;
+priv fn helper() -> i32 {
+    return secret;
+}
+fn answer() -> i32 {
+    return helper();
+}
+priv type Token i32;
+type User struct {
+    name: str,
+    priv password: str
+};
+fn (u: User) greet() -> str {
+    return u.name;
+}
+priv fn (u: User) check() -> str {
+    return u.password;
+}
+let admin: User = @User{name: "root", password: "hunter2"};`
+
+	tests := []struct {
+		name    string
+		main    string
+		wantErr bool
+		wantMsg string
+	}{
+		{"public variable", `import "app/data"; let n = data::name;`, false, ""},
+		{"public function", `import "app/data"; let n = data::answer();`, false, ""},
+		{"private variable", `import "app/data"; let s = data::secret;`, true, "'secret' is private to module 'app/data'"},
+		{"private function", `import "app/data"; let s = data::helper();`, true, "'helper' is private to module 'app/data'"},
+		{"private type", `import "app/data"; let t: data::Token = 1;`, true, "'Token' is private to module 'app/data'"},
+		{"public field", `import "app/data"; let u: data::User = data::admin; let n = u.name;`, false, ""},
+		{"private field", `import "app/data"; let u: data::User = data::admin; let p = u.password;`, true, "field 'password' of 'User' is private to module 'app/data'"},
+		{"public method", `import "app/data"; let u: data::User = data::admin; let g = u.greet();`, false, ""},
+		{"private method", `import "app/data"; let u: data::User = data::admin; let c = u.check();`, true, "method 'check' of 'User' is private to module 'app/data'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports := checkProject(t, map[string]string{"data.fer": data, "main.fer": tt.main}, "main.fer")
+			expectProjectReports(t, reports, tt.wantErr, tt.wantMsg)
+		})
+	}
+}
+
+func TestPrivateLocalDeclaration(t *testing.T) {
+	expectReports(t, `fn f() -> i32 {
+    priv let x = 1;
+    return x;
+}
+let y = f();`, true, "'priv' is only allowed on top-level declarations")
+}
+
+func TestImportLists(t *testing.T) {
+	files := map[string]string{
+		"maths.fer": `fn add(a: i32, b: i32) -> i32 {
+    return a + b;
+}
+fn sub(a: i32, b: i32) -> i32 {
+    return a - b;
+}
+priv fn secret() -> i32 {
+    return 42;
+}
+type Vector struct {
+    x: i32,
+    y: i32
+};`,
+		"others.fer": `fn add(a: i32) -> i32 {
+    return a;
+}`,
+		"facade.fer": `pub import "app/maths" { add, Vector as Vec };`,
+	}
+
+	tests := []struct {
+		name    string
+		main    string
+		wantErr bool
+		wantMsg string
+	}{
+		{"selective", `import "app/maths" { add }; let n: i32 = add(1, 2);`, false, ""},
+		{"renamed", `import "app/maths" { add as plus }; let n: i32 = plus(1, 2);`, false, ""},
+		{"renamed hides original", `import "app/maths" { add as plus }; let n = add(1, 2);`, true, "undeclared variable: add"},
+		{"list binds no alias", `import "app/maths" { add }; let n = maths::sub(1, 2);`, true, "module 'maths' not found"},
+		{"imported type", `import "app/maths" { Vector }; fn len(v: Vector) -> i32 { return v.x + v.y; }`, false, ""},
+		{"wildcard", `import "app/maths" { * }; let n: i32 = sub(add(1, 2), 1);`, false, ""},
+		{"wildcard skips private", `import "app/maths" { * }; let n = secret();`, true, "undeclared variable: secret"},
+		{"private name", `import "app/maths" { secret }; let n = secret();`, true, "'secret' is private to module 'app/maths'"},
+		{"missing name", `import "app/maths" { mul }; let n = 1;`, true, "'mul' not found in module 'app/maths'"},
+		{"re-export", `import "app/facade"; let n: i32 = facade::add(1, 2);`, false, ""},
+		{"re-export renamed", `import "app/facade" { Vec }; fn len(v: Vec) -> i32 { return v.x; }`, false, ""},
+		{"not re-exported", `import "app/facade"; let n = facade::sub(1, 2);`, true, "variable 'sub' not found in module 'facade'"},
+		{"import collision", `import "app/maths" { add }; import "app/others" { add }; let n = add(1, 2);`, true, "'add' is already imported from 'app/maths'"},
+		{"declaration collision", `import "app/maths" { add }; fn add() {}`, true, "'add' is already imported from 'app/maths'"},
+		{"collision renamed", `import "app/maths" { add }; import "app/others" { add as add1 }; let n: i32 = add(add1(1), 2);`, false, ""},
+		{"same symbol twice", `import "app/maths" { add }; import "app/facade" { add }; let n: i32 = add(1, 2);`, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := map[string]string{"main.fer": tt.main}
+			for name, src := range files {
+				project[name] = src
+			}
+			reports := checkProject(t, project, "main.fer")
+			expectProjectReports(t, reports, tt.wantErr, tt.wantMsg)
+		})
+	}
+}
+
+func TestDirectoryModules(t *testing.T) {
+	add := `mod maths;
+import "app/data";
+fn add(a: i32, b: i32) -> i32 {
+    return a + b;
+}
+priv fn helper() -> i32 {
+    return data::one;
+}`
+	double := `mod maths;
+fn double(a: i32) -> i32 {
+    return add(a, a) + helper();
+}
+let two = data::one + data::one;`
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		entry   string
+		wantErr bool
+		wantMsg string
+	}{
+		{"shared namespace", map[string]string{
+			"maths/add.fer":    add,
+			"maths/double.fer": double,
+			"main.fer":         `import "app/maths"; let n: i32 = maths::double(maths::two);`,
+		}, "main.fer", false, ""},
+		{"private stays in module", map[string]string{
+			"maths/add.fer":    add,
+			"maths/double.fer": double,
+			"main.fer":         `import "app/maths"; let n = maths::helper();`,
+		}, "main.fer", true, "'helper' is private to module 'app/maths'"},
+		{"entry file of a directory module", map[string]string{
+			"cmd/main.fer": `mod cmd; let n: i32 = twice(2);`,
+			"cmd/util.fer": `mod cmd; fn twice(a: i32) -> i32 { return a * 2; }`,
+		}, "cmd/main.fer", false, ""},
+		{"mismatched module name", map[string]string{
+			"maths/add.fer":    add,
+			"maths/double.fer": strings.Replace(double, "mod maths;", "mod geometry;", 1),
+			"main.fer":         `import "app/maths"; let n = maths::add(1, 2);`,
+		}, "main.fer", true, "module name 'geometry' does not match 'maths' declared by the other files of 'app/maths'"},
+		{"missing module declaration", map[string]string{
+			"maths/add.fer":    add,
+			"maths/double.fer": strings.Replace(double, "mod maths;", "", 1),
+			"main.fer":         `import "app/maths"; let n = maths::add(1, 2);`,
+		}, "main.fer", true, "file is part of module 'app/maths' but does not declare it"},
+		{"importing a file of a module", map[string]string{
+			"maths/add.fer":    add,
+			"maths/double.fer": double,
+			"main.fer":         `import "app/maths/add"; let n = add::add(1, 2);`,
+		}, "main.fer", true, "'app/maths/add' is a file of module 'app/maths'"},
+		{"redeclared across files", map[string]string{
+			"maths/add.fer":    add,
+			"maths/double.fer": double + "\nfn add() {}",
+			"main.fer":         `import "app/maths"; let n = maths::add(1, 2);`,
+		}, "main.fer", true, "symbol 'add' already declared"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string]string{"data.fer": `let one = 1;`}
+			for name, src := range tt.files {
+				files[name] = src
+			}
+			reports := checkProject(t, files, tt.entry)
+			expectProjectReports(t, reports, tt.wantErr, tt.wantMsg)
+		})
+	}
+}
+
+func TestProjectNameAndImportAliases(t *testing.T) {
+	files := map[string]string{
+		"maths.fer":           `fn add(a: i32, b: i32) -> i32 { return a + b; }`,
+		"vendor/lib/list.fer": `fn size() -> i32 { return 3; }`,
+		"src/utils.fer":       `fn twice(x: i32) -> i32 { return x * 2; }`,
+	}
+	configure := func(c *ctx.CompilerContext) {
+		c.ProjectConfig.Name = "core"
+		c.ProjectConfig.Imports = map[string]string{
+			"@lib/":  "vendor/lib/",
+			"@utils": "src/utils",
+			"@fmt":   "std/fmt",
+			"@out/":  "../outside/",
+		}
+	}
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+		wantMsg string
+	}{
+		{"configured name", `import "core/maths"; let x: i32 = maths::add(1, 2);`, false, ""},
+		{"directory name", `import "app/maths";`, true, "external imports are not supported yet: app/maths"},
+		{"prefix alias", `import "@lib/list"; let n: i32 = list::size();`, false, ""},
+		{"exact alias", `import "@utils" { twice }; let n: i32 = twice(2);`, false, ""},
+		{"standard library alias", `import "@fmt"; fmt::println("hi");`, false, ""},
+		{"alias and path", `import "@lib/list" as l; import "core/vendor/lib/list" { size };
+let n: i32 = l::size() + size();`, false, ""},
+		{"alias cannot leave the project", `import "@out/file";`, true, "module not found: core/outside/file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files["main.fer"] = tt.input
+			reports := checkProjectWith(t, files, "main.fer", configure)
+			expectProjectReports(t, reports, tt.wantErr, tt.wantMsg)
+		})
+	}
+}
+
+func TestStandardLibrary(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+		wantMsg string
+	}{
+		{"every module", `import "std/fmt";
+import "std/io";
+import "std/math";
+import "std/strings";
+let line = io::readLine();
+let n: i32 = strings::len(strings::repeat(line, 3));
+let root: f64 = math::sqrt(math::clamp(2.0, 0.0, math::pi));
+fmt::println(fmt::formatFloat(root) + fmt::formatInt(n));`, false, ""},
+		{"import list", `import "std/math" { abs, pi };
+let x: f64 = abs(pi);`, false, ""},
+		{"argument type", `import "std/fmt"; fmt::println(42);`, true, ""},
+		{"unknown module", `import "std/nothing";`, true, "standard library module not found: std/nothing"},
+		{"unknown function", `import "std/fmt"; fmt::printf("x");`, true, "'printf' not found in module 'fmt'"},
+		{"function without body", `fn native(x: i32) -> i32;`, true, "function 'native' has no body"},
+		{"method without body", `type P struct { x: i32 };
+fn (p: P) get() -> i32;`, true, "method 'get' has no body"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports := checkProject(t, map[string]string{"main.fer": tt.input}, "main.fer")
+			expectProjectReports(t, reports, tt.wantErr, tt.wantMsg)
+		})
+	}
+}
+
+func TestRemoteImports(t *testing.T) {
+	files := map[string]string{
+		"/user/repo/main/.ferret.json": `{"remote": {"enabled": true, "share": true}}`,
+		"/user/repo/main/lib/util.fer": `fn double(x: i32) -> i32 { return x * 2; }
+priv fn helper() -> i32 { return 1; }`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if content, ok := files[r.URL.Path]; ok {
+			w.Write([]byte(content))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		input   string
+		enabled bool
+		wantErr bool
+		wantMsg string
+	}{
+		{"remote module", `import "github.com/user/repo/lib/util"; let n: i32 = util::double(2);`, true, false, ""},
+		{"import list", `import "github.com/user/repo/lib/util" { double }; let n: i32 = double(2);`, true, false, ""},
+		{"private function", `import "github.com/user/repo/lib/util"; let n = util::helper();`, true, true, "'helper' is private to module 'github.com/user/repo/lib/util'"},
+		{"missing module", `import "github.com/user/repo/lib/nothing";`, true, true, "module not found: github.com/user/repo/lib/nothing"},
+		{"remote disabled", `import "github.com/user/repo/lib/util";`, false, true, "remote imports are disabled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports := checkProjectWith(t, map[string]string{"main.fer": tt.input}, "main.fer", func(c *ctx.CompilerContext) {
+				c.ProjectConfig.Remote.Enabled = tt.enabled
+				c.Fetcher = &remote.HTTPFetcher{BaseURL: server.URL}
+			})
+			expectProjectReports(t, reports, tt.wantErr, tt.wantMsg)
+		})
+	}
+}
+
+func TestRemoteSharePolicy(t *testing.T) {
+	files := map[string]string{
+		"/user/lib/main/.ferret.json": `{
+  "remote": {
+    "exports": ["api/"]
+  }
+}`,
+		"/user/lib/main/api/shapes.fer": `import "github.com/user/lib/internal/geometry";
+fn area(r: f64) -> f64 { return geometry::circle(r); }`,
+		"/user/lib/main/internal/geometry.fer": `fn circle(r: f64) -> f64 { return r * r * 3.14; }`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if content, ok := files[r.URL.Path]; ok {
+			w.Write([]byte(content))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	configure := func(c *ctx.CompilerContext) {
+		c.ProjectConfig.Remote.Enabled = true
+		c.Fetcher = &remote.HTTPFetcher{BaseURL: server.URL}
+	}
+
+	// An exported module imports a private one of its own repository
+	reports := checkProjectWith(t, map[string]string{"main.fer": `import "github.com/user/lib/api/shapes";
+let a: f64 = shapes::area(2.0);`}, "main.fer", configure)
+	expectProjectReports(t, reports, false, "")
+
+	reports = checkProjectWith(t, map[string]string{"main.fer": `import "github.com/user/lib/internal/geometry";`}, "main.fer", configure)
+	expectProjectReports(t, reports, true, "github.com/user/lib/internal/geometry is private to its repository, 'internal/geometry' is not in its remote.exports")
+	for _, r := range reports {
+		if !strings.HasSuffix(r.FilePath, "github.com/user/lib@main/.ferret.json") || r.Location.Start.Line != 3 || r.Location.Start.Column != 5 {
+			t.Errorf("expected the report at remote.exports of the dependency's config, got %s:%d:%d", r.FilePath, r.Location.Start.Line, r.Location.Start.Column)
+		}
+		if len(r.Related) != 1 || !strings.HasSuffix(r.Related[0].FilePath, "main.fer") {
+			t.Errorf("expected the import as related location, got %+v", r.Related)
+		}
+	}
+}
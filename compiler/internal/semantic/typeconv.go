@@ -1,6 +1,8 @@
 package semantic
 
 import (
+	"fmt"
+
 	"compiler/internal/frontend/ast"
 	"compiler/internal/types"
 )
@@ -32,16 +34,31 @@ func ASTToSemanticType(astType ast.DataType) Type {
 		}
 	case *ast.StructType:
 		fields := make(map[string]Type)
+		structType := &StructType{
+			Name:   t.TypeName,
+			Fields: fields,
+		}
 		for _, field := range t.Fields {
 			if field.FieldType != nil {
 				fieldName := field.FieldIdentifier.Name
 				fieldType := ASTToSemanticType(field.FieldType)
 				fields[fieldName] = fieldType
+				if field.IsPrivate {
+					structType.SetPrivate(fieldName)
+				}
 			}
 		}
-		return &StructType{
-			Name:   t.TypeName,
-			Fields: fields,
+		return structType
+	case *ast.InterfaceType:
+		methods := make(map[string]*FunctionType)
+		for _, method := range t.Methods {
+			if method.Name != nil {
+				methods[method.Name.Name] = signatureToSemanticType(method.Params, method.ReturnType)
+			}
+		}
+		return &InterfaceType{
+			Name:    t.TypeName,
+			Methods: methods,
 		}
 	case *ast.FunctionType:
 		var params []Type
@@ -63,6 +80,28 @@ func ASTToSemanticType(astType ast.DataType) Type {
 	}
 }
 
+// FunctionLiteralToSemanticType converts the signature of a function literal to a semantic function type
+func FunctionLiteralToSemanticType(fn *ast.FunctionLiteral) *FunctionType {
+	return signatureToSemanticType(fn.Params, fn.ReturnType)
+}
+
+// signatureToSemanticType converts parameters and return types to a semantic function type
+func signatureToSemanticType(params []ast.Parameter, returnTypes []ast.DataType) *FunctionType {
+	var paramTypes []Type
+	for _, param := range params {
+		paramTypes = append(paramTypes, ASTToSemanticType(param.Type))
+	}
+	var returns []Type
+	for _, ret := range returnTypes {
+		returns = append(returns, ASTToSemanticType(ret))
+	}
+	return &FunctionType{
+		Parameters:  paramTypes,
+		ReturnTypes: returns,
+		Name:        types.FUNCTION,
+	}
+}
+
 // CreatePrimitiveType creates a semantic primitive type
 func CreatePrimitiveType(typeName types.TYPE_NAME) Type {
 	return &PrimitiveType{Name: typeName}
@@ -138,9 +177,49 @@ func IsAssignableFrom(target, source Type) bool {
 		return true
 	}
 
+	// Implicit interface implementation
+	if targetInterface, ok := target.(*InterfaceType); ok {
+		return len(InterfaceMismatches(targetInterface, source)) == 0
+	}
+
 	return false
 }
 
+// methodSet returns the methods a type provides for interface satisfaction
+func methodSet(t Type) map[string]*FunctionType {
+	switch typ := t.(type) {
+	case *StructType:
+		return typ.Methods
+	case *InterfaceType:
+		return typ.Methods
+	case *UserType:
+		if typ.Definition != nil {
+			return methodSet(typ.Definition)
+		}
+	}
+	return nil
+}
+
+// InterfaceMismatches lists every interface method the source type is missing or has with a different signature.
+// An empty result means the source type implements the interface.
+func InterfaceMismatches(iface *InterfaceType, source Type) []string {
+	methods := methodSet(source)
+
+	var problems []string
+	for _, methodName := range iface.MethodNames() {
+		want := iface.Methods[methodName]
+		have, exists := methods[methodName]
+		if !exists {
+			problems = append(problems, fmt.Sprintf("missing method '%s'", methodName))
+			continue
+		}
+		if !have.Equals(want) {
+			problems = append(problems, fmt.Sprintf("method '%s' has signature %s, want %s", methodName, have.String(), want.String()))
+		}
+	}
+	return problems
+}
+
 // isNumericPromotion checks if source type can be promoted to target type
 func isNumericPromotion(target, source Type) bool {
 	targetPrim, targetOk := target.(*PrimitiveType)
@@ -58,8 +58,11 @@ func (u *UserType) Equals(other Type) bool {
 
 // StructType represents struct types with named fields
 type StructType struct {
-	Name   types.TYPE_NAME
-	Fields map[string]Type
+	Name    types.TYPE_NAME
+	Fields  map[string]Type
+	Methods map[string]*FunctionType // Methods declared with this struct as receiver
+	Module  string                   // Import path of the declaring module, empty for anonymous structs
+	Private map[string]bool          // Fields and methods declared with 'priv'
 }
 
 func (s *StructType) TypeName() types.TYPE_NAME {
@@ -105,6 +108,80 @@ func (s *StructType) HasField(fieldName string) bool {
 	return exists
 }
 
+// GetMethod returns the method with the given name, or nil if not found
+func (s *StructType) GetMethod(methodName string) *FunctionType {
+	return s.Methods[methodName]
+}
+
+// IsHiddenFrom reports whether a field or method is private and the given module is not the one declaring the struct
+func (s *StructType) IsHiddenFrom(member, importPath string) bool {
+	return s.Private[member] && s.Module != "" && s.Module != importPath
+}
+
+// SetPrivate marks a field or method as private
+func (s *StructType) SetPrivate(member string) {
+	if s.Private == nil {
+		s.Private = make(map[string]bool)
+	}
+	s.Private[member] = true
+}
+
+// AddMethod registers a method on the struct, returns false if a method or field with that name already exists
+func (s *StructType) AddMethod(methodName string, method *FunctionType) bool {
+	if s.HasField(methodName) || s.GetMethod(methodName) != nil {
+		return false
+	}
+	if s.Methods == nil {
+		s.Methods = make(map[string]*FunctionType)
+	}
+	s.Methods[methodName] = method
+	return true
+}
+
+// InterfaceType represents interface types, satisfied implicitly by any type having all of its methods
+type InterfaceType struct {
+	Name    types.TYPE_NAME
+	Module  string // Import path of the declaring module, empty for interface literals
+	Methods map[string]*FunctionType
+}
+
+func (i *InterfaceType) TypeName() types.TYPE_NAME {
+	return i.Name
+}
+
+func (i *InterfaceType) String() string {
+	if i.Name != types.INTERFACE {
+		return string(i.Name)
+	}
+	if len(i.Methods) == 0 {
+		return "interface {}"
+	}
+
+	var methodStrs []string
+	for _, methodName := range i.MethodNames() {
+		// "fn(i32) -> str" becomes "fn name(i32) -> str"
+		methodStrs = append(methodStrs, strings.Replace(i.Methods[methodName].String(), "fn", "fn "+methodName, 1))
+	}
+	return fmt.Sprintf("interface { %s }", strings.Join(methodStrs, ", "))
+}
+
+func (i *InterfaceType) Equals(other Type) bool {
+	if otherInterface, ok := other.(*InterfaceType); ok {
+		return i.Name == otherInterface.Name
+	}
+	return false
+}
+
+// MethodNames returns the interface method names in alphabetical order
+func (i *InterfaceType) MethodNames() []string {
+	names := make([]string, 0, len(i.Methods))
+	for name := range i.Methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // ArrayType represents array types
 type ArrayType struct {
 	ElementType Type
@@ -0,0 +1,187 @@
+// Package semver parses semantic versions, "v1.2.3", and the constraints dependencies put on them:
+// "^1.2" (compatible with), "~0.3.1" (patch updates) and exact versions.
+package semver
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version. Tag is the text it was parsed from, which is also the ref to fetch it at.
+type Version struct {
+	Major, Minor, Patch int
+	Pre                 string // pre-release, "rc.1" in v1.0.0-rc.1
+	Tag                 string
+}
+
+// Parse parses "v1.2.3", "1.2.3" or a shorter form like "v1.2", the missing parts are zero
+func Parse(text string) (Version, error) {
+	v := Version{Tag: text}
+	core := strings.TrimPrefix(text, "v")
+	if i := strings.IndexByte(core, '+'); i >= 0 {
+		core = core[:i] // build metadata does not take part in ordering
+	}
+	if i := strings.IndexByte(core, '-'); i >= 0 {
+		core, v.Pre = core[:i], core[i+1:]
+		if v.Pre == "" {
+			return Version{}, fmt.Errorf("invalid version: %s", text)
+		}
+	}
+	parts := strings.Split(core, ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return Version{}, fmt.Errorf("invalid version: %s", text)
+	}
+	numbers := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || (len(part) > 1 && part[0] == '0') {
+			return Version{}, fmt.Errorf("invalid version: %s", text)
+		}
+		*numbers[i] = n
+	}
+	return v, nil
+}
+
+// Compare returns -1, 0 or 1 as v sorts before, with or after o. A pre-release sorts before its release.
+func (v Version) Compare(o Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	switch {
+	case v.Pre == o.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case o.Pre == "":
+		return -1
+	}
+	return comparePre(v.Pre, o.Pre)
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// comparePre orders pre-release identifiers, numeric ones numerically and below alphanumeric ones
+func comparePre(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				return sign(an - bn)
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return sign(len(as) - len(bs))
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// Constraint is a range of versions, from Min up to but not including Max
+type Constraint struct {
+	Text string
+	Min  Version
+	Max  *Version // nil for an exact version, which only allows Min
+}
+
+// ParseConstraint parses "^1.2", "~0.3.1" or an exact version.
+// ^ allows changes that do not modify the left-most non-zero part, ~ allows patch changes. A partial
+// version leaves the parts it omits free: ~1 allows any 1.x.y, ^0.0 any 0.0.y.
+func ParseConstraint(text string) (Constraint, error) {
+	c := Constraint{Text: text}
+	op := ""
+	if strings.HasPrefix(text, "^") || strings.HasPrefix(text, "~") {
+		op, text = text[:1], text[1:]
+	}
+	min, err := Parse(text)
+	if err != nil {
+		return Constraint{}, fmt.Errorf("invalid version constraint: %s", c.Text)
+	}
+	c.Min = min
+
+	parts := precision(text)
+	var max Version
+	switch {
+	case op == "~" && parts == 1:
+		max = Version{Major: min.Major + 1}
+	case op == "~":
+		max = Version{Major: min.Major, Minor: min.Minor + 1}
+	case op == "^" && (min.Major > 0 || parts == 1):
+		max = Version{Major: min.Major + 1}
+	case op == "^" && (min.Minor > 0 || parts == 2):
+		max = Version{Minor: min.Minor + 1}
+	case op == "^":
+		max = Version{Patch: min.Patch + 1}
+	default:
+		return c, nil
+	}
+	c.Max = &max
+	return c, nil
+}
+
+// precision returns how many of the major, minor and patch parts a version names
+func precision(text string) int {
+	core, _, _ := strings.Cut(strings.TrimPrefix(text, "v"), "-")
+	core, _, _ = strings.Cut(core, "+")
+	return strings.Count(core, ".") + 1
+}
+
+// IsConstraint reports whether text is a semver constraint rather than a branch or other ref
+func IsConstraint(text string) bool {
+	_, err := ParseConstraint(text)
+	return err == nil
+}
+
+// Allows reports whether v is in the range. Pre-releases are only allowed by a constraint that names one.
+func (c Constraint) Allows(v Version) bool {
+	if c.Max == nil {
+		return v.Compare(c.Min) == 0
+	}
+	if v.Pre != "" && c.Min.Pre == "" {
+		return false
+	}
+	return v.Compare(c.Min) >= 0 && v.Compare(*c.Max) < 0
+}
+
+func (c Constraint) String() string {
+	return c.Text
+}
+
+// ParseTags returns the tags that are semantic versions, sorted from lowest to highest
+func ParseTags(tags []string) []Version {
+	var versions []Version
+	for _, tag := range tags {
+		if v, err := Parse(tag); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	sort.SliceStable(versions, func(i, j int) bool { return versions[i].Compare(versions[j]) < 0 })
+	return versions
+}
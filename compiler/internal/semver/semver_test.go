@@ -0,0 +1,119 @@
+package semver
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{"v1.2.3", "v1.2.3", false},
+		{"1.2.3", "v1.2.3", false},
+		{"v1.2", "v1.2.0", false},
+		{"v1", "v1.0.0", false},
+		{"v1.0.0-rc.1", "v1.0.0-rc.1", false},
+		{"v1.0.0+build.5", "v1.0.0", false},
+
+		// invalid
+		{"main", "", true},
+		{"v1.2.3.4", "", true},
+		{"v01.2.3", "", true},
+		{"v1.-2", "", true},
+		{"v1.0.0-", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			v, err := Parse(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+			}
+			if err == nil && (v.String() != tt.want || v.Tag != tt.text) {
+				t.Errorf("Parse(%q) = %s (tag %q), want %s", tt.text, v, v.Tag, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	ordered := []string{"v0.1.0", "v0.1.1", "v1.0.0-alpha", "v1.0.0-alpha.1", "v1.0.0-alpha.beta", "v1.0.0-beta.2", "v1.0.0-beta.11", "v1.0.0", "v1.2.0", "v2.0.0"}
+	for i := range ordered {
+		for j := range ordered {
+			a, _ := Parse(ordered[i])
+			b, _ := Parse(ordered[j])
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if got := a.Compare(b); got != want {
+				t.Errorf("Compare(%s, %s) = %d, want %d", a, b, got, want)
+			}
+		}
+	}
+}
+
+func TestConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		allowed    []string
+		rejected   []string
+	}{
+		{"^1.2", []string{"v1.2.0", "v1.9.3"}, []string{"v1.1.9", "v2.0.0", "v1.3.0-rc.1"}},
+		{"^0.3.1", []string{"v0.3.1", "v0.3.9"}, []string{"v0.3.0", "v0.4.0"}},
+		{"^0.0.2", []string{"v0.0.2"}, []string{"v0.0.3"}},
+		{"~0.3.1", []string{"v0.3.1", "v0.3.7"}, []string{"v0.4.0", "v0.3.0"}},
+		{"~1.2", []string{"v1.2.0", "v1.2.5"}, []string{"v1.3.0"}},
+		{"v1.2.0", []string{"v1.2.0", "1.2.0"}, []string{"v1.2.1"}},
+		{"~1", []string{"v1.0.0", "v1.1.0", "v1.9.9"}, []string{"v0.9.0", "v2.0.0"}},
+		{"~0", []string{"v0.0.1", "v0.9.0"}, []string{"v1.0.0"}},
+		{"^1", []string{"v1.0.0", "v1.9.0"}, []string{"v2.0.0"}},
+		{"^0", []string{"v0.0.1", "v0.9.0"}, []string{"v1.0.0"}},
+		{"^0.0", []string{"v0.0.0", "v0.0.9"}, []string{"v0.1.0"}},
+		{"^0.2", []string{"v0.2.0", "v0.2.9"}, []string{"v0.3.0", "v0.1.9"}},
+		{"^v1.2", []string{"v1.2.0", "v1.9.0"}, []string{"v2.0.0"}},
+		{"^1.0.0-rc.1", []string{"v1.0.0-rc.2", "v1.0.0", "v1.5.0"}, []string{"v1.0.0-alpha", "v2.0.0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			c, err := ParseConstraint(tt.constraint)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, text := range tt.allowed {
+				if v, _ := Parse(text); !c.Allows(v) {
+					t.Errorf("%s should allow %s", c, text)
+				}
+			}
+			for _, text := range tt.rejected {
+				if v, _ := Parse(text); c.Allows(v) {
+					t.Errorf("%s should not allow %s", c, text)
+				}
+			}
+		})
+	}
+
+	for _, text := range []string{"main", "^", "~main", ">=1.0", "^1.x"} {
+		if IsConstraint(text) {
+			t.Errorf("IsConstraint(%q) = true, want false", text)
+		}
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	versions := ParseTags([]string{"v1.10.0", "latest", "v1.2.0", "v1.2.0-rc.1", "0.9.0"})
+	var tags []string
+	for _, v := range versions {
+		tags = append(tags, v.Tag)
+	}
+	if want := []string{"0.9.0", "v1.2.0-rc.1", "v1.2.0", "v1.10.0"}; !slices.Equal(tags, want) {
+		t.Errorf("ParseTags() = %v, want %v", tags, want)
+	}
+}
@@ -7,7 +7,7 @@ import (
 )
 
 // CreateTempProject creates a temporary directory structure for testing
-func CreateTempProject(t *testing.T) string {
+func CreateTempProject(t testing.TB) string {
 	tempDir := t.TempDir()
 
 	// Create cache directory structure
@@ -30,7 +30,7 @@ func CreateTestFile(t *testing.T, content string) string {
 }
 
 // CreateTestFileInDir creates a test file in a specific directory
-func CreateTestFileInDir(t *testing.T, dir, filename, content string) string {
+func CreateTestFileInDir(t testing.TB, dir, filename, content string) string {
 	// Ensure the target directory exists
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		t.Fatalf("Failed to create directory: %v", err)
@@ -0,0 +1,75 @@
+// Package fixture sets up compiler contexts for tests. It lives apart from testutil because it
+// depends on the compiler, which the lexer and parser tests of testutil cannot.
+package fixture
+
+import (
+	"path/filepath"
+	"testing"
+
+	"compiler/ctx"
+	"compiler/internal/config"
+	"compiler/internal/frontend/parser"
+	"compiler/internal/report"
+	"compiler/internal/semantic"
+	"compiler/internal/testutil"
+)
+
+// Pass is a phase run over every module of a context after parsing, like resolver.ResolveModules
+// or typecheck.CheckModules
+type Pass func(context *ctx.CompilerContext, debug bool)
+
+// Project writes the files into a project named "app" and returns a compiler context for it.
+// The names of the files are relative to the project root, like "main.fer" or "lib/util.fer".
+func Project(tb testing.TB, files map[string]string) *ctx.CompilerContext {
+	tb.Helper()
+
+	projectRoot := filepath.ToSlash(filepath.Join(testutil.CreateTempProject(tb), "app"))
+	for name, content := range files {
+		path := filepath.Join(projectRoot, filepath.FromSlash(name))
+		testutil.CreateTestFileInDir(tb, filepath.Dir(path), filepath.Base(path), content)
+	}
+
+	return &ctx.CompilerContext{
+		Builtins:      semantic.AddPreludeSymbols(semantic.NewSymbolTable(nil)),
+		Modules:       make(map[string]*ctx.Module),
+		Reports:       report.Reports{},
+		ProjectConfig: &config.ProjectConfig{ProjectRoot: projectRoot},
+		ProjectRoot:   projectRoot,
+		CachePath:     projectRoot + "/.ferret",
+	}
+}
+
+// Compile parses the entry file of the project and runs the passes in order, a pass only runs when
+// the ones before it reported no errors. It returns the reports.
+func Compile(context *ctx.CompilerContext, entry string, passes ...Pass) (reports report.Reports) {
+	// Syntax and critical errors stop compilation with a panic
+	defer func() {
+		recover()
+		reports = context.Reports
+	}()
+
+	parser.NewParser(context.ProjectRoot+"/"+entry, context, false).Parse()
+	for _, pass := range passes {
+		if context.Reports.HasErrors() {
+			break
+		}
+		pass(context, false)
+	}
+	return context.Reports
+}
+
+// Check writes the files into a project and compiles main.fer with the passes, it fails the test
+// when the program has errors
+func Check(tb testing.TB, files map[string]string, passes ...Pass) *ctx.CompilerContext {
+	tb.Helper()
+
+	context := Project(tb, files)
+	if reports := Compile(context, "main.fer", passes...); reports.HasErrors() {
+		var msgs []string
+		for _, r := range reports {
+			msgs = append(msgs, r.Message)
+		}
+		tb.Fatalf("the program has errors: %v", msgs)
+	}
+	return context
+}
@@ -0,0 +1,298 @@
+package fixture
+
+// Program is a project run by every backend test, the interpreter, the VM and the C backend
+// must print the same and fail the same way
+type Program struct {
+	Name   string
+	Files  map[string]string // main.fer and the modules it imports
+	Stdin  string
+	Stdout string
+	Err    string   // part of the runtime error message, empty when the program ends normally
+	Trace  []string // function and line:column of each frame of the error, innermost first
+}
+
+// Programs are the programs the backends are tested on
+var Programs = []Program{
+	{Name: "arithmetic", Files: mainFile(`import "std/fmt";
+let a = 7;
+let b: i64 = 2;
+fmt::println(fmt::formatInt(a / 2) + " " + fmt::formatInt(a % 3) + " " + fmt::formatInt(a * b - 20));
+fmt::println(fmt::formatFloat(a / 2.0) + " " + fmt::formatFloat(7.5 % 2.0));
+fmt::println(fmt::formatBool(1.5 > a) + " " + fmt::formatBool("abc" < "abd") + " " + fmt::formatBool("x" + "y" == "xy"));`),
+		Stdout: "3 1 -6\n3.5 1.5\nfalse true true\n"},
+	{Name: "fixed width integers", Files: mainFile(`import "std/fmt";
+let max: i32 = 2147483647;
+let wrapped = max + 1;
+let min: i32 = -max - 1;
+let widened: i64 = max;
+widened = widened + 1;
+let m = min;
+m--;
+fmt::println(fmt::formatInt(wrapped) + " " + fmt::formatInt(min / -1) + " " + fmt::formatInt(widened) + " " + fmt::formatInt(m));
+let n: i32 = 1;
+for let i = 0; i < 40; i++ {
+    n = n * 3;
+}
+fmt::println(fmt::formatInt(n));`),
+		Stdout: "-2147483648 -2147483648 2147483648 2147483647\n689956897\n"},
+	{Name: "increment and decrement", Files: mainFile(`import "std/fmt";
+let x = 5;
+let y = x++;
+let z = --x;
+fmt::println(fmt::formatInt(x) + fmt::formatInt(y) + fmt::formatInt(z));`),
+		Stdout: "555\n"},
+	{Name: "strings and conditions", Files: mainFile(`import "std/fmt";
+import "std/strings";
+fn grade(score: i32) -> str {
+    if score >= 90 {
+        return "A";
+    } else if score >= 80 {
+        return "B";
+    }
+    return "C";
+}
+let s = strings::toUpper("ab") + grade(95) + grade(85) + grade(10);
+if strings::len(s) == 5 && !(s < "AB") {
+    fmt::println(s);
+}`),
+		Stdout: "ABABC\n"},
+	{Name: "locals and scopes", Files: mainFile(`import "std/fmt";
+fn f(n: i32) -> i32 {
+    let x = n * 2;
+    if x > 4 {
+        let x = 100;
+        n = n + x;
+    }
+    if n > 0 {
+        let y = x + 1;
+        n = n + y;
+    }
+    return n + x;
+}
+fmt::println(fmt::formatInt(f(1)) + " " + fmt::formatInt(f(3)));`),
+		Stdout: "6 116\n"},
+	{Name: "loops", Files: mainFile(`import "std/fmt";
+let total = 0;
+for let i = 0; i < 10; i++ {
+    let odd = i % 2;
+    if odd == 0 {
+        continue;
+    }
+    if i > 7 {
+        break;
+    }
+    total = total + i;
+}
+let n = 0;
+while true_after(n) {
+    n++;
+}
+fn true_after(n: i32) -> bool {
+    return n < 3;
+}
+fmt::println(fmt::formatInt(total) + " " + fmt::formatInt(n));`),
+		Stdout: "16 3\n"},
+	{Name: "short circuit", Files: mainFile(`import "std/fmt";
+let calls = 0;
+fn check(b: bool) -> bool {
+    calls++;
+    return b;
+}
+if check(1 > 2) && check(1 < 2) {
+    fmt::println("no");
+} else if check(1 > 2) || check(1 < 2) {
+    fmt::println(fmt::formatInt(calls));
+}`),
+		Stdout: "3\n"},
+	{Name: "evaluation order", Files: mainFile(`import "std/fmt";
+let log = "";
+fn note(s: str, n: i32) -> i32 {
+    log = log + s;
+    return n;
+}
+let x = note("a", 1) - note("b", 2) * note("c", 3);
+let a = [note("d", 0), note("e", 1)];
+a[note("f", 0)] = note("g", 5);
+fmt::println(log + " " + fmt::formatInt(x) + " " + fmt::formatInt(a[0]));`),
+		Stdout: "abcdegf -5 5\n"},
+	{Name: "closures share captured variables", Files: mainFile(`import "std/fmt";
+fn counter() -> fn() -> i32 {
+    let count = 0;
+    let inc = fn() -> i32 {
+        count = count + 1;
+        return count;
+    };
+    let nested = fn() -> i32 {
+        let twice = fn() -> i32 {
+            inc();
+            return inc();
+        };
+        return twice();
+    };
+    nested();
+    return inc;
+}
+let first = counter();
+let second = counter();
+first();
+fmt::println(fmt::formatInt(first()) + fmt::formatInt(second()));`),
+		Stdout: "43\n"},
+	{Name: "closures capture each iteration", Files: mainFile(`import "std/fmt";
+let a = fn() -> i32 { return 0; };
+let b = a;
+let c = a;
+for let i = 0; i < 3; i++ {
+    let j = i * 10;
+    let f = fn() -> i32 { return j; };
+    if i == 0 { a = f; }
+    if i == 1 { b = f; }
+    if i == 2 { c = f; }
+}
+fmt::println(fmt::formatInt(a() + b() + c()) + " " + fmt::formatInt(b()));`),
+		Stdout: "30 10\n"},
+	{Name: "recursion", Files: mainFile(`import "std/fmt";
+fn fib(n: i32) -> i32 {
+    if n < 2 {
+        return n;
+    }
+    return fib(n - 1) + fib(n - 2);
+}
+fn outer() -> i32 {
+    fn fact(n: i64) -> i64 {
+        if n <= 1 {
+            return 1;
+        }
+        return n * fact(n - 1);
+    }
+    return fact(5);
+}
+fmt::println(fmt::formatInt(fib(20)) + " " + fmt::formatInt(outer()));`),
+		Stdout: "6765 120\n"},
+	{Name: "multiple results", Files: mainFile(`import "std/fmt";
+fn divmod(a: i32, b: i32) -> (i32, i32) {
+    return a / b, a % b;
+}
+fn swap(a: i32, b: i32) -> (i32, i32) {
+    return b, a;
+}
+let q = 0;
+let r = 0;
+q, r = divmod(17, 5);
+let x = 1;
+let y = 2;
+x, y = swap(x, y);
+fmt::println(fmt::formatInt(q) + fmt::formatInt(r) + fmt::formatInt(x) + fmt::formatInt(y));`),
+		Stdout: "3221\n"},
+	{Name: "arrays and structs", Files: mainFile(`import "std/fmt";
+type Point struct { x: i32, y: i32 };
+fn (p: Point) sum() -> i32 {
+    return p.x + p.y;
+}
+fn moved(p: Point) -> Point {
+    p.x = p.x + 100;
+    return p;
+}
+let a = [1, 2, 3];
+let shared = a;
+shared[0] = 10;
+let p = @Point{x: 1, y: 2};
+let q = p;
+q.x = 5;
+let points = [p, q];
+points[1].y = 7;
+let sum = q.sum;
+q.x = 100;
+let wide: []i64 = a;
+fmt::println(fmt::formatInt(a[0] + a[1] + a[2]) + " " + fmt::formatInt(p.sum()) + " " + fmt::formatInt(q.sum()) + " " + fmt::formatInt(points[1].sum()));
+fmt::println(fmt::formatInt(sum()) + " " + fmt::formatInt(wide[0]) + " " + fmt::formatBool(p == points[0]) + " " + fmt::formatBool(a == shared) + " " + fmt::formatInt(moved(p).x + p.x));`),
+		Stdout: "15 3 102 12\n7 10 true true 102\n"},
+	{Name: "standard library", Files: mainFile(`import "std/fmt";
+import "std/strings";
+import "std/io";
+import "std/math";
+import "std/math" { sqrt, clamp, pi };
+let name = io::readLine();
+fmt::println(strings::toUpper(name) + " " + fmt::formatInt(strings::len(name)) + " " + fmt::formatBool(strings::contains(name, "rr")));
+fmt::println(strings::repeat("ab", 3) + " " + fmt::formatFloat(math::sqrt(2.0)) + " " + fmt::formatFloat(math::max(math::pi, 3.0)));
+fmt::println(fmt::formatFloat(sqrt(16.0)) + " " + fmt::formatFloat(clamp(pi, 0.0, 3.0)));`),
+		Stdin:  "ferret\n",
+		Stdout: "FERRET 6 true\nababab 1.4142135623730951 3.141592653589793\n4 3\n"},
+	{Name: "modules", Files: map[string]string{
+		"main.fer": `import "std/fmt";
+import "app/shapes";
+import "app/shapes" { area as rectArea };
+let r = shapes::newRect(2, 3);
+fmt::println(fmt::formatInt(shapes::count) + " " + fmt::formatInt(rectArea(r)) + " " + fmt::formatInt(r.perimeter()));`,
+		"shapes.fer": `type Rect struct { w: i32, h: i32 };
+let count = 2 + 1;
+fn (r: Rect) perimeter() -> i32 {
+    return 2 * (r.w + r.h);
+}
+fn newRect(w: i32, h: i32) -> Rect {
+    return @Rect{w: w, h: h};
+}
+fn area(r: Rect) -> i32 {
+    return r.w * r.h;
+}`},
+		Stdout: "3 6 10\n"},
+	{Name: "interfaces", Files: map[string]string{
+		"main.fer": `import "std/fmt";
+import "app/shapes";
+type Named interface { fn name() -> str };
+type Slot struct { shape: shapes::Shape, count: i32 };
+fn describe(s: shapes::Shape) -> str {
+    return s.name() + " " + fmt::formatInt(s.area());
+}
+let sq = shapes::square(3);
+let shape: shapes::Shape = sq;
+sq.side = 10;
+let slot = @Slot{shape: shapes::rect(2, 5), count: 1};
+let named: Named = shape;
+let area = shape.area;
+let same: shapes::Shape = shapes::square(3);
+fmt::println(describe(shape) + ", " + describe(slot.shape) + ", " + named.name());
+fmt::println(fmt::formatInt(area() + shape.area()) + " " + fmt::formatBool(shape == same) + " " + fmt::formatBool(shape == slot.shape));`,
+		"shapes.fer": `type Shape interface { fn area() -> i32, fn name() -> str };
+type Rect struct { w: i32, h: i32 };
+type Square struct { side: i32 };
+fn (r: Rect) area() -> i32 { return r.w * r.h; }
+fn (r: Rect) name() -> str { return "rect"; }
+fn (s: Square) area() -> i32 {
+    s.side = s.side + 1;
+    return (s.side - 1) * (s.side - 1);
+}
+fn (s: Square) name() -> str { return "square"; }
+fn square(side: i32) -> Square {
+    return @Square{side: side};
+}
+fn rect(w: i32, h: i32) -> Shape {
+    return @Rect{w: w, h: h};
+}`},
+		Stdout: "square 9, rect 10, square\n18 true false\n"},
+	{Name: "division by zero", Files: mainFile(`import "std/fmt";
+fn divide(a: i32, b: i32) -> i32 {
+    return a / b;
+}
+fn average(total: i32, count: i32) -> i32 {
+    return divide(total, count);
+}
+fmt::println("before");
+let x = average(10, 0);
+fmt::println("after");`),
+		Stdout: "before\n", Err: "integer division by zero", Trace: []string{"divide 3:12", "average 6:12", "<app/main> 9:9"}},
+	{Name: "index out of range", Files: mainFile(`let a = [1, 2, 3];
+for let i = 0; i < 5; i++ {
+    let x = a[i];
+}`),
+		Err: "index 3 out of range for an array of length 3", Trace: []string{"<app/main> 3:13"}},
+	{Name: "stack overflow", Files: mainFile(`fn forever(n: i32) -> i32 {
+    return forever(n + 1);
+}
+forever(0);`),
+		Err: "stack overflow, more than 10000 nested calls"},
+}
+
+// mainFile is the files of a project made of main.fer alone
+func mainFile(src string) map[string]string {
+	return map[string]string{"main.fer": src}
+}
@@ -7,13 +7,20 @@ import (
 	"strings"
 
 	"compiler/ctx"
+	"compiler/internal/remote"
+	"compiler/std"
 )
 
 const EXT = ".fer"
-const REMOTE_HOST = "github.com/"
+const REMOTE_HOST = remote.Host + "/"
 
 func IsRemote(importPath string) bool {
-	return strings.HasPrefix(importPath, REMOTE_HOST)
+	return remote.IsRemote(importPath)
+}
+
+// IsStd reports whether an import path names a module of the bundled standard library
+func IsStd(importPath string) bool {
+	return strings.HasPrefix(importPath, std.Root+"/")
 }
 
 // Check if file exists and is a regular file
@@ -22,6 +29,27 @@ func IsValidFile(filename string) bool {
 	return err == nil && fileInfo.Mode().IsRegular()
 }
 
+// IsModuleDir reports whether path is a directory holding at least one .fer file
+func IsModuleDir(path string) bool {
+	files, err := ModuleFiles(path)
+	return err == nil && len(files) > 0
+}
+
+// ModuleFiles returns the .fer files directly inside a directory module, sorted by name
+func ModuleFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.FromSlash(dir))
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && filepath.Ext(entry.Name()) == EXT {
+			files = append(files, filepath.ToSlash(filepath.Join(dir, entry.Name())))
+		}
+	}
+	return files, nil
+}
+
 // GitHubPathToRawURL converts a GitHub import path to a raw.githubusercontent.com URL.
 // Example: "github.com/user/repo/path/file" → "https://raw.githubusercontent.com/user/repo/main/path/file"
 func GitHubPathToRawURL(importPath, defaultBranch string) (string, string) {
@@ -76,9 +104,10 @@ func LastPart(path string) string {
 }
 
 func ResolveModule(importPath, currentFileFullPath string, ctxx *ctx.CompilerContext) (string, error) {
+	importPath = ctxx.ExpandImportPath(importPath, currentFileFullPath)
 
 	if IsRemote(importPath) {
-		return "", fmt.Errorf("remote imports are not supported yet: %s", importPath)
+		return resolveRemote(importPath, currentFileFullPath, ctxx)
 	}
 
 	//the first part of the import path is the root
@@ -87,16 +116,33 @@ func ResolveModule(importPath, currentFileFullPath string, ctxx *ctx.CompilerCon
 		return "", fmt.Errorf("invalid import path: %s", importPath)
 	}
 
-	projectRoot := LastPart(ctxx.ProjectRoot)
-	if projectRoot == "" {
+	if importRoot == std.Root {
+		if !IsStd(importPath) {
+			return "", fmt.Errorf("invalid import path: %s", importPath)
+		}
+		return std.Extract(ctxx.StdPath(), strings.TrimPrefix(importPath, std.Root+"/"))
+	}
+
+	if ctxx.ProjectRoot == "" {
 		return "", fmt.Errorf("invalid project root: %s", ctxx.ProjectRoot)
 	}
+	if _, err := ctxx.Members(); err != nil {
+		return "", err
+	}
 
-	if importRoot == projectRoot {
-		resolvedPath := filepath.Join(strings.TrimSuffix(ctxx.ProjectRoot, projectRoot), importPath+EXT)
+	// A project's modules start with its import root, its name in .ferret.json or its directory name.
+	// The members of a workspace import each other's modules by their names.
+	if projectRoot, ok := ctxx.ProjectRootOf(importRoot); ok {
+		modulePath := strings.TrimPrefix(importPath, importRoot)
+		resolvedPath := filepath.Join(projectRoot, modulePath+EXT)
 		if IsValidFile(resolvedPath) {
 			return resolvedPath, nil
 		}
+		// A directory of .fer files is a module too, a file with the same name takes precedence
+		dirPath := filepath.Join(projectRoot, modulePath)
+		if IsModuleDir(dirPath) {
+			return dirPath, nil
+		}
 		return "", fmt.Errorf("module not found: %s", importPath)
 	}
 
@@ -1,11 +1,18 @@
 package fs
 
 import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"compiler/ctx"
+	"compiler/internal/config"
+	"compiler/internal/deps"
+	"compiler/internal/remote"
 )
 
 func TestIsRemote(t *testing.T) {
@@ -141,6 +148,7 @@ func TestResolveModule(t *testing.T) {
 	// Create context
 	ctxx := &ctx.CompilerContext{
 		ProjectRoot: projectDir,
+		CachePath:   filepath.ToSlash(filepath.Join(projectDir, ".ferret")),
 	}
 
 	tests := []struct {
@@ -152,16 +160,253 @@ func TestResolveModule(t *testing.T) {
 		{"Remote import", "github.com/user/repo/module", "", true},
 		{"Empty import", "", "", true},
 		{"Non-existent local module", "testproject/nonexistent", "", true},
-		// Note: Valid local module test would require mocking IsValidFile or setting up more complex file structure
+		{"Local file module", "testproject/module/test", "", false},
+		{"Local directory module", "testproject/module", "", false},
+		{"Standard library module", "std/fmt", "", false},
+		{"Unknown standard library module", "std/nothing", "", true},
+		{"Standard library root", "std", "", true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := ResolveModule(tt.importPath, tt.currentFileFullPath, ctxx)
+			path, err := ResolveModule(tt.importPath, tt.currentFileFullPath, ctxx)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ResolveModule(%q, %q, ctx) error = %v, wantErr %v",
 					tt.importPath, tt.currentFileFullPath, err, tt.wantErr)
 			}
+			if err == nil && IsStd(tt.importPath) && ctxx.FullPathToImportPath(path) != tt.importPath {
+				t.Errorf("standard library file %q maps back to %q", path, ctxx.FullPathToImportPath(path))
+			}
 		})
 	}
 }
+
+func TestModuleFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.fer", "a.fer", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "nested.fer"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ModuleFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.ToSlash(filepath.Join(dir, "a.fer")), filepath.ToSlash(filepath.Join(dir, "b.fer"))}
+	if len(files) != len(want) || files[0] != want[0] || files[1] != want[1] {
+		t.Errorf("ModuleFiles() = %v, want %v", files, want)
+	}
+	if !IsModuleDir(dir) || IsModuleDir(filepath.Join(dir, "nested.fer")) {
+		t.Error("expected only the directory holding .fer files to be a module directory")
+	}
+}
+
+// remoteServer serves the files of user/repo on the main branch and counts the requests it gets
+func remoteServer(t *testing.T, files map[string]string) (*httptest.Server, *int) {
+	t.Helper()
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		content, ok := files[strings.TrimPrefix(r.URL.Path, "/user/repo/main/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestResolveRemoteModule(t *testing.T) {
+	server, requests := remoteServer(t, map[string]string{
+		".ferret.json": `{"remote": {"enabled": true, "share": true}}`,
+		"lib/util.fer": "fn util() {}",
+	})
+	projectDir := filepath.ToSlash(filepath.Join(t.TempDir(), "app"))
+	newContext := func(enabled bool) *ctx.CompilerContext {
+		return &ctx.CompilerContext{
+			ProjectRoot:   projectDir,
+			ProjectConfig: &config.ProjectConfig{ProjectRoot: projectDir, Remote: config.RemoteConfig{Enabled: enabled}},
+			CachePath:     projectDir + "/.ferret/modules",
+			Fetcher:       &remote.HTTPFetcher{BaseURL: server.URL},
+		}
+	}
+
+	ctxx := newContext(true)
+	path, err := ResolveModule("github.com/user/repo/lib/util", "", ctxx)
+	if err != nil {
+		t.Fatalf("ResolveModule() error = %v", err)
+	}
+	if want := projectDir + "/.ferret/modules/github.com/user/repo@main/lib/util.fer"; path != want {
+		t.Errorf("ResolveModule() = %q, want %q", path, want)
+	}
+	if got := ctxx.FullPathToImportPath(path); got != "github.com/user/repo/lib/util" {
+		t.Errorf("FullPathToImportPath() = %q", got)
+	}
+	if cfg := ctxx.FindNearestRemoteConfig("github.com/user/repo/lib/util"); cfg == nil || !cfg.Remote.Share {
+		t.Errorf("expected the repository config to be cached, got %+v", cfg)
+	}
+
+	// A new compilation finds everything in the cache
+	fetched := *requests
+	if _, err := ResolveModule("github.com/user/repo/lib/util", "", newContext(true)); err != nil || *requests != fetched {
+		t.Errorf("expected the cached module without requests, error = %v, requests = %d", err, *requests-fetched)
+	}
+
+	if _, err := ResolveModule("github.com/user/repo/lib/util", "", newContext(false)); err == nil || !strings.Contains(err.Error(), "remote imports are disabled") {
+		t.Errorf("expected remote imports to be disabled, got %v", err)
+	}
+	if _, err := ResolveModule("github.com/user/repo/lib/missing", "", ctxx); err == nil || !strings.Contains(err.Error(), "module not found") {
+		t.Errorf("expected a missing module, got %v", err)
+	}
+	if _, err := ResolveModule("github.com/user/other/lib", "", ctxx); err == nil || !strings.Contains(err.Error(), "is not a Ferret project") {
+		t.Errorf("expected a repository without config, got %v", err)
+	}
+}
+
+func TestRemoteLockFile(t *testing.T) {
+	files := map[string]string{
+		".ferret.json": `{"remote": {"share": true}}`,
+		"lib/util.fer": "fn util() {}",
+	}
+	server, _ := remoteServer(t, files)
+	projectDir := filepath.ToSlash(filepath.Join(t.TempDir(), "app"))
+	os.MkdirAll(projectDir, 0755)
+	newContext := func(locked bool) *ctx.CompilerContext {
+		return &ctx.CompilerContext{
+			ProjectRoot:   projectDir,
+			ProjectConfig: &config.ProjectConfig{ProjectRoot: projectDir, Remote: config.RemoteConfig{Enabled: true}},
+			CachePath:     projectDir + "/.ferret/modules",
+			Fetcher:       &remote.HTTPFetcher{BaseURL: server.URL},
+			Locked:        locked,
+		}
+	}
+	const importPath = "github.com/user/repo/lib/util"
+
+	if _, err := ResolveModule(importPath, "", newContext(true)); err == nil || !strings.Contains(err.Error(), "--locked") {
+		t.Fatalf("expected --locked to refuse an unpinned file, got %v", err)
+	}
+
+	ctxx := newContext(false)
+	if _, err := ResolveModule(importPath, "", ctxx); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctxx.SaveLockFile(); err != nil {
+		t.Fatal(err)
+	}
+	lock, _ := ctxx.LockFile()
+	if entry, ok := lock.Get("github.com/user/repo/lib/util.fer"); !ok || entry.Version != remote.DefaultBranch || entry.Source != "github.com/user/repo" {
+		t.Errorf("expected the module to be pinned, got %+v", lock.Entries())
+	}
+
+	if _, err := ResolveModule(importPath, "", newContext(true)); err != nil {
+		t.Errorf("expected the pinned module to resolve with --locked, got %v", err)
+	}
+
+	// The module changes upstream after the cache is cleaned
+	os.RemoveAll(filepath.FromSlash(projectDir + "/.ferret"))
+	files["lib/util.fer"] = "fn util() { changed(); }"
+	if _, err := ResolveModule(importPath, "", newContext(false)); err == nil || !strings.Contains(err.Error(), "does not match ferret.lock") {
+		t.Errorf("expected drift from the lockfile to be an error, got %v", err)
+	}
+}
+
+func TestResolveVendoredModule(t *testing.T) {
+	server, requests := remoteServer(t, map[string]string{
+		".ferret.json": `{"remote": {"share": true}}`,
+		"lib/util.fer": "fn util() {}",
+		"lib/more.fer": "fn more() {}",
+	})
+	projectDir := filepath.ToSlash(filepath.Join(t.TempDir(), "app"))
+	os.MkdirAll(projectDir, 0755)
+	projectConfig := &config.ProjectConfig{ProjectRoot: projectDir, Remote: config.RemoteConfig{Enabled: true}}
+	newContext := func() *ctx.CompilerContext {
+		return &ctx.CompilerContext{
+			ProjectRoot:   projectDir,
+			ProjectConfig: projectConfig,
+			CachePath:     projectDir + "/.ferret/modules",
+			Fetcher:       &remote.HTTPFetcher{BaseURL: server.URL},
+			Vendored:      true,
+		}
+	}
+
+	ctxx := newContext()
+	if _, err := ResolveModule("github.com/user/repo/lib/util", "", ctxx); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctxx.SaveLockFile(); err != nil {
+		t.Fatal(err)
+	}
+	project, err := deps.OpenProject(projectConfig, ctxx.Fetcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := project.Vendor(); err != nil {
+		t.Fatal(err)
+	}
+	os.RemoveAll(filepath.FromSlash(projectDir + "/.ferret"))
+
+	// The vendored copy is used without the cache or the network
+	fetched := *requests
+	ctxx = newContext()
+	path, err := ResolveModule("github.com/user/repo/lib/util", "", ctxx)
+	if err != nil || *requests != fetched {
+		t.Fatalf("expected the vendored module without requests, error = %v, requests = %d", err, *requests-fetched)
+	}
+	if want := projectDir + "/vendor/github.com/user/repo/lib/util.fer"; path != want {
+		t.Errorf("ResolveModule() = %q, want %q", path, want)
+	}
+	if got := ctxx.FullPathToImportPath(path); got != "github.com/user/repo/lib/util" {
+		t.Errorf("FullPathToImportPath() = %q", got)
+	}
+	if cfg := ctxx.FindNearestRemoteConfig("github.com/user/repo/lib/util"); cfg == nil || !cfg.Remote.Share {
+		t.Errorf("expected the vendored repository config, got %+v", cfg)
+	}
+
+	// A module that is not vendored is still fetched
+	if _, err := ResolveModule("github.com/user/repo/lib/more", "", ctxx); err != nil || *requests == fetched {
+		t.Errorf("expected the module missing from vendor to be fetched, error = %v", err)
+	}
+
+	os.WriteFile(filepath.FromSlash(path), []byte("fn util() { edited(); }"), 0644)
+	if _, err := ResolveModule("github.com/user/repo/lib/util", "", newContext()); err == nil || !strings.Contains(err.Error(), "is stale") {
+		t.Errorf("expected an edited vendored module to be rejected, got %v", err)
+	}
+}
+
+func TestRemoteSharePolicy(t *testing.T) {
+	server, _ := remoteServer(t, map[string]string{
+		".ferret.json": "{\n  \"remote\": { \"share\": false }\n}",
+		"lib/util.fer": "fn util() {}",
+	})
+	projectDir := filepath.ToSlash(filepath.Join(t.TempDir(), "app"))
+	ctxx := &ctx.CompilerContext{
+		ProjectRoot:   projectDir,
+		ProjectConfig: &config.ProjectConfig{ProjectRoot: projectDir, Remote: config.RemoteConfig{Enabled: true}},
+		CachePath:     projectDir + "/.ferret/modules",
+		Fetcher:       &remote.HTTPFetcher{BaseURL: server.URL},
+	}
+
+	_, err := ResolveModule("github.com/user/repo/lib/util", projectDir+"/main.fer", ctxx)
+	var shareErr *ShareError
+	if !errors.As(err, &shareErr) {
+		t.Fatalf("expected a ShareError, got %v", err)
+	}
+	if want := projectDir + "/.ferret/modules/github.com/user/repo@main/.ferret.json"; shareErr.ConfigPath != want {
+		t.Errorf("ConfigPath = %q, want %q", shareErr.ConfigPath, want)
+	}
+	if loc := shareErr.Location; loc.Start.Line != 2 || loc.Start.Column != 15 || loc.End.Column != 29 {
+		t.Errorf("expected remote.share to be located, got %d:%d-%d", loc.Start.Line, loc.Start.Column, loc.End.Column)
+	}
+
+	// The files of the repository import each other
+	importer := projectDir + "/.ferret/modules/github.com/user/repo@main/lib/other.fer"
+	if _, err := ResolveModule("github.com/user/repo/lib/util", importer, ctxx); err != nil {
+		t.Errorf("expected the repository to import its own module, got %v", err)
+	}
+}
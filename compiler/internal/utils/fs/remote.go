@@ -0,0 +1,133 @@
+package fs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"compiler/ctx"
+	"compiler/internal/config"
+	"compiler/internal/deps"
+	"compiler/internal/remote"
+	"compiler/internal/source"
+)
+
+// resolveRemote returns the cached file of a remote module. On first use the module is fetched into
+// the cache together with the .ferret.json of its repository, later imports are served from the cache.
+// The repository is fetched at the version selected for it from the constraints of the dependency graph,
+// its default branch when nothing depends on it. Modules the repository does not share are refused,
+// unless they are imported by the repository itself.
+func resolveRemote(importPath, importerFullPath string, ctxx *ctx.CompilerContext) (string, error) {
+	if ctxx.ProjectConfig == nil || !ctxx.ProjectConfig.Remote.Enabled {
+		return "", fmt.Errorf("remote imports are disabled by remote.enabled in %s: %s", config.CONFIG_FILE, importPath)
+	}
+	module, err := remote.ParseModule(importPath)
+	if err != nil {
+		return "", err
+	}
+	if ctxx.Vendored {
+		modulePath, configPath, found, err := resolveVendored(module, ctxx)
+		if err == nil && found && configPath != "" {
+			err = checkShared(module, configPath, importerFullPath, ctxx)
+		}
+		if found || err != nil {
+			return modulePath, err
+		}
+	}
+	store, err := ctxx.Store()
+	if err != nil {
+		return "", fmt.Errorf("remote imports need the module cache: %w", err)
+	}
+	version, err := ctxx.RemoteVersion(module)
+	if err != nil {
+		return "", err
+	}
+
+	configPath, err := store.Config(module, version)
+	if err != nil {
+		return "", err
+	}
+	ctxx.UseRemoteConfig(configPath)
+	if err := checkShared(module, configPath, importerFullPath, ctxx); err != nil {
+		return "", err
+	}
+
+	modulePath, err := store.File(module, version, module.Path+EXT, nil)
+	if errors.Is(err, remote.ErrNotFound) {
+		return "", fmt.Errorf("module not found: %s", importPath)
+	}
+	return modulePath, err
+}
+
+// resolveVendored returns the vendored copy of a remote module and of the .ferret.json of its repository,
+// found is false when ferret.lock does not pin it or it is not vendored, so it is resolved through the cache.
+// Vendored copies are checked against ferret.lock, and need neither the cache nor the network.
+func resolveVendored(module remote.Module, ctxx *ctx.CompilerContext) (modulePath, configPath string, found bool, err error) {
+	lock, err := ctxx.LockFile()
+	if err != nil {
+		return "", "", false, err
+	}
+	entry, pinned := lock.Get(deps.LockPath(module, module.Path+EXT))
+	if !pinned {
+		return "", "", false, nil
+	}
+	modulePath, found, err = deps.ReadVendored(ctxx.ProjectRoot, entry)
+	if !found || err != nil {
+		return "", "", found, err
+	}
+	if entry, pinned := lock.Get(deps.LockPath(module, config.CONFIG_FILE)); pinned {
+		vendoredConfig, found, err := deps.ReadVendored(ctxx.ProjectRoot, entry)
+		if err != nil {
+			return "", "", true, err
+		}
+		if found {
+			ctxx.UseRemoteConfig(vendoredConfig)
+			configPath = vendoredConfig
+		}
+	}
+	return modulePath, configPath, true, nil
+}
+
+// ShareError is an import of a module its repository does not share. It is located in the .ferret.json
+// of the repository, at the setting that keeps the module private.
+type ShareError struct {
+	Module     string // the import path
+	ConfigPath string
+	Location   *source.Location
+	Reason     string
+}
+
+func (e *ShareError) Error() string {
+	return fmt.Sprintf("%s is private to its repository, %s", e.Module, e.Reason)
+}
+
+// checkShared refuses a module of another repository that its .ferret.json does not share, see
+// config.ProjectConfig.Shares. The files of a repository import its private modules freely.
+func checkShared(module remote.Module, configPath, importerFullPath string, ctxx *ctx.CompilerContext) error {
+	if importer, err := remote.ParseModule(ctxx.FullPathToImportPath(importerFullPath)); err == nil && importer.RepoPath() == module.RepoPath() {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.FromSlash(configPath))
+	if err != nil {
+		return err
+	}
+	var repoConfig config.ProjectConfig
+	if err := json.Unmarshal(data, &repoConfig); err != nil {
+		return fmt.Errorf("invalid %s of %s: %w", config.CONFIG_FILE, module.RepoPath(), err)
+	}
+	if repoConfig.Shares(module.Path) {
+		return nil
+	}
+
+	shareErr := &ShareError{Module: module.RepoPath() + "/" + module.Path, ConfigPath: configPath}
+	if len(repoConfig.Remote.Exports) > 0 {
+		shareErr.Location = config.Locate(data, "remote", "exports")
+		shareErr.Reason = fmt.Sprintf("'%s' is not in its remote.exports", module.Path)
+	} else {
+		shareErr.Location = config.Locate(data, "remote", "share")
+		shareErr.Reason = "its remote.share is false and it lists no remote.exports"
+	}
+	return shareErr
+}
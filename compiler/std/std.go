@@ -0,0 +1,76 @@
+// Package std bundles the Ferret standard library into the compiler binary.
+// The modules are written out to disk on first import, so they are parsed and
+// reported on like any other file.
+package std
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Root is the first part of every standard library import path, e.g. "std/fmt"
+const Root = "std"
+
+const ext = ".fer"
+
+//go:embed *.fer
+var files embed.FS
+
+// Source returns the source of a standard library module, name is the import path without "std/"
+func Source(name string) ([]byte, bool) {
+	data, err := files.ReadFile(name + ext)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Modules returns the names of every standard library module, sorted
+func Modules() []string {
+	entries, _ := fs.ReadDir(files, ".")
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ext))
+	}
+	return names
+}
+
+// Extract writes a standard library module into dir and returns the path of the file.
+// An up to date file is left alone, so the modules are only written when the compiler changes.
+func Extract(dir, name string) (string, error) {
+	data, ok := Source(name)
+	if !ok {
+		return "", fmt.Errorf("standard library module not found: %s/%s", Root, name)
+	}
+
+	path := filepath.ToSlash(filepath.Join(dir, name+ext))
+	if existing, err := os.ReadFile(filepath.FromSlash(path)); err == nil && bytes.Equal(existing, data) {
+		return path, nil
+	}
+	if err := os.MkdirAll(filepath.FromSlash(dir), 0755); err != nil {
+		return "", err
+	}
+
+	// Written next to the target and renamed, so a compiler running at the same time never reads half a file
+	tmp, err := os.CreateTemp(filepath.FromSlash(dir), name+"-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), filepath.FromSlash(path)); err != nil {
+		return "", err
+	}
+	return path, nil
+}
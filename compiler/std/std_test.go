@@ -0,0 +1,46 @@
+package std
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestModules(t *testing.T) {
+	want := []string{"fmt", "io", "math", "strings"}
+	if got := Modules(); !slices.Equal(got, want) {
+		t.Errorf("Modules() = %v, want %v", got, want)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	dir := filepath.ToSlash(t.TempDir())
+
+	path, err := Extract(dir, "fmt")
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if path != dir+"/fmt.fer" {
+		t.Errorf("Extract() = %q, want %q", path, dir+"/fmt.fer")
+	}
+	source, _ := Source("fmt")
+	if data, err := os.ReadFile(path); err != nil || string(data) != string(source) {
+		t.Fatalf("extracted file does not match the embedded source, err = %v", err)
+	}
+
+	// A stale file is replaced
+	if err := os.WriteFile(path, []byte("fn old();"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Extract(dir, "fmt"); err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != string(source) {
+		t.Error("expected a stale file to be rewritten")
+	}
+
+	if _, err := Extract(dir, "nothing"); err == nil {
+		t.Error("expected an error for an unknown module")
+	}
+}